@@ -2,6 +2,7 @@ package autobet
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,6 +19,17 @@ type AutoBet struct {
 	BalanceFraction decimal.Decimal
 }
 
+// Rejection is returned by the better when it refuses to place the bet,
+// such as when the user has insufficient funds or a limit is exceeded.
+type Rejection struct {
+	Code    string
+	Message string
+}
+
+func (r Rejection) Error() string {
+	return r.Message
+}
+
 type Worker struct {
 	db     DB
 	better Better
@@ -68,7 +80,7 @@ func (w *Worker) process() error {
 			return err
 		}
 
-		if !ok {
+		if !ok || u.Excluded(time.Now()) {
 			continue
 		}
 
@@ -86,17 +98,23 @@ func (w *Worker) process() error {
 			continue
 		}
 
-		mx := bet.WinnerHome
-		mxOdds := sel.OddsHome
-
-		if !a.HighRisk && sel.OddsAway.LessThan(sel.OddsHome) {
-			mx = bet.WinnerAway
-			mxOdds = sel.OddsAway
+		if len(sel.Outcomes) == 0 {
+			continue
 		}
 
-		if a.HighRisk && sel.OddsAway.GreaterThan(sel.OddsAway) {
-			mx = bet.WinnerAway
-			mxOdds = sel.OddsAway
+		mx := sel.Outcomes[0].Winner
+		mxOdds := sel.Outcomes[0].Odds
+
+		for _, o := range sel.Outcomes[1:] {
+			if !a.HighRisk && o.Odds.LessThan(mxOdds) {
+				mx = o.Winner
+				mxOdds = o.Odds
+			}
+
+			if a.HighRisk && o.Odds.GreaterThan(mxOdds) {
+				mx = o.Winner
+				mxOdds = o.Odds
+			}
 		}
 
 		b := bet.Bet{
@@ -111,6 +129,12 @@ func (w *Worker) process() error {
 		}
 
 		if err := w.better.Bet(context.Background(), &b, &u); err != nil {
+			var rej Rejection
+			if errors.As(err, &rej) {
+				w.log.Warn().Str("user_uuid", a.UserUUID.String()).Str("code", rej.Code).Str("reason", rej.Message).Msg("bet rejected")
+				continue
+			}
+
 			return err
 		}
 
@@ -25,7 +25,12 @@ func (a *autobetDB) FetchSelectionBest(ctx context.Context, highRisk bool) (bet.
 		return bet.EventSelection{}, false, nil
 	}
 
-	return decodeSelection(sel), true, nil
+	decoded, err := fillSelection(ctx, a.db, a.db.NoTX(), sel)
+	if err != nil {
+		return bet.EventSelection{}, false, err
+	}
+
+	return decoded, true, nil
 }
 
 func (a *autobetDB) FetchAutoBets(ctx context.Context) ([]autobet.AutoBet, error) {
@@ -62,6 +67,17 @@ func (a *autobetDB) FetchBetUser(ctx context.Context, id uuid.UUID) (user.BetUse
 }
 
 func (a *autobetDB) Bet(ctx context.Context, b *bet.Bet, au *user.BetUser) error {
-	_, err := a.bet.Bet(ctx, b, au)
-	return err
+	resp, err := a.bet.Bet(ctx, b, bet.OddsPolicyAny, au)
+	if err != nil {
+		return err
+	}
+
+	if !resp.Ok {
+		return autobet.Rejection{
+			Code:    string(resp.ErrorCode),
+			Message: resp.ErrorMessage,
+		}
+	}
+
+	return nil
 }
@@ -50,7 +50,8 @@ func (o *oddsWorker) updateOdds() error {
 	}
 
 	for _, s := range sels {
-		if !s.AutoOdds {
+		// Markets that are suspended or settled keep their odds.
+		if !s.AutoOdds || s.Winner.Finalized() || s.Status != bet.MarketStatusOpen {
 			continue
 		}
 
@@ -63,33 +64,20 @@ func (o *oddsWorker) updateOdds() error {
 			continue
 		}
 
-		var (
-			awayCnt int
-			homeCnt int
-		)
+		cnt := make(map[bet.Winner]int64, len(s.Outcomes))
 
 		for _, b := range bb {
-			if b.SelectionWinner == bet.WinnerAway {
-				awayCnt++
-			}
-
-			if b.SelectionWinner == bet.WinnerHome {
-				homeCnt++
-			}
+			cnt[b.SelectionWinner]++
 		}
 
 		tot := decimal.NewFromInt(int64(len(bb)))
 
-		away := decimal.NewFromInt(int64(awayCnt))
-		home := decimal.NewFromInt(int64(homeCnt))
-
-		homeOdds := lerp(decimal.NewFromFloat(1), decimal.NewFromFloat(3), home.Div(tot))
-		awayOdds := lerp(decimal.NewFromFloat(1), decimal.NewFromFloat(3), away.Div(tot))
-
-		s.OddsHome = homeOdds
-		s.OddsAway = awayOdds
+		for _, out := range s.Outcomes {
+			share := decimal.NewFromInt(cnt[out.Winner]).Div(tot)
+			s.SetOdds(out.Winner, lerp(decimal.NewFromFloat(1), decimal.NewFromFloat(3), share))
+		}
 
-		if err := o.db.UpdateSelection(context.Background(), s); err != nil {
+		if err := o.db.UpdateSelectionOdds(context.Background(), s); err != nil {
 			return err
 		}
 
@@ -106,5 +94,7 @@ func lerp(v0, v1, t decimal.Decimal) decimal.Decimal {
 type OddsDB interface {
 	FetchSelections(context.Context) ([]bet.EventSelection, error)
 	FetchBetsBySelection(context.Context, uuid.UUID) ([]bet.Bet, error)
-	UpdateSelection(context.Context, bet.EventSelection) error
+	// UpdateSelectionOdds stores the odds of the outcomes of the selection
+	// only, leaving its status and winner as they are.
+	UpdateSelectionOdds(context.Context, bet.EventSelection) error
 }
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ramasauskas/ispbet/bet"
+	"github.com/rs/zerolog"
+	"github.com/shopspring/decimal"
+)
+
+// oddsDB serves one selection with its bets and records the odds
+// updates.
+type oddsDB struct {
+	sel     bet.EventSelection
+	bets    []bet.Bet
+	updated []bet.EventSelection
+}
+
+func (d *oddsDB) FetchSelections(context.Context) ([]bet.EventSelection, error) {
+	return []bet.EventSelection{d.sel}, nil
+}
+
+func (d *oddsDB) FetchBetsBySelection(context.Context, uuid.UUID) ([]bet.Bet, error) {
+	return d.bets, nil
+}
+
+func (d *oddsDB) UpdateSelectionOdds(_ context.Context, s bet.EventSelection) error {
+	d.updated = append(d.updated, s)
+	return nil
+}
+
+func TestOddsWorkerUpdateOdds(t *testing.T) {
+	tests := map[string]struct {
+		autoOdds bool
+		status   bet.MarketStatus
+		winner   bet.Winner
+		bets     int
+		// odds are the home and away odds stored, none if not updated.
+		odds []string
+	}{
+		"open market": {
+			autoOdds: true,
+			status:   bet.MarketStatusOpen,
+			winner:   bet.WinnerTBD,
+			bets:     4,
+			odds:     []string{"2.5", "1.5"},
+		},
+		"too few bets": {
+			autoOdds: true,
+			status:   bet.MarketStatusOpen,
+			winner:   bet.WinnerTBD,
+			bets:     1,
+		},
+		"manual odds": {
+			autoOdds: false,
+			status:   bet.MarketStatusOpen,
+			winner:   bet.WinnerTBD,
+			bets:     4,
+		},
+		"suspended market": {
+			autoOdds: true,
+			status:   bet.MarketStatusSuspended,
+			winner:   bet.WinnerTBD,
+			bets:     4,
+		},
+		"settled market": {
+			autoOdds: true,
+			status:   bet.MarketStatusOpen,
+			winner:   bet.WinnerHome,
+			bets:     4,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := &oddsDB{
+				sel: bet.EventSelection{
+					UUID:     uuid.New(),
+					Type:     bet.MarketTypeMatchWinner,
+					Status:   test.status,
+					Winner:   test.winner,
+					AutoOdds: test.autoOdds,
+					Outcomes: []bet.Outcome{
+						{Winner: bet.WinnerHome, Odds: decimal.NewFromInt(2)},
+						{Winner: bet.WinnerAway, Odds: decimal.NewFromInt(2)},
+					},
+				},
+			}
+
+			// Three in four bets are on home.
+			for i := 0; i < test.bets; i++ {
+				w := bet.WinnerHome
+				if i%4 == 3 {
+					w = bet.WinnerAway
+				}
+
+				d.bets = append(d.bets, bet.Bet{SelectionWinner: w})
+			}
+
+			if err := newOddsWorker(d, zerolog.Nop()).updateOdds(); err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+
+			if test.odds == nil {
+				if len(d.updated) != 0 {
+					t.Fatalf("want no update, got %d", len(d.updated))
+				}
+
+				return
+			}
+
+			if len(d.updated) != 1 {
+				t.Fatalf("want 1 update, got %d", len(d.updated))
+			}
+
+			for i, w := range []bet.Winner{bet.WinnerHome, bet.WinnerAway} {
+				if odds := d.updated[0].Odds(w); !odds.Equal(decimal.RequireFromString(test.odds[i])) {
+					t.Errorf("want %s odds %s, got %s", w, test.odds[i], odds)
+				}
+			}
+		})
+	}
+}
@@ -21,7 +21,12 @@ func (a *autoOddsDB) FetchSelections(ctx context.Context) ([]bet.EventSelection,
 	var decoded []bet.EventSelection
 
 	for _, s := range sels {
-		decoded = append(decoded, decodeSelection(s))
+		sel, err := fillSelection(ctx, a.db, a.db.NoTX(), s)
+		if err != nil {
+			return nil, err
+		}
+
+		decoded = append(decoded, sel)
 	}
 
 	return decoded, nil
@@ -42,6 +47,19 @@ func (a *autoOddsDB) FetchBetsBySelection(ctx context.Context, id uuid.UUID) ([]
 	return decoded, nil
 }
 
-func (a *autoOddsDB) UpdateSelection(ctx context.Context, s bet.EventSelection) error {
-	return a.db.UpdateSelection(ctx, a.db.NoTX(), encodeSelection(s, s.EventUUID))
+func (a *autoOddsDB) UpdateSelectionOdds(ctx context.Context, s bet.EventSelection) error {
+	tx, err := a.db.NewTX(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	for _, o := range encodeSelectionOutcomes(s) {
+		if err := a.db.UpdateSelectionOutcome(ctx, tx, o); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
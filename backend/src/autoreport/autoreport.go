@@ -61,7 +61,12 @@ func (w *Worker) processReport(r report.AutoReport) error {
 			return err
 		}
 
-		if err := w.sender.SendEmail(context.Background(), r.SendTo, fmt.Sprintf("Depsits sum: %s", &deposits)); err != nil {
+		msg := fmt.Sprintf("Depsits sum: %s %s", deposits.Total, deposits.Currency)
+		for _, ca := range deposits.ByCurrency {
+			msg += fmt.Sprintf("\n %s: %s", ca.Currency, ca.Amount)
+		}
+
+		if err := w.sender.SendEmail(context.Background(), r.SendTo, msg); err != nil {
 			return err
 		}
 
@@ -75,7 +80,10 @@ func (w *Worker) processReport(r report.AutoReport) error {
 		return err
 	}
 
-	msg := fmt.Sprintf("Profit: %s\n Loss: %s\n Total profit: %s", reports.Profit, reports.Loss, reports.Final)
+	msg := fmt.Sprintf("Profit: %s\n Loss: %s\n Total profit: %s %s", reports.Profit, reports.Loss, reports.Final, reports.Currency)
+	for _, ca := range reports.ByCurrency {
+		msg += fmt.Sprintf("\n %s: %s", ca.Currency, ca.Amount)
+	}
 
 	return w.sender.SendEmail(context.Background(), r.SendTo, msg)
 }
@@ -84,15 +92,32 @@ func (w *Worker) Close() {
 	w.cr.Stop()
 }
 
+// ProfitReport holds the profit converted to the base currency and the
+// total profit made in each currency.
 type ProfitReport struct {
-	Profit decimal.Decimal
-	Loss   decimal.Decimal
-	Final  decimal.Decimal
+	Currency   string
+	Profit     decimal.Decimal
+	Loss       decimal.Decimal
+	Final      decimal.Decimal
+	ByCurrency []CurrencyAmount
+}
+
+// DepositReport holds the deposits converted to the base currency and the
+// deposits made in each currency.
+type DepositReport struct {
+	Currency   string
+	Total      decimal.Decimal
+	ByCurrency []CurrencyAmount
+}
+
+type CurrencyAmount struct {
+	Currency string
+	Amount   decimal.Decimal
 }
 
 type DB interface {
 	FetchAutoReports(context.Context) ([]report.AutoReport, error)
-	FetchTotalDeposits(ctx context.Context, from, to time.Time) (decimal.Decimal, error)
+	FetchTotalDeposits(ctx context.Context, from, to time.Time) (DepositReport, error)
 	FetchProfitReport(ctx context.Context, from, to time.Time) (ProfitReport, error)
 }
 
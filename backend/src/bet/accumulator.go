@@ -0,0 +1,161 @@
+package bet
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ramasauskas/ispbet/purse"
+	"github.com/shopspring/decimal"
+)
+
+type AccumulatorLeg struct {
+	UUID            uuid.UUID
+	SelectionUUID   uuid.UUID
+	SelectionWinner Winner
+	Odds            decimal.Decimal
+	State           BetState
+}
+
+// resolve settles the leg if it is still open, a settled leg has to be
+// reopened before it can be resolved again.
+func (l *AccumulatorLeg) resolve(sel EventSelection) {
+	if l.State != BetStateTBD {
+		return
+	}
+
+	if st := sel.Settle(l.SelectionWinner); st != BetStateTBD {
+		l.State = st
+	}
+}
+
+type Accumulator struct {
+	UUID      uuid.UUID
+	UserUUID  uuid.UUID
+	Legs      []AccumulatorLeg
+	Stake     decimal.Decimal
+	Odds      decimal.Decimal
+	State     BetState
+	Timestamp time.Time
+	Currency  purse.Currency
+}
+
+func (a Accumulator) Validate() error {
+	if len(a.Legs) < 2 {
+		return errors.New("accumulator must have at least 2 legs")
+	}
+
+	if a.Stake.LessThanOrEqual(decimal.Zero) {
+		return errors.New("stake cannot be less than or equal to 0")
+	}
+
+	seen := make(map[uuid.UUID]struct{}, len(a.Legs))
+
+	for _, l := range a.Legs {
+		if !l.SelectionWinner.Finalized() || l.SelectionWinner == WinnnerNone {
+			return errors.New("invalid leg winner")
+		}
+
+		if _, ok := seen[l.SelectionUUID]; ok {
+			return errors.New("selection used in more than one leg")
+		}
+
+		seen[l.SelectionUUID] = struct{}{}
+	}
+
+	return nil
+}
+
+// CombinedOdds is the product of the odds of all legs that were not
+// voided.
+func (a Accumulator) CombinedOdds() decimal.Decimal {
+	odds := decimal.NewFromInt(1)
+
+	for _, l := range a.Legs {
+		if l.State == BetStateVoid {
+			continue
+		}
+
+		odds = odds.Mul(l.Odds)
+	}
+
+	return odds
+}
+
+// Resolve settles the legs placed on the provided selection. The
+// accumulator itself is lost as soon as any leg is lost, and is won (or
+// voided, if every leg was voided) only once all legs are settled.
+func (a *Accumulator) Resolve(sel EventSelection) {
+	if !sel.Winner.Finalized() {
+		return
+	}
+
+	for i := range a.Legs {
+		if a.Legs[i].SelectionUUID == sel.UUID {
+			a.Legs[i].resolve(sel)
+		}
+	}
+
+	if a.State != BetStateTBD {
+		return
+	}
+
+	var (
+		settled = true
+		void    = true
+	)
+
+	for _, l := range a.Legs {
+		switch l.State {
+		case BetStateLost:
+			a.State = BetStateLost
+			return
+		case BetStateTBD:
+			settled = false
+		case BetStateWon:
+			void = false
+		}
+	}
+
+	if !settled {
+		return
+	}
+
+	if void {
+		a.State = BetStateVoid
+		return
+	}
+
+	a.State = BetStateWon
+}
+
+// Unsettle reopens the legs placed on the selection. The accumulator stays
+// lost if any other leg is lost and is open again otherwise.
+func (a *Accumulator) Unsettle(sel uuid.UUID) {
+	for i := range a.Legs {
+		if a.Legs[i].SelectionUUID == sel {
+			a.Legs[i].State = BetStateTBD
+		}
+	}
+
+	a.State = BetStateTBD
+
+	for _, l := range a.Legs {
+		if l.State == BetStateLost {
+			a.State = BetStateLost
+			return
+		}
+	}
+}
+
+// Payout returns the amount owed to the user for a settled accumulator.
+func (a Accumulator) Payout() decimal.Decimal {
+	switch a.State {
+	case BetStateWon:
+		return a.Stake.Mul(a.CombinedOdds())
+	case BetStateVoid:
+		return a.Stake
+	default:
+		return decimal.Zero
+	}
+}
@@ -0,0 +1,195 @@
+package bet
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestAccumulatorCombinedOdds(t *testing.T) {
+	leg := func(odds string, st BetState) AccumulatorLeg {
+		return AccumulatorLeg{
+			SelectionUUID: uuid.New(),
+			Odds:          decimal.RequireFromString(odds),
+			State:         st,
+		}
+	}
+
+	tests := map[string]struct {
+		legs   []AccumulatorLeg
+		odds   string
+		state  BetState
+		payout string
+	}{
+		"no void legs": {
+			legs:   []AccumulatorLeg{leg("2", BetStateWon), leg("1.5", BetStateWon), leg("3", BetStateWon)},
+			odds:   "9",
+			state:  BetStateWon,
+			payout: "90",
+		},
+		"void leg left out": {
+			legs:   []AccumulatorLeg{leg("2", BetStateWon), leg("1.5", BetStateVoid), leg("3", BetStateWon)},
+			odds:   "6",
+			state:  BetStateWon,
+			payout: "60",
+		},
+		"all but one leg void": {
+			legs:   []AccumulatorLeg{leg("2", BetStateVoid), leg("1.5", BetStateWon), leg("3", BetStateVoid)},
+			odds:   "1.5",
+			state:  BetStateWon,
+			payout: "15",
+		},
+		"every leg void": {
+			legs:   []AccumulatorLeg{leg("2", BetStateVoid), leg("3", BetStateVoid)},
+			odds:   "1",
+			state:  BetStateVoid,
+			payout: "10",
+		},
+		"lost leg": {
+			legs:   []AccumulatorLeg{leg("2", BetStateVoid), leg("3", BetStateLost)},
+			odds:   "3",
+			state:  BetStateLost,
+			payout: "0",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			acc := Accumulator{
+				Legs:  test.legs,
+				Stake: decimal.NewFromInt(10),
+				State: test.state,
+			}
+
+			if odds := acc.CombinedOdds(); !odds.Equal(decimal.RequireFromString(test.odds)) {
+				t.Errorf("want odds %s, got %s", test.odds, odds)
+			}
+
+			if payout := acc.Payout(); !payout.Equal(decimal.RequireFromString(test.payout)) {
+				t.Errorf("want payout %s, got %s", test.payout, payout)
+			}
+		})
+	}
+}
+
+func TestAccumulatorResolveAndUnsettle(t *testing.T) {
+	sel := func(w Winner) EventSelection {
+		return EventSelection{
+			UUID:   uuid.New(),
+			Type:   MarketTypeMatchWinner,
+			Winner: w,
+		}
+	}
+
+	tests := map[string]struct {
+		results []Winner
+		// reopen is the index of the selection resolved again.
+		reopen   int
+		resolved BetState
+		reopened BetState
+	}{
+		"won accumulator reopens": {
+			results:  []Winner{WinnerHome, WinnerHome},
+			reopen:   0,
+			resolved: BetStateWon,
+			reopened: BetStateTBD,
+		},
+		"lost leg reopened": {
+			results:  []Winner{WinnerAway, WinnerHome},
+			reopen:   0,
+			resolved: BetStateLost,
+			reopened: BetStateTBD,
+		},
+		"other leg still lost": {
+			results:  []Winner{WinnerAway, WinnerHome},
+			reopen:   1,
+			resolved: BetStateLost,
+			reopened: BetStateLost,
+		},
+		"void accumulator reopens": {
+			results:  []Winner{WinnnerNone, WinnnerNone},
+			reopen:   1,
+			resolved: BetStateVoid,
+			reopened: BetStateTBD,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var (
+				acc  = Accumulator{Stake: decimal.NewFromInt(10), State: BetStateTBD}
+				sels []EventSelection
+			)
+
+			for _, w := range test.results {
+				s := sel(w)
+				sels = append(sels, s)
+
+				acc.Legs = append(acc.Legs, AccumulatorLeg{
+					SelectionUUID:   s.UUID,
+					SelectionWinner: WinnerHome,
+					Odds:            decimal.NewFromInt(2),
+					State:           BetStateTBD,
+				})
+			}
+
+			for _, s := range sels {
+				acc.Resolve(s)
+			}
+
+			if acc.State != test.resolved {
+				t.Fatalf("want resolved state %s, got %s", test.resolved, acc.State)
+			}
+
+			acc.Unsettle(sels[test.reopen].UUID)
+
+			if acc.State != test.reopened {
+				t.Fatalf("want reopened state %s, got %s", test.reopened, acc.State)
+			}
+
+			if st := acc.Legs[test.reopen].State; st != BetStateTBD {
+				t.Errorf("want reopened leg open, got %s", st)
+			}
+
+			// Resolving the selection again must settle the accumulator
+			// the same way as before.
+			acc.Resolve(sels[test.reopen])
+
+			if acc.State != test.resolved {
+				t.Errorf("want state %s after resolving again, got %s", test.resolved, acc.State)
+			}
+		})
+	}
+}
+
+func TestAccumulatorResolveSettledLeg(t *testing.T) {
+	tests := map[string]struct {
+		leg    BetState
+		winner Winner
+	}{
+		"won leg resolved as lost": {leg: BetStateWon, winner: WinnerAway},
+		"lost leg resolved as won": {leg: BetStateLost, winner: WinnerHome},
+		"void leg resolved as won": {leg: BetStateVoid, winner: WinnerHome},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sel := EventSelection{UUID: uuid.New(), Type: MarketTypeMatchWinner, Winner: test.winner}
+
+			acc := Accumulator{
+				State: BetStateTBD,
+				Legs: []AccumulatorLeg{
+					{SelectionUUID: sel.UUID, SelectionWinner: WinnerHome, State: test.leg},
+					{SelectionUUID: uuid.New(), SelectionWinner: WinnerHome, State: BetStateTBD},
+				},
+			}
+
+			acc.Resolve(sel)
+
+			if acc.Legs[0].State != test.leg {
+				t.Errorf("want leg kept %s, got %s", test.leg, acc.Legs[0].State)
+			}
+		})
+	}
+}
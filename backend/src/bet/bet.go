@@ -1,9 +1,11 @@
 package bet
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ramasauskas/ispbet/purse"
 	"github.com/shopspring/decimal"
 )
 
@@ -13,6 +15,9 @@ const (
 	BetStateTBD  BetState = "tbd"
 	BetStateWon  BetState = "won"
 	BetStateLost BetState = "lost"
+	BetStateVoid BetState = "void"
+
+	BetStateCashedOut BetState = "cashed_out"
 )
 
 type Bet struct {
@@ -23,18 +28,175 @@ type Bet struct {
 	Stake           decimal.Decimal
 	Odds            decimal.Decimal
 	State           BetState
+	CashOutAmount   decimal.Decimal
 	Timestamp       time.Time
+	// Currency of the stake and payout, the one of the user's wallet.
+	Currency purse.Currency
+	// TokenUUID is the bonus token used to place the bet, if any.
+	TokenUUID uuid.UUID
+	// FreeBet is set when the stake was not paid by the user, so that it is
+	// neither returned with the winnings nor refunded.
+	FreeBet bool
 }
 
-func (b *Bet) Resolve(winner Winner) {
-	if winner == WinnerTBD {
-		return
+// OddsPolicy tells how a bet placed at the odds the user saw is handled
+// when the price changed before it was accepted.
+type OddsPolicy string
+
+const (
+	OddsPolicyExact  OddsPolicy = "exact"
+	OddsPolicyHigher OddsPolicy = "higher"
+	OddsPolicyAny    OddsPolicy = "any"
+)
+
+func (p OddsPolicy) Validate() error {
+	switch p {
+	case OddsPolicyExact, OddsPolicyHigher, OddsPolicyAny:
+		return nil
+	default:
+		return errors.New("invalid odds policy, must be exact, higher or any")
+	}
+}
+
+// Accepts reports whether a bet seen at the given odds may be placed at
+// the current odds.
+func (p OddsPolicy) Accepts(seen, current decimal.Decimal) bool {
+	switch p {
+	case OddsPolicyAny:
+		return true
+	case OddsPolicyHigher:
+		return current.GreaterThanOrEqual(seen)
+	default:
+		return current.Equal(seen)
 	}
+}
 
-	if winner == b.SelectionWinner {
-		b.State = BetStateWon
+func (b *Bet) Resolve(sel EventSelection) {
+	if b.State != BetStateTBD {
 		return
 	}
 
-	b.State = BetStateLost
+	if st := sel.Settle(b.SelectionWinner); st != BetStateTBD {
+		b.State = st
+	}
+}
+
+// Payout returns the amount owed to the user for a settled bet.
+func (b Bet) Payout() decimal.Decimal {
+	switch b.State {
+	case BetStateWon:
+		if b.FreeBet {
+			return b.Stake.Mul(b.Odds).Sub(b.Stake)
+		}
+
+		return b.Stake.Mul(b.Odds)
+	case BetStateVoid:
+		if b.FreeBet {
+			return decimal.Zero
+		}
+
+		return b.Stake
+	default:
+		return decimal.Zero
+	}
+}
+
+// Unsettle reopens a settled bet so that it can be resolved again. Cashed
+// out bets are not affected by the result and cannot be reopened.
+func (b *Bet) Unsettle() error {
+	switch b.State {
+	case BetStateWon, BetStateLost, BetStateVoid:
+	default:
+		return errors.New("bet is not settled")
+	}
+
+	b.State = BetStateTBD
+
+	return nil
+}
+
+// CashOutValue prices an open bet by comparing the odds it was placed at
+// with the current odds of its outcome. The margin is the fraction of the
+// fair value kept by the house.
+func (b Bet) CashOutValue(sel EventSelection, margin decimal.Decimal) (decimal.Decimal, error) {
+	if b.State != BetStateTBD {
+		return decimal.Zero, errors.New("bet already settled")
+	}
+
+	if b.TokenUUID != uuid.Nil {
+		return decimal.Zero, errors.New("bets placed with a bonus token cannot be cashed out")
+	}
+
+	if sel.Winner.Finalized() {
+		return decimal.Zero, errors.New("selection already finalized")
+	}
+
+	if sel.Status != MarketStatusOpen {
+		return decimal.Zero, errors.New("market is " + string(sel.Status))
+	}
+
+	current := sel.Odds(b.SelectionWinner)
+	if current.LessThanOrEqual(decimal.Zero) {
+		return decimal.Zero, errors.New("selection no longer offers this outcome")
+	}
+
+	fair := b.Stake.Mul(b.Odds).Div(current)
+
+	return fair.Mul(decimal.NewFromInt(1).Sub(margin)).RoundDown(2), nil
+}
+
+// CashOut settles the bet for the given amount.
+func (b *Bet) CashOut(amount decimal.Decimal) {
+	b.State = BetStateCashedOut
+	b.CashOutAmount = amount
+}
+
+// Wagered sums the stakes the user paid for the bets and accumulators
+// placed from since on.
+func Wagered(bb []Bet, accs []Accumulator, since time.Time) decimal.Decimal {
+	sum := decimal.Zero
+
+	for _, b := range bb {
+		if !b.FreeBet && !b.Timestamp.Before(since) {
+			sum = sum.Add(b.Stake)
+		}
+	}
+
+	for _, acc := range accs {
+		if !acc.Timestamp.Before(since) {
+			sum = sum.Add(acc.Stake)
+		}
+	}
+
+	return sum
+}
+
+// Lost sums the stakes the user paid for the bets and accumulators placed
+// from since on, less what they returned. The stakes of open bets count
+// as lost until the bets are settled.
+func Lost(bb []Bet, accs []Accumulator, since time.Time) decimal.Decimal {
+	sum := decimal.Zero
+
+	for _, b := range bb {
+		if b.FreeBet || b.Timestamp.Before(since) {
+			continue
+		}
+
+		sum = sum.Add(b.Stake)
+
+		if b.State == BetStateCashedOut {
+			sum = sum.Sub(b.CashOutAmount)
+			continue
+		}
+
+		sum = sum.Sub(b.Payout())
+	}
+
+	for _, acc := range accs {
+		if !acc.Timestamp.Before(since) {
+			sum = sum.Add(acc.Stake).Sub(acc.Payout())
+		}
+	}
+
+	return sum
 }
@@ -0,0 +1,162 @@
+package bet
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestBetPayout(t *testing.T) {
+	tests := map[string]struct {
+		state   BetState
+		freeBet bool
+		payout  string
+	}{
+		"won":           {state: BetStateWon, payout: "25"},
+		"lost":          {state: BetStateLost, payout: "0"},
+		"void":          {state: BetStateVoid, payout: "10"},
+		"open":          {state: BetStateTBD, payout: "0"},
+		"free bet won":  {state: BetStateWon, freeBet: true, payout: "15"},
+		"free bet lost": {state: BetStateLost, freeBet: true, payout: "0"},
+		"free bet void": {state: BetStateVoid, freeBet: true, payout: "0"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := Bet{
+				Stake:   decimal.NewFromInt(10),
+				Odds:    decimal.RequireFromString("2.5"),
+				State:   test.state,
+				FreeBet: test.freeBet,
+			}
+
+			if payout := b.Payout(); !payout.Equal(decimal.RequireFromString(test.payout)) {
+				t.Errorf("want payout %s, got %s", test.payout, payout)
+			}
+		})
+	}
+}
+
+func TestBetUnsettle(t *testing.T) {
+	tests := map[string]struct {
+		state BetState
+		err   bool
+	}{
+		"won":        {state: BetStateWon},
+		"lost":       {state: BetStateLost},
+		"void":       {state: BetStateVoid},
+		"open":       {state: BetStateTBD, err: true},
+		"cashed out": {state: BetStateCashedOut, err: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := Bet{State: test.state}
+
+			err := b.Unsettle()
+			if test.err {
+				if err == nil {
+					t.Fatal("want error, got nil")
+				}
+
+				if b.State != test.state {
+					t.Errorf("want state %s kept, got %s", test.state, b.State)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+
+			if b.State != BetStateTBD {
+				t.Errorf("want state %s, got %s", BetStateTBD, b.State)
+			}
+
+			if !b.Payout().IsZero() {
+				t.Errorf("want no payout for a reopened bet, got %s", b.Payout())
+			}
+		})
+	}
+}
+
+func TestBetCashOutValue(t *testing.T) {
+	sel := EventSelection{
+		Type:   MarketTypeMatchWinner,
+		Status: MarketStatusOpen,
+		Winner: WinnerTBD,
+		Outcomes: []Outcome{
+			{Winner: WinnerHome, Odds: decimal.NewFromInt(4)},
+			{Winner: WinnerAway, Odds: decimal.NewFromInt(2)},
+		},
+	}
+
+	tests := map[string]struct {
+		bet   Bet
+		value string
+		err   bool
+	}{
+		"open bet": {
+			bet: Bet{
+				SelectionWinner: WinnerHome,
+				Stake:           decimal.NewFromInt(10),
+				Odds:            decimal.NewFromInt(2),
+				State:           BetStateTBD,
+			},
+			value: "4.5",
+		},
+		"bonus token bet": {
+			bet: Bet{
+				SelectionWinner: WinnerHome,
+				Stake:           decimal.NewFromInt(10),
+				Odds:            decimal.NewFromInt(2),
+				State:           BetStateTBD,
+				TokenUUID:       uuid.New(),
+			},
+			err: true,
+		},
+		"free bet placed with a token": {
+			bet: Bet{
+				SelectionWinner: WinnerHome,
+				Stake:           decimal.NewFromInt(10),
+				Odds:            decimal.NewFromInt(2),
+				State:           BetStateTBD,
+				TokenUUID:       uuid.New(),
+				FreeBet:         true,
+			},
+			err: true,
+		},
+		"settled bet": {
+			bet: Bet{
+				SelectionWinner: WinnerHome,
+				Stake:           decimal.NewFromInt(10),
+				Odds:            decimal.NewFromInt(2),
+				State:           BetStateWon,
+			},
+			err: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			value, err := test.bet.CashOutValue(sel, decimal.RequireFromString("0.1"))
+			if test.err {
+				if err == nil {
+					t.Fatalf("want error, got value %s", value)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+
+			if !value.Equal(decimal.RequireFromString(test.value)) {
+				t.Errorf("want value %s, got %s", test.value, value)
+			}
+		})
+	}
+}
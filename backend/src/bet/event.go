@@ -15,26 +15,24 @@ const (
 	SportFootball   Sport = "football"
 )
 
+// Winner names the outcome of a market. Match winner markets use home,
+// away and draw, other market types define their own outcome names.
 type Winner string
 
-func (w Winner) Validate() error {
-	switch w {
-	case WinnerHome, WinnerAway, WinnerTBD, WinnnerNone:
-		return nil
-	default:
-		return errors.New("invalid winner set, must be home, away or tbd")
-	}
-}
-
 func (w Winner) Finalized() bool {
-	return w == WinnerHome || w == WinnerAway || w == WinnnerNone
+	return w != "" && w != WinnerTBD
 }
 
 const (
 	WinnerHome  Winner = "home"
 	WinnerAway  Winner = "away"
+	WinnerDraw  Winner = "draw"
 	WinnnerNone Winner = "none"
 	WinnerTBD   Winner = "tbd"
+	// WinnerOther resolves a market with a result none of its outcomes
+	// names, such as a correct score that was not offered. Every bet on the
+	// market loses, unlike with WinnnerNone which voids them.
+	WinnerOther Winner = "other"
 )
 
 type Team struct {
@@ -50,13 +48,80 @@ type Player struct {
 }
 
 type Event struct {
-	UUID       uuid.UUID
-	Name       string
-	Selections []EventSelection
-	Sport      Sport
-	BeginsAt   time.Time
-	HomeTeam   Team
-	AwayTeam   Team
+	UUID          uuid.UUID
+	Name          string
+	Selections    []EventSelection
+	Sport         Sport
+	BeginsAt      time.Time
+	Status        EventStatus
+	HomeTeam      Team
+	AwayTeam      Team
+	FinalScore    *Score
+	HalfTimeScore *Score
+}
+
+// RecordScore stores the final and, optionally, the half-time score of
+// the event.
+func (e *Event) RecordScore(final Score, halfTime *Score) error {
+	if e.FinalScore != nil {
+		return errors.New("event score already recorded")
+	}
+
+	if e.Status == EventStatusCancelled || e.Status == EventStatusPostponed {
+		return errors.New("event is " + string(e.Status))
+	}
+
+	if final.Home < 0 || final.Away < 0 {
+		return errors.New("score cannot be negative")
+	}
+
+	if halfTime != nil {
+		if halfTime.Home < 0 || halfTime.Away < 0 {
+			return errors.New("half-time score cannot be negative")
+		}
+
+		if halfTime.Home > final.Home || halfTime.Away > final.Away {
+			return errors.New("half-time score cannot exceed final score")
+		}
+	}
+
+	e.FinalScore = &final
+	e.HalfTimeScore = halfTime
+
+	return nil
+}
+
+// ScoreSelections returns the unsettled selections of the event with
+// their winners derived from the recorded score. Selections whose winner
+// cannot be derived (e.g. half-time markets without a half-time score)
+// are left out.
+func (e Event) ScoreSelections() []EventSelection {
+	var sels []EventSelection
+
+	for _, sel := range e.Selections {
+		if sel.Winner.Finalized() {
+			continue
+		}
+
+		score := e.FinalScore
+		if sel.Period == PeriodHalfTime {
+			score = e.HalfTimeScore
+		}
+
+		if score == nil {
+			continue
+		}
+
+		w, ok := sel.Result(*score)
+		if !ok {
+			continue
+		}
+
+		sel.Winner = w
+		sels = append(sels, sel)
+	}
+
+	return sels
 }
 
 func (e Event) Finished() bool {
@@ -73,20 +138,123 @@ type EventSelection struct {
 	UUID      uuid.UUID
 	EventUUID uuid.UUID
 	Name      string
-	OddsHome  decimal.Decimal
-	OddsAway  decimal.Decimal
+	Type      MarketType
+	Period    Period
+	Status    MarketStatus
+	Line      decimal.NullDecimal
+	Outcomes  []Outcome
 	AutoOdds  bool
 	Winner    Winner
 }
 
+func (es EventSelection) Validate() error {
+	rule, ok := marketRules[es.Type]
+	if !ok {
+		return errors.New("invalid market type, must be match_winner, over_under, handicap or correct_score")
+	}
+
+	if es.Period != PeriodFullTime && es.Period != PeriodHalfTime {
+		return errors.New("invalid period, must be full_time or half_time")
+	}
+
+	if len(es.Outcomes) < 2 {
+		return errors.New("market must have at least 2 outcomes")
+	}
+
+	seen := make(map[Winner]struct{}, len(es.Outcomes))
+
+	for _, o := range es.Outcomes {
+		if o.Winner == "" || o.Winner == WinnerTBD || o.Winner == WinnnerNone || o.Winner == WinnerOther {
+			return errors.New("invalid outcome name")
+		}
+
+		if _, ok := seen[o.Winner]; ok {
+			return errors.New("outcome listed more than once")
+		}
+
+		if o.Odds.LessThanOrEqual(decimal.Zero) {
+			return errors.New("outcome odds must be greater than 0")
+		}
+
+		seen[o.Winner] = struct{}{}
+	}
+
+	return rule.validate(es)
+}
+
+func (es EventSelection) Offers(w Winner) bool {
+	for _, o := range es.Outcomes {
+		if o.Winner == w {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanResolve checks that the selection can be resolved with the winner:
+// one of its outcomes, none to void it, or other if the result of a
+// correct score market was not offered.
+func (es EventSelection) CanResolve(w Winner) error {
+	switch w {
+	case WinnnerNone:
+		return nil
+	case WinnerOther:
+		if es.Type != MarketTypeCorrectScore {
+			return errors.New("only correct score markets can be resolved with an unlisted outcome")
+		}
+
+		return nil
+	}
+
+	if !es.Offers(w) {
+		return errors.New("selection does not offer this outcome")
+	}
+
+	return nil
+}
+
 func (es EventSelection) WinnerOdds() decimal.Decimal {
-	if es.Winner == WinnerTBD || es.Winner == WinnnerNone {
-		return decimal.Zero
+	return es.Odds(es.Winner)
+}
+
+func (es EventSelection) Odds(w Winner) decimal.Decimal {
+	for _, o := range es.Outcomes {
+		if o.Winner == w {
+			return o.Odds
+		}
 	}
 
-	if es.Winner == WinnerHome {
-		return es.OddsHome
+	return decimal.Zero
+}
+
+func (es *EventSelection) SetOdds(w Winner, odds decimal.Decimal) {
+	for i := range es.Outcomes {
+		if es.Outcomes[i].Winner == w {
+			es.Outcomes[i].Odds = odds
+		}
+	}
+}
+
+// Result derives the winning outcome from the score of the market's
+// period. False is returned if the market type cannot be settled from a
+// score.
+func (es EventSelection) Result(s Score) (Winner, bool) {
+	rule, ok := marketRules[es.Type]
+	if !ok || rule.result == nil {
+		return "", false
+	}
+
+	return rule.result(es, s), true
+}
+
+// Settle returns the state of a bet on the provided outcome according to
+// the settlement rule of the market type.
+func (es EventSelection) Settle(pick Winner) BetState {
+	rule, ok := marketRules[es.Type]
+	if !ok {
+		return settleExact(es, pick)
 	}
 
-	return es.OddsAway
+	return rule.settle(es, pick)
 }
@@ -0,0 +1,117 @@
+package bet
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrStakeBelowMinimum  = errors.New("stake is below the minimum")
+	ErrStakeAboveMaximum  = errors.New("stake is above the maximum")
+	ErrPayoutAboveMaximum = errors.New("potential payout is above the maximum")
+	ErrLiabilityExceeded  = errors.New("selection liability limit reached")
+)
+
+type LimitScope string
+
+const (
+	LimitScopeGlobal    LimitScope = "global"
+	LimitScopeEvent     LimitScope = "event"
+	LimitScopeSelection LimitScope = "selection"
+)
+
+// Limits restricts the stakes and payouts accepted on bets. Global limits
+// apply to every bet and may be overridden per event or per selection.
+// Zero amounts mean no limit.
+type Limits struct {
+	Scope     LimitScope
+	ScopeUUID uuid.UUID
+
+	MinStake decimal.Decimal
+	MaxStake decimal.Decimal
+	// MaxPayout caps the potential payout of a single bet.
+	MaxPayout decimal.Decimal
+	// MaxLiability caps the total potential payout of open bets on a
+	// single outcome of a selection, across all users.
+	MaxLiability decimal.Decimal
+}
+
+func (l Limits) Validate() error {
+	switch l.Scope {
+	case LimitScopeGlobal:
+		if l.ScopeUUID != uuid.Nil {
+			return errors.New("global limits cannot have a scope uuid")
+		}
+	case LimitScopeEvent, LimitScopeSelection:
+		if l.ScopeUUID == uuid.Nil {
+			return errors.New("scope uuid not provided")
+		}
+	default:
+		return errors.New("invalid limit scope")
+	}
+
+	for _, v := range []decimal.Decimal{l.MinStake, l.MaxStake, l.MaxPayout, l.MaxLiability} {
+		if v.IsNegative() {
+			return errors.New("limits cannot be negative")
+		}
+	}
+
+	if l.MaxStake.IsPositive() && l.MinStake.GreaterThan(l.MaxStake) {
+		return errors.New("min stake cannot be greater than max stake")
+	}
+
+	return nil
+}
+
+// Override returns the limits with every amount set in o replacing the
+// corresponding amount of l.
+func (l Limits) Override(o Limits) Limits {
+	res := l
+
+	if o.MinStake.IsPositive() {
+		res.MinStake = o.MinStake
+	}
+
+	if o.MaxStake.IsPositive() {
+		res.MaxStake = o.MaxStake
+	}
+
+	if o.MaxPayout.IsPositive() {
+		res.MaxPayout = o.MaxPayout
+	}
+
+	if o.MaxLiability.IsPositive() {
+		res.MaxLiability = o.MaxLiability
+	}
+
+	return res
+}
+
+// CheckStake checks the stake and potential payout of a bet.
+func (l Limits) CheckStake(stake, odds decimal.Decimal) error {
+	if l.MinStake.IsPositive() && stake.LessThan(l.MinStake) {
+		return ErrStakeBelowMinimum
+	}
+
+	if l.MaxStake.IsPositive() && stake.GreaterThan(l.MaxStake) {
+		return ErrStakeAboveMaximum
+	}
+
+	if l.MaxPayout.IsPositive() && stake.Mul(odds).GreaterThan(l.MaxPayout) {
+		return ErrPayoutAboveMaximum
+	}
+
+	return nil
+}
+
+// CheckLiability checks whether a new potential payout fits the liability
+// remaining on an outcome that already carries the given liability.
+func (l Limits) CheckLiability(liability, payout decimal.Decimal) error {
+	if l.MaxLiability.IsPositive() && liability.Add(payout).GreaterThan(l.MaxLiability) {
+		return ErrLiabilityExceeded
+	}
+
+	return nil
+}
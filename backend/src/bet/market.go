@@ -0,0 +1,231 @@
+package bet
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+type MarketType string
+
+const (
+	MarketTypeMatchWinner  MarketType = "match_winner"
+	MarketTypeOverUnder    MarketType = "over_under"
+	MarketTypeHandicap     MarketType = "handicap"
+	MarketTypeCorrectScore MarketType = "correct_score"
+)
+
+type Period string
+
+const (
+	PeriodFullTime Period = "full_time"
+	PeriodHalfTime Period = "half_time"
+)
+
+const (
+	WinnerOver  Winner = "over"
+	WinnerUnder Winner = "under"
+)
+
+type Outcome struct {
+	Winner Winner
+	Odds   decimal.Decimal
+}
+
+type Score struct {
+	Home int
+	Away int
+}
+
+// ParseScore parses correct score outcome names, e.g. "2:1".
+func ParseScore(w Winner) (Score, error) {
+	home, away, ok := strings.Cut(string(w), ":")
+	if !ok {
+		return Score{}, errors.New("score must be in home:away format")
+	}
+
+	h, err := strconv.Atoi(home)
+	if err != nil || h < 0 {
+		return Score{}, errors.New("invalid home score")
+	}
+
+	a, err := strconv.Atoi(away)
+	if err != nil || a < 0 {
+		return Score{}, errors.New("invalid away score")
+	}
+
+	return Score{
+		Home: h,
+		Away: a,
+	}, nil
+}
+
+func (s Score) Winner() Winner {
+	return Winner(fmt.Sprintf("%d:%d", s.Home, s.Away))
+}
+
+type marketRule struct {
+	validate func(EventSelection) error
+	settle   func(sel EventSelection, pick Winner) BetState
+	result   func(sel EventSelection, s Score) Winner
+}
+
+var marketRules = map[MarketType]marketRule{
+	MarketTypeMatchWinner: {
+		validate: validateMatchWinner,
+		settle:   settleExact,
+		result:   matchWinnerResult,
+	},
+	MarketTypeOverUnder: {
+		validate: validateOverUnder,
+		settle:   settleExact,
+		result:   overUnderResult,
+	},
+	MarketTypeHandicap: {
+		validate: validateHandicap,
+		settle:   settleExact,
+		result:   handicapResult,
+	},
+	MarketTypeCorrectScore: {
+		validate: validateCorrectScore,
+		settle:   settleExact,
+		result:   correctScoreResult,
+	},
+}
+
+// settleExact wins bets on the resolved outcome and voids every bet when
+// the market is resolved with no winner (e.g. a push on a whole line).
+func settleExact(sel EventSelection, pick Winner) BetState {
+	switch sel.Winner {
+	case WinnerTBD:
+		return BetStateTBD
+	case WinnnerNone:
+		return BetStateVoid
+	case pick:
+		return BetStateWon
+	default:
+		return BetStateLost
+	}
+}
+
+// matchWinnerResult voids two-way markets that end in a draw.
+func matchWinnerResult(sel EventSelection, s Score) Winner {
+	switch {
+	case s.Home > s.Away:
+		return WinnerHome
+	case s.Home < s.Away:
+		return WinnerAway
+	case sel.Offers(WinnerDraw):
+		return WinnerDraw
+	default:
+		return WinnnerNone
+	}
+}
+
+// overUnderResult compares the total score against the line, landing
+// exactly on it is a push.
+func overUnderResult(sel EventSelection, s Score) Winner {
+	total := decimal.NewFromInt(int64(s.Home + s.Away))
+
+	switch total.Cmp(sel.Line.Decimal) {
+	case 1:
+		return WinnerOver
+	case -1:
+		return WinnerUnder
+	default:
+		return WinnnerNone
+	}
+}
+
+// handicapResult applies the line to the home team's score, landing
+// exactly on it is a push.
+func handicapResult(sel EventSelection, s Score) Winner {
+	home := decimal.NewFromInt(int64(s.Home)).Add(sel.Line.Decimal)
+
+	switch home.Cmp(decimal.NewFromInt(int64(s.Away))) {
+	case 1:
+		return WinnerHome
+	case -1:
+		return WinnerAway
+	default:
+		return WinnnerNone
+	}
+}
+
+// correctScoreResult returns the score itself, or other if the score was
+// not offered so that every bet loses.
+func correctScoreResult(sel EventSelection, s Score) Winner {
+	if w := s.Winner(); sel.Offers(w) {
+		return w
+	}
+
+	return WinnerOther
+}
+
+func validateMatchWinner(sel EventSelection) error {
+	if sel.Line.Valid {
+		return errors.New("match winner market cannot have a line")
+	}
+
+	if !sel.Offers(WinnerHome) || !sel.Offers(WinnerAway) {
+		return errors.New("match winner market must offer home and away")
+	}
+
+	return onlyOutcomes(sel, WinnerHome, WinnerAway, WinnerDraw)
+}
+
+func validateOverUnder(sel EventSelection) error {
+	if !sel.Line.Valid {
+		return errors.New("over/under market must have a line")
+	}
+
+	if sel.Line.Decimal.IsNegative() {
+		return errors.New("over/under line cannot be negative")
+	}
+
+	return onlyOutcomes(sel, WinnerOver, WinnerUnder)
+}
+
+func validateHandicap(sel EventSelection) error {
+	if !sel.Line.Valid {
+		return errors.New("handicap market must have a line")
+	}
+
+	return onlyOutcomes(sel, WinnerHome, WinnerAway)
+}
+
+func validateCorrectScore(sel EventSelection) error {
+	if sel.Line.Valid {
+		return errors.New("correct score market cannot have a line")
+	}
+
+	for _, o := range sel.Outcomes {
+		if _, err := ParseScore(o.Winner); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func onlyOutcomes(sel EventSelection, allowed ...Winner) error {
+	for _, o := range sel.Outcomes {
+		var ok bool
+
+		for _, w := range allowed {
+			if o.Winner == w {
+				ok = true
+				break
+			}
+		}
+
+		if !ok {
+			return fmt.Errorf("outcome %q is not allowed in %s market", o.Winner, sel.Type)
+		}
+	}
+
+	return nil
+}
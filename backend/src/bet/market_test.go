@@ -0,0 +1,140 @@
+package bet
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func correctScoreSelection(scores ...Winner) EventSelection {
+	sel := EventSelection{
+		Type:   MarketTypeCorrectScore,
+		Period: PeriodFullTime,
+		Winner: WinnerTBD,
+	}
+
+	for _, w := range scores {
+		sel.Outcomes = append(sel.Outcomes, Outcome{Winner: w, Odds: decimal.NewFromInt(5)})
+	}
+
+	return sel
+}
+
+func TestEventSelectionCanResolve(t *testing.T) {
+	var (
+		correctScore = correctScoreSelection("1:0", "0:0")
+		matchWinner  = EventSelection{
+			Type: MarketTypeMatchWinner,
+			Outcomes: []Outcome{
+				{Winner: WinnerHome, Odds: decimal.NewFromInt(2)},
+				{Winner: WinnerAway, Odds: decimal.NewFromInt(2)},
+			},
+		}
+	)
+
+	tests := map[string]struct {
+		sel    EventSelection
+		winner Winner
+		err    bool
+	}{
+		"offered outcome": {
+			sel:    matchWinner,
+			winner: WinnerHome,
+		},
+		"outcome not offered": {
+			sel:    matchWinner,
+			winner: WinnerDraw,
+			err:    true,
+		},
+		"void": {
+			sel:    matchWinner,
+			winner: WinnnerNone,
+		},
+		"unlisted outcome of a match winner market": {
+			sel:    matchWinner,
+			winner: WinnerOther,
+			err:    true,
+		},
+		"offered score": {
+			sel:    correctScore,
+			winner: "1:0",
+		},
+		"score not offered": {
+			sel:    correctScore,
+			winner: "2:1",
+			err:    true,
+		},
+		"unlisted score": {
+			sel:    correctScore,
+			winner: WinnerOther,
+		},
+		"void correct score": {
+			sel:    correctScore,
+			winner: WinnnerNone,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.sel.CanResolve(test.winner)
+			if test.err && err == nil {
+				t.Fatal("want error, got nil")
+			}
+
+			if !test.err && err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCorrectScoreSettle(t *testing.T) {
+	tests := map[string]struct {
+		score Score
+		// states are the states of the bets on 1:0 and 0:0.
+		states [2]BetState
+	}{
+		"offered score": {
+			score:  Score{Home: 1, Away: 0},
+			states: [2]BetState{BetStateWon, BetStateLost},
+		},
+		"score not offered": {
+			score:  Score{Home: 2, Away: 1},
+			states: [2]BetState{BetStateLost, BetStateLost},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ev := Event{
+				Selections: []EventSelection{correctScoreSelection("1:0", "0:0")},
+				FinalScore: &test.score,
+			}
+
+			sels := ev.ScoreSelections()
+			if len(sels) != 1 {
+				t.Fatalf("want 1 selection, got %d", len(sels))
+			}
+
+			for i, pick := range []Winner{"1:0", "0:0"} {
+				if st := sels[0].Settle(pick); st != test.states[i] {
+					t.Errorf("bet on %s: want %s, got %s", pick, test.states[i], st)
+				}
+			}
+		})
+	}
+
+	// Resolving the market by hand with no listed outcome loses every
+	// bet, while none voids them.
+	sel := correctScoreSelection("1:0", "0:0")
+
+	sel.Winner = WinnerOther
+	if st := sel.Settle("1:0"); st != BetStateLost {
+		t.Errorf("want bet lost with no listed outcome, got %s", st)
+	}
+
+	sel.Winner = WinnnerNone
+	if st := sel.Settle("1:0"); st != BetStateVoid {
+		t.Errorf("want bet void with no winner, got %s", st)
+	}
+}
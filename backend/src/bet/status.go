@@ -0,0 +1,132 @@
+package bet
+
+import (
+	"errors"
+	"time"
+)
+
+type EventStatus string
+
+const (
+	EventStatusScheduled EventStatus = "scheduled"
+	EventStatusLive      EventStatus = "live"
+	EventStatusSuspended EventStatus = "suspended"
+	EventStatusPostponed EventStatus = "postponed"
+	EventStatusCancelled EventStatus = "cancelled"
+	EventStatusFinished  EventStatus = "finished"
+)
+
+// eventTransitions lists the statuses an event may move to from each
+// status. Cancelled and finished events are terminal.
+var eventTransitions = map[EventStatus][]EventStatus{
+	EventStatusScheduled: {EventStatusLive, EventStatusSuspended, EventStatusPostponed, EventStatusCancelled, EventStatusFinished},
+	EventStatusLive:      {EventStatusSuspended, EventStatusCancelled, EventStatusFinished},
+	EventStatusSuspended: {EventStatusScheduled, EventStatusLive, EventStatusPostponed, EventStatusCancelled, EventStatusFinished},
+	EventStatusPostponed: {EventStatusScheduled, EventStatusCancelled},
+}
+
+func (s EventStatus) Validate() error {
+	switch s {
+	case EventStatusScheduled, EventStatusLive, EventStatusSuspended,
+		EventStatusPostponed, EventStatusCancelled, EventStatusFinished:
+		return nil
+	default:
+		return errors.New("invalid event status")
+	}
+}
+
+func (s EventStatus) CanTransition(to EventStatus) bool {
+	for _, st := range eventTransitions[s] {
+		if st == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Transition moves the event to the given status.
+func (e *Event) Transition(to EventStatus) error {
+	if err := to.Validate(); err != nil {
+		return err
+	}
+
+	if !e.Status.CanTransition(to) {
+		return errors.New("event cannot move from " + string(e.Status) + " to " + string(to))
+	}
+
+	e.Status = to
+
+	return nil
+}
+
+// Cancel moves the event to cancelled and voids every unsettled
+// selection. The voided selections are returned so that their bets can
+// be refunded.
+func (e *Event) Cancel() ([]EventSelection, error) {
+	if err := e.Transition(EventStatusCancelled); err != nil {
+		return nil, err
+	}
+
+	var sels []EventSelection
+
+	for _, sel := range e.Selections {
+		if sel.Winner.Finalized() {
+			continue
+		}
+
+		sel.Winner = WinnnerNone
+		sels = append(sels, sel)
+	}
+
+	return sels, nil
+}
+
+// AcceptsBets checks whether bets can be placed on the selection of the
+// event at the given time.
+func (e Event) AcceptsBets(sel EventSelection, now time.Time) error {
+	switch e.Status {
+	case EventStatusScheduled:
+	case EventStatusLive:
+		return errors.New("event already started")
+	default:
+		return errors.New("event is " + string(e.Status))
+	}
+
+	if !now.Before(e.BeginsAt) {
+		return errors.New("event already started")
+	}
+
+	if sel.Status != MarketStatusOpen {
+		return errors.New("market is " + string(sel.Status))
+	}
+
+	return nil
+}
+
+type MarketStatus string
+
+const (
+	MarketStatusOpen      MarketStatus = "open"
+	MarketStatusSuspended MarketStatus = "suspended"
+)
+
+// Transition opens or suspends the market. Settled markets cannot change
+// their status.
+func (es *EventSelection) Transition(to MarketStatus) error {
+	if to != MarketStatusOpen && to != MarketStatusSuspended {
+		return errors.New("invalid market status")
+	}
+
+	if es.Winner.Finalized() {
+		return errors.New("market already settled")
+	}
+
+	if es.Status == to {
+		return errors.New("market is already " + string(to))
+	}
+
+	es.Status = to
+
+	return nil
+}
@@ -2,21 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/ramasauskas/ispbet/bet"
+	"github.com/ramasauskas/ispbet/bonus"
 	"github.com/ramasauskas/ispbet/db"
+	"github.com/ramasauskas/ispbet/purse"
 	"github.com/ramasauskas/ispbet/user"
+	"github.com/shopspring/decimal"
 )
 
 type betDBAdapter struct {
 	db *db.DB
 }
 
-func (b *betDBAdapter) UpdateEvent(ctx context.Context, e bet.Event) error {
-	return b.db.UpdateEvent(ctx, b.db.NoTX(), encodeEvent(e))
-}
-
 func (b *betDBAdapter) FetchBetUserByUUID(ctx context.Context, uuid uuid.UUID) (user.BetUser, bool, error) {
 	bu, ok, err := b.db.FetchBetUser(ctx, b.db.NoTX(), db.FetchUserByUUID(uuid))
 	if err != nil {
@@ -30,6 +31,19 @@ func (b *betDBAdapter) FetchBetUserByUUID(ctx context.Context, uuid uuid.UUID) (
 	return decodeBetUser(bu), true, nil
 }
 
+func (b *betDBAdapter) FetchBet(ctx context.Context, id uuid.UUID) (bet.Bet, bool, error) {
+	bt, ok, err := b.db.FetchBet(ctx, b.db.NoTX(), id)
+	if err != nil {
+		return bet.Bet{}, false, err
+	}
+
+	if !ok {
+		return bet.Bet{}, false, nil
+	}
+
+	return decodeBet(bt), true, nil
+}
+
 func (b *betDBAdapter) FetchBetsBySelection(ctx context.Context, uuid uuid.UUID) ([]bet.Bet, error) {
 	bets, err := b.db.FetchBets(ctx, b.db.NoTX(), db.SelectionBets(uuid))
 	if err != nil {
@@ -45,6 +59,90 @@ func (b *betDBAdapter) FetchBetsBySelection(ctx context.Context, uuid uuid.UUID)
 	return bb, nil
 }
 
+func (b *betDBAdapter) FetchUserBetsSince(ctx context.Context, id uuid.UUID, since time.Time) ([]bet.Bet, error) {
+	bets, err := b.db.FetchBets(ctx, b.db.NoTX(), db.UserBetsSince(id, since))
+	if err != nil {
+		return nil, err
+	}
+
+	var bb []bet.Bet
+
+	for _, b := range bets {
+		bb = append(bb, decodeBet(b))
+	}
+
+	return bb, nil
+}
+
+func (b *betDBAdapter) FetchUserAccumulatorsSince(ctx context.Context, id uuid.UUID, since time.Time) ([]bet.Accumulator, error) {
+	accs, err := b.db.FetchAccumulators(ctx, b.db.NoTX(), db.UserAccumulatorsSince(id, since))
+	if err != nil {
+		return nil, err
+	}
+
+	var aa []bet.Accumulator
+
+	for _, acc := range accs {
+		a, err := fillAccumulator(ctx, b.db, b.db.NoTX(), acc)
+		if err != nil {
+			return nil, err
+		}
+
+		aa = append(aa, a)
+	}
+
+	return aa, nil
+}
+
+func (b *betDBAdapter) FetchGamblingLimits(ctx context.Context, id uuid.UUID) ([]user.GamblingLimit, error) {
+	ll, err := b.db.FetchGamblingLimits(ctx, b.db.NoTX(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	var limits []user.GamblingLimit
+
+	for _, l := range ll {
+		limits = append(limits, decodeGamblingLimit(l))
+	}
+
+	return limits, nil
+}
+
+// FetchLimits returns the global limits overridden by the limits of the
+// event and then the selection, where set.
+func (b *betDBAdapter) FetchLimits(ctx context.Context, eventUUID, selectionUUID uuid.UUID) (bet.Limits, error) {
+	limits := bet.Limits{
+		Scope: bet.LimitScopeGlobal,
+	}
+
+	scopes := []struct {
+		scope bet.LimitScope
+		id    uuid.UUID
+	}{
+		{bet.LimitScopeGlobal, uuid.Nil},
+		{bet.LimitScopeEvent, eventUUID},
+		{bet.LimitScopeSelection, selectionUUID},
+	}
+
+	for _, sc := range scopes {
+		bl, ok, err := b.db.FetchBetLimit(ctx, b.db.NoTX(), string(sc.scope), sc.id)
+		if err != nil {
+			return bet.Limits{}, err
+		}
+
+		if ok {
+			limits = limits.Override(decodeBetLimit(bl))
+		}
+	}
+
+	return limits, nil
+}
+
+func (b *betDBAdapter) FetchSelectionLiability(ctx context.Context, id uuid.UUID, w bet.Winner) (decimal.Decimal, error) {
+	return b.db.FetchSelectionLiability(ctx, b.db.NoTX(), id, string(w))
+}
+
 func (b *betDBAdapter) InsertBet(ctx context.Context, bt bet.Bet, u user.BetUser) error {
 	tx, err := b.db.NewTX(ctx)
 	if err != nil {
@@ -57,7 +155,31 @@ func (b *betDBAdapter) InsertBet(ctx context.Context, bt bet.Bet, u user.BetUser
 		return err
 	}
 
-	if err := b.db.UpdateBetUser(ctx, tx, encodeBetUser(u)); err != nil {
+	if bt.TokenUUID != uuid.Nil {
+		tk := db.BonusToken{
+			UUID:    bt.TokenUUID,
+			Status:  string(bonus.TokenStatusUsed),
+			BetUUID: bt.UUID,
+		}
+
+		ok, err := b.db.UpdateBonusToken(ctx, tx, tk, string(bonus.TokenStatusAvailable))
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return errors.New("bonus token already used")
+		}
+	}
+
+	var entries []purse.Entry
+
+	// the stake of a free bet is not paid by the user.
+	if !bt.FreeBet {
+		entries = append(entries, purse.StakeEntry(bt.UserUUID, bt.UUID, bt.Stake, bt.Timestamp))
+	}
+
+	if err := postEntries(ctx, b.db, tx, entries, u); err != nil {
 		return err
 	}
 
@@ -76,15 +198,149 @@ func (b *betDBAdapter) UpdateBet(ctx context.Context, bt bet.Bet, u user.BetUser
 		return err
 	}
 
-	if err := b.db.UpdateBetUser(ctx, tx, encodeBetUser(u)); err != nil {
+	var entries []purse.Entry
+
+	if bt.State == bet.BetStateCashedOut && bt.CashOutAmount.IsPositive() {
+		entries = append(entries, purse.SettlementEntry(purse.EntryTypeCashOut, bt.UserUUID, bt.UUID, bt.CashOutAmount, time.Now()))
+	}
+
+	if err := postEntries(ctx, b.db, tx, entries, u); err != nil {
 		return err
 	}
 
 	return tx.Commit()
 }
 
-func (b *betDBAdapter) UpdateSelection(ctx context.Context, sel bet.EventSelection) error {
-	return b.db.UpdateSelection(ctx, b.db.NoTX(), encodeSelection(sel, uuid.Nil))
+func (b *betDBAdapter) FetchAccumulatorsBySelection(ctx context.Context, id uuid.UUID) ([]bet.Accumulator, error) {
+	accs, err := b.db.FetchAccumulators(ctx, b.db.NoTX(), db.SelectionAccumulators(id))
+	if err != nil {
+		return nil, err
+	}
+
+	var aa []bet.Accumulator
+
+	for _, acc := range accs {
+		a, err := fillAccumulator(ctx, b.db, b.db.NoTX(), acc)
+		if err != nil {
+			return nil, err
+		}
+
+		aa = append(aa, a)
+	}
+
+	return aa, nil
+}
+
+func (b *betDBAdapter) InsertAccumulator(ctx context.Context, acc bet.Accumulator, u user.BetUser) error {
+	tx, err := b.db.NewTX(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err := b.db.InsertAccumulator(ctx, tx, encodeAccumulator(acc)); err != nil {
+		return err
+	}
+
+	for _, l := range acc.Legs {
+		if err := b.db.InsertAccumulatorLeg(ctx, tx, encodeAccumulatorLeg(l, acc.UUID)); err != nil {
+			return err
+		}
+	}
+
+	stake := purse.StakeEntry(acc.UserUUID, acc.UUID, acc.Stake, acc.Timestamp)
+
+	if err := postEntries(ctx, b.db, tx, []purse.Entry{stake}, u); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// InsertSettlement stores every change of the settlement in a single
+// transaction.
+func (b *betDBAdapter) InsertSettlement(ctx context.Context, st Settlement) error {
+	tx, err := b.db.NewTX(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	for _, sel := range st.Selections {
+		if err := saveSelection(ctx, b.db, tx, sel); err != nil {
+			return err
+		}
+	}
+
+	for _, bt := range st.Bets {
+		if err := b.db.UpdateBet(ctx, tx, encodeBet(bt)); err != nil {
+			return err
+		}
+	}
+
+	for _, acc := range st.Accumulators {
+		if err := b.db.UpdateAccumulator(ctx, tx, encodeAccumulator(acc)); err != nil {
+			return err
+		}
+
+		for _, l := range acc.Legs {
+			if err := b.db.UpdateAccumulatorLeg(ctx, tx, encodeAccumulatorLeg(l, acc.UUID)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, g := range st.Grants {
+		if err := b.db.UpdateBonusGrant(ctx, tx, encodeBonusGrant(g)); err != nil {
+			return err
+		}
+	}
+
+	if err := postEntries(ctx, b.db, tx, st.Entries, st.Users...); err != nil {
+		return err
+	}
+
+	for _, ba := range st.Adjustments {
+		if err := b.db.InsertBalanceAdjustment(ctx, tx, encodeBalanceAdjustment(ba)); err != nil {
+			return err
+		}
+	}
+
+	if err := b.db.UpdateEvent(ctx, tx, encodeEvent(st.Event)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (b *betDBAdapter) FetchBonusToken(ctx context.Context, id uuid.UUID) (bonus.Token, bool, error) {
+	tk, ok, err := b.db.FetchBonusToken(ctx, b.db.NoTX(), id)
+	if err != nil {
+		return bonus.Token{}, false, err
+	}
+
+	if !ok {
+		return bonus.Token{}, false, nil
+	}
+
+	return decodeBonusToken(tk), true, nil
+}
+
+func (b *betDBAdapter) FetchActiveBonusGrants(ctx context.Context, id uuid.UUID) ([]bonus.Grant, error) {
+	gg, err := b.db.FetchBonusGrants(ctx, b.db.NoTX(), db.UserBonusGrantsWithStatus(id, string(bonus.GrantStatusActive)))
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := make([]bonus.Grant, 0, len(gg))
+
+	for _, g := range gg {
+		decoded = append(decoded, decodeBonusGrant(g))
+	}
+
+	return decoded, nil
 }
 
 func (b *betDBAdapter) FetchSelection(ctx context.Context, uuid uuid.UUID) (bet.EventSelection, bool, error) {
@@ -97,7 +353,12 @@ func (b *betDBAdapter) FetchSelection(ctx context.Context, uuid uuid.UUID) (bet.
 		return bet.EventSelection{}, false, nil
 	}
 
-	return decodeSelection(sel), true, nil
+	decoded, err := fillSelection(ctx, b.db, b.db.NoTX(), sel)
+	if err != nil {
+		return bet.EventSelection{}, false, err
+	}
+
+	return decoded, true, nil
 }
 
 func (b *betDBAdapter) FetchEvent(ctx context.Context, uuid uuid.UUID) (bet.Event, bool, error) {
@@ -128,6 +389,10 @@ func encodeBet(b bet.Bet) db.Bet {
 		SelectionWinner: string(b.SelectionWinner),
 		Odds:            b.Odds,
 		State:           string(b.State),
+		CashOut:         b.CashOutAmount,
+		Currency:        string(b.Currency),
+		TokenUUID:       b.TokenUUID,
+		FreeBet:         b.FreeBet,
 	}
 }
 
@@ -140,6 +405,67 @@ func decodeBet(b db.Bet) bet.Bet {
 		Stake:           b.Stake,
 		Odds:            b.Odds,
 		State:           bet.BetState(b.State),
+		CashOutAmount:   b.CashOut,
 		Timestamp:       b.Timestamp,
+		Currency:        purse.Currency(b.Currency),
+		TokenUUID:       b.TokenUUID,
+		FreeBet:         b.FreeBet,
+	}
+}
+
+func fillAccumulator(ctx context.Context, db *db.DB, tx db.TX, acc db.Accumulator) (bet.Accumulator, error) {
+	legs, err := db.FetchAccumulatorLegs(ctx, tx, acc.UUID)
+	if err != nil {
+		return bet.Accumulator{}, err
+	}
+
+	var decodedLegs []bet.AccumulatorLeg
+
+	for _, l := range legs {
+		decodedLegs = append(decodedLegs, decodeAccumulatorLeg(l))
+	}
+
+	return bet.Accumulator{
+		UUID:      acc.UUID,
+		UserUUID:  acc.UserUUID,
+		Legs:      decodedLegs,
+		Stake:     acc.Stake,
+		Odds:      acc.Odds,
+		State:     bet.BetState(acc.State),
+		Timestamp: acc.Timestamp,
+		Currency:  purse.Currency(acc.Currency),
+	}, nil
+}
+
+func encodeAccumulator(acc bet.Accumulator) db.Accumulator {
+	return db.Accumulator{
+		UUID:      acc.UUID,
+		UserUUID:  acc.UserUUID,
+		Stake:     acc.Stake,
+		Odds:      acc.Odds,
+		State:     string(acc.State),
+		Timestamp: acc.Timestamp,
+		Currency:  string(acc.Currency),
+	}
+}
+
+func encodeAccumulatorLeg(l bet.AccumulatorLeg, accUUID uuid.UUID) db.AccumulatorLeg {
+	return db.AccumulatorLeg{
+		UUID:            l.UUID,
+		AccumulatorUUID: accUUID,
+		SelectionUUID:   l.SelectionUUID,
+		SelectionWinner: string(l.SelectionWinner),
+		Odds:            l.Odds,
+		State:           string(l.State),
+	}
+}
+
+func decodeAccumulatorLeg(l db.AccumulatorLeg) bet.AccumulatorLeg {
+	return bet.AccumulatorLeg{
+		UUID:            l.UUID,
+		SelectionUUID:   l.SelectionUUID,
+		SelectionWinner: bet.Winner(l.SelectionWinner),
+		Odds:            l.Odds,
+		State:           bet.BetState(l.State),
 	}
 }
@@ -4,27 +4,72 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/ramasauskas/ispbet/bet"
+	"github.com/ramasauskas/ispbet/bonus"
+	"github.com/ramasauskas/ispbet/purse"
 	"github.com/ramasauskas/ispbet/user"
 	"github.com/shopspring/decimal"
 )
 
+type BetErrorCode string
+
+const (
+	BetErrorSelectionNotFound    BetErrorCode = "selection_not_found"
+	BetErrorSelectionFinalized   BetErrorCode = "selection_finalized"
+	BetErrorSelectionNotSettled  BetErrorCode = "selection_not_settled"
+	BetErrorEventNotFound        BetErrorCode = "event_not_found"
+	BetErrorBettingClosed        BetErrorCode = "betting_closed"
+	BetErrorInsufficientFunds    BetErrorCode = "insufficient_funds"
+	BetErrorInvalidStake         BetErrorCode = "invalid_stake"
+	BetErrorOutcomeNotOffered    BetErrorCode = "outcome_not_offered"
+	BetErrorOddsChanged          BetErrorCode = "odds_changed"
+	BetErrorInvalidAccumulator   BetErrorCode = "invalid_accumulator"
+	BetErrorStakeBelowMinimum    BetErrorCode = "stake_below_minimum"
+	BetErrorStakeAboveMaximum    BetErrorCode = "stake_above_maximum"
+	BetErrorPayoutAboveMaximum   BetErrorCode = "payout_above_maximum"
+	BetErrorLiabilityExceeded    BetErrorCode = "liability_exceeded"
+	BetErrorBetNotFound          BetErrorCode = "bet_not_found"
+	BetErrorCashOutUnavailable   BetErrorCode = "cash_out_unavailable"
+	BetErrorCashOutPriceChanged  BetErrorCode = "cash_out_price_changed"
+	BetErrorInvalidStatus        BetErrorCode = "invalid_status"
+	BetErrorInvalidBonusToken    BetErrorCode = "invalid_bonus_token"
+	BetErrorGamblingLimitReached BetErrorCode = "gambling_limit_reached"
+	BetErrorUserExcluded         BetErrorCode = "user_excluded"
+)
+
 type BetResponse struct {
 	Ok           bool
+	ErrorCode    BetErrorCode
 	ErrorMessage string
+	// Odds holds the current price when the bet was rejected because the
+	// odds changed.
+	Odds decimal.Decimal
 }
 
 type better struct {
 	mu sync.Mutex
 	db BetDB
+
+	cashOutMargin decimal.Decimal
 }
 
-func (b *better) Bet(ctx context.Context, bt *bet.Bet, u *user.BetUser) (BetResponse, error) {
+// Bet places the bet at the current odds of its outcome. The odds set on
+// the bet are the ones the user saw, the policy decides whether the bet is
+// still accepted if the price has changed since. A bonus token set on the
+// bet is used up by it: a free bet token stakes its amount for the user
+// and an odds boost token raises the odds the bet is placed at. Any other
+// stake is debited from the balance only, never the bonus balance.
+func (b *better) Bet(ctx context.Context, bt *bet.Bet, policy bet.OddsPolicy, u *user.BetUser) (BetResponse, error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	if u.Excluded(time.Now()) {
+		return excludedResponse(), nil
+	}
+
 	sel, ok, err := b.db.FetchSelection(ctx, bt.SelectionUUID)
 	if err != nil {
 		return BetResponse{}, err
@@ -33,6 +78,7 @@ func (b *better) Bet(ctx context.Context, bt *bet.Bet, u *user.BetUser) (BetResp
 	if !ok {
 		return BetResponse{
 			Ok:           false,
+			ErrorCode:    BetErrorSelectionNotFound,
 			ErrorMessage: "cannot find selection",
 		}, nil
 	}
@@ -40,33 +86,126 @@ func (b *better) Bet(ctx context.Context, bt *bet.Bet, u *user.BetUser) (BetResp
 	if sel.Winner.Finalized() {
 		return BetResponse{
 			Ok:           false,
+			ErrorCode:    BetErrorSelectionFinalized,
 			ErrorMessage: "evenet already finalized",
 		}, nil
 	}
 
-	userCopy := *u
+	ev, ok, err := b.db.FetchEvent(ctx, sel.EventUUID)
+	if err != nil {
+		return BetResponse{}, err
+	}
+
+	if !ok {
+		return BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorEventNotFound,
+			ErrorMessage: "cannot find event",
+		}, nil
+	}
 
-	if err := userCopy.Debit(bt.Stake); err != nil {
+	if err := ev.AcceptsBets(sel, time.Now()); err != nil {
 		return BetResponse{
 			Ok:           false,
+			ErrorCode:    BetErrorBettingClosed,
 			ErrorMessage: err.Error(),
 		}, nil
 	}
 
+	var tk bonus.Token
+
+	if bt.TokenUUID != uuid.Nil {
+		tk, ok, err = b.db.FetchBonusToken(ctx, bt.TokenUUID)
+		if err != nil {
+			return BetResponse{}, err
+		}
+
+		if !ok {
+			return BetResponse{
+				Ok:           false,
+				ErrorCode:    BetErrorInvalidBonusToken,
+				ErrorMessage: "cannot find bonus token",
+			}, nil
+		}
+
+		if err := tk.Use(u.UUID, bt.UUID, time.Now()); err != nil {
+			return BetResponse{
+				Ok:           false,
+				ErrorCode:    BetErrorInvalidBonusToken,
+				ErrorMessage: err.Error(),
+			}, nil
+		}
+
+		if tk.Type == bonus.CampaignTypeFreeBet {
+			bt.Stake = tk.Amount
+			bt.FreeBet = true
+		}
+	}
+
+	userCopy := *u
+
+	if !bt.FreeBet {
+		if err := userCopy.Debit(bt.Stake); err != nil {
+			return insufficientFundsResponse(*u, err), nil
+		}
+	}
+
 	if bt.Stake.LessThanOrEqual(decimal.Zero) {
 		return BetResponse{
 			Ok:           false,
+			ErrorCode:    BetErrorInvalidStake,
 			ErrorMessage: "stake cannot be less than or equal to 0",
 		}, nil
 	}
 
-	if err := bt.SelectionWinner.Validate(); err != nil {
+	if !sel.Offers(bt.SelectionWinner) {
 		return BetResponse{
 			Ok:           false,
-			ErrorMessage: err.Error(),
+			ErrorCode:    BetErrorOutcomeNotOffered,
+			ErrorMessage: "selection does not offer this outcome",
+		}, nil
+	}
+
+	odds := sel.Odds(bt.SelectionWinner)
+
+	if !policy.Accepts(bt.Odds, odds) {
+		return BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorOddsChanged,
+			ErrorMessage: "odds changed",
+			Odds:         odds,
 		}, nil
 	}
 
+	odds = tk.BoostOdds(odds)
+
+	limits, err := b.db.FetchLimits(ctx, ev.UUID, sel.UUID)
+	if err != nil {
+		return BetResponse{}, err
+	}
+
+	if err := limits.CheckStake(bt.Stake, odds); err != nil {
+		return limitResponse(err), nil
+	}
+
+	liability, err := b.db.FetchSelectionLiability(ctx, sel.UUID, bt.SelectionWinner)
+	if err != nil {
+		return BetResponse{}, err
+	}
+
+	if err := limits.CheckLiability(liability, bt.Stake.Mul(odds)); err != nil {
+		return limitResponse(err), nil
+	}
+
+	if !bt.FreeBet {
+		if resp, ok, err := b.checkGamblingLimits(ctx, u.UUID, bt.Stake); err != nil || !ok {
+			return resp, err
+		}
+	}
+
+	bt.Odds = odds
+	bt.Currency = u.Currency
+
 	if err := b.db.InsertBet(ctx, *bt, userCopy); err != nil {
 		return BetResponse{}, err
 	}
@@ -78,25 +217,794 @@ func (b *better) Bet(ctx context.Context, bt *bet.Bet, u *user.BetUser) (BetResp
 	}, nil
 }
 
-func (b *better) ResolveEventSelection(ctx context.Context, sel bet.EventSelection) error {
+func (b *better) Accumulate(ctx context.Context, acc *bet.Accumulator, u *user.BetUser) (BetResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if u.Excluded(time.Now()) {
+		return excludedResponse(), nil
+	}
+
+	if err := acc.Validate(); err != nil {
+		return BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorInvalidAccumulator,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+
+	events := make(map[uuid.UUID]struct{}, len(acc.Legs))
+	legLimits := make([]bet.Limits, 0, len(acc.Legs))
+
+	for i := range acc.Legs {
+		sel, ok, err := b.db.FetchSelection(ctx, acc.Legs[i].SelectionUUID)
+		if err != nil {
+			return BetResponse{}, err
+		}
+
+		if !ok {
+			return BetResponse{
+				Ok:           false,
+				ErrorCode:    BetErrorSelectionNotFound,
+				ErrorMessage: "cannot find selection",
+			}, nil
+		}
+
+		if sel.Winner.Finalized() {
+			return BetResponse{
+				Ok:           false,
+				ErrorCode:    BetErrorSelectionFinalized,
+				ErrorMessage: "evenet already finalized",
+			}, nil
+		}
+
+		ev, ok, err := b.db.FetchEvent(ctx, sel.EventUUID)
+		if err != nil {
+			return BetResponse{}, err
+		}
+
+		if !ok {
+			return BetResponse{
+				Ok:           false,
+				ErrorCode:    BetErrorEventNotFound,
+				ErrorMessage: "cannot find event",
+			}, nil
+		}
+
+		if err := ev.AcceptsBets(sel, time.Now()); err != nil {
+			return BetResponse{
+				Ok:           false,
+				ErrorCode:    BetErrorBettingClosed,
+				ErrorMessage: err.Error(),
+			}, nil
+		}
+
+		if _, ok := events[sel.EventUUID]; ok {
+			return BetResponse{
+				Ok:           false,
+				ErrorCode:    BetErrorInvalidAccumulator,
+				ErrorMessage: "accumulator legs must be on different events",
+			}, nil
+		}
+
+		if !sel.Offers(acc.Legs[i].SelectionWinner) {
+			return BetResponse{
+				Ok:           false,
+				ErrorCode:    BetErrorOutcomeNotOffered,
+				ErrorMessage: "selection does not offer this outcome",
+			}, nil
+		}
+
+		limits, err := b.db.FetchLimits(ctx, ev.UUID, sel.UUID)
+		if err != nil {
+			return BetResponse{}, err
+		}
+
+		events[sel.EventUUID] = struct{}{}
+		legLimits = append(legLimits, limits)
+
+		acc.Legs[i].Odds = sel.Odds(acc.Legs[i].SelectionWinner)
+		acc.Legs[i].State = bet.BetStateTBD
+	}
+
+	acc.Odds = acc.CombinedOdds()
+	acc.Currency = u.Currency
+
+	payout := acc.Stake.Mul(acc.Odds)
+
+	for i, limits := range legLimits {
+		if err := limits.CheckStake(acc.Stake, acc.Odds); err != nil {
+			return limitResponse(err), nil
+		}
+
+		liability, err := b.db.FetchSelectionLiability(ctx, acc.Legs[i].SelectionUUID, acc.Legs[i].SelectionWinner)
+		if err != nil {
+			return BetResponse{}, err
+		}
+
+		if err := limits.CheckLiability(liability, payout); err != nil {
+			return limitResponse(err), nil
+		}
+	}
+
+	if resp, ok, err := b.checkGamblingLimits(ctx, u.UUID, acc.Stake); err != nil || !ok {
+		return resp, err
+	}
+
+	userCopy := *u
+
+	if err := userCopy.Debit(acc.Stake); err != nil {
+		return insufficientFundsResponse(*u, err), nil
+	}
+
+	if err := b.db.InsertAccumulator(ctx, *acc, userCopy); err != nil {
+		return BetResponse{}, err
+	}
+
+	*u = userCopy
+
+	return BetResponse{
+		Ok: true,
+	}, nil
+}
+
+// checkGamblingLimits checks the stake against the wager and loss limits
+// the user set, over the bets the user placed in each limit's period.
+func (b *better) checkGamblingLimits(ctx context.Context, userUUID uuid.UUID, stake decimal.Decimal) (BetResponse, bool, error) {
+	ll, err := b.db.FetchGamblingLimits(ctx, userUUID)
+	if err != nil {
+		return BetResponse{}, false, err
+	}
+
+	now := time.Now()
+
+	var (
+		checked []user.GamblingLimit
+		since   time.Time
+	)
+
+	for _, l := range ll {
+		l = l.Effective(now)
+
+		if l.Type == user.GamblingLimitDeposit || !l.Amount.IsPositive() {
+			continue
+		}
+
+		checked = append(checked, l)
+
+		if start := l.Period.Start(now); since.IsZero() || start.Before(since) {
+			since = start
+		}
+	}
+
+	if len(checked) == 0 {
+		return BetResponse{Ok: true}, true, nil
+	}
+
+	bb, err := b.db.FetchUserBetsSince(ctx, userUUID, since)
+	if err != nil {
+		return BetResponse{}, false, err
+	}
+
+	accs, err := b.db.FetchUserAccumulatorsSince(ctx, userUUID, since)
+	if err != nil {
+		return BetResponse{}, false, err
+	}
+
+	for _, l := range checked {
+		used := bet.Wagered(bb, accs, l.Period.Start(now))
+		if l.Type == user.GamblingLimitLoss {
+			used = bet.Lost(bb, accs, l.Period.Start(now))
+		}
+
+		if err := l.Check(used, stake, now); err != nil {
+			return BetResponse{
+				Ok:           false,
+				ErrorCode:    BetErrorGamblingLimitReached,
+				ErrorMessage: err.Error(),
+			}, false, nil
+		}
+	}
+
+	return BetResponse{Ok: true}, true, nil
+}
+
+func excludedResponse() BetResponse {
+	return BetResponse{
+		Ok:           false,
+		ErrorCode:    BetErrorUserExcluded,
+		ErrorMessage: "user is excluded from betting",
+	}
+}
+
+// insufficientFundsResponse rejects a stake the balance cannot cover.
+// Stakes never come from the bonus balance, as the bonus is only released
+// once it is wagered through with real money, so users holding bonus
+// funds are told why they cannot stake them.
+func insufficientFundsResponse(u user.BetUser, err error) BetResponse {
+	msg := err.Error()
+	if u.BonusBalance.IsPositive() {
+		msg += ", bonus balance cannot be staked until its wagering requirement is met"
+	}
+
+	return BetResponse{
+		Ok:           false,
+		ErrorCode:    BetErrorInsufficientFunds,
+		ErrorMessage: msg,
+	}
+}
+
+var limitErrorCodes = map[error]BetErrorCode{
+	bet.ErrStakeBelowMinimum:  BetErrorStakeBelowMinimum,
+	bet.ErrStakeAboveMaximum:  BetErrorStakeAboveMaximum,
+	bet.ErrPayoutAboveMaximum: BetErrorPayoutAboveMaximum,
+	bet.ErrLiabilityExceeded:  BetErrorLiabilityExceeded,
+}
+
+func limitResponse(err error) BetResponse {
+	return BetResponse{
+		Ok:           false,
+		ErrorCode:    limitErrorCodes[err],
+		ErrorMessage: err.Error(),
+	}
+}
+
+// QuoteCashOut prices an open bet of the user at the current odds.
+func (b *better) QuoteCashOut(ctx context.Context, id uuid.UUID, u user.BetUser) (decimal.Decimal, BetResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, amount, resp, err := b.quoteCashOut(ctx, id, u)
+
+	return amount, resp, err
+}
+
+// CashOut settles an open bet of the user at the current cash-out price,
+// as long as it still matches the price the user was quoted.
+func (b *better) CashOut(ctx context.Context, id uuid.UUID, quoted decimal.Decimal, u *user.BetUser) (decimal.Decimal, BetResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bt, amount, resp, err := b.quoteCashOut(ctx, id, *u)
+	if err != nil || !resp.Ok {
+		return amount, resp, err
+	}
+
+	if !amount.Equal(quoted) {
+		return amount, BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorCashOutPriceChanged,
+			ErrorMessage: "cash-out price changed",
+		}, nil
+	}
+
+	userCopy := *u
+
+	if err := userCopy.Credit(amount); err != nil {
+		return amount, BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorCashOutUnavailable,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+
+	bt.CashOut(amount)
+
+	if err := b.db.UpdateBet(ctx, bt, userCopy); err != nil {
+		return decimal.Zero, BetResponse{}, err
+	}
+
+	*u = userCopy
+
+	return amount, BetResponse{
+		Ok: true,
+	}, nil
+}
+
+func (b *better) quoteCashOut(ctx context.Context, id uuid.UUID, u user.BetUser) (bet.Bet, decimal.Decimal, BetResponse, error) {
+	bt, ok, err := b.db.FetchBet(ctx, id)
+	if err != nil {
+		return bet.Bet{}, decimal.Zero, BetResponse{}, err
+	}
+
+	if !ok || bt.UserUUID != u.UUID {
+		return bet.Bet{}, decimal.Zero, BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorBetNotFound,
+			ErrorMessage: "cannot find bet",
+		}, nil
+	}
+
+	sel, ok, err := b.db.FetchSelection(ctx, bt.SelectionUUID)
+	if err != nil {
+		return bet.Bet{}, decimal.Zero, BetResponse{}, err
+	}
+
+	if !ok {
+		return bet.Bet{}, decimal.Zero, BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorSelectionNotFound,
+			ErrorMessage: "cannot find selection",
+		}, nil
+	}
+
+	ev, ok, err := b.db.FetchEvent(ctx, sel.EventUUID)
+	if err != nil {
+		return bet.Bet{}, decimal.Zero, BetResponse{}, err
+	}
+
+	if !ok || ev.Status == bet.EventStatusSuspended {
+		return bet.Bet{}, decimal.Zero, BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorCashOutUnavailable,
+			ErrorMessage: "cash-out is not available",
+		}, nil
+	}
+
+	amount, err := bt.CashOutValue(sel, b.cashOutMargin)
+	if err != nil {
+		return bet.Bet{}, decimal.Zero, BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorCashOutUnavailable,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+
+	return bt, amount, BetResponse{
+		Ok: true,
+	}, nil
+}
+
+// Payout describes how settlement affects a single bet or accumulator.
+type Payout struct {
+	BetUUID  uuid.UUID
+	UserUUID uuid.UUID
+	Stake    decimal.Decimal
+	State    bet.BetState
+	Amount   decimal.Decimal
+}
+
+// Settlement collects every change caused by resolving event selections,
+// so that it can be stored in one transaction or previewed.
+type Settlement struct {
+	Event        bet.Event
+	Selections   []bet.EventSelection
+	Bets         []bet.Bet
+	Accumulators []bet.Accumulator
+	Users        []user.BetUser
+	Payouts      []Payout
+	// Adjustments lists the balance changes made when correcting an
+	// already settled selection.
+	Adjustments []purse.BalanceAdjustment
+	Entries     []purse.Entry
+	// Grants lists the bonus grants the settled stakes were wagered on.
+	Grants []bonus.Grant
+}
+
+// ResolveEventSelection settles the selection with the winner. The
+// selection is read again under the lock, so that a selection settled in
+// the meantime is not paid out twice.
+func (b *better) ResolveEventSelection(ctx context.Context, id uuid.UUID, w bet.Winner) (BetResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sel, ok, err := b.db.FetchSelection(ctx, id)
+	if err != nil {
+		return BetResponse{}, err
+	}
+
+	if !ok {
+		return BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorSelectionNotFound,
+			ErrorMessage: "cannot find selection",
+		}, nil
+	}
+
+	if sel.Winner.Finalized() {
+		return BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorSelectionFinalized,
+			ErrorMessage: "selection already settled, resettle it to change the winner",
+		}, nil
+	}
+
+	if !w.Finalized() {
+		return BetResponse{
+			Ok: true,
+		}, nil
+	}
+
+	ev, ok, err := b.db.FetchEvent(ctx, sel.EventUUID)
+	if err != nil {
+		return BetResponse{}, err
+	}
+
+	if !ok {
+		return BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorEventNotFound,
+			ErrorMessage: "cannot find event",
+		}, nil
+	}
+
+	sel.Winner = w
+
+	st, err := b.settle(ctx, ev, []bet.EventSelection{sel})
+	if err != nil {
+		return BetResponse{}, err
+	}
+
+	if err := b.db.InsertSettlement(ctx, st); err != nil {
+		return BetResponse{}, err
+	}
+
+	return BetResponse{
+		Ok: true,
+	}, nil
+}
+
+// ResolveEvent records the score of the event and settles every selection
+// whose winner can be derived from it. With dryRun set nothing is stored
+// and the returned settlement only shows what would be paid out.
+func (b *better) ResolveEvent(ctx context.Context, id uuid.UUID, final bet.Score, halfTime *bet.Score, dryRun bool) (Settlement, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ev, ok, err := b.db.FetchEvent(ctx, id)
+	if err != nil {
+		return Settlement{}, err
+	}
+
+	if !ok {
+		return Settlement{}, errors.New("event not found")
+	}
+
+	if err := ev.RecordScore(final, halfTime); err != nil {
+		return Settlement{}, err
+	}
+
+	st, err := b.settle(ctx, ev, ev.ScoreSelections())
+	if err != nil {
+		return Settlement{}, err
+	}
+
+	if dryRun {
+		return st, nil
+	}
+
+	if err := b.db.InsertSettlement(ctx, st); err != nil {
+		return Settlement{}, err
+	}
+
+	return st, nil
+}
+
+// CancelEvent cancels the event, voiding every unsettled selection and
+// refunding the open bets placed on them.
+func (b *better) CancelEvent(ctx context.Context, id uuid.UUID) (BetResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ev, ok, err := b.db.FetchEvent(ctx, id)
+	if err != nil {
+		return BetResponse{}, err
+	}
+
+	if !ok {
+		return BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorEventNotFound,
+			ErrorMessage: "cannot find event",
+		}, nil
+	}
+
+	sels, err := ev.Cancel()
+	if err != nil {
+		return BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorInvalidStatus,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+
+	st, err := b.settle(ctx, ev, sels)
+	if err != nil {
+		return BetResponse{}, err
+	}
+
+	if err := b.db.InsertSettlement(ctx, st); err != nil {
+		return BetResponse{}, err
+	}
+
+	return BetResponse{
+		Ok: true,
+	}, nil
+}
+
+// ResettleSelection corrects the winner of an already settled selection.
+// Every payout made for the previous winner is taken back, leaving the
+// user with a negative balance only if allowNegative is set, and the
+// reopened bets are settled again for the new winner. Each balance change
+// is recorded as an adjustment made by the admin.
+func (b *better) ResettleSelection(ctx context.Context, id uuid.UUID, w bet.Winner, allowNegative, dryRun bool, admin uuid.UUID) (Settlement, BetResponse, error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	sel, ok, err := b.db.FetchSelection(ctx, id)
+	if err != nil {
+		return Settlement{}, BetResponse{}, err
+	}
+
+	if !ok {
+		return Settlement{}, BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorSelectionNotFound,
+			ErrorMessage: "cannot find selection",
+		}, nil
+	}
+
 	if !sel.Winner.Finalized() {
-		return nil
+		return Settlement{}, BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorSelectionNotSettled,
+			ErrorMessage: "selection is not settled",
+		}, nil
+	}
+
+	if sel.Winner == w {
+		return Settlement{}, BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorSelectionFinalized,
+			ErrorMessage: "selection is already settled with this winner",
+		}, nil
+	}
+
+	ev, ok, err := b.db.FetchEvent(ctx, sel.EventUUID)
+	if err != nil {
+		return Settlement{}, BetResponse{}, err
+	}
+
+	if !ok {
+		return Settlement{}, BetResponse{
+			Ok:           false,
+			ErrorCode:    BetErrorEventNotFound,
+			ErrorMessage: "cannot find event",
+		}, nil
 	}
 
 	bets, err := b.db.FetchBetsBySelection(ctx, sel.UUID)
 	if err != nil {
-		return err
+		return Settlement{}, BetResponse{}, err
+	}
+
+	accs, err := b.db.FetchAccumulatorsBySelection(ctx, sel.UUID)
+	if err != nil {
+		return Settlement{}, BetResponse{}, err
+	}
+
+	s := newSettler(b.db, ev)
+	s.admin = admin
+	s.reason = purse.AdjustmentReasonUnsettle
+
+	reverse := func(userUUID, betUUID uuid.UUID, amount decimal.Decimal) (BetResponse, error) {
+		u, ok, err := s.fetchUser(ctx, userUUID)
+		if err != nil || !ok || amount.IsZero() {
+			return BetResponse{Ok: true}, err
+		}
+
+		if err := u.Reverse(amount, allowNegative); err != nil {
+			return BetResponse{
+				Ok:           false,
+				ErrorCode:    BetErrorInsufficientFunds,
+				ErrorMessage: "cannot take back payout of " + betUUID.String() + ": " + err.Error(),
+			}, nil
+		}
+
+		ba := s.adjust(*u, betUUID, sel.UUID, amount.Neg())
+		s.st.Entries = append(s.st.Entries, purse.AdjustmentEntry(ba))
+
+		return BetResponse{Ok: true}, nil
+	}
+
+	for i := range bets {
+		paid := bets[i].Payout()
+
+		if err := bets[i].Unsettle(); err != nil {
+			continue
+		}
+
+		resp, err := reverse(bets[i].UserUUID, bets[i].UUID, paid)
+		if err != nil || !resp.Ok {
+			return Settlement{}, resp, err
+		}
+	}
+
+	for i := range accs {
+		prev := accs[i].State
+		paid := accs[i].Payout()
+
+		accs[i].Unsettle(sel.UUID)
+
+		if prev == bet.BetStateTBD || accs[i].State != bet.BetStateTBD {
+			continue
+		}
+
+		resp, err := reverse(accs[i].UserUUID, accs[i].UUID, paid)
+		if err != nil || !resp.Ok {
+			return Settlement{}, resp, err
+		}
+	}
+
+	sel.Winner = w
+	s.reason = purse.AdjustmentReasonResettle
+
+	if err := s.settleSelection(ctx, sel, bets, accs); err != nil {
+		return Settlement{}, BetResponse{}, err
+	}
+
+	st := s.settlement()
+
+	if dryRun {
+		return st, BetResponse{Ok: true}, nil
 	}
 
-	if err := b.db.UpdateSelection(ctx, sel); err != nil {
+	if err := b.db.InsertSettlement(ctx, st); err != nil {
+		return Settlement{}, BetResponse{}, err
+	}
+
+	return st, BetResponse{Ok: true}, nil
+}
+
+func (b *better) settle(ctx context.Context, ev bet.Event, sels []bet.EventSelection) (Settlement, error) {
+	s := newSettler(b.db, ev)
+
+	for _, sel := range sels {
+		bets, err := b.db.FetchBetsBySelection(ctx, sel.UUID)
+		if err != nil {
+			return Settlement{}, err
+		}
+
+		accs, err := b.db.FetchAccumulatorsBySelection(ctx, sel.UUID)
+		if err != nil {
+			return Settlement{}, err
+		}
+
+		if err := s.settleSelection(ctx, sel, bets, accs); err != nil {
+			return Settlement{}, err
+		}
+	}
+
+	return s.settlement(), nil
+}
+
+// settler builds a settlement, keeping a single copy of every user whose
+// balance is changed by it.
+type settler struct {
+	db     BetDB
+	st     Settlement
+	users  map[uuid.UUID]*user.BetUser
+	grants map[uuid.UUID][]bonus.Grant
+
+	// reason, when set, makes every balance change be recorded as an
+	// adjustment made by the admin.
+	reason purse.AdjustmentReason
+	admin  uuid.UUID
+}
+
+func newSettler(db BetDB, ev bet.Event) *settler {
+	return &settler{
+		db: db,
+		st: Settlement{
+			Event: ev,
+		},
+		users:  make(map[uuid.UUID]*user.BetUser),
+		grants: make(map[uuid.UUID][]bonus.Grant),
+	}
+}
+
+func (s *settler) fetchUser(ctx context.Context, id uuid.UUID) (*user.BetUser, bool, error) {
+	if u, ok := s.users[id]; ok {
+		return u, true, nil
+	}
+
+	u, ok, err := s.db.FetchBetUserByUUID(ctx, id)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+
+	s.users[id] = &u
+
+	return &u, true, nil
+}
+
+func (s *settler) adjust(u user.BetUser, betUUID, selUUID uuid.UUID, amount decimal.Decimal) purse.BalanceAdjustment {
+	ba := purse.BalanceAdjustment{
+		UUID:          uuid.New(),
+		UserUUID:      u.UUID,
+		AdminUUID:     s.admin,
+		BetUUID:       betUUID,
+		SelectionUUID: selUUID,
+		Amount:        amount,
+		Balance:       u.Balance,
+		Reason:        s.reason,
+		Timestamp:     time.Now(),
+	}
+
+	if s.reason != "" {
+		s.st.Adjustments = append(s.st.Adjustments, ba)
+	}
+
+	return ba
+}
+
+// pay credits the user with the payout of a settled bet or accumulator.
+func (s *settler) pay(u *user.BetUser, betUUID, selUUID uuid.UUID, state bet.BetState, amount decimal.Decimal) error {
+	if err := u.Credit(amount); err != nil {
 		return err
 	}
 
+	if amount.IsZero() {
+		return nil
+	}
+
+	typ := purse.EntryTypePayout
+	if state == bet.BetStateVoid {
+		typ = purse.EntryTypeRefund
+	}
+
+	s.st.Entries = append(s.st.Entries, purse.SettlementEntry(typ, u.UUID, betUUID, amount, time.Now()))
+	s.adjust(*u, betUUID, selUUID, amount)
+
+	return nil
+}
+
+// wager counts the stake of a bet settled as won or lost towards the
+// wagering requirements of the user's active bonus grants, oldest first.
+// Corrections of settled selections do not count stakes again.
+func (s *settler) wager(ctx context.Context, userUUID uuid.UUID, stake, odds decimal.Decimal, placed time.Time) error {
+	if s.reason != "" {
+		return nil
+	}
+
+	gg, ok := s.grants[userUUID]
+	if !ok {
+		var err error
+
+		gg, err = s.db.FetchActiveBonusGrants(ctx, userUUID)
+		if err != nil {
+			return err
+		}
+
+		s.grants[userUUID] = gg
+	}
+
+	for i := range gg {
+		if stake = gg[i].Wager(stake, odds, placed); stake.IsZero() {
+			break
+		}
+	}
+
+	return nil
+}
+
+// settleSelection resolves the open bets and the accumulators placed on
+// the selection and credits their payouts.
+func (s *settler) settleSelection(ctx context.Context, sel bet.EventSelection, bets []bet.Bet, accs []bet.Accumulator) error {
+	for i := range s.st.Event.Selections {
+		if s.st.Event.Selections[i].UUID == sel.UUID {
+			s.st.Event.Selections[i] = sel
+		}
+	}
+
+	s.st.Selections = append(s.st.Selections, sel)
+
 	for _, bt := range bets {
-		u, ok, err := b.db.FetchBetUserByUUID(ctx, bt.UserUUID)
+		if bt.State != bet.BetStateTBD {
+			continue
+		}
+
+		u, ok, err := s.fetchUser(ctx, bt.UserUUID)
 		if err != nil {
 			return err
 		}
@@ -105,48 +1013,124 @@ func (b *better) ResolveEventSelection(ctx context.Context, sel bet.EventSelecti
 			continue
 		}
 
-		bt.Resolve(sel.Winner)
+		bt.Resolve(sel)
 
-		if bt.State == bet.BetStateWon {
-			if err := u.Credit(bt.Stake.Mul(sel.WinnerOdds())); err != nil {
-				continue
-			}
+		amount := bt.Payout()
+
+		if err := s.pay(u, bt.UUID, sel.UUID, bt.State, amount); err != nil {
+			continue
 		}
 
-		if sel.Winner == bet.WinnnerNone {
-			if err := u.Credit(bt.Stake); err != nil {
-				continue
+		if !bt.FreeBet && (bt.State == bet.BetStateWon || bt.State == bet.BetStateLost) {
+			if err := s.wager(ctx, bt.UserUUID, bt.Stake, bt.Odds, bt.Timestamp); err != nil {
+				return err
 			}
 		}
 
-		if err := b.db.UpdateBet(ctx, bt, u); err != nil {
+		s.st.Bets = append(s.st.Bets, bt)
+		s.st.Payouts = append(s.st.Payouts, Payout{
+			BetUUID:  bt.UUID,
+			UserUUID: bt.UserUUID,
+			Stake:    bt.Stake,
+			State:    bt.State,
+			Amount:   amount,
+		})
+	}
+
+	for _, acc := range accs {
+		u, ok, err := s.fetchUser(ctx, acc.UserUUID)
+		if err != nil {
 			return err
 		}
+
+		if !ok {
+			continue
+		}
+
+		prev := acc.State
+
+		acc.Resolve(sel)
+
+		if prev == bet.BetStateTBD && acc.State != bet.BetStateTBD {
+			if err := s.pay(u, acc.UUID, sel.UUID, acc.State, acc.Payout()); err != nil {
+				continue
+			}
+
+			if acc.State == bet.BetStateWon || acc.State == bet.BetStateLost {
+				if err := s.wager(ctx, acc.UserUUID, acc.Stake, acc.Odds, acc.Timestamp); err != nil {
+					return err
+				}
+			}
+
+			s.st.Payouts = append(s.st.Payouts, Payout{
+				BetUUID:  acc.UUID,
+				UserUUID: acc.UserUUID,
+				Stake:    acc.Stake,
+				State:    acc.State,
+				Amount:   acc.Payout(),
+			})
+		}
+
+		s.st.Accumulators = append(s.st.Accumulators, acc)
 	}
 
-	ev, ok, err := b.db.FetchEvent(ctx, sel.EventUUID)
-	if err != nil {
-		return nil
+	return nil
+}
+
+func (s *settler) settlement() Settlement {
+	st := s.st
+
+	if st.Event.Finished() && st.Event.Status.CanTransition(bet.EventStatusFinished) {
+		st.Event.Status = bet.EventStatusFinished
 	}
 
-	if !ok {
-		return errors.New("event not found")
+	// the funds of a grant become withdrawable once it is wagered through,
+	// the grants still active keep theirs. The grants were all active when
+	// fetched, so each completed one is released once.
+	for id, gg := range s.grants {
+		st.Grants = append(st.Grants, gg...)
+
+		u, ok := s.users[id]
+		if !ok {
+			continue
+		}
+
+		for _, g := range gg {
+			if g.Status != bonus.GrantStatusCompleted {
+				continue
+			}
+
+			if amount := u.ReleaseBonus(g.Amount); amount.IsPositive() {
+				st.Entries = append(st.Entries, purse.BonusReleaseEntry(id, g.UUID, amount, time.Now()))
+			}
+		}
 	}
 
-	if err := b.db.UpdateEvent(ctx, ev); err != nil {
-		return err
+	for _, u := range s.users {
+		st.Users = append(st.Users, *u)
 	}
 
-	return nil
+	return st
 }
 
 type BetDB interface {
 	FetchSelection(context.Context, uuid.UUID) (bet.EventSelection, bool, error)
 	FetchEvent(context.Context, uuid.UUID) (bet.Event, bool, error)
 	FetchBetUserByUUID(context.Context, uuid.UUID) (user.BetUser, bool, error)
+	FetchBet(context.Context, uuid.UUID) (bet.Bet, bool, error)
 	FetchBetsBySelection(context.Context, uuid.UUID) ([]bet.Bet, error)
-	UpdateSelection(context.Context, bet.EventSelection) error
+	FetchLimits(ctx context.Context, eventUUID, selectionUUID uuid.UUID) (bet.Limits, error)
+	FetchSelectionLiability(context.Context, uuid.UUID, bet.Winner) (decimal.Decimal, error)
+	FetchAccumulatorsBySelection(context.Context, uuid.UUID) ([]bet.Accumulator, error)
+	FetchBonusToken(context.Context, uuid.UUID) (bonus.Token, bool, error)
+	FetchGamblingLimits(context.Context, uuid.UUID) ([]user.GamblingLimit, error)
+	// FetchUserBetsSince returns the bets the user placed from the given
+	// time on.
+	FetchUserBetsSince(context.Context, uuid.UUID, time.Time) ([]bet.Bet, error)
+	FetchUserAccumulatorsSince(context.Context, uuid.UUID, time.Time) ([]bet.Accumulator, error)
+	FetchActiveBonusGrants(context.Context, uuid.UUID) ([]bonus.Grant, error)
 	InsertBet(context.Context, bet.Bet, user.BetUser) error
 	UpdateBet(context.Context, bet.Bet, user.BetUser) error
-	UpdateEvent(context.Context, bet.Event) error
+	InsertAccumulator(context.Context, bet.Accumulator, user.BetUser) error
+	InsertSettlement(context.Context, Settlement) error
 }
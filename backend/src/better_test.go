@@ -0,0 +1,526 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ramasauskas/ispbet/bet"
+	"github.com/ramasauskas/ispbet/bonus"
+	"github.com/ramasauskas/ispbet/purse"
+	"github.com/ramasauskas/ispbet/user"
+	"github.com/shopspring/decimal"
+)
+
+// settlerDB serves the users, bonus grants, selections and bets the
+// settler reads. It stores the changes of a settlement when applied.
+type settlerDB struct {
+	BetDB
+
+	users  map[uuid.UUID]user.BetUser
+	grants []bonus.Grant
+	event  bet.Event
+	bets   []bet.Bet
+	accs   []bet.Accumulator
+}
+
+func (d *settlerDB) FetchSelection(_ context.Context, id uuid.UUID) (bet.EventSelection, bool, error) {
+	for _, sel := range d.event.Selections {
+		if sel.UUID == id {
+			return sel, true, nil
+		}
+	}
+
+	return bet.EventSelection{}, false, nil
+}
+
+func (d *settlerDB) FetchEvent(context.Context, uuid.UUID) (bet.Event, bool, error) {
+	return d.event, true, nil
+}
+
+func (d *settlerDB) FetchBetsBySelection(_ context.Context, id uuid.UUID) ([]bet.Bet, error) {
+	var bb []bet.Bet
+
+	for _, b := range d.bets {
+		if b.SelectionUUID == id {
+			bb = append(bb, b)
+		}
+	}
+
+	return bb, nil
+}
+
+func (d *settlerDB) FetchAccumulatorsBySelection(_ context.Context, id uuid.UUID) ([]bet.Accumulator, error) {
+	var accs []bet.Accumulator
+
+	for _, acc := range d.accs {
+		for _, l := range acc.Legs {
+			if l.SelectionUUID == id {
+				accs = append(accs, acc)
+				break
+			}
+		}
+	}
+
+	return accs, nil
+}
+
+func (d *settlerDB) InsertSettlement(_ context.Context, st Settlement) error {
+	d.apply(st)
+	return nil
+}
+
+func (d *settlerDB) FetchBetUserByUUID(_ context.Context, id uuid.UUID) (user.BetUser, bool, error) {
+	u, ok := d.users[id]
+	return u, ok, nil
+}
+
+func (d *settlerDB) FetchActiveBonusGrants(_ context.Context, id uuid.UUID) ([]bonus.Grant, error) {
+	var gg []bonus.Grant
+
+	for _, g := range d.grants {
+		if g.UserUUID == id && g.Status == bonus.GrantStatusActive {
+			gg = append(gg, g)
+		}
+	}
+
+	return gg, nil
+}
+
+func (d *settlerDB) apply(st Settlement) {
+	for _, u := range st.Users {
+		d.users[u.UUID] = u
+	}
+
+	for _, sel := range st.Selections {
+		for i := range d.event.Selections {
+			if d.event.Selections[i].UUID == sel.UUID {
+				d.event.Selections[i] = sel
+			}
+		}
+	}
+
+	for _, b := range st.Bets {
+		for i := range d.bets {
+			if d.bets[i].UUID == b.UUID {
+				d.bets[i] = b
+			}
+		}
+	}
+
+	for _, acc := range st.Accumulators {
+		for i := range d.accs {
+			if d.accs[i].UUID == acc.UUID {
+				d.accs[i] = acc
+			}
+		}
+	}
+
+	for _, g := range st.Grants {
+		for i := range d.grants {
+			if d.grants[i].UUID == g.UUID {
+				d.grants[i] = g
+			}
+		}
+	}
+}
+
+// settle resolves a selection with the home team winning, settling the
+// bets of the user on it.
+func (d *settlerDB) settle(t *testing.T, userUUID uuid.UUID, bb []bet.Bet) Settlement {
+	t.Helper()
+
+	sel := bet.EventSelection{
+		UUID:   uuid.New(),
+		Type:   bet.MarketTypeMatchWinner,
+		Winner: bet.WinnerHome,
+	}
+
+	for i := range bb {
+		bb[i].UUID = uuid.New()
+		bb[i].UserUUID = userUUID
+		bb[i].SelectionUUID = sel.UUID
+		bb[i].State = bet.BetStateTBD
+	}
+
+	s := newSettler(d, bet.Event{Selections: []bet.EventSelection{sel}})
+
+	if err := s.settleSelection(context.Background(), sel, bb, nil); err != nil {
+		t.Fatalf("cannot settle selection: %v", err)
+	}
+
+	st := s.settlement()
+	d.apply(st)
+
+	return st
+}
+
+func TestSettlerBonusRelease(t *testing.T) {
+	placed := time.Now()
+
+	type grant struct {
+		amount   string
+		required string
+		minOdds  string
+	}
+
+	type stake struct {
+		amount  string
+		winner  bet.Winner
+		freeBet bool
+	}
+
+	tests := map[string]struct {
+		// grants are the active grants of the user, oldest first, with
+		// their amounts in the bonus balance.
+		grants []grant
+		// settlements lists the bets at odds 2 settled one selection at a
+		// time.
+		settlements [][]stake
+		// released are the amounts released, one per completed grant.
+		released []string
+		balance  string
+		bonus    string
+	}{
+		"wagering not met": {
+			grants: []grant{{amount: "10", required: "20", minOdds: "1.5"}},
+			settlements: [][]stake{
+				{{amount: "10", winner: bet.WinnerAway}},
+			},
+			balance: "0",
+			bonus:   "10",
+		},
+		"wagering met by one settlement": {
+			grants: []grant{{amount: "10", required: "20", minOdds: "1.5"}},
+			settlements: [][]stake{
+				{{amount: "10", winner: bet.WinnerAway}, {amount: "10", winner: bet.WinnerAway}},
+			},
+			released: []string{"10"},
+			balance:  "10",
+			bonus:    "0",
+		},
+		"wagering met past the requirement": {
+			grants: []grant{{amount: "10", required: "20", minOdds: "1.5"}},
+			settlements: [][]stake{
+				{{amount: "10", winner: bet.WinnerAway}, {amount: "10", winner: bet.WinnerAway}, {amount: "10", winner: bet.WinnerAway}},
+			},
+			released: []string{"10"},
+			balance:  "10",
+			bonus:    "0",
+		},
+		"wagering met over several settlements": {
+			grants: []grant{{amount: "10", required: "20", minOdds: "1.5"}},
+			settlements: [][]stake{
+				{{amount: "10", winner: bet.WinnerAway}},
+				{{amount: "10", winner: bet.WinnerHome}},
+				{{amount: "10", winner: bet.WinnerAway}},
+			},
+			released: []string{"10"},
+			balance:  "30",
+			bonus:    "0",
+		},
+		"free bets are not wagered": {
+			grants: []grant{{amount: "10", required: "20", minOdds: "1.5"}},
+			settlements: [][]stake{
+				{{amount: "10", winner: bet.WinnerAway, freeBet: true}, {amount: "10", winner: bet.WinnerAway, freeBet: true}},
+			},
+			balance: "0",
+			bonus:   "10",
+		},
+		"older grant still active": {
+			grants: []grant{
+				{amount: "100", required: "1000", minOdds: "3"},
+				{amount: "10", required: "20", minOdds: "1.5"},
+			},
+			settlements: [][]stake{
+				{{amount: "10", winner: bet.WinnerAway}, {amount: "10", winner: bet.WinnerAway}},
+			},
+			released: []string{"10"},
+			balance:  "10",
+			bonus:    "100",
+		},
+		"both grants wagered through": {
+			grants: []grant{
+				{amount: "100", required: "20", minOdds: "1.5"},
+				{amount: "10", required: "20", minOdds: "1.5"},
+			},
+			settlements: [][]stake{
+				{{amount: "30", winner: bet.WinnerAway}},
+				{{amount: "10", winner: bet.WinnerAway}},
+			},
+			released: []string{"100", "10"},
+			balance:  "110",
+			bonus:    "0",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := &settlerDB{
+				users: make(map[uuid.UUID]user.BetUser),
+			}
+
+			u := user.BetUser{
+				User:         user.User{UUID: uuid.New()},
+				BonusBalance: decimal.Zero,
+			}
+
+			for i, g := range test.grants {
+				amount := decimal.RequireFromString(g.amount)
+				u.BonusBalance = u.BonusBalance.Add(amount)
+
+				d.grants = append(d.grants, bonus.Grant{
+					UUID:             uuid.New(),
+					UserUUID:         u.UUID,
+					Amount:           amount,
+					WageringRequired: decimal.RequireFromString(g.required),
+					Wagered:          decimal.Zero,
+					MinOdds:          decimal.RequireFromString(g.minOdds),
+					Status:           bonus.GrantStatusActive,
+					CreatedAt:        placed.Add(-time.Duration(len(test.grants)-i) * time.Hour),
+				})
+			}
+
+			d.users[u.UUID] = u
+
+			var released []purse.Entry
+
+			for _, ss := range test.settlements {
+				bb := make([]bet.Bet, 0, len(ss))
+
+				for _, s := range ss {
+					bb = append(bb, bet.Bet{
+						SelectionWinner: s.winner,
+						Stake:           decimal.RequireFromString(s.amount),
+						Odds:            decimal.NewFromInt(2),
+						Timestamp:       placed,
+						FreeBet:         s.freeBet,
+					})
+				}
+
+				for _, e := range d.settle(t, u.UUID, bb).Entries {
+					if e.Type == purse.EntryTypeBonusRelease {
+						released = append(released, e)
+					}
+				}
+			}
+
+			if len(released) != len(test.released) {
+				t.Fatalf("want %d bonus releases, got %d", len(test.released), len(released))
+			}
+
+			for i, e := range released {
+				if !e.Amount.Equal(decimal.RequireFromString(test.released[i])) {
+					t.Errorf("release %d: want %s, got %s", i, test.released[i], e.Amount)
+				}
+			}
+
+			u = d.users[u.UUID]
+
+			if !u.Balance.Equal(decimal.RequireFromString(test.balance)) {
+				t.Errorf("want balance %s, got %s", test.balance, u.Balance)
+			}
+
+			if !u.BonusBalance.Equal(decimal.RequireFromString(test.bonus)) {
+				t.Errorf("want bonus balance %s, got %s", test.bonus, u.BonusBalance)
+			}
+		})
+	}
+}
+
+func TestSettlerFreeBetStake(t *testing.T) {
+	tests := map[string]struct {
+		winner  bet.Winner
+		state   bet.BetState
+		balance string
+	}{
+		"won":  {winner: bet.WinnerHome, state: bet.BetStateWon, balance: "15"},
+		"lost": {winner: bet.WinnerAway, state: bet.BetStateLost, balance: "0"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			u := user.BetUser{
+				User: user.User{UUID: uuid.New()},
+			}
+
+			d := &settlerDB{
+				users: map[uuid.UUID]user.BetUser{u.UUID: u},
+			}
+
+			st := d.settle(t, u.UUID, []bet.Bet{{
+				SelectionWinner: test.winner,
+				Stake:           decimal.NewFromInt(10),
+				Odds:            decimal.RequireFromString("2.5"),
+				Timestamp:       time.Now(),
+				FreeBet:         true,
+			}})
+
+			if len(st.Bets) != 1 || st.Bets[0].State != test.state {
+				t.Fatalf("want the bet %s, got %+v", test.state, st.Bets)
+			}
+
+			if u = d.users[u.UUID]; !u.Balance.Equal(decimal.RequireFromString(test.balance)) {
+				t.Errorf("want balance %s, got %s", test.balance, u.Balance)
+			}
+		})
+	}
+}
+
+func TestBetterResolveEventSelection(t *testing.T) {
+	var (
+		u    = user.BetUser{User: user.User{UUID: uuid.New()}}
+		sel  = bet.EventSelection{UUID: uuid.New(), Type: bet.MarketTypeMatchWinner, Winner: bet.WinnerTBD}
+		sel2 = bet.EventSelection{UUID: uuid.New(), Type: bet.MarketTypeMatchWinner, Winner: bet.WinnerTBD}
+	)
+
+	d := &settlerDB{
+		users: map[uuid.UUID]user.BetUser{u.UUID: u},
+		event: bet.Event{Selections: []bet.EventSelection{sel, sel2}},
+		bets: []bet.Bet{{
+			UUID:            uuid.New(),
+			UserUUID:        u.UUID,
+			SelectionUUID:   sel.UUID,
+			SelectionWinner: bet.WinnerHome,
+			Stake:           decimal.NewFromInt(10),
+			Odds:            decimal.NewFromInt(2),
+			State:           bet.BetStateTBD,
+		}},
+		accs: []bet.Accumulator{{
+			UUID:     uuid.New(),
+			UserUUID: u.UUID,
+			Stake:    decimal.NewFromInt(10),
+			Odds:     decimal.NewFromInt(6),
+			State:    bet.BetStateTBD,
+			Legs: []bet.AccumulatorLeg{
+				{SelectionUUID: sel.UUID, SelectionWinner: bet.WinnerHome, Odds: decimal.NewFromInt(2), State: bet.BetStateTBD},
+				{SelectionUUID: sel2.UUID, SelectionWinner: bet.WinnerHome, Odds: decimal.NewFromInt(3), State: bet.BetStateTBD},
+			},
+		}},
+	}
+
+	b := &better{db: d}
+
+	// Concurrent resolves of the same selection settle it once.
+	var (
+		wg    sync.WaitGroup
+		resps = make([]BetResponse, 4)
+	)
+
+	for i := range resps {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			resp, err := b.ResolveEventSelection(context.Background(), sel.UUID, bet.WinnerHome)
+			if err != nil {
+				t.Errorf("want no error, got %v", err)
+			}
+
+			resps[i] = resp
+		}(i)
+	}
+
+	wg.Wait()
+
+	var ok int
+
+	for _, resp := range resps {
+		if resp.Ok {
+			ok++
+			continue
+		}
+
+		if resp.ErrorCode != BetErrorSelectionFinalized {
+			t.Errorf("want %s, got %s", BetErrorSelectionFinalized, resp.ErrorCode)
+		}
+	}
+
+	if ok != 1 {
+		t.Fatalf("want 1 resolve to settle the selection, got %d", ok)
+	}
+
+	if bal := d.users[u.UUID].Balance; !bal.Equal(decimal.NewFromInt(20)) {
+		t.Errorf("want balance 20, got %s", bal)
+	}
+
+	resp, err := b.ResolveEventSelection(context.Background(), sel2.UUID, bet.WinnerHome)
+	if err != nil || !resp.Ok {
+		t.Fatalf("cannot resolve second selection: %v %+v", err, resp)
+	}
+
+	if bal := d.users[u.UUID].Balance; !bal.Equal(decimal.NewFromInt(80)) {
+		t.Errorf("want balance 80 with the accumulator paid once, got %s", bal)
+	}
+}
+
+func TestBetterBetBonusBalance(t *testing.T) {
+	sel := bet.EventSelection{
+		UUID:   uuid.New(),
+		Type:   bet.MarketTypeMatchWinner,
+		Winner: bet.WinnerTBD,
+		Status: bet.MarketStatusOpen,
+	}
+
+	tests := map[string]struct {
+		balance string
+		bonus   string
+		hint    bool
+	}{
+		"no bonus balance": {
+			balance: "5",
+			bonus:   "0",
+		},
+		"bonus balance held": {
+			balance: "5",
+			bonus:   "50",
+			hint:    true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			u := user.BetUser{
+				User:         user.User{UUID: uuid.New()},
+				Balance:      decimal.RequireFromString(test.balance),
+				BonusBalance: decimal.RequireFromString(test.bonus),
+			}
+
+			d := &settlerDB{
+				event: bet.Event{
+					Status:     bet.EventStatusScheduled,
+					BeginsAt:   time.Now().Add(time.Hour),
+					Selections: []bet.EventSelection{sel},
+				},
+			}
+
+			b := &better{db: d}
+
+			resp, err := b.Bet(context.Background(), &bet.Bet{
+				UUID:            uuid.New(),
+				SelectionUUID:   sel.UUID,
+				SelectionWinner: bet.WinnerHome,
+				Stake:           decimal.NewFromInt(10),
+			}, bet.OddsPolicyAny, &u)
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+
+			if resp.Ok || resp.ErrorCode != BetErrorInsufficientFunds {
+				t.Fatalf("want %s, got %+v", BetErrorInsufficientFunds, resp)
+			}
+
+			if hint := strings.Contains(resp.ErrorMessage, "bonus balance"); hint != test.hint {
+				t.Errorf("want bonus balance explained %t, got %q", test.hint, resp.ErrorMessage)
+			}
+
+			if !u.Balance.Equal(decimal.RequireFromString(test.balance)) || !u.BonusBalance.Equal(decimal.RequireFromString(test.bonus)) {
+				t.Errorf("want balances unchanged, got %s and %s", u.Balance, u.BonusBalance)
+			}
+		})
+	}
+}
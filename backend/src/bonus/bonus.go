@@ -0,0 +1,280 @@
+package bonus
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ramasauskas/ispbet/purse"
+	"github.com/shopspring/decimal"
+)
+
+type CampaignType string
+
+const (
+	// CampaignTypeDepositMatch grants bonus funds matching a share of a
+	// deposit.
+	CampaignTypeDepositMatch CampaignType = "deposit_match"
+	// CampaignTypeFreeBet issues a token placing a single bet without
+	// paying its stake.
+	CampaignTypeFreeBet CampaignType = "free_bet"
+	// CampaignTypeOddsBoost issues a token raising the odds of a single
+	// bet.
+	CampaignTypeOddsBoost CampaignType = "odds_boost"
+)
+
+func (t CampaignType) Validate() error {
+	switch t {
+	case CampaignTypeDepositMatch, CampaignTypeFreeBet, CampaignTypeOddsBoost:
+		return nil
+	default:
+		return errors.New("invalid campaign type, must be deposit_match, free_bet or odds_boost")
+	}
+}
+
+// Campaign is a promotion defined by an admin, claimed by every user at
+// most once while it runs. Only the fields of its type are used.
+type Campaign struct {
+	UUID     uuid.UUID
+	Name     string
+	Type     CampaignType
+	Currency purse.Currency
+	// MatchRate is the share of the deposit granted, up to MaxAmount.
+	MatchRate decimal.Decimal
+	MaxAmount decimal.Decimal
+	// WageringMultiplier of the granted amount has to be staked on settled
+	// bets at MinOdds or higher before the bonus funds can be withdrawn.
+	WageringMultiplier decimal.Decimal
+	MinOdds            decimal.Decimal
+	// FreeBetAmount is the stake of the free bet.
+	FreeBetAmount decimal.Decimal
+	// OddsBoost is the fraction the odds of the boosted bet are raised by.
+	OddsBoost decimal.Decimal
+	StartsAt  time.Time
+	EndsAt    time.Time
+	CreatedBy uuid.UUID
+	CreatedAt time.Time
+}
+
+func (c Campaign) Validate() error {
+	if c.Name == "" {
+		return errors.New("campaign name must be set")
+	}
+
+	if err := c.Type.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Currency.Validate(); err != nil {
+		return err
+	}
+
+	if !c.EndsAt.After(c.StartsAt) {
+		return errors.New("campaign must end after it starts")
+	}
+
+	switch c.Type {
+	case CampaignTypeDepositMatch:
+		if !c.MatchRate.IsPositive() {
+			return errors.New("match rate must be positive")
+		}
+
+		if !c.MaxAmount.IsPositive() {
+			return errors.New("max amount must be positive")
+		}
+
+		if !c.WageringMultiplier.IsPositive() {
+			return errors.New("wagering multiplier must be positive")
+		}
+
+		if c.MinOdds.IsNegative() {
+			return errors.New("min odds cannot be negative")
+		}
+	case CampaignTypeFreeBet:
+		if !c.FreeBetAmount.IsPositive() {
+			return errors.New("free bet amount must be positive")
+		}
+	case CampaignTypeOddsBoost:
+		if !c.OddsBoost.IsPositive() {
+			return errors.New("odds boost must be positive")
+		}
+	}
+
+	return nil
+}
+
+// Running reports whether the campaign can be claimed at the given time.
+func (c Campaign) Running(t time.Time) bool {
+	return !t.Before(c.StartsAt) && t.Before(c.EndsAt)
+}
+
+func (c Campaign) claimable(currency purse.Currency, t time.Time) error {
+	if !c.Running(t) {
+		return errors.New("campaign is not running")
+	}
+
+	if c.Currency != currency {
+		return errors.New("campaign is not available in the wallet currency")
+	}
+
+	return nil
+}
+
+// GrantDeposit matches the confirmed deposit of the user with bonus funds.
+// Only deposits made while the campaign runs can be matched.
+func (c Campaign) GrantDeposit(d purse.Deposit, t time.Time) (Grant, error) {
+	if c.Type != CampaignTypeDepositMatch {
+		return Grant{}, errors.New("campaign does not match deposits")
+	}
+
+	if err := c.claimable(d.Currency, t); err != nil {
+		return Grant{}, err
+	}
+
+	if d.Status != purse.DepositStatusConfirmed {
+		return Grant{}, errors.New("deposit is not confirmed")
+	}
+
+	if !c.Running(d.Timestamp) {
+		return Grant{}, errors.New("deposit was not made during the campaign")
+	}
+
+	amount := decimal.Min(d.Amount.Mul(c.MatchRate).RoundDown(2), c.MaxAmount)
+	if !amount.IsPositive() {
+		return Grant{}, errors.New("deposit is too small to be matched")
+	}
+
+	return Grant{
+		UUID:             uuid.New(),
+		CampaignUUID:     c.UUID,
+		UserUUID:         d.UserUUID,
+		DepositUUID:      d.UUID,
+		Amount:           amount,
+		WageringRequired: amount.Mul(c.WageringMultiplier),
+		Wagered:          decimal.Zero,
+		MinOdds:          c.MinOdds,
+		Status:           GrantStatusActive,
+		CreatedAt:        t,
+		UpdatedAt:        t,
+	}, nil
+}
+
+// IssueToken gives the user the token of a free bet or odds boost
+// campaign, usable until the campaign ends.
+func (c Campaign) IssueToken(userUUID uuid.UUID, currency purse.Currency, t time.Time) (Token, error) {
+	if c.Type != CampaignTypeFreeBet && c.Type != CampaignTypeOddsBoost {
+		return Token{}, errors.New("campaign does not issue tokens")
+	}
+
+	if err := c.claimable(currency, t); err != nil {
+		return Token{}, err
+	}
+
+	return Token{
+		UUID:         uuid.New(),
+		CampaignUUID: c.UUID,
+		UserUUID:     userUUID,
+		Type:         c.Type,
+		Amount:       c.FreeBetAmount,
+		Boost:        c.OddsBoost,
+		Status:       TokenStatusAvailable,
+		ExpiresAt:    c.EndsAt,
+		CreatedAt:    t,
+	}, nil
+}
+
+type GrantStatus string
+
+const (
+	GrantStatusActive    GrantStatus = "active"
+	GrantStatusCompleted GrantStatus = "completed"
+)
+
+// Grant is the bonus funds given to a user for a matched deposit. They are
+// kept in the user's bonus balance until the wagering requirement is met.
+type Grant struct {
+	UUID             uuid.UUID
+	CampaignUUID     uuid.UUID
+	UserUUID         uuid.UUID
+	DepositUUID      uuid.UUID
+	Amount           decimal.Decimal
+	WageringRequired decimal.Decimal
+	Wagered          decimal.Decimal
+	MinOdds          decimal.Decimal
+	Status           GrantStatus
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// Wager counts the stake of a settled bet towards the wagering
+// requirement. Bets placed before the grant or below its minimum odds are
+// not counted. The part of the stake not needed to complete the grant is
+// returned, so that it can be counted towards the next one.
+func (g *Grant) Wager(stake, odds decimal.Decimal, placed time.Time) decimal.Decimal {
+	if g.Status != GrantStatusActive || placed.Before(g.CreatedAt) || odds.LessThan(g.MinOdds) {
+		return stake
+	}
+
+	counted := decimal.Min(stake, g.WageringRequired.Sub(g.Wagered))
+
+	g.Wagered = g.Wagered.Add(counted)
+	g.UpdatedAt = time.Now()
+
+	if g.Wagered.GreaterThanOrEqual(g.WageringRequired) {
+		g.Status = GrantStatusCompleted
+	}
+
+	return stake.Sub(counted)
+}
+
+type TokenStatus string
+
+const (
+	TokenStatusAvailable TokenStatus = "available"
+	TokenStatusUsed      TokenStatus = "used"
+)
+
+// Token is a free bet or odds boost a user can use on one single bet.
+type Token struct {
+	UUID         uuid.UUID
+	CampaignUUID uuid.UUID
+	UserUUID     uuid.UUID
+	Type         CampaignType
+	// Amount is the stake of a free bet.
+	Amount decimal.Decimal
+	// Boost is the fraction the odds are raised by with an odds boost.
+	Boost     decimal.Decimal
+	Status    TokenStatus
+	BetUUID   uuid.UUID
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// Use spends the token of the user on the bet.
+func (t *Token) Use(userUUID, betUUID uuid.UUID, at time.Time) error {
+	if t.UserUUID != userUUID {
+		return errors.New("token does not belong to the user")
+	}
+
+	if t.Status != TokenStatusAvailable {
+		return errors.New("token already used")
+	}
+
+	if !at.Before(t.ExpiresAt) {
+		return errors.New("token expired")
+	}
+
+	t.Status = TokenStatusUsed
+	t.BetUUID = betUUID
+
+	return nil
+}
+
+// BoostOdds returns the odds raised by the odds boost.
+func (t Token) BoostOdds(odds decimal.Decimal) decimal.Decimal {
+	if t.Type != CampaignTypeOddsBoost {
+		return odds
+	}
+
+	return odds.Mul(decimal.NewFromInt(1).Add(t.Boost)).Round(2)
+}
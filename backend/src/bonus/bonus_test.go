@@ -0,0 +1,100 @@
+package bonus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestGrantWager(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	type wager struct {
+		stake  string
+		odds   string
+		placed time.Time
+		// left is the part of the stake not counted towards the grant.
+		left string
+	}
+
+	tests := map[string]struct {
+		wagers  []wager
+		wagered string
+		status  GrantStatus
+	}{
+		"partly wagered": {
+			wagers: []wager{
+				{stake: "10", odds: "2", placed: created, left: "0"},
+			},
+			wagered: "10",
+			status:  GrantStatusActive,
+		},
+		"wagered through": {
+			wagers: []wager{
+				{stake: "10", odds: "2", placed: created, left: "0"},
+				{stake: "10", odds: "2", placed: created, left: "0"},
+			},
+			wagered: "20",
+			status:  GrantStatusCompleted,
+		},
+		"stake over the requirement is left": {
+			wagers: []wager{
+				{stake: "15", odds: "2", placed: created, left: "0"},
+				{stake: "15", odds: "2", placed: created, left: "10"},
+			},
+			wagered: "20",
+			status:  GrantStatusCompleted,
+		},
+		"completed grant counts nothing": {
+			wagers: []wager{
+				{stake: "20", odds: "2", placed: created, left: "0"},
+				{stake: "10", odds: "2", placed: created, left: "10"},
+			},
+			wagered: "20",
+			status:  GrantStatusCompleted,
+		},
+		"odds below minimum": {
+			wagers: []wager{
+				{stake: "20", odds: "1.2", placed: created, left: "20"},
+			},
+			wagered: "0",
+			status:  GrantStatusActive,
+		},
+		"bet placed before the grant": {
+			wagers: []wager{
+				{stake: "20", odds: "2", placed: created.Add(-time.Second), left: "20"},
+			},
+			wagered: "0",
+			status:  GrantStatusActive,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := Grant{
+				Amount:           decimal.NewFromInt(10),
+				WageringRequired: decimal.NewFromInt(20),
+				Wagered:          decimal.Zero,
+				MinOdds:          decimal.RequireFromString("1.5"),
+				Status:           GrantStatusActive,
+				CreatedAt:        created,
+			}
+
+			for i, w := range test.wagers {
+				left := g.Wager(decimal.RequireFromString(w.stake), decimal.RequireFromString(w.odds), w.placed)
+				if !left.Equal(decimal.RequireFromString(w.left)) {
+					t.Errorf("wager %d: want %s left, got %s", i, w.left, left)
+				}
+			}
+
+			if !g.Wagered.Equal(decimal.RequireFromString(test.wagered)) {
+				t.Errorf("want %s wagered, got %s", test.wagered, g.Wagered)
+			}
+
+			if g.Status != test.status {
+				t.Errorf("want status %s, got %s", test.status, g.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/ramasauskas/ispbet/db"
+	"github.com/rs/zerolog"
+)
+
+// tokenCleaner periodically deletes the emailed tokens that expired
+// without being used.
+type tokenCleaner struct {
+	doneCh   chan struct{}
+	db       *db.DB
+	interval time.Duration
+	log      zerolog.Logger
+}
+
+func newTokenCleaner(db *db.DB, interval time.Duration, log zerolog.Logger) *tokenCleaner {
+	return &tokenCleaner{
+		doneCh:   make(chan struct{}, 1),
+		db:       db,
+		interval: interval,
+		log:      log,
+	}
+}
+
+func (c *tokenCleaner) work() {
+	tick := time.NewTicker(c.interval)
+
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-c.doneCh:
+			return
+		case <-tick.C:
+			if err := c.db.DeleteExpiredEmailVerifications(context.Background(), c.db.NoTX(), time.Now()); err != nil {
+				c.log.Error().Err(err).Msg("cannot delete expired email verifications")
+			}
+		}
+	}
+}
+
+func (c *tokenCleaner) stop() {
+	close(c.doneCh)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ramasauskas/ispbet/purse"
+	"github.com/shopspring/decimal"
+)
+
+type config struct {
+	// CashOutMargin is the fraction of a bet's fair cash-out value kept
+	// by the house.
+	CashOutMargin decimal.Decimal
+	// FourEyesWithdrawalAmount is the amount above which withdrawals need
+	// the approval of two admins.
+	FourEyesWithdrawalAmount decimal.Decimal
+	// BaseCurrency is the currency of new wallets by default and the one
+	// reports are converted to.
+	BaseCurrency purse.Currency
+	// SimulatedPaymentSecret signs the callbacks of the simulated payment
+	// provider. A random one is used if it is not set.
+	SimulatedPaymentSecret []byte
+	// SimulatedPaymentCallbackURL is where the simulated payment provider
+	// sends its callbacks.
+	SimulatedPaymentCallbackURL string
+	// SimulatedPaymentDelay is how long the simulated payment provider
+	// takes to confirm a payment.
+	SimulatedPaymentDelay time.Duration
+	// ReconciliationInterval is how often the balances of bet users are
+	// checked against their history.
+	ReconciliationInterval time.Duration
+	// ReconciliationEmail receives the balance discrepancies found by the
+	// reconciliation. Nothing is sent if it is not set.
+	ReconciliationEmail string
+	// AdminTwoFactorRequired makes two-factor authentication mandatory
+	// for admins.
+	AdminTwoFactorRequired bool
+	// GamblingLimitCoolingOff is how long raising or removing a gambling
+	// limit takes to apply.
+	GamblingLimitCoolingOff time.Duration
+}
+
+func loadConfig() (config, error) {
+	cfg := config{
+		CashOutMargin:               decimal.NewFromFloat(0.05),
+		FourEyesWithdrawalAmount:    decimal.NewFromInt(1000),
+		BaseCurrency:                "EUR",
+		SimulatedPaymentCallbackURL: "http://localhost:8080/payments/callback/simulated",
+		SimulatedPaymentDelay:       time.Second * 2,
+		ReconciliationInterval:      time.Hour,
+		GamblingLimitCoolingOff:     time.Hour * 24,
+	}
+
+	if v, ok := os.LookupEnv("ISPBET_CASH_OUT_MARGIN"); ok {
+		margin, err := decimal.NewFromString(v)
+		if err != nil {
+			return config{}, err
+		}
+
+		if margin.IsNegative() || margin.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+			return config{}, errors.New("cash-out margin must be between 0 and 1")
+		}
+
+		cfg.CashOutMargin = margin
+	}
+
+	if v, ok := os.LookupEnv("ISPBET_FOUR_EYES_WITHDRAWAL_AMOUNT"); ok {
+		amount, err := decimal.NewFromString(v)
+		if err != nil {
+			return config{}, err
+		}
+
+		if amount.IsNegative() {
+			return config{}, errors.New("four-eyes withdrawal amount cannot be negative")
+		}
+
+		cfg.FourEyesWithdrawalAmount = amount
+	}
+
+	if v, ok := os.LookupEnv("ISPBET_BASE_CURRENCY"); ok {
+		cfg.BaseCurrency = purse.Currency(v)
+
+		if err := cfg.BaseCurrency.Validate(); err != nil {
+			return config{}, err
+		}
+	}
+
+	if v, ok := os.LookupEnv("ISPBET_SIMULATED_PAYMENT_SECRET"); ok && v != "" {
+		cfg.SimulatedPaymentSecret = []byte(v)
+	} else {
+		cfg.SimulatedPaymentSecret = make([]byte, 32)
+
+		if _, err := rand.Read(cfg.SimulatedPaymentSecret); err != nil {
+			return config{}, err
+		}
+	}
+
+	if v, ok := os.LookupEnv("ISPBET_SIMULATED_PAYMENT_CALLBACK_URL"); ok {
+		cfg.SimulatedPaymentCallbackURL = v
+	}
+
+	if v, ok := os.LookupEnv("ISPBET_SIMULATED_PAYMENT_DELAY"); ok {
+		delay, err := time.ParseDuration(v)
+		if err != nil {
+			return config{}, err
+		}
+
+		if delay < 0 {
+			return config{}, errors.New("simulated payment delay cannot be negative")
+		}
+
+		cfg.SimulatedPaymentDelay = delay
+	}
+
+	if v, ok := os.LookupEnv("ISPBET_RECONCILIATION_INTERVAL"); ok {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return config{}, err
+		}
+
+		if interval <= 0 {
+			return config{}, errors.New("reconciliation interval must be positive")
+		}
+
+		cfg.ReconciliationInterval = interval
+	}
+
+	if v, ok := os.LookupEnv("ISPBET_RECONCILIATION_EMAIL"); ok {
+		cfg.ReconciliationEmail = v
+	}
+
+	if v, ok := os.LookupEnv("ISPBET_ADMIN_TWO_FACTOR_REQUIRED"); ok {
+		required, err := strconv.ParseBool(v)
+		if err != nil {
+			return config{}, err
+		}
+
+		cfg.AdminTwoFactorRequired = required
+	}
+
+	if v, ok := os.LookupEnv("ISPBET_GAMBLING_LIMIT_COOLING_OFF"); ok {
+		coolingOff, err := time.ParseDuration(v)
+		if err != nil {
+			return config{}, err
+		}
+
+		if coolingOff < 0 {
+			return config{}, errors.New("gambling limit cooling-off period cannot be negative")
+		}
+
+		cfg.GamblingLimitCoolingOff = coolingOff
+	}
+
+	return cfg, nil
+}
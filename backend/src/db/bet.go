@@ -33,6 +33,16 @@ func UserBets(id uuid.UUID) fetchBetCriteria {
 	}
 }
 
+// UserBetsSince matches the bets of the user placed from since on.
+func UserBetsSince(id uuid.UUID, since time.Time) fetchBetCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(sq.And{
+			sq.Eq{columnPredicate(prefix, "user_uuid"): id},
+			sq.GtOrEq{columnPredicate(prefix, "timestamp"): since},
+		})
+	}
+}
+
 func SelectionBets(id uuid.UUID) fetchBetCriteria {
 	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
 		return b.Where(sq.Eq{columnPredicate(prefix, "selection_uuid"): id})
@@ -48,26 +58,96 @@ type Bet struct {
 	Stake           decimal.Decimal `db:"bt.stake"`
 	Odds            decimal.Decimal `db:"bt.odds"`
 	State           string          `db:"bt.state"`
+	CashOut         decimal.Decimal `db:"bt.cash_out"`
+	Currency        string          `db:"bt.currency"`
+	TokenUUID       uuid.UUID       `db:"bt.token_uuid"`
+	FreeBet         bool            `db:"bt.free_bet"`
+}
+
+type fetchAccumulatorCriteria func(b sq.SelectBuilder, prefix string) sq.SelectBuilder
+
+func UserAccumulators(id uuid.UUID) fetchAccumulatorCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(sq.Eq{columnPredicate(prefix, "user_uuid"): id})
+	}
+}
+
+// UserAccumulatorsSince matches the accumulators of the user placed from
+// since on.
+func UserAccumulatorsSince(id uuid.UUID, since time.Time) fetchAccumulatorCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(sq.And{
+			sq.Eq{columnPredicate(prefix, "user_uuid"): id},
+			sq.GtOrEq{columnPredicate(prefix, "timestamp"): since},
+		})
+	}
+}
+
+func SelectionAccumulators(id uuid.UUID) fetchAccumulatorCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(sq.Expr(columnPredicate(prefix, "uuid")+" IN (SELECT accumulator_uuid FROM accumulator_leg WHERE selection_uuid = ?)", id))
+	}
+}
+
+func AccumulatorsBetween(from, to time.Time) fetchAccumulatorCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(sq.And{
+			sq.GtOrEq{columnPredicate(prefix, "timestamp"): from},
+			sq.Lt{columnPredicate(prefix, "timestamp"): to},
+		})
+	}
+}
+
+type Accumulator struct {
+	UUID      uuid.UUID       `db:"acc.uuid"`
+	UserUUID  uuid.UUID       `db:"acc.user_uuid"`
+	Stake     decimal.Decimal `db:"acc.stake"`
+	Odds      decimal.Decimal `db:"acc.odds"`
+	State     string          `db:"acc.state"`
+	Timestamp time.Time       `db:"acc.timestamp"`
+	Currency  string          `db:"acc.currency"`
+}
+
+type AccumulatorLeg struct {
+	UUID            uuid.UUID       `db:"accleg.uuid"`
+	AccumulatorUUID uuid.UUID       `db:"accleg.accumulator_uuid"`
+	SelectionUUID   uuid.UUID       `db:"accleg.selection_uuid"`
+	SelectionWinner string          `db:"accleg.selection_winner"`
+	Odds            decimal.Decimal `db:"accleg.odds"`
+	State           string          `db:"accleg.state"`
 }
 
 type Event struct {
-	UUID         uuid.UUID `db:"betev.uuid"`
-	Name         string    `db:"betev.name"`
-	Sport        string    `db:"betev.sport_name"`
-	BeginsAt     time.Time `db:"betev.begins_at"`
-	Finished     bool      `db:"betev.finished"`
-	HomeTeamUUID uuid.UUID `db:"betev.home_team_uuid"`
-	AwayTeamUUID uuid.UUID `db:"betev.away_team_uuid"`
+	UUID              uuid.UUID     `db:"betev.uuid"`
+	Name              string        `db:"betev.name"`
+	Sport             string        `db:"betev.sport_name"`
+	BeginsAt          time.Time     `db:"betev.begins_at"`
+	Finished          bool          `db:"betev.finished"`
+	Status            string        `db:"betev.status"`
+	HomeTeamUUID      uuid.UUID     `db:"betev.home_team_uuid"`
+	AwayTeamUUID      uuid.UUID     `db:"betev.away_team_uuid"`
+	HomeScore         sql.NullInt64 `db:"betev.home_score"`
+	AwayScore         sql.NullInt64 `db:"betev.away_score"`
+	HalfTimeHomeScore sql.NullInt64 `db:"betev.half_time_home_score"`
+	HalfTimeAwayScore sql.NullInt64 `db:"betev.half_time_away_score"`
 }
 
 type EventSelection struct {
-	UUID      uuid.UUID       `db:"es.uuid"`
-	EventUUID uuid.UUID       `db:"es.event_uuid"`
-	Name      string          `db:"es.name"`
-	OddsHome  decimal.Decimal `db:"es.odds_home"`
-	OddsAway  decimal.Decimal `db:"es.odds_away"`
-	AutoOdds  bool            `db:"es.auto_odds"`
-	Winner    string          `db:"es.winner"`
+	UUID       uuid.UUID           `db:"es.uuid"`
+	EventUUID  uuid.UUID           `db:"es.event_uuid"`
+	Name       string              `db:"es.name"`
+	MarketType string              `db:"es.market_type"`
+	Period     string              `db:"es.period"`
+	Status     string              `db:"es.status"`
+	Line       decimal.NullDecimal `db:"es.line"`
+	AutoOdds   bool                `db:"es.auto_odds"`
+	Winner     string              `db:"es.winner"`
+}
+
+type SelectionOutcome struct {
+	SelectionUUID uuid.UUID       `db:"so.selection_uuid"`
+	Winner        string          `db:"so.winner"`
+	Odds          decimal.Decimal `db:"so.odds"`
 }
 
 type Team struct {
@@ -88,6 +168,7 @@ func (d *DB) InsertEvent(ctx context.Context, e sq.ExecerContext, ev Event) erro
 		"sport_name":     ev.Sport,
 		"begins_at":      ev.BeginsAt,
 		"finished":       ev.Finished,
+		"status":         ev.Status,
 		"home_team_uuid": ev.HomeTeamUUID,
 		"away_team_uuid": ev.AwayTeamUUID,
 	})
@@ -98,19 +179,56 @@ func (d *DB) InsertEvent(ctx context.Context, e sq.ExecerContext, ev Event) erro
 
 func (d *DB) InsertEventSelection(ctx context.Context, e sq.ExecerContext, se EventSelection) error {
 	b := sq.Insert("event_selection").SetMap(map[string]interface{}{
-		"uuid":       se.UUID,
-		"name":       se.Name,
-		"odds_home":  se.OddsHome,
-		"odds_away":  se.OddsAway,
-		"auto_odds":  se.AutoOdds,
-		"winner":     se.Winner,
-		"event_uuid": se.EventUUID,
+		"uuid":        se.UUID,
+		"name":        se.Name,
+		"market_type": se.MarketType,
+		"period":      se.Period,
+		"status":      se.Status,
+		"line":        se.Line,
+		"auto_odds":   se.AutoOdds,
+		"winner":      se.Winner,
+		"event_uuid":  se.EventUUID,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) InsertSelectionOutcome(ctx context.Context, e sq.ExecerContext, so SelectionOutcome) error {
+	b := sq.Insert("selection_outcome").SetMap(map[string]interface{}{
+		"selection_uuid": so.SelectionUUID,
+		"winner":         so.Winner,
+		"odds":           so.Odds,
 	})
 
 	_, err := sq.ExecContextWith(ctx, e, b)
 	return err
 }
 
+func (d *DB) UpdateSelectionOutcome(ctx context.Context, e sq.ExecerContext, so SelectionOutcome) error {
+	b := sq.Update("selection_outcome").SetMap(map[string]interface{}{
+		"odds": so.Odds,
+	}).Where(sq.Eq{"selection_uuid": so.SelectionUUID, "winner": so.Winner})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) FetchSelectionOutcomes(ctx context.Context, q sq.QueryerContext, id uuid.UUID) ([]SelectionOutcome, error) {
+	b := sq.Select()
+
+	b = selectionOutcomeQuery(b, "so").From("selection_outcome AS so").Where(sq.Eq{"so.selection_uuid": id})
+	qr, args := b.MustSql()
+
+	var oo []SelectionOutcome
+
+	if err := d.d.SelectContext(ctx, &oo, qr, args...); err != nil {
+		return nil, err
+	}
+
+	return oo, nil
+}
+
 func (d *DB) InsertTeam(ctx context.Context, e sq.ExecerContext, tm Team) error {
 	b := sq.Insert("team").SetMap(map[string]interface{}{
 		"uuid": tm.UUID,
@@ -277,7 +395,11 @@ func (d *DB) InsertBet(ctx context.Context, e sq.ExecerContext, bt Bet) error {
 		"stake":            bt.Stake,
 		"odds":             bt.Odds,
 		"state":            bt.State,
+		"cash_out":         bt.CashOut,
 		"timestamp":        bt.Timestamp,
+		"currency":         bt.Currency,
+		"token_uuid":       bt.TokenUUID,
+		"free_bet":         bt.FreeBet,
 	})
 
 	_, err := sq.ExecContextWith(ctx, e, b)
@@ -299,19 +421,122 @@ func (d *DB) FetchBets(ctx context.Context, q sq.QueryerContext, c fetchBetCrite
 	return bb, nil
 }
 
+func (d *DB) FetchBet(ctx context.Context, q sq.QueryerContext, id uuid.UUID) (Bet, bool, error) {
+	b := sq.Select()
+
+	b = betQuery(b, "bt").From("bet AS bt").Where(sq.Eq{"bt.uuid": id})
+	qr, args := b.MustSql()
+
+	var bt Bet
+
+	err := d.d.GetContext(ctx, &bt, qr, args...)
+	switch err {
+	case nil:
+		return bt, true, nil
+	case sql.ErrNoRows:
+		return Bet{}, false, nil
+	default:
+		return Bet{}, false, err
+	}
+}
+
 func (d *DB) UpdateBet(ctx context.Context, e sq.ExecerContext, bt Bet) error {
 	b := sq.Update("bet").SetMap(map[string]interface{}{
-		"state": bt.State,
+		"state":    bt.State,
+		"cash_out": bt.CashOut,
 	}).Where(sq.Eq{"uuid": bt.UUID})
 
 	_, err := sq.ExecContextWith(ctx, e, b)
 	return err
 }
 
+func (d *DB) InsertAccumulator(ctx context.Context, e sq.ExecerContext, acc Accumulator) error {
+	b := sq.Insert("accumulator").SetMap(map[string]interface{}{
+		"uuid":      acc.UUID,
+		"user_uuid": acc.UserUUID,
+		"stake":     acc.Stake,
+		"odds":      acc.Odds,
+		"state":     acc.State,
+		"timestamp": acc.Timestamp,
+		"currency":  acc.Currency,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) InsertAccumulatorLeg(ctx context.Context, e sq.ExecerContext, l AccumulatorLeg) error {
+	b := sq.Insert("accumulator_leg").SetMap(map[string]interface{}{
+		"uuid":             l.UUID,
+		"accumulator_uuid": l.AccumulatorUUID,
+		"selection_uuid":   l.SelectionUUID,
+		"selection_winner": l.SelectionWinner,
+		"odds":             l.Odds,
+		"state":            l.State,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) FetchAccumulators(ctx context.Context, q sq.QueryerContext, c fetchAccumulatorCriteria) ([]Accumulator, error) {
+	b := sq.Select()
+
+	b = c(accumulatorQuery(b, "acc").From("accumulator AS acc"), "acc")
+	qr, args := b.MustSql()
+
+	var aa []Accumulator
+
+	if err := d.d.SelectContext(ctx, &aa, qr, args...); err != nil {
+		return nil, err
+	}
+
+	return aa, nil
+}
+
+func (d *DB) FetchAccumulatorLegs(ctx context.Context, q sq.QueryerContext, id uuid.UUID) ([]AccumulatorLeg, error) {
+	b := sq.Select()
+
+	b = accumulatorLegQuery(b, "accleg").From("accumulator_leg AS accleg").Where(sq.Eq{"accleg.accumulator_uuid": id})
+	qr, args := b.MustSql()
+
+	var ll []AccumulatorLeg
+
+	if err := d.d.SelectContext(ctx, &ll, qr, args...); err != nil {
+		return nil, err
+	}
+
+	return ll, nil
+}
+
+func (d *DB) UpdateAccumulator(ctx context.Context, e sq.ExecerContext, acc Accumulator) error {
+	b := sq.Update("accumulator").SetMap(map[string]interface{}{
+		"state": acc.State,
+	}).Where(sq.Eq{"uuid": acc.UUID})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) UpdateAccumulatorLeg(ctx context.Context, e sq.ExecerContext, l AccumulatorLeg) error {
+	b := sq.Update("accumulator_leg").SetMap(map[string]interface{}{
+		"state": l.State,
+	}).Where(sq.Eq{"uuid": l.UUID})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
 func (d *DB) UpdateEvent(ctx context.Context, e sq.ExecerContext, ev Event) error {
-	b := sq.Update("event").SetMap(map[string]interface{}{
-		"finished": ev.Finished,
-		"name":     ev.Name,
+	b := sq.Update("bet_event").SetMap(map[string]interface{}{
+		"finished":             ev.Finished,
+		"status":               ev.Status,
+		"begins_at":            ev.BeginsAt,
+		"name":                 ev.Name,
+		"home_score":           ev.HomeScore,
+		"away_score":           ev.AwayScore,
+		"half_time_home_score": ev.HalfTimeHomeScore,
+		"half_time_away_score": ev.HalfTimeAwayScore,
 	}).Where(sq.Eq{"uuid": ev.UUID})
 
 	_, err := sq.ExecContextWith(ctx, e, b)
@@ -321,10 +546,9 @@ func (d *DB) UpdateEvent(ctx context.Context, e sq.ExecerContext, ev Event) erro
 func (d *DB) UpdateSelection(ctx context.Context, e sq.ExecerContext, sel EventSelection) error {
 	b := sq.Update("event_selection").SetMap(map[string]interface{}{
 		"winner":    sel.Winner,
-		"odds_away": sel.OddsAway,
+		"status":    sel.Status,
 		"auto_odds": sel.AutoOdds,
 		"name":      sel.Name,
-		"odds_home": sel.OddsHome,
 	}).Where(sq.Eq{"uuid": sel.UUID})
 
 	_, err := sq.ExecContextWith(ctx, e, b)
@@ -334,12 +558,16 @@ func (d *DB) UpdateSelection(ctx context.Context, e sq.ExecerContext, sel EventS
 func (d *DB) FetchSelectionBest(ctx context.Context, highRisk bool) (EventSelection, bool, error) {
 	b := sq.Select()
 
-	asc := "MAX(es.odds_away, es.odds_home) DESC"
+	asc := "(SELECT MAX(so.odds) FROM selection_outcome AS so WHERE so.selection_uuid = es.uuid) DESC"
 	if !highRisk {
-		asc = "MIN(es.odds_away, es.odds_home) ASC"
+		asc = "(SELECT MIN(so.odds) FROM selection_outcome AS so WHERE so.selection_uuid = es.uuid) ASC"
 	}
 
-	b = selectionQuery(b, "es").From("event_selection AS es").OrderBy(asc).Limit(1).Where(sq.Eq{"es.winner": "tbd"})
+	b = selectionQuery(b, "es").From("event_selection AS es").
+		Join("bet_event AS betev ON betev.uuid=es.event_uuid").
+		OrderBy(asc).Limit(1).
+		Where(sq.Eq{"es.winner": "tbd", "es.status": "open", "betev.status": "scheduled"}).
+		Where(sq.Gt{"betev.begins_at": time.Now()})
 	qr, args := b.MustSql()
 
 	var sel EventSelection
@@ -404,6 +632,13 @@ func (d *DB) DeleteAutoBet(ctx context.Context, e sq.ExecerContext, id uuid.UUID
 	return err
 }
 
+func (d *DB) DeleteUserAutoBets(ctx context.Context, e sq.ExecerContext, userUUID uuid.UUID) error {
+	b := sq.Delete("auto_bet").Where(sq.Eq{"user_uuid": userUUID})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
 func autoBetQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
 	return b.Columns(
 		column(prefix, "uuid"),
@@ -420,8 +655,13 @@ func eventQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
 		column(prefix, "name"),
 		column(prefix, "begins_at"),
 		column(prefix, "finished"),
+		column(prefix, "status"),
 		column(prefix, "home_team_uuid"),
 		column(prefix, "away_team_uuid"),
+		column(prefix, "home_score"),
+		column(prefix, "away_score"),
+		column(prefix, "half_time_home_score"),
+		column(prefix, "half_time_away_score"),
 	)
 }
 
@@ -429,14 +669,24 @@ func selectionQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
 	return b.Columns(
 		column(prefix, "uuid"),
 		column(prefix, "name"),
-		column(prefix, "odds_home"),
+		column(prefix, "market_type"),
+		column(prefix, "period"),
+		column(prefix, "status"),
+		column(prefix, "line"),
 		column(prefix, "auto_odds"),
-		column(prefix, "odds_away"),
 		column(prefix, "winner"),
 		column(prefix, "event_uuid"),
 	)
 }
 
+func selectionOutcomeQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "selection_uuid"),
+		column(prefix, "winner"),
+		column(prefix, "odds"),
+	)
+}
+
 func teamQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
 	return b.Columns(
 		column(prefix, "uuid"),
@@ -461,6 +711,33 @@ func betQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
 		column(prefix, "stake"),
 		column(prefix, "odds"),
 		column(prefix, "state"),
+		column(prefix, "cash_out"),
 		column(prefix, "timestamp"),
+		column(prefix, "currency"),
+		column(prefix, "token_uuid"),
+		column(prefix, "free_bet"),
+	)
+}
+
+func accumulatorQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "uuid"),
+		column(prefix, "user_uuid"),
+		column(prefix, "stake"),
+		column(prefix, "odds"),
+		column(prefix, "state"),
+		column(prefix, "timestamp"),
+		column(prefix, "currency"),
+	)
+}
+
+func accumulatorLegQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "uuid"),
+		column(prefix, "accumulator_uuid"),
+		column(prefix, "selection_uuid"),
+		column(prefix, "selection_winner"),
+		column(prefix, "odds"),
+		column(prefix, "state"),
 	)
 }
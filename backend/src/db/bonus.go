@@ -0,0 +1,342 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type BonusCampaign struct {
+	UUID               uuid.UUID       `db:"bc.uuid"`
+	Name               string          `db:"bc.name"`
+	Type               string          `db:"bc.type"`
+	Currency           string          `db:"bc.currency"`
+	MatchRate          decimal.Decimal `db:"bc.match_rate"`
+	MaxAmount          decimal.Decimal `db:"bc.max_amount"`
+	WageringMultiplier decimal.Decimal `db:"bc.wagering_multiplier"`
+	MinOdds            decimal.Decimal `db:"bc.min_odds"`
+	FreeBetAmount      decimal.Decimal `db:"bc.free_bet_amount"`
+	OddsBoost          decimal.Decimal `db:"bc.odds_boost"`
+	StartsAt           time.Time       `db:"bc.starts_at"`
+	EndsAt             time.Time       `db:"bc.ends_at"`
+	CreatedBy          uuid.UUID       `db:"bc.created_by"`
+	CreatedAt          time.Time       `db:"bc.created_at"`
+}
+
+type fetchBonusCampaignCriteria func(b sq.SelectBuilder, prefix string) sq.SelectBuilder
+
+func AllBonusCampaigns() fetchBonusCampaignCriteria {
+	return func(b sq.SelectBuilder, _ string) sq.SelectBuilder {
+		return b
+	}
+}
+
+// RunningBonusCampaigns selects the campaigns in the currency that can be
+// claimed at the given time.
+func RunningBonusCampaigns(currency string, t time.Time) fetchBonusCampaignCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(
+			sq.Eq{columnPredicate(prefix, "currency"): currency},
+			sq.LtOrEq{columnPredicate(prefix, "starts_at"): t},
+			sq.Gt{columnPredicate(prefix, "ends_at"): t},
+		)
+	}
+}
+
+func (d *DB) InsertBonusCampaign(ctx context.Context, e sq.ExecerContext, bc BonusCampaign) error {
+	b := sq.Insert("bonus_campaign").SetMap(map[string]interface{}{
+		"uuid":                bc.UUID,
+		"name":                bc.Name,
+		"type":                bc.Type,
+		"currency":            bc.Currency,
+		"match_rate":          bc.MatchRate,
+		"max_amount":          bc.MaxAmount,
+		"wagering_multiplier": bc.WageringMultiplier,
+		"min_odds":            bc.MinOdds,
+		"free_bet_amount":     bc.FreeBetAmount,
+		"odds_boost":          bc.OddsBoost,
+		"starts_at":           bc.StartsAt,
+		"ends_at":             bc.EndsAt,
+		"created_by":          bc.CreatedBy,
+		"created_at":          bc.CreatedAt,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) FetchBonusCampaign(ctx context.Context, q sq.QueryerContext, id uuid.UUID) (BonusCampaign, bool, error) {
+	b := sq.Select()
+
+	b = bonusCampaignQuery(b, "bc").From("bonus_campaign AS bc").Where(sq.Eq{"bc.uuid": id})
+	qr, args := b.MustSql()
+
+	var bc BonusCampaign
+
+	err := d.d.GetContext(ctx, &bc, qr, args...)
+	switch err {
+	case nil:
+		return bc, true, nil
+	case sql.ErrNoRows:
+		return BonusCampaign{}, false, nil
+	default:
+		return BonusCampaign{}, false, err
+	}
+}
+
+func (d *DB) FetchBonusCampaigns(ctx context.Context, q sq.QueryerContext, c fetchBonusCampaignCriteria) ([]BonusCampaign, error) {
+	b := sq.Select()
+
+	b = c(bonusCampaignQuery(b, "bc").From("bonus_campaign AS bc"), "bc").OrderBy("bc.starts_at")
+	qr, args := b.MustSql()
+
+	var cc []BonusCampaign
+
+	if err := d.d.SelectContext(ctx, &cc, qr, args...); err != nil {
+		return nil, err
+	}
+
+	return cc, nil
+}
+
+func bonusCampaignQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "uuid"),
+		column(prefix, "name"),
+		column(prefix, "type"),
+		column(prefix, "currency"),
+		column(prefix, "match_rate"),
+		column(prefix, "max_amount"),
+		column(prefix, "wagering_multiplier"),
+		column(prefix, "min_odds"),
+		column(prefix, "free_bet_amount"),
+		column(prefix, "odds_boost"),
+		column(prefix, "starts_at"),
+		column(prefix, "ends_at"),
+		column(prefix, "created_by"),
+		column(prefix, "created_at"),
+	)
+}
+
+type BonusGrant struct {
+	UUID             uuid.UUID       `db:"bg.uuid"`
+	CampaignUUID     uuid.UUID       `db:"bg.campaign_uuid"`
+	UserUUID         uuid.UUID       `db:"bg.user_uuid"`
+	DepositUUID      uuid.UUID       `db:"bg.deposit_uuid"`
+	Amount           decimal.Decimal `db:"bg.amount"`
+	WageringRequired decimal.Decimal `db:"bg.wagering_required"`
+	Wagered          decimal.Decimal `db:"bg.wagered"`
+	MinOdds          decimal.Decimal `db:"bg.min_odds"`
+	Status           string          `db:"bg.status"`
+	CreatedAt        time.Time       `db:"bg.created_at"`
+	UpdatedAt        time.Time       `db:"bg.updated_at"`
+}
+
+type fetchBonusGrantCriteria func(b sq.SelectBuilder, prefix string) sq.SelectBuilder
+
+func UserBonusGrants(id uuid.UUID) fetchBonusGrantCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(sq.Eq{columnPredicate(prefix, "user_uuid"): id})
+	}
+}
+
+func UserBonusGrantsWithStatus(id uuid.UUID, status string) fetchBonusGrantCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(sq.Eq{
+			columnPredicate(prefix, "user_uuid"): id,
+			columnPredicate(prefix, "status"):    status,
+		})
+	}
+}
+
+func (d *DB) InsertBonusGrant(ctx context.Context, e sq.ExecerContext, bg BonusGrant) error {
+	b := sq.Insert("bonus_grant").SetMap(map[string]interface{}{
+		"uuid":              bg.UUID,
+		"campaign_uuid":     bg.CampaignUUID,
+		"user_uuid":         bg.UserUUID,
+		"deposit_uuid":      bg.DepositUUID,
+		"amount":            bg.Amount,
+		"wagering_required": bg.WageringRequired,
+		"wagered":           bg.Wagered,
+		"min_odds":          bg.MinOdds,
+		"status":            bg.Status,
+		"created_at":        bg.CreatedAt,
+		"updated_at":        bg.UpdatedAt,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) UpdateBonusGrant(ctx context.Context, e sq.ExecerContext, bg BonusGrant) error {
+	b := sq.Update("bonus_grant").SetMap(map[string]interface{}{
+		"wagered":    bg.Wagered,
+		"status":     bg.Status,
+		"updated_at": bg.UpdatedAt,
+	}).Where(sq.Eq{"uuid": bg.UUID})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+// FetchBonusGrants returns the grants oldest first, the order their
+// wagering requirements are met in.
+func (d *DB) FetchBonusGrants(ctx context.Context, q sq.QueryerContext, c fetchBonusGrantCriteria) ([]BonusGrant, error) {
+	b := sq.Select()
+
+	b = c(bonusGrantQuery(b, "bg").From("bonus_grant AS bg"), "bg").OrderBy("bg.created_at")
+	qr, args := b.MustSql()
+
+	var gg []BonusGrant
+
+	if err := d.d.SelectContext(ctx, &gg, qr, args...); err != nil {
+		return nil, err
+	}
+
+	return gg, nil
+}
+
+func bonusGrantQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "uuid"),
+		column(prefix, "campaign_uuid"),
+		column(prefix, "user_uuid"),
+		column(prefix, "deposit_uuid"),
+		column(prefix, "amount"),
+		column(prefix, "wagering_required"),
+		column(prefix, "wagered"),
+		column(prefix, "min_odds"),
+		column(prefix, "status"),
+		column(prefix, "created_at"),
+		column(prefix, "updated_at"),
+	)
+}
+
+type BonusToken struct {
+	UUID         uuid.UUID       `db:"btk.uuid"`
+	CampaignUUID uuid.UUID       `db:"btk.campaign_uuid"`
+	UserUUID     uuid.UUID       `db:"btk.user_uuid"`
+	Type         string          `db:"btk.type"`
+	Amount       decimal.Decimal `db:"btk.amount"`
+	Boost        decimal.Decimal `db:"btk.boost"`
+	Status       string          `db:"btk.status"`
+	BetUUID      uuid.UUID       `db:"btk.bet_uuid"`
+	ExpiresAt    time.Time       `db:"btk.expires_at"`
+	CreatedAt    time.Time       `db:"btk.created_at"`
+}
+
+func (d *DB) InsertBonusToken(ctx context.Context, e sq.ExecerContext, tk BonusToken) error {
+	b := sq.Insert("bonus_token").SetMap(map[string]interface{}{
+		"uuid":          tk.UUID,
+		"campaign_uuid": tk.CampaignUUID,
+		"user_uuid":     tk.UserUUID,
+		"type":          tk.Type,
+		"amount":        tk.Amount,
+		"boost":         tk.Boost,
+		"status":        tk.Status,
+		"bet_uuid":      tk.BetUUID,
+		"expires_at":    tk.ExpiresAt,
+		"created_at":    tk.CreatedAt,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+// UpdateBonusToken stores the token only if its stored status is still
+// the given one, so that a token cannot be used twice. False is returned
+// if the status has changed.
+func (d *DB) UpdateBonusToken(ctx context.Context, e sq.ExecerContext, tk BonusToken, from string) (bool, error) {
+	b := sq.Update("bonus_token").SetMap(map[string]interface{}{
+		"status":   tk.Status,
+		"bet_uuid": tk.BetUUID,
+	}).Where(sq.Eq{"uuid": tk.UUID, "status": from})
+
+	res, err := sq.ExecContextWith(ctx, e, b)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (d *DB) FetchBonusToken(ctx context.Context, q sq.QueryerContext, id uuid.UUID) (BonusToken, bool, error) {
+	b := sq.Select()
+
+	b = bonusTokenQuery(b, "btk").From("bonus_token AS btk").Where(sq.Eq{"btk.uuid": id})
+	qr, args := b.MustSql()
+
+	var tk BonusToken
+
+	err := d.d.GetContext(ctx, &tk, qr, args...)
+	switch err {
+	case nil:
+		return tk, true, nil
+	case sql.ErrNoRows:
+		return BonusToken{}, false, nil
+	default:
+		return BonusToken{}, false, err
+	}
+}
+
+func (d *DB) FetchUserBonusTokens(ctx context.Context, q sq.QueryerContext, id uuid.UUID) ([]BonusToken, error) {
+	b := sq.Select()
+
+	b = bonusTokenQuery(b, "btk").From("bonus_token AS btk").Where(sq.Eq{"btk.user_uuid": id}).OrderBy("btk.created_at")
+	qr, args := b.MustSql()
+
+	var tt []BonusToken
+
+	if err := d.d.SelectContext(ctx, &tt, qr, args...); err != nil {
+		return nil, err
+	}
+
+	return tt, nil
+}
+
+// UserClaimedCampaign reports whether the user already has a grant or a
+// token of the campaign.
+func (d *DB) UserClaimedCampaign(ctx context.Context, q sq.QueryerContext, campaignUUID, userUUID uuid.UUID) (bool, error) {
+	where := sq.Eq{"campaign_uuid": campaignUUID, "user_uuid": userUUID}
+
+	grants := sq.Select("COUNT(*)").From("bonus_grant").Where(where)
+	tokens := sq.Select("COUNT(*)").From("bonus_token").Where(where)
+
+	b := sq.Select().Column(sq.Alias(grants, "grants")).Column(sq.Alias(tokens, "tokens"))
+	qr, args := b.MustSql()
+
+	var res struct {
+		Grants int `db:"grants"`
+		Tokens int `db:"tokens"`
+	}
+
+	if err := d.d.GetContext(ctx, &res, qr, args...); err != nil {
+		return false, err
+	}
+
+	return res.Grants+res.Tokens > 0, nil
+}
+
+func bonusTokenQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "uuid"),
+		column(prefix, "campaign_uuid"),
+		column(prefix, "user_uuid"),
+		column(prefix, "type"),
+		column(prefix, "amount"),
+		column(prefix, "boost"),
+		column(prefix, "status"),
+		column(prefix, "bet_uuid"),
+		column(prefix, "expires_at"),
+		column(prefix, "created_at"),
+	)
+}
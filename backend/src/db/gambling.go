@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shopspring/decimal"
+)
+
+type GamblingLimit struct {
+	UserUUID      uuid.UUID       `db:"gl.user_uuid"`
+	Type          string          `db:"gl.type"`
+	Period        string          `db:"gl.period"`
+	Amount        decimal.Decimal `db:"gl.amount"`
+	Pending       bool            `db:"gl.pending"`
+	PendingAmount decimal.Decimal `db:"gl.pending_amount"`
+	PendingAt     time.Time       `db:"gl.pending_at"`
+	UpdatedAt     time.Time       `db:"gl.updated_at"`
+}
+
+type GamblingLimitChange struct {
+	UUID        uuid.UUID       `db:"glc.uuid"`
+	UserUUID    uuid.UUID       `db:"glc.user_uuid"`
+	Type        string          `db:"glc.type"`
+	Period      string          `db:"glc.period"`
+	OldAmount   decimal.Decimal `db:"glc.old_amount"`
+	NewAmount   decimal.Decimal `db:"glc.new_amount"`
+	EffectiveAt time.Time       `db:"glc.effective_at"`
+	CreatedAt   time.Time       `db:"glc.created_at"`
+}
+
+func (d *DB) UpsertGamblingLimit(ctx context.Context, e sq.ExecerContext, gl GamblingLimit) error {
+	b := sq.Replace("gambling_limit").SetMap(map[string]interface{}{
+		"user_uuid":      gl.UserUUID,
+		"type":           gl.Type,
+		"period":         gl.Period,
+		"amount":         gl.Amount,
+		"pending":        gl.Pending,
+		"pending_amount": gl.PendingAmount,
+		"pending_at":     gl.PendingAt,
+		"updated_at":     gl.UpdatedAt,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) FetchGamblingLimits(ctx context.Context, q sq.QueryerContext, userUUID uuid.UUID) ([]GamblingLimit, error) {
+	b := sq.Select()
+
+	b = gamblingLimitQuery(b, "gl").From("gambling_limit AS gl").
+		Where(sq.Eq{"gl.user_uuid": userUUID}).
+		OrderBy("gl.type", "gl.period")
+
+	rows, err := sq.QueryContextWith(ctx, q, b)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ll []GamblingLimit
+
+	if err := sqlx.StructScan(rows, &ll); err != nil {
+		return nil, err
+	}
+
+	return ll, nil
+}
+
+func (d *DB) InsertGamblingLimitChange(ctx context.Context, e sq.ExecerContext, glc GamblingLimitChange) error {
+	b := sq.Insert("gambling_limit_change").SetMap(map[string]interface{}{
+		"uuid":         glc.UUID,
+		"user_uuid":    glc.UserUUID,
+		"type":         glc.Type,
+		"period":       glc.Period,
+		"old_amount":   glc.OldAmount,
+		"new_amount":   glc.NewAmount,
+		"effective_at": glc.EffectiveAt,
+		"created_at":   glc.CreatedAt,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+// FetchGamblingLimitChanges returns the limit changes of the user, newest
+// first.
+func (d *DB) FetchGamblingLimitChanges(ctx context.Context, q sq.QueryerContext, userUUID uuid.UUID) ([]GamblingLimitChange, error) {
+	b := sq.Select()
+
+	b = gamblingLimitChangeQuery(b, "glc").From("gambling_limit_change AS glc").
+		Where(sq.Eq{"glc.user_uuid": userUUID}).
+		OrderBy("glc.created_at DESC")
+	qr, args := b.MustSql()
+
+	var cc []GamblingLimitChange
+
+	if err := d.d.SelectContext(ctx, &cc, qr, args...); err != nil {
+		return nil, err
+	}
+
+	return cc, nil
+}
+
+func gamblingLimitQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "user_uuid"),
+		column(prefix, "type"),
+		column(prefix, "period"),
+		column(prefix, "amount"),
+		column(prefix, "pending"),
+		column(prefix, "pending_amount"),
+		column(prefix, "pending_at"),
+		column(prefix, "updated_at"),
+	)
+}
+
+func gamblingLimitChangeQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "uuid"),
+		column(prefix, "user_uuid"),
+		column(prefix, "type"),
+		column(prefix, "period"),
+		column(prefix, "old_amount"),
+		column(prefix, "new_amount"),
+		column(prefix, "effective_at"),
+		column(prefix, "created_at"),
+	)
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type BetLimit struct {
+	Scope        string          `db:"bl.scope"`
+	ScopeUUID    uuid.UUID       `db:"bl.scope_uuid"`
+	MinStake     decimal.Decimal `db:"bl.min_stake"`
+	MaxStake     decimal.Decimal `db:"bl.max_stake"`
+	MaxPayout    decimal.Decimal `db:"bl.max_payout"`
+	MaxLiability decimal.Decimal `db:"bl.max_liability"`
+}
+
+func (d *DB) UpsertBetLimit(ctx context.Context, e sq.ExecerContext, bl BetLimit) error {
+	b := sq.Replace("bet_limit").SetMap(map[string]interface{}{
+		"scope":         bl.Scope,
+		"scope_uuid":    bl.ScopeUUID,
+		"min_stake":     bl.MinStake,
+		"max_stake":     bl.MaxStake,
+		"max_payout":    bl.MaxPayout,
+		"max_liability": bl.MaxLiability,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) DeleteBetLimit(ctx context.Context, e sq.ExecerContext, scope string, id uuid.UUID) error {
+	b := sq.Delete("bet_limit").Where(sq.Eq{"scope": scope, "scope_uuid": id})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) FetchBetLimits(ctx context.Context, q sq.QueryerContext) ([]BetLimit, error) {
+	b := sq.Select()
+
+	b = betLimitQuery(b, "bl").From("bet_limit AS bl")
+	qr, args := b.MustSql()
+
+	var ll []BetLimit
+
+	if err := d.d.SelectContext(ctx, &ll, qr, args...); err != nil {
+		return nil, err
+	}
+
+	return ll, nil
+}
+
+func (d *DB) FetchBetLimit(ctx context.Context, q sq.QueryerContext, scope string, id uuid.UUID) (BetLimit, bool, error) {
+	b := sq.Select()
+
+	b = betLimitQuery(b, "bl").From("bet_limit AS bl").Where(sq.Eq{"bl.scope": scope, "bl.scope_uuid": id})
+	qr, args := b.MustSql()
+
+	var bl BetLimit
+
+	err := d.d.GetContext(ctx, &bl, qr, args...)
+	switch err {
+	case nil:
+		return bl, true, nil
+	case sql.ErrNoRows:
+		return BetLimit{}, false, nil
+	default:
+		return BetLimit{}, false, err
+	}
+}
+
+// FetchSelectionLiability sums the potential payouts of open bets placed
+// on the outcome of the selection, along with the ones of open
+// accumulators whose leg on the outcome is not settled yet.
+func (d *DB) FetchSelectionLiability(ctx context.Context, q sq.QueryerContext, id uuid.UUID, winner string) (decimal.Decimal, error) {
+	bets := sq.Select("IFNULL(SUM(stake * odds), 0) AS amnt").From("bet").Where(sq.Eq{
+		"selection_uuid":   id,
+		"selection_winner": winner,
+		"state":            "tbd",
+	})
+
+	accs := sq.Select("IFNULL(SUM(acc.stake * acc.odds), 0) AS amnt").
+		From("accumulator_leg AS accleg").
+		InnerJoin("accumulator acc ON acc.uuid=accleg.accumulator_uuid").
+		Where(sq.Eq{
+			"accleg.selection_uuid":   id,
+			"accleg.selection_winner": winner,
+			"accleg.state":            "tbd",
+			"acc.state":               "tbd",
+		})
+
+	liability := decimal.Zero
+
+	for _, b := range []sq.SelectBuilder{bets, accs} {
+		qr, args := b.MustSql()
+
+		var res struct {
+			Amount decimal.Decimal `db:"amnt"`
+		}
+
+		if err := d.d.GetContext(ctx, &res, qr, args...); err != nil {
+			return decimal.Zero, err
+		}
+
+		liability = liability.Add(res.Amount)
+	}
+
+	return liability, nil
+}
+
+func betLimitQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "scope"),
+		column(prefix, "scope_uuid"),
+		column(prefix, "min_stake"),
+		column(prefix, "max_stake"),
+		column(prefix, "max_payout"),
+		column(prefix, "max_liability"),
+	)
+}
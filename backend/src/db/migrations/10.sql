@@ -0,0 +1,50 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS journal_entry (
+	uuid TEXT PRIMARY KEY NOT NULL,
+	type TEXT NOT NULL,
+	from_account TEXT NOT NULL,
+	to_account TEXT NOT NULL,
+	amount NUMERIC NOT NULL,
+	user_uuid TEXT NOT NULL,
+	reference_uuid TEXT NOT NULL,
+	timestamp TIMESTAMP NOT NULL,
+
+	CONSTRAINT chk_amount_positive CHECK(amount > 0),
+	CONSTRAINT chk_accounts_differ CHECK(from_account <> to_account)
+);
+
+CREATE INDEX IF NOT EXISTS idx_journal_entry_from_account ON journal_entry(from_account);
+CREATE INDEX IF NOT EXISTS idx_journal_entry_to_account ON journal_entry(to_account);
+
+-- +migrate StatementBegin
+CREATE TRIGGER IF NOT EXISTS journal_entry_no_update BEFORE UPDATE ON journal_entry
+BEGIN
+	SELECT RAISE(ABORT, 'journal entries are immutable');
+END;
+-- +migrate StatementEnd
+
+-- +migrate StatementBegin
+CREATE TRIGGER IF NOT EXISTS journal_entry_no_delete BEFORE DELETE ON journal_entry
+BEGIN
+	SELECT RAISE(ABORT, 'journal entries are immutable');
+END;
+-- +migrate StatementEnd
+
+-- balances held before the ledger existed are carried over as opening
+-- entries so that every balance matches the journal
+INSERT INTO journal_entry (uuid, type, from_account, to_account, amount, user_uuid, reference_uuid, timestamp)
+SELECT
+	lower(substr(h, 1, 8) || '-' || substr(h, 9, 4) || '-4' || substr(h, 14, 3) || '-8' || substr(h, 18, 3) || '-' || substr(h, 21, 12)),
+	'opening',
+	CASE WHEN balance > 0 THEN 'opening' ELSE 'user:' || user_uuid END,
+	CASE WHEN balance > 0 THEN 'user:' || user_uuid ELSE 'opening' END,
+	abs(balance),
+	user_uuid,
+	user_uuid,
+	CURRENT_TIMESTAMP
+FROM (SELECT hex(randomblob(16)) AS h, user_uuid, balance FROM bet_user WHERE balance <> 0);
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS journal_entry_no_delete;
+DROP TRIGGER IF EXISTS journal_entry_no_update;
+DROP TABLE IF EXISTS journal_entry;
@@ -0,0 +1,15 @@
+-- +migrate Up
+ALTER TABLE withdrawal ADD COLUMN status TEXT NOT NULL DEFAULT 'paid';
+ALTER TABLE withdrawal ADD COLUMN approved_by TEXT NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE withdrawal ADD COLUMN second_approved_by TEXT NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE withdrawal ADD COLUMN resolved_by TEXT NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE withdrawal ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+
+UPDATE withdrawal SET updated_at = timestamp;
+
+-- +migrate Down
+ALTER TABLE withdrawal DROP COLUMN updated_at;
+ALTER TABLE withdrawal DROP COLUMN resolved_by;
+ALTER TABLE withdrawal DROP COLUMN second_approved_by;
+ALTER TABLE withdrawal DROP COLUMN approved_by;
+ALTER TABLE withdrawal DROP COLUMN status;
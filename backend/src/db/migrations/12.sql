@@ -0,0 +1,27 @@
+-- +migrate Up
+ALTER TABLE deposit ADD COLUMN status TEXT NOT NULL DEFAULT 'confirmed';
+ALTER TABLE deposit ADD COLUMN provider TEXT NOT NULL DEFAULT '';
+ALTER TABLE deposit ADD COLUMN reference TEXT NOT NULL DEFAULT '';
+ALTER TABLE deposit ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+
+UPDATE deposit SET updated_at = timestamp;
+
+CREATE UNIQUE INDEX IF NOT EXISTS deposit_provider_reference ON deposit(provider, reference) WHERE reference != '';
+
+ALTER TABLE withdrawal ADD COLUMN provider TEXT NOT NULL DEFAULT '';
+ALTER TABLE withdrawal ADD COLUMN reference TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS withdrawal_provider_reference ON withdrawal(provider, reference);
+
+-- +migrate Down
+DROP INDEX IF EXISTS withdrawal_provider_reference;
+
+ALTER TABLE withdrawal DROP COLUMN reference;
+ALTER TABLE withdrawal DROP COLUMN provider;
+
+DROP INDEX IF EXISTS deposit_provider_reference;
+
+ALTER TABLE deposit DROP COLUMN updated_at;
+ALTER TABLE deposit DROP COLUMN reference;
+ALTER TABLE deposit DROP COLUMN provider;
+ALTER TABLE deposit DROP COLUMN status;
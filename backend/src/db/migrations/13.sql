@@ -0,0 +1,42 @@
+-- +migrate Up
+ALTER TABLE bet_user ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+ALTER TABLE deposit ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+ALTER TABLE withdrawal ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+ALTER TABLE bet ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+ALTER TABLE accumulator ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS exchange_rate (
+	uuid TEXT PRIMARY KEY NOT NULL,
+	currency TEXT NOT NULL,
+	rate NUMERIC NOT NULL CHECK (rate > 0),
+	valid_from TIMESTAMP NOT NULL,
+	admin_uuid TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS exchange_rate_currency_valid_from ON exchange_rate(currency, valid_from);
+
+-- +migrate StatementBegin
+CREATE TRIGGER IF NOT EXISTS exchange_rate_no_update BEFORE UPDATE ON exchange_rate
+BEGIN
+	SELECT RAISE(ABORT, 'exchange rates cannot be changed');
+END;
+-- +migrate StatementEnd
+
+-- +migrate StatementBegin
+CREATE TRIGGER IF NOT EXISTS exchange_rate_no_delete BEFORE DELETE ON exchange_rate
+BEGIN
+	SELECT RAISE(ABORT, 'exchange rates cannot be deleted');
+END;
+-- +migrate StatementEnd
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS exchange_rate_no_delete;
+DROP TRIGGER IF EXISTS exchange_rate_no_update;
+DROP INDEX IF EXISTS exchange_rate_currency_valid_from;
+DROP TABLE IF EXISTS exchange_rate;
+
+ALTER TABLE accumulator DROP COLUMN currency;
+ALTER TABLE bet DROP COLUMN currency;
+ALTER TABLE withdrawal DROP COLUMN currency;
+ALTER TABLE deposit DROP COLUMN currency;
+ALTER TABLE bet_user DROP COLUMN currency;
@@ -0,0 +1,66 @@
+-- +migrate Up
+ALTER TABLE bet_user ADD COLUMN bonus_balance NUMERIC NOT NULL DEFAULT 0;
+ALTER TABLE bet ADD COLUMN token_uuid TEXT NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE bet ADD COLUMN free_bet BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS bonus_campaign (
+	uuid TEXT PRIMARY KEY NOT NULL,
+	name TEXT NOT NULL,
+	type TEXT NOT NULL,
+	currency TEXT NOT NULL,
+	match_rate NUMERIC NOT NULL,
+	max_amount NUMERIC NOT NULL,
+	wagering_multiplier NUMERIC NOT NULL,
+	min_odds NUMERIC NOT NULL,
+	free_bet_amount NUMERIC NOT NULL,
+	odds_boost NUMERIC NOT NULL,
+	starts_at TIMESTAMP NOT NULL,
+	ends_at TIMESTAMP NOT NULL,
+	created_by TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS bonus_grant (
+	uuid TEXT PRIMARY KEY NOT NULL,
+	campaign_uuid TEXT NOT NULL,
+	user_uuid TEXT NOT NULL,
+	deposit_uuid TEXT NOT NULL UNIQUE,
+	amount NUMERIC NOT NULL,
+	wagering_required NUMERIC NOT NULL,
+	wagered NUMERIC NOT NULL,
+	min_odds NUMERIC NOT NULL,
+	status TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	UNIQUE (campaign_uuid, user_uuid),
+	CONSTRAINT fk_campaign_uuid_bonus_campaign_uuid FOREIGN KEY(campaign_uuid) REFERENCES bonus_campaign(uuid),
+	CONSTRAINT fk_user_uuid_bet_user_user_uuid FOREIGN KEY(user_uuid) REFERENCES bet_user(user_uuid)
+);
+
+CREATE INDEX IF NOT EXISTS bonus_grant_user_uuid_status ON bonus_grant(user_uuid, status);
+
+CREATE TABLE IF NOT EXISTS bonus_token (
+	uuid TEXT PRIMARY KEY NOT NULL,
+	campaign_uuid TEXT NOT NULL,
+	user_uuid TEXT NOT NULL,
+	type TEXT NOT NULL,
+	amount NUMERIC NOT NULL,
+	boost NUMERIC NOT NULL,
+	status TEXT NOT NULL,
+	bet_uuid TEXT NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	UNIQUE (campaign_uuid, user_uuid),
+	CONSTRAINT fk_campaign_uuid_bonus_campaign_uuid FOREIGN KEY(campaign_uuid) REFERENCES bonus_campaign(uuid),
+	CONSTRAINT fk_user_uuid_bet_user_user_uuid FOREIGN KEY(user_uuid) REFERENCES bet_user(user_uuid)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS bonus_token;
+DROP INDEX IF EXISTS bonus_grant_user_uuid_status;
+DROP TABLE IF EXISTS bonus_grant;
+DROP TABLE IF EXISTS bonus_campaign;
+
+ALTER TABLE bet DROP COLUMN free_bet;
+ALTER TABLE bet DROP COLUMN token_uuid;
+ALTER TABLE bet_user DROP COLUMN bonus_balance;
@@ -0,0 +1,16 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS balance_discrepancy (
+	uuid TEXT PRIMARY KEY NOT NULL,
+	user_uuid TEXT NOT NULL,
+	expected NUMERIC NOT NULL,
+	actual NUMERIC NOT NULL,
+	currency TEXT NOT NULL,
+	detected_at TIMESTAMP NOT NULL,
+	CONSTRAINT fk_user_uuid_bet_user_user_uuid FOREIGN KEY(user_uuid) REFERENCES bet_user(user_uuid)
+);
+
+CREATE INDEX IF NOT EXISTS balance_discrepancy_user_uuid_detected_at ON balance_discrepancy(user_uuid, detected_at);
+
+-- +migrate Down
+DROP INDEX IF EXISTS balance_discrepancy_user_uuid_detected_at;
+DROP TABLE IF EXISTS balance_discrepancy;
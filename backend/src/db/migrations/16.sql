@@ -0,0 +1,13 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS password_reset_token (
+	token TEXT PRIMARY KEY NOT NULL,
+	user_uuid TEXT NOT NULL,
+	used BOOLEAN NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+
+	CONSTRAINT fk_user_uuid_user_uuid FOREIGN KEY(user_uuid) REFERENCES user(uuid)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS password_reset_token;
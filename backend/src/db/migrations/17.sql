@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE email_verification_token ADD COLUMN email TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE email_verification_token DROP COLUMN email;
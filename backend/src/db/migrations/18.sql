@@ -0,0 +1,26 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS two_factor (
+	user_uuid TEXT PRIMARY KEY NOT NULL,
+	secret TEXT NOT NULL,
+	enabled BOOLEAN NOT NULL,
+	last_step INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+
+	CONSTRAINT fk_user_uuid_user_uuid FOREIGN KEY(user_uuid) REFERENCES user(uuid)
+);
+
+CREATE TABLE IF NOT EXISTS recovery_code (
+	hash TEXT PRIMARY KEY NOT NULL,
+	user_uuid TEXT NOT NULL,
+	used BOOLEAN NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+
+	CONSTRAINT fk_user_uuid_user_uuid FOREIGN KEY(user_uuid) REFERENCES user(uuid)
+);
+
+CREATE INDEX IF NOT EXISTS recovery_code_user_uuid ON recovery_code(user_uuid);
+
+-- +migrate Down
+DROP INDEX IF EXISTS recovery_code_user_uuid;
+DROP TABLE IF EXISTS recovery_code;
+DROP TABLE IF EXISTS two_factor;
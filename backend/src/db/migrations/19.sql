@@ -0,0 +1,23 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS login_throttle (
+	scope TEXT NOT NULL,
+	key TEXT NOT NULL,
+	failures INTEGER NOT NULL,
+	last_failure_at TIMESTAMP NOT NULL,
+	locked_until TIMESTAMP NOT NULL,
+	PRIMARY KEY (scope, key)
+);
+
+CREATE TABLE IF NOT EXISTS lockout_event (
+	uuid TEXT PRIMARY KEY NOT NULL,
+	scope TEXT NOT NULL,
+	key TEXT NOT NULL,
+	user_uuid TEXT NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000',
+	ip TEXT NOT NULL,
+	locked_until TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS lockout_event;
+DROP TABLE IF EXISTS login_throttle;
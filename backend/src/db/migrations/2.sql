@@ -0,0 +1,27 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS accumulator (
+	uuid TEXT PRIMARY KEY NOT NULL,
+	user_uuid TEXT NOT NULL,
+	stake NUMERIC NOT NULL,
+	odds NUMERIC NOT NULL,
+	state TEXT NOT NULL,
+	timestamp TIMESTAMP NOT NULL,
+
+	CONSTRAINT fk_accumulator_user_uuid_bet_user_user_uuid FOREIGN KEY(user_uuid) REFERENCES bet_user(user_uuid)
+);
+
+CREATE TABLE IF NOT EXISTS accumulator_leg (
+	uuid TEXT PRIMARY KEY NOT NULL,
+	accumulator_uuid TEXT NOT NULL,
+	selection_uuid TEXT NOT NULL,
+	selection_winner TEXT NOT NULL,
+	odds NUMERIC NOT NULL,
+	state TEXT NOT NULL,
+
+	CONSTRAINT fk_accumulator_leg_accumulator_uuid_accumulator_uuid FOREIGN KEY(accumulator_uuid) REFERENCES accumulator(uuid),
+	CONSTRAINT fk_accumulator_leg_selection_uuid_event_selection_uuid FOREIGN KEY(selection_uuid) REFERENCES event_selection(uuid)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS accumulator_leg;
+DROP TABLE IF EXISTS accumulator;
@@ -0,0 +1,19 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS session (
+	id TEXT PRIMARY KEY NOT NULL,
+	user_key TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	ip TEXT NOT NULL,
+	agent_os TEXT NOT NULL,
+	agent_browser TEXT NOT NULL,
+	meta TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS session_user_key ON session(user_key);
+CREATE INDEX IF NOT EXISTS session_expires_at ON session(expires_at);
+
+-- +migrate Down
+DROP INDEX IF EXISTS session_expires_at;
+DROP INDEX IF EXISTS session_user_key;
+DROP TABLE IF EXISTS session;
@@ -0,0 +1,10 @@
+-- +migrate Up
+ALTER TABLE email_verification_token ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00 +0000 UTC';
+ALTER TABLE email_verification_token ADD COLUMN expires_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00 +0000 UTC';
+
+CREATE INDEX IF NOT EXISTS email_verification_token_user_uuid ON email_verification_token(user_uuid, created_at);
+
+-- +migrate Down
+DROP INDEX IF EXISTS email_verification_token_user_uuid;
+ALTER TABLE email_verification_token DROP COLUMN expires_at;
+ALTER TABLE email_verification_token DROP COLUMN created_at;
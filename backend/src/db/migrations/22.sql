@@ -0,0 +1,36 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS gambling_limit (
+	user_uuid TEXT NOT NULL,
+	type TEXT NOT NULL,
+	period TEXT NOT NULL,
+	amount NUMERIC NOT NULL,
+	pending BOOLEAN NOT NULL,
+	pending_amount NUMERIC NOT NULL,
+	pending_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (user_uuid, type, period),
+	CONSTRAINT fk_user_uuid_bet_user_user_uuid FOREIGN KEY(user_uuid) REFERENCES bet_user(user_uuid)
+);
+
+CREATE TABLE IF NOT EXISTS gambling_limit_change (
+	uuid TEXT PRIMARY KEY NOT NULL,
+	user_uuid TEXT NOT NULL,
+	type TEXT NOT NULL,
+	period TEXT NOT NULL,
+	old_amount NUMERIC NOT NULL,
+	new_amount NUMERIC NOT NULL,
+	effective_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	CONSTRAINT fk_user_uuid_bet_user_user_uuid FOREIGN KEY(user_uuid) REFERENCES bet_user(user_uuid)
+);
+
+CREATE INDEX IF NOT EXISTS gambling_limit_change_user_uuid_created_at ON gambling_limit_change(user_uuid, created_at);
+CREATE INDEX IF NOT EXISTS bet_user_uuid_timestamp ON bet(user_uuid, timestamp);
+CREATE INDEX IF NOT EXISTS accumulator_user_uuid_timestamp ON accumulator(user_uuid, timestamp);
+
+-- +migrate Down
+DROP INDEX IF EXISTS accumulator_user_uuid_timestamp;
+DROP INDEX IF EXISTS bet_user_uuid_timestamp;
+DROP INDEX IF EXISTS gambling_limit_change_user_uuid_created_at;
+DROP TABLE IF EXISTS gambling_limit_change;
+DROP TABLE IF EXISTS gambling_limit;
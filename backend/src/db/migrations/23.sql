@@ -0,0 +1,21 @@
+-- +migrate Up
+ALTER TABLE bet_user ADD COLUMN exclusion_kind TEXT NOT NULL DEFAULT '';
+ALTER TABLE bet_user ADD COLUMN excluded_until TIMESTAMP NOT NULL DEFAULT '0001-01-01 00:00:00 +0000 UTC';
+
+CREATE TABLE IF NOT EXISTS exclusion (
+	uuid TEXT PRIMARY KEY NOT NULL,
+	user_uuid TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	period TEXT NOT NULL,
+	ends_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	CONSTRAINT fk_user_uuid_bet_user_user_uuid FOREIGN KEY(user_uuid) REFERENCES bet_user(user_uuid)
+);
+
+CREATE INDEX IF NOT EXISTS exclusion_user_uuid_created_at ON exclusion(user_uuid, created_at);
+
+-- +migrate Down
+DROP INDEX IF EXISTS exclusion_user_uuid_created_at;
+DROP TABLE IF EXISTS exclusion;
+ALTER TABLE bet_user DROP COLUMN excluded_until;
+ALTER TABLE bet_user DROP COLUMN exclusion_kind;
@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE event_selection ADD COLUMN odds_draw NUMERIC NOT NULL DEFAULT 0;
+ALTER TABLE event_selection ADD COLUMN has_draw BOOLEAN NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE event_selection DROP COLUMN has_draw;
+ALTER TABLE event_selection DROP COLUMN odds_draw;
@@ -0,0 +1,37 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS selection_outcome (
+	selection_uuid TEXT NOT NULL,
+	winner TEXT NOT NULL,
+	odds NUMERIC NOT NULL,
+
+	PRIMARY KEY(selection_uuid, winner),
+	CONSTRAINT fk_selection_outcome_selection_uuid_event_selection_uuid FOREIGN KEY(selection_uuid) REFERENCES event_selection(uuid)
+);
+
+ALTER TABLE event_selection ADD COLUMN market_type TEXT NOT NULL DEFAULT 'match_winner';
+ALTER TABLE event_selection ADD COLUMN line NUMERIC;
+
+INSERT INTO selection_outcome (selection_uuid, winner, odds) SELECT uuid, 'home', odds_home FROM event_selection;
+INSERT INTO selection_outcome (selection_uuid, winner, odds) SELECT uuid, 'away', odds_away FROM event_selection;
+INSERT INTO selection_outcome (selection_uuid, winner, odds) SELECT uuid, 'draw', odds_draw FROM event_selection WHERE has_draw;
+
+ALTER TABLE event_selection DROP COLUMN odds_home;
+ALTER TABLE event_selection DROP COLUMN odds_away;
+ALTER TABLE event_selection DROP COLUMN odds_draw;
+ALTER TABLE event_selection DROP COLUMN has_draw;
+
+-- +migrate Down
+ALTER TABLE event_selection ADD COLUMN odds_home NUMERIC NOT NULL DEFAULT 0;
+ALTER TABLE event_selection ADD COLUMN odds_away NUMERIC NOT NULL DEFAULT 0;
+ALTER TABLE event_selection ADD COLUMN odds_draw NUMERIC NOT NULL DEFAULT 0;
+ALTER TABLE event_selection ADD COLUMN has_draw BOOLEAN NOT NULL DEFAULT 0;
+
+UPDATE event_selection SET odds_home = IFNULL((SELECT odds FROM selection_outcome WHERE selection_uuid = event_selection.uuid AND winner = 'home'), 0);
+UPDATE event_selection SET odds_away = IFNULL((SELECT odds FROM selection_outcome WHERE selection_uuid = event_selection.uuid AND winner = 'away'), 0);
+UPDATE event_selection SET odds_draw = IFNULL((SELECT odds FROM selection_outcome WHERE selection_uuid = event_selection.uuid AND winner = 'draw'), 0);
+UPDATE event_selection SET has_draw = EXISTS (SELECT 1 FROM selection_outcome WHERE selection_uuid = event_selection.uuid AND winner = 'draw');
+
+ALTER TABLE event_selection DROP COLUMN line;
+ALTER TABLE event_selection DROP COLUMN market_type;
+
+DROP TABLE IF EXISTS selection_outcome;
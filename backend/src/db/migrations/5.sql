@@ -0,0 +1,13 @@
+-- +migrate Up
+ALTER TABLE bet_event ADD COLUMN home_score INTEGER;
+ALTER TABLE bet_event ADD COLUMN away_score INTEGER;
+ALTER TABLE bet_event ADD COLUMN half_time_home_score INTEGER;
+ALTER TABLE bet_event ADD COLUMN half_time_away_score INTEGER;
+ALTER TABLE event_selection ADD COLUMN period TEXT NOT NULL DEFAULT 'full_time';
+
+-- +migrate Down
+ALTER TABLE event_selection DROP COLUMN period;
+ALTER TABLE bet_event DROP COLUMN half_time_away_score;
+ALTER TABLE bet_event DROP COLUMN half_time_home_score;
+ALTER TABLE bet_event DROP COLUMN away_score;
+ALTER TABLE bet_event DROP COLUMN home_score;
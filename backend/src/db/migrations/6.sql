@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE bet ADD COLUMN cash_out NUMERIC NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE bet DROP COLUMN cash_out;
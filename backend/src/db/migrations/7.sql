@@ -0,0 +1,9 @@
+-- +migrate Up
+ALTER TABLE bet_event ADD COLUMN status TEXT NOT NULL DEFAULT 'scheduled';
+ALTER TABLE event_selection ADD COLUMN status TEXT NOT NULL DEFAULT 'open';
+
+UPDATE bet_event SET status = 'finished' WHERE finished;
+
+-- +migrate Down
+ALTER TABLE event_selection DROP COLUMN status;
+ALTER TABLE bet_event DROP COLUMN status;
@@ -0,0 +1,16 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS bet_limit (
+	scope TEXT NOT NULL,
+	scope_uuid TEXT NOT NULL,
+	min_stake NUMERIC NOT NULL DEFAULT 0,
+	max_stake NUMERIC NOT NULL DEFAULT 0,
+	max_payout NUMERIC NOT NULL DEFAULT 0,
+	max_liability NUMERIC NOT NULL DEFAULT 0,
+
+	PRIMARY KEY(scope, scope_uuid)
+);
+
+INSERT INTO bet_limit (scope, scope_uuid) VALUES ("global", "00000000-0000-0000-0000-000000000000");
+
+-- +migrate Down
+DROP TABLE IF EXISTS bet_limit;
@@ -0,0 +1,18 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS balance_adjustment (
+	uuid TEXT PRIMARY KEY NOT NULL,
+	user_uuid TEXT NOT NULL,
+	admin_uuid TEXT NOT NULL,
+	bet_uuid TEXT NOT NULL,
+	selection_uuid TEXT NOT NULL,
+	amount NUMERIC NOT NULL,
+	balance NUMERIC NOT NULL,
+	reason TEXT NOT NULL,
+	timestamp TIMESTAMP NOT NULL,
+
+	CONSTRAINT fk_user_uuid_bet_user_user_uuid FOREIGN KEY(user_uuid) REFERENCES bet_user(user_uuid),
+	CONSTRAINT fk_admin_uuid_admin_user_uuid FOREIGN KEY(admin_uuid) REFERENCES admin_user(user_uuid)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS balance_adjustment;
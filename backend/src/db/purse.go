@@ -2,10 +2,13 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/shopspring/decimal"
 )
 
@@ -14,35 +17,500 @@ type Deposit struct {
 	Amount    decimal.Decimal `db:"dep.amount"`
 	Timestamp time.Time       `db:"dep.timestamp"`
 	UserUUID  uuid.UUID       `db:"dep.user_uuid"`
+	Status    string          `db:"dep.status"`
+	Provider  string          `db:"dep.provider"`
+	Reference string          `db:"dep.reference"`
+	UpdatedAt time.Time       `db:"dep.updated_at"`
+	Currency  string          `db:"dep.currency"`
+}
+
+type fetchDepositCriteria func(b sq.SelectBuilder, prefix string) sq.SelectBuilder
+
+func UserDeposits(id uuid.UUID) fetchDepositCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(sq.Eq{columnPredicate(prefix, "user_uuid"): id})
+	}
+}
+
+func DepositByUUID(id uuid.UUID) fetchDepositCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(sq.Eq{columnPredicate(prefix, "uuid"): id})
+	}
+}
+
+func DepositByReference(provider, ref string) fetchDepositCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(sq.Eq{
+			columnPredicate(prefix, "provider"):  provider,
+			columnPredicate(prefix, "reference"): ref,
+		})
+	}
 }
 
 type Withdrawal struct {
-	UUID      uuid.UUID       `db:"wd.uuid"`
-	Amount    decimal.Decimal `db:"wd.amount"`
-	Timestamp time.Time       `db:"wd.timestamp"`
-	UserUUID  uuid.UUID       `db:"wd.user_uuid"`
+	UUID             uuid.UUID       `db:"wd.uuid"`
+	Amount           decimal.Decimal `db:"wd.amount"`
+	Timestamp        time.Time       `db:"wd.timestamp"`
+	UserUUID         uuid.UUID       `db:"wd.user_uuid"`
+	Status           string          `db:"wd.status"`
+	ApprovedBy       uuid.UUID       `db:"wd.approved_by"`
+	SecondApprovedBy uuid.UUID       `db:"wd.second_approved_by"`
+	ResolvedBy       uuid.UUID       `db:"wd.resolved_by"`
+	UpdatedAt        time.Time       `db:"wd.updated_at"`
+	Provider         string          `db:"wd.provider"`
+	Reference        string          `db:"wd.reference"`
+	Currency         string          `db:"wd.currency"`
+}
+
+type fetchWithdrawalCriteria func(b sq.SelectBuilder, prefix string) sq.SelectBuilder
+
+func UserWithdrawals(id uuid.UUID) fetchWithdrawalCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(sq.Eq{columnPredicate(prefix, "user_uuid"): id})
+	}
+}
+
+func WithdrawalsWithStatus(statuses ...string) fetchWithdrawalCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(sq.Eq{columnPredicate(prefix, "status"): statuses})
+	}
+}
+
+func WithdrawalByReference(provider, ref string) fetchWithdrawalCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(sq.Eq{
+			columnPredicate(prefix, "provider"):  provider,
+			columnPredicate(prefix, "reference"): ref,
+		})
+	}
 }
 
 func (d *DB) InsertDeposit(ctx context.Context, e sq.ExecerContext, dep Deposit) error {
 	b := sq.Insert("deposit").SetMap(map[string]interface{}{
-		"uuid":      dep.UUID,
-		"user_uuid": dep.UserUUID,
-		"timestamp": dep.Timestamp,
-		"amount":    dep.Amount,
+		"uuid":       dep.UUID,
+		"user_uuid":  dep.UserUUID,
+		"timestamp":  dep.Timestamp,
+		"amount":     dep.Amount,
+		"status":     dep.Status,
+		"provider":   dep.Provider,
+		"reference":  dep.Reference,
+		"updated_at": dep.UpdatedAt,
+		"currency":   dep.Currency,
 	})
 
 	_, err := sq.ExecContextWith(ctx, e, b)
 	return err
 }
 
+// UpdateDeposit stores the deposit only if its stored status is still the
+// given one, reporting whether it was updated, so that a repeated provider
+// callback cannot credit the deposit twice.
+func (d *DB) UpdateDeposit(ctx context.Context, e sq.ExecerContext, dep Deposit, from string) (bool, error) {
+	b := sq.Update("deposit").SetMap(map[string]interface{}{
+		"status":     dep.Status,
+		"updated_at": dep.UpdatedAt,
+	}).Where(sq.Eq{"uuid": dep.UUID, "status": from})
+
+	res, err := sq.ExecContextWith(ctx, e, b)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (d *DB) FetchDeposit(ctx context.Context, q sq.QueryerContext, c fetchDepositCriteria) (Deposit, bool, error) {
+	b := sq.Select()
+
+	b = c(depositQuery(b, "dep").From("deposit AS dep"), "dep")
+	qr, args := b.MustSql()
+
+	var dep Deposit
+
+	err := d.d.GetContext(ctx, &dep, qr, args...)
+	switch err {
+	case nil:
+		return dep, true, nil
+	case sql.ErrNoRows:
+		return Deposit{}, false, nil
+	default:
+		return Deposit{}, false, err
+	}
+}
+
+func (d *DB) FetchDeposits(ctx context.Context, q sq.QueryerContext, c fetchDepositCriteria) ([]Deposit, error) {
+	b := sq.Select()
+
+	b = c(depositQuery(b, "dep").From("deposit AS dep"), "dep").OrderBy("dep.timestamp")
+
+	rows, err := sq.QueryContextWith(ctx, q, b)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var dd []Deposit
+
+	if err := sqlx.StructScan(rows, &dd); err != nil {
+		return nil, err
+	}
+
+	return dd, nil
+}
+
+func depositQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "uuid"),
+		column(prefix, "amount"),
+		column(prefix, "timestamp"),
+		column(prefix, "user_uuid"),
+		column(prefix, "status"),
+		column(prefix, "provider"),
+		column(prefix, "reference"),
+		column(prefix, "updated_at"),
+		column(prefix, "currency"),
+	)
+}
+
 func (d *DB) InsertWithdrawal(ctx context.Context, e sq.ExecerContext, wd Withdrawal) error {
 	b := sq.Insert("withdrawal").SetMap(map[string]interface{}{
-		"uuid":      wd.UUID,
-		"user_uuid": wd.UserUUID,
-		"timestamp": wd.Timestamp,
-		"amount":    wd.Amount,
+		"uuid":               wd.UUID,
+		"user_uuid":          wd.UserUUID,
+		"timestamp":          wd.Timestamp,
+		"amount":             wd.Amount,
+		"status":             wd.Status,
+		"approved_by":        wd.ApprovedBy,
+		"second_approved_by": wd.SecondApprovedBy,
+		"resolved_by":        wd.ResolvedBy,
+		"updated_at":         wd.UpdatedAt,
+		"provider":           wd.Provider,
+		"reference":          wd.Reference,
+		"currency":           wd.Currency,
 	})
 
 	_, err := sq.ExecContextWith(ctx, e, b)
 	return err
 }
+
+// UpdateWithdrawal stores the withdrawal only if its stored status is
+// still the given one, reporting whether it was updated, so that
+// concurrent reviews cannot both succeed.
+func (d *DB) UpdateWithdrawal(ctx context.Context, e sq.ExecerContext, wd Withdrawal, from string) (bool, error) {
+	b := sq.Update("withdrawal").SetMap(map[string]interface{}{
+		"status":             wd.Status,
+		"approved_by":        wd.ApprovedBy,
+		"second_approved_by": wd.SecondApprovedBy,
+		"resolved_by":        wd.ResolvedBy,
+		"updated_at":         wd.UpdatedAt,
+		"reference":          wd.Reference,
+	}).Where(sq.Eq{"uuid": wd.UUID, "status": from})
+
+	res, err := sq.ExecContextWith(ctx, e, b)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (d *DB) FetchWithdrawal(ctx context.Context, q sq.QueryerContext, id uuid.UUID) (Withdrawal, bool, error) {
+	b := sq.Select()
+
+	b = withdrawalQuery(b, "wd").From("withdrawal AS wd").Where(sq.Eq{"wd.uuid": id})
+	qr, args := b.MustSql()
+
+	var wd Withdrawal
+
+	err := d.d.GetContext(ctx, &wd, qr, args...)
+	switch err {
+	case nil:
+		return wd, true, nil
+	case sql.ErrNoRows:
+		return Withdrawal{}, false, nil
+	default:
+		return Withdrawal{}, false, err
+	}
+}
+
+func (d *DB) FetchWithdrawals(ctx context.Context, q sq.QueryerContext, c fetchWithdrawalCriteria) ([]Withdrawal, error) {
+	b := sq.Select()
+
+	b = c(withdrawalQuery(b, "wd").From("withdrawal AS wd"), "wd").OrderBy("wd.timestamp")
+	qr, args := b.MustSql()
+
+	var ww []Withdrawal
+
+	if err := d.d.SelectContext(ctx, &ww, qr, args...); err != nil {
+		return nil, err
+	}
+
+	return ww, nil
+}
+
+func withdrawalQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "uuid"),
+		column(prefix, "amount"),
+		column(prefix, "timestamp"),
+		column(prefix, "user_uuid"),
+		column(prefix, "status"),
+		column(prefix, "approved_by"),
+		column(prefix, "second_approved_by"),
+		column(prefix, "resolved_by"),
+		column(prefix, "updated_at"),
+		column(prefix, "provider"),
+		column(prefix, "reference"),
+		column(prefix, "currency"),
+	)
+}
+
+type BalanceAdjustment struct {
+	UUID          uuid.UUID       `db:"ba.uuid"`
+	UserUUID      uuid.UUID       `db:"ba.user_uuid"`
+	AdminUUID     uuid.UUID       `db:"ba.admin_uuid"`
+	BetUUID       uuid.UUID       `db:"ba.bet_uuid"`
+	SelectionUUID uuid.UUID       `db:"ba.selection_uuid"`
+	Amount        decimal.Decimal `db:"ba.amount"`
+	Balance       decimal.Decimal `db:"ba.balance"`
+	Reason        string          `db:"ba.reason"`
+	Timestamp     time.Time       `db:"ba.timestamp"`
+}
+
+type fetchBalanceAdjustmentCriteria func(b sq.SelectBuilder, prefix string) sq.SelectBuilder
+
+func AllBalanceAdjustments() fetchBalanceAdjustmentCriteria {
+	return func(b sq.SelectBuilder, _ string) sq.SelectBuilder {
+		return b
+	}
+}
+
+func UserBalanceAdjustments(id uuid.UUID) fetchBalanceAdjustmentCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(sq.Eq{columnPredicate(prefix, "user_uuid"): id})
+	}
+}
+
+func (d *DB) InsertBalanceAdjustment(ctx context.Context, e sq.ExecerContext, ba BalanceAdjustment) error {
+	b := sq.Insert("balance_adjustment").SetMap(map[string]interface{}{
+		"uuid":           ba.UUID,
+		"user_uuid":      ba.UserUUID,
+		"admin_uuid":     ba.AdminUUID,
+		"bet_uuid":       ba.BetUUID,
+		"selection_uuid": ba.SelectionUUID,
+		"amount":         ba.Amount,
+		"balance":        ba.Balance,
+		"reason":         ba.Reason,
+		"timestamp":      ba.Timestamp,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) FetchBalanceAdjustments(ctx context.Context, q sq.QueryerContext, c fetchBalanceAdjustmentCriteria) ([]BalanceAdjustment, error) {
+	b := sq.Select()
+
+	b = c(balanceAdjustmentQuery(b, "ba").From("balance_adjustment AS ba"), "ba").OrderBy("ba.timestamp")
+	qr, args := b.MustSql()
+
+	var aa []BalanceAdjustment
+
+	if err := d.d.SelectContext(ctx, &aa, qr, args...); err != nil {
+		return nil, err
+	}
+
+	return aa, nil
+}
+
+func balanceAdjustmentQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "uuid"),
+		column(prefix, "user_uuid"),
+		column(prefix, "admin_uuid"),
+		column(prefix, "bet_uuid"),
+		column(prefix, "selection_uuid"),
+		column(prefix, "amount"),
+		column(prefix, "balance"),
+		column(prefix, "reason"),
+		column(prefix, "timestamp"),
+	)
+}
+
+type JournalEntry struct {
+	UUID          uuid.UUID       `db:"je.uuid"`
+	Type          string          `db:"je.type"`
+	FromAccount   string          `db:"je.from_account"`
+	ToAccount     string          `db:"je.to_account"`
+	Amount        decimal.Decimal `db:"je.amount"`
+	UserUUID      uuid.UUID       `db:"je.user_uuid"`
+	ReferenceUUID uuid.UUID       `db:"je.reference_uuid"`
+	Timestamp     time.Time       `db:"je.timestamp"`
+}
+
+type fetchJournalEntryCriteria func(b sq.SelectBuilder, prefix string) sq.SelectBuilder
+
+func AccountJournalEntries(account string) fetchJournalEntryCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(sq.Or{
+			sq.Eq{columnPredicate(prefix, "from_account"): account},
+			sq.Eq{columnPredicate(prefix, "to_account"): account},
+		})
+	}
+}
+
+func (d *DB) InsertJournalEntry(ctx context.Context, e sq.ExecerContext, je JournalEntry) error {
+	b := sq.Insert("journal_entry").SetMap(map[string]interface{}{
+		"uuid":           je.UUID,
+		"type":           je.Type,
+		"from_account":   je.FromAccount,
+		"to_account":     je.ToAccount,
+		"amount":         je.Amount,
+		"user_uuid":      je.UserUUID,
+		"reference_uuid": je.ReferenceUUID,
+		"timestamp":      je.Timestamp,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+// FetchJournalEntries runs on the provided queryer so that entries
+// inserted by an open transaction are included.
+func (d *DB) FetchJournalEntries(ctx context.Context, q sq.QueryerContext, c fetchJournalEntryCriteria) ([]JournalEntry, error) {
+	b := sq.Select()
+
+	b = c(journalEntryQuery(b, "je").From("journal_entry AS je"), "je").OrderBy("je.timestamp", "je.rowid")
+
+	rows, err := sq.QueryContextWith(ctx, q, b)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ee []JournalEntry
+
+	if err := sqlx.StructScan(rows, &ee); err != nil {
+		return nil, err
+	}
+
+	return ee, nil
+}
+
+func journalEntryQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "uuid"),
+		column(prefix, "type"),
+		column(prefix, "from_account"),
+		column(prefix, "to_account"),
+		column(prefix, "amount"),
+		column(prefix, "user_uuid"),
+		column(prefix, "reference_uuid"),
+		column(prefix, "timestamp"),
+	)
+}
+
+type ExchangeRate struct {
+	UUID      uuid.UUID       `db:"er.uuid"`
+	Currency  string          `db:"er.currency"`
+	Rate      decimal.Decimal `db:"er.rate"`
+	ValidFrom time.Time       `db:"er.valid_from"`
+	AdminUUID uuid.UUID       `db:"er.admin_uuid"`
+}
+
+type fetchExchangeRateCriteria func(b sq.SelectBuilder, prefix string) sq.SelectBuilder
+
+func AllExchangeRates() fetchExchangeRateCriteria {
+	return func(b sq.SelectBuilder, _ string) sq.SelectBuilder {
+		return b
+	}
+}
+
+func CurrencyExchangeRates(currency string) fetchExchangeRateCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(sq.Eq{columnPredicate(prefix, "currency"): currency})
+	}
+}
+
+func (d *DB) InsertExchangeRate(ctx context.Context, e sq.ExecerContext, er ExchangeRate) error {
+	b := sq.Insert("exchange_rate").SetMap(map[string]interface{}{
+		"uuid":       er.UUID,
+		"currency":   er.Currency,
+		"rate":       er.Rate,
+		"valid_from": er.ValidFrom,
+		"admin_uuid": er.AdminUUID,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+// FetchExchangeRates returns the rates ordered by currency and the time
+// they took effect.
+func (d *DB) FetchExchangeRates(ctx context.Context, q sq.QueryerContext, c fetchExchangeRateCriteria) ([]ExchangeRate, error) {
+	b := sq.Select()
+
+	b = c(exchangeRateQuery(b, "er").From("exchange_rate AS er"), "er").OrderBy("er.currency", "er.valid_from")
+	qr, args := b.MustSql()
+
+	var rr []ExchangeRate
+
+	if err := d.d.SelectContext(ctx, &rr, qr, args...); err != nil {
+		return nil, err
+	}
+
+	return rr, nil
+}
+
+func exchangeRateQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "uuid"),
+		column(prefix, "currency"),
+		column(prefix, "rate"),
+		column(prefix, "valid_from"),
+		column(prefix, "admin_uuid"),
+	)
+}
+
+// baseRate selects the rate converting the amounts of the table's rows to
+// the base currency, in effect at the time of each row. Rows older than
+// the first rate of their currency are converted at that first rate, the
+// rate is NULL if the currency has none.
+func baseRate(prefix, base string) sq.Sqlizer {
+	return sq.Expr(fmt.Sprintf(`IIF(%[1]s.currency = ?, 1, IFNULL(
+		(SELECT er.rate FROM exchange_rate AS er WHERE er.currency = %[1]s.currency AND er.valid_from <= %[1]s.timestamp ORDER BY er.valid_from DESC LIMIT 1),
+		(SELECT er.rate FROM exchange_rate AS er WHERE er.currency = %[1]s.currency ORDER BY er.valid_from LIMIT 1)
+	)) AS rate`, prefix), base)
+}
+
+// BackfillCurrency sets the currency of the wallets, payments and bets
+// that predate currencies, which have none, to the base currency.
+func (d *DB) BackfillCurrency(ctx context.Context, currency string) error {
+	tx, err := d.NewTX(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	for _, table := range []string{"bet_user", "deposit", "withdrawal", "bet", "accumulator"} {
+		b := sq.Update(table).Set("currency", currency).Where(sq.Eq{"currency": ""})
+
+		if _, err = sq.ExecContextWith(ctx, tx, b); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/shopspring/decimal"
+)
+
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	d, err := NewDB(filepath.Join(t.TempDir(), "test.sql"), zerolog.Nop())
+	if err != nil {
+		t.Fatalf("cannot create database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		d.Close()
+	})
+
+	return d
+}
+
+func newTestBetUser(t *testing.T, d *DB) BetUser {
+	t.Helper()
+
+	ctx := context.Background()
+
+	u := BetUser{
+		User: User{
+			UUID:      uuid.New(),
+			Email:     uuid.NewString() + "@example.com",
+			FirstName: "First",
+			LastName:  "Last",
+		},
+		Currency: "EUR",
+	}
+
+	if err := d.InsertUser(ctx, d.NoTX(), u.User); err != nil {
+		t.Fatalf("cannot insert user: %v", err)
+	}
+
+	if err := d.InsertBetUser(ctx, d.NoTX(), u); err != nil {
+		t.Fatalf("cannot insert bet user: %v", err)
+	}
+
+	return u
+}
+
+func TestJournalEntryConstraints(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+
+	u := newTestBetUser(t, d)
+	account := "user:" + u.UUID.String()
+
+	je := JournalEntry{
+		UUID:          uuid.New(),
+		Type:          "deposit",
+		FromAccount:   "house",
+		ToAccount:     account,
+		Amount:        decimal.NewFromInt(50),
+		UserUUID:      u.UUID,
+		ReferenceUUID: uuid.New(),
+		Timestamp:     time.Now(),
+	}
+
+	if err := d.InsertJournalEntry(ctx, d.NoTX(), je); err != nil {
+		t.Fatalf("cannot insert journal entry: %v", err)
+	}
+
+	tests := map[string]struct {
+		exec func() error
+		err  string
+	}{
+		"update": {
+			exec: func() error {
+				_, err := d.NoTX().ExecContext(ctx, "UPDATE journal_entry SET amount = 60 WHERE uuid = ?", je.UUID)
+				return err
+			},
+			err: "journal entries are immutable",
+		},
+		"delete": {
+			exec: func() error {
+				_, err := d.NoTX().ExecContext(ctx, "DELETE FROM journal_entry WHERE uuid = ?", je.UUID)
+				return err
+			},
+			err: "journal entries are immutable",
+		},
+		"zero amount": {
+			exec: func() error {
+				e := je
+				e.UUID = uuid.New()
+				e.Amount = decimal.Zero
+
+				return d.InsertJournalEntry(ctx, d.NoTX(), e)
+			},
+			err: "chk_amount_positive",
+		},
+		"same accounts": {
+			exec: func() error {
+				e := je
+				e.UUID = uuid.New()
+				e.FromAccount = account
+
+				return d.InsertJournalEntry(ctx, d.NoTX(), e)
+			},
+			err: "chk_accounts_differ",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.exec()
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("want error %q, got %v", test.err, err)
+			}
+		})
+	}
+
+	ee, err := d.FetchJournalEntries(ctx, d.NoTX(), AccountJournalEntries(account))
+	if err != nil {
+		t.Fatalf("cannot fetch journal entries: %v", err)
+	}
+
+	if len(ee) != 1 || ee[0].UUID != je.UUID || !ee[0].Amount.Equal(je.Amount) {
+		t.Errorf("want only the inserted entry unchanged, got %+v", ee)
+	}
+}
+
+func TestUpdateWithdrawal(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+
+	u := newTestBetUser(t, d)
+
+	wd := Withdrawal{
+		UUID:      uuid.New(),
+		Amount:    decimal.NewFromInt(50),
+		Timestamp: time.Now(),
+		UserUUID:  u.UUID,
+		Status:    "pending",
+		UpdatedAt: time.Now(),
+		Currency:  "EUR",
+	}
+
+	if err := d.InsertWithdrawal(ctx, d.NoTX(), wd); err != nil {
+		t.Fatalf("cannot insert withdrawal: %v", err)
+	}
+
+	tests := []struct {
+		from    string
+		status  string
+		updated bool
+	}{
+		{from: "pending", status: "approved", updated: true},
+		{from: "pending", status: "rejected"},
+		{from: "approved", status: "paid", updated: true},
+	}
+
+	for i, test := range tests {
+		wd.Status = test.status
+
+		ok, err := d.UpdateWithdrawal(ctx, d.NoTX(), wd, test.from)
+		if err != nil {
+			t.Fatalf("update %d: want no error, got %v", i, err)
+		}
+
+		if ok != test.updated {
+			t.Fatalf("update %d: want updated %t, got %t", i, test.updated, ok)
+		}
+	}
+
+	stored, ok, err := d.FetchWithdrawal(ctx, d.NoTX(), wd.UUID)
+	if err != nil || !ok {
+		t.Fatalf("cannot fetch withdrawal: %v", err)
+	}
+
+	if stored.Status != "paid" {
+		t.Errorf("want status paid, got %s", stored.Status)
+	}
+}
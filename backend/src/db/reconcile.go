@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type BalanceDiscrepancy struct {
+	UUID       uuid.UUID       `db:"bd.uuid"`
+	UserUUID   uuid.UUID       `db:"bd.user_uuid"`
+	Expected   decimal.Decimal `db:"bd.expected"`
+	Actual     decimal.Decimal `db:"bd.actual"`
+	Currency   string          `db:"bd.currency"`
+	DetectedAt time.Time       `db:"bd.detected_at"`
+}
+
+type fetchBalanceDiscrepancyCriteria func(b sq.SelectBuilder, prefix string) sq.SelectBuilder
+
+func BalanceDiscrepanciesBetween(from, to time.Time) fetchBalanceDiscrepancyCriteria {
+	return func(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+		return b.Where(sq.And{
+			sq.GtOrEq{columnPredicate(prefix, "detected_at"): from},
+			sq.Lt{columnPredicate(prefix, "detected_at"): to},
+		})
+	}
+}
+
+func (d *DB) InsertBalanceDiscrepancy(ctx context.Context, e sq.ExecerContext, bd BalanceDiscrepancy) error {
+	b := sq.Insert("balance_discrepancy").SetMap(map[string]interface{}{
+		"uuid":        bd.UUID,
+		"user_uuid":   bd.UserUUID,
+		"expected":    bd.Expected,
+		"actual":      bd.Actual,
+		"currency":    bd.Currency,
+		"detected_at": bd.DetectedAt,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) FetchBalanceDiscrepancies(ctx context.Context, q sq.QueryerContext, c fetchBalanceDiscrepancyCriteria) ([]BalanceDiscrepancy, error) {
+	b := sq.Select()
+
+	b = c(balanceDiscrepancyQuery(b, "bd").From("balance_discrepancy AS bd"), "bd").OrderBy("bd.detected_at")
+	qr, args := b.MustSql()
+
+	var dd []BalanceDiscrepancy
+
+	if err := d.d.SelectContext(ctx, &dd, qr, args...); err != nil {
+		return nil, err
+	}
+
+	return dd, nil
+}
+
+// FetchLatestBalanceDiscrepancy returns the discrepancy last recorded for
+// the bet user.
+func (d *DB) FetchLatestBalanceDiscrepancy(ctx context.Context, q sq.QueryerContext, userUUID uuid.UUID) (BalanceDiscrepancy, bool, error) {
+	b := sq.Select()
+
+	b = balanceDiscrepancyQuery(b, "bd").From("balance_discrepancy AS bd").
+		Where(sq.Eq{"bd.user_uuid": userUUID}).
+		OrderBy("bd.detected_at DESC").
+		Limit(1)
+	qr, args := b.MustSql()
+
+	var bd BalanceDiscrepancy
+
+	err := d.d.GetContext(ctx, &bd, qr, args...)
+	switch err {
+	case nil:
+		return bd, true, nil
+	case sql.ErrNoRows:
+		return BalanceDiscrepancy{}, false, nil
+	default:
+		return BalanceDiscrepancy{}, false, err
+	}
+}
+
+func balanceDiscrepancyQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "uuid"),
+		column(prefix, "user_uuid"),
+		column(prefix, "expected"),
+		column(prefix, "actual"),
+		column(prefix, "currency"),
+		column(prefix, "detected_at"),
+	)
+}
@@ -2,7 +2,6 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
@@ -19,64 +18,71 @@ type AutoReport struct {
 type ProfitOpts struct {
 	From time.Time
 	To   time.Time
+	// BaseCurrency is the currency the amounts are converted to.
+	BaseCurrency string
 }
 
+// ProfitReport holds the profit of bets and accumulators in one currency,
+// and the same amounts converted to the base currency at the rates in
+// effect when they were placed. MissingRates counts the bets that could
+// not be converted because their currency has no rate.
 type ProfitReport struct {
-	Profit decimal.Decimal
-	Loss   decimal.Decimal
-	Final  decimal.Decimal
+	Currency     string          `db:"currency"`
+	Profit       decimal.Decimal `db:"profit"`
+	Loss         decimal.Decimal `db:"loss"`
+	BaseProfit   decimal.Decimal `db:"base_profit"`
+	BaseLoss     decimal.Decimal `db:"base_loss"`
+	MissingRates int             `db:"missing_rates"`
 }
 
-func (d *DB) ProfitReport(ctx context.Context, opts ProfitOpts) (ProfitReport, error) {
-	b := sq.Select("IFNULL((SUM(IIF(state='lost', stake, 0))), 0) AS amnt").From("bet").Where(
-		sq.GtOrEq{"timestamp": opts.From},
-		sq.Lt{"timestamp": opts.To},
-	)
+// ProfitReport returns the profit of the bets and accumulators placed in
+// the period broken down by currency.
+func (d *DB) ProfitReport(ctx context.Context, opts ProfitOpts) ([]ProfitReport, error) {
+	// accumulators pay the product of the odds of their legs that were
+	// not voided, which is the odds they were placed at unless a leg was.
+	accOdds := `IIF(EXISTS (SELECT 1 FROM accumulator_leg AS accleg WHERE accleg.accumulator_uuid = acc.uuid AND accleg.state = 'void'),
+		(SELECT IFNULL(EXP(SUM(LN(accleg.odds))), 1) FROM accumulator_leg AS accleg WHERE accleg.accumulator_uuid = acc.uuid AND accleg.state != 'void'),
+		acc.odds) AS odds`
+
+	accs := sq.Select("acc.currency", "acc.state", "acc.stake", accOdds, "false AS free_bet", "0 AS cash_out").
+		Column(baseRate("acc", opts.BaseCurrency)).
+		From("accumulator AS acc").
+		Where(
+			sq.GtOrEq{"acc.timestamp": opts.From},
+			sq.Lt{"acc.timestamp": opts.To},
+		)
+
+	bets := sq.Select("bt.currency", "bt.state", "bt.stake", "bt.odds", "bt.free_bet", "bt.cash_out").
+		Column(baseRate("bt", opts.BaseCurrency)).
+		From("bet AS bt").
+		Where(
+			sq.GtOrEq{"bt.timestamp": opts.From},
+			sq.Lt{"bt.timestamp": opts.To},
+		).
+		SuffixExpr(sq.ConcatExpr("UNION ALL ", accs))
+
+	// the stake of a free bet was never paid, so it is neither won by the
+	// house nor paid back with the winnings.
+	lost := "IIF(state IN ('lost', 'cashed_out') AND NOT free_bet, stake, 0)"
+	won := "IIF(state='won', stake * odds - IIF(free_bet, stake, 0), IIF(state='cashed_out', cash_out, 0))"
+
+	b := sq.Select(
+		"currency",
+		"IFNULL(SUM("+lost+"), 0) AS profit",
+		"IFNULL(SUM("+won+"), 0) AS loss",
+		"IFNULL(SUM("+lost+" * rate), 0) AS base_profit",
+		"IFNULL(SUM("+won+" * rate), 0) AS base_loss",
+		"SUM(IIF(rate IS NULL, 1, 0)) AS missing_rates",
+	).FromSelect(bets, "bt").GroupBy("currency").OrderBy("currency")
 	q, args := b.MustSql()
 
-	var res struct {
-		Amount decimal.Decimal `db:"amnt"`
-	}
-
-	err := d.d.GetContext(ctx, &res, q, args...)
-	switch err {
-	case nil:
-	case sql.ErrNoRows:
-		return ProfitReport{
-			Final: decimal.Zero,
-		}, nil
-	default:
-		return ProfitReport{}, err
-	}
-
-	lost := res.Amount
-
-	var won struct {
-		Amount decimal.Decimal `db:"amnt"`
-	}
+	var rr []ProfitReport
 
-	b = sq.Select("IFNULL((SUM(IIF(state='won', stake * odds, 0))), 0) AS amnt").From("bet").Where(
-		sq.GtOrEq{"timestamp": opts.From},
-		sq.Lt{"timestamp": opts.To},
-	)
-	q, args = b.MustSql()
-
-	err = d.d.GetContext(ctx, &won, q, args...)
-	switch err {
-	case nil:
-	case sql.ErrNoRows:
-		return ProfitReport{
-			Final: decimal.Zero,
-		}, nil
-	default:
-		return ProfitReport{}, err
+	if err := d.d.SelectContext(ctx, &rr, q, args...); err != nil {
+		return nil, err
 	}
 
-	return ProfitReport{
-		Profit: lost,
-		Loss:   won.Amount,
-		Final:  lost.Sub(won.Amount),
-	}, nil
+	return rr, nil
 }
 
 func (d *DB) FetchAdmins(ctx context.Context) ([]AdminUser, error) {
@@ -129,26 +135,42 @@ func (d *DB) FetchBetReport(ctx context.Context, from, to time.Time) ([]Bet, err
 	return bb, nil
 }
 
-func (d *DB) FetchTotalDeposits(ctx context.Context, from, to time.Time) (decimal.Decimal, error) {
-	b := sq.Select("IFNULL(SUM(amount), 0) AS amnt").From("deposit").Where(
+// DepositTotal holds the sum of confirmed deposits in one currency, and
+// the same sum converted to the base currency at the rates in effect when
+// the deposits were made.
+type DepositTotal struct {
+	Currency     string          `db:"currency"`
+	Amount       decimal.Decimal `db:"amnt"`
+	BaseAmount   decimal.Decimal `db:"base_amnt"`
+	MissingRates int             `db:"missing_rates"`
+}
+
+// FetchTotalDeposits returns the sum of the deposits confirmed in the
+// period broken down by currency.
+func (d *DB) FetchTotalDeposits(ctx context.Context, from, to time.Time, base string) ([]DepositTotal, error) {
+	deposits := sq.Select("dep.*").Column(baseRate("dep", base)).From("deposit AS dep").Where(
 		sq.And{
-			sq.GtOrEq{"timestamp": from},
-			sq.Lt{"timestamp": to},
+			sq.GtOrEq{"dep.timestamp": from},
+			sq.Lt{"dep.timestamp": to},
+			sq.Eq{"dep.status": "confirmed"},
 		},
 	)
+
+	b := sq.Select(
+		"currency",
+		"IFNULL(SUM(amount), 0) AS amnt",
+		"IFNULL(SUM(amount * rate), 0) AS base_amnt",
+		"SUM(IIF(rate IS NULL, 1, 0)) AS missing_rates",
+	).FromSelect(deposits, "dep").GroupBy("currency").OrderBy("currency")
 	qr, args := b.MustSql()
 
-	var resp struct {
-		Amount decimal.Decimal `db:"amnt"`
-	}
+	var tt []DepositTotal
 
-	err := d.d.GetContext(ctx, &resp, qr, args...)
-	switch err {
-	case nil:
-		return resp.Amount, nil
-	default:
-		return decimal.Zero, err
+	if err := d.d.SelectContext(ctx, &tt, qr, args...); err != nil {
+		return nil, err
 	}
+
+	return tt, nil
 }
 
 func (d *DB) InsertAutoReport(ctx context.Context, e sq.ExecerContext, ar AutoReport) error {
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type Session struct {
+	ID           string    `db:"ses.id"`
+	UserKey      string    `db:"ses.user_key"`
+	CreatedAt    time.Time `db:"ses.created_at"`
+	ExpiresAt    time.Time `db:"ses.expires_at"`
+	IP           string    `db:"ses.ip"`
+	AgentOS      string    `db:"ses.agent_os"`
+	AgentBrowser string    `db:"ses.agent_browser"`
+	Meta         string    `db:"ses.meta"`
+}
+
+// InsertSession inserts the session unless one with the same ID exists,
+// reporting whether it was inserted.
+func (d *DB) InsertSession(ctx context.Context, e sq.ExecerContext, s Session) (bool, error) {
+	b := sq.Insert("session").Options("OR IGNORE").SetMap(map[string]interface{}{
+		"id":            s.ID,
+		"user_key":      s.UserKey,
+		"created_at":    s.CreatedAt,
+		"expires_at":    s.ExpiresAt,
+		"ip":            s.IP,
+		"agent_os":      s.AgentOS,
+		"agent_browser": s.AgentBrowser,
+		"meta":          s.Meta,
+	})
+
+	res, err := sq.ExecContextWith(ctx, e, b)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// FetchSession returns the session with the ID unless it expired by t.
+func (d *DB) FetchSession(ctx context.Context, q sq.QueryerContext, id string, t time.Time) (Session, bool, error) {
+	b := sq.Select()
+
+	b = sessionQuery(b, "ses").From("session AS ses").Where(sq.And{
+		sq.Eq{"ses.id": id},
+		sq.Gt{"ses.expires_at": t},
+	})
+	qr, args := b.MustSql()
+
+	var s Session
+
+	err := d.d.GetContext(ctx, &s, qr, args...)
+	switch err {
+	case nil:
+		return s, true, nil
+	case sql.ErrNoRows:
+		return Session{}, false, nil
+	default:
+		return Session{}, false, err
+	}
+}
+
+// FetchUserSessions returns the sessions of the user key that have not
+// expired by t.
+func (d *DB) FetchUserSessions(ctx context.Context, q sq.QueryerContext, key string, t time.Time) ([]Session, error) {
+	b := sq.Select()
+
+	b = sessionQuery(b, "ses").From("session AS ses").Where(sq.And{
+		sq.Eq{"ses.user_key": key},
+		sq.Gt{"ses.expires_at": t},
+	}).OrderBy("ses.created_at")
+	qr, args := b.MustSql()
+
+	var ss []Session
+
+	if err := d.d.SelectContext(ctx, &ss, qr, args...); err != nil {
+		return nil, err
+	}
+
+	return ss, nil
+}
+
+func (d *DB) DeleteSession(ctx context.Context, e sq.ExecerContext, id string) error {
+	b := sq.Delete("session").Where(sq.Eq{"id": id})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+// DeleteUserSessions deletes the sessions of the user key, except the
+// ones with the given IDs.
+func (d *DB) DeleteUserSessions(ctx context.Context, e sq.ExecerContext, key string, except []string) error {
+	b := sq.Delete("session").Where(sq.Eq{"user_key": key})
+
+	if len(except) > 0 {
+		b = b.Where(sq.NotEq{"id": except})
+	}
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) DeleteExpiredSessions(ctx context.Context, e sq.ExecerContext, t time.Time) error {
+	b := sq.Delete("session").Where(sq.LtOrEq{"expires_at": t})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func sessionQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "id"),
+		column(prefix, "user_key"),
+		column(prefix, "created_at"),
+		column(prefix, "expires_at"),
+		column(prefix, "ip"),
+		column(prefix, "agent_os"),
+		column(prefix, "agent_browser"),
+		column(prefix, "meta"),
+	)
+}
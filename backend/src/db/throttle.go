@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+type LoginThrottle struct {
+	Scope         string    `db:"lt.scope"`
+	Key           string    `db:"lt.key"`
+	Failures      int       `db:"lt.failures"`
+	LastFailureAt time.Time `db:"lt.last_failure_at"`
+	LockedUntil   time.Time `db:"lt.locked_until"`
+}
+
+type LockoutEvent struct {
+	UUID        uuid.UUID `db:"le.uuid"`
+	Scope       string    `db:"le.scope"`
+	Key         string    `db:"le.key"`
+	UserUUID    uuid.UUID `db:"le.user_uuid"`
+	IP          string    `db:"le.ip"`
+	LockedUntil time.Time `db:"le.locked_until"`
+	CreatedAt   time.Time `db:"le.created_at"`
+}
+
+func (d *DB) UpsertLoginThrottle(ctx context.Context, e sq.ExecerContext, lt LoginThrottle) error {
+	b := sq.Replace("login_throttle").SetMap(map[string]interface{}{
+		"scope":           lt.Scope,
+		"key":             lt.Key,
+		"failures":        lt.Failures,
+		"last_failure_at": lt.LastFailureAt,
+		"locked_until":    lt.LockedUntil,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) DeleteLoginThrottle(ctx context.Context, e sq.ExecerContext, scope, key string) error {
+	b := sq.Delete("login_throttle").Where(sq.Eq{"scope": scope, "key": key})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) FetchLoginThrottle(ctx context.Context, q sq.QueryerContext, scope, key string) (LoginThrottle, bool, error) {
+	b := sq.Select()
+
+	b = loginThrottleQuery(b, "lt").From("login_throttle AS lt").Where(sq.Eq{"lt.scope": scope, "lt.key": key})
+	qr, args := b.MustSql()
+
+	var lt LoginThrottle
+
+	err := d.d.GetContext(ctx, &lt, qr, args...)
+	switch err {
+	case nil:
+		return lt, true, nil
+	case sql.ErrNoRows:
+		return LoginThrottle{}, false, nil
+	default:
+		return LoginThrottle{}, false, err
+	}
+}
+
+func (d *DB) InsertLockoutEvent(ctx context.Context, e sq.ExecerContext, le LockoutEvent) error {
+	b := sq.Insert("lockout_event").SetMap(map[string]interface{}{
+		"uuid":         le.UUID,
+		"scope":        le.Scope,
+		"key":          le.Key,
+		"user_uuid":    le.UserUUID,
+		"ip":           le.IP,
+		"locked_until": le.LockedUntil,
+		"created_at":   le.CreatedAt,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+// FetchLockoutEvents returns the lockout events newest first.
+func (d *DB) FetchLockoutEvents(ctx context.Context, q sq.QueryerContext) ([]LockoutEvent, error) {
+	b := sq.Select()
+
+	b = lockoutEventQuery(b, "le").From("lockout_event AS le").OrderBy("le.created_at DESC")
+	qr, args := b.MustSql()
+
+	var ee []LockoutEvent
+
+	if err := d.d.SelectContext(ctx, &ee, qr, args...); err != nil {
+		return nil, err
+	}
+
+	return ee, nil
+}
+
+// AnonymizeLockoutEvents replaces the key and the address of the lockout
+// events of the user, keeping the record of the lockouts.
+func (d *DB) AnonymizeLockoutEvents(ctx context.Context, e sq.ExecerContext, userUUID uuid.UUID, key string) error {
+	b := sq.Update("lockout_event").SetMap(map[string]interface{}{
+		"key": key,
+		"ip":  "",
+	}).Where(sq.Eq{"user_uuid": userUUID})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func loginThrottleQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "scope"),
+		column(prefix, "key"),
+		column(prefix, "failures"),
+		column(prefix, "last_failure_at"),
+		column(prefix, "locked_until"),
+	)
+}
+
+func lockoutEventQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "uuid"),
+		column(prefix, "scope"),
+		column(prefix, "key"),
+		column(prefix, "user_uuid"),
+		column(prefix, "ip"),
+		column(prefix, "locked_until"),
+		column(prefix, "created_at"),
+	)
+}
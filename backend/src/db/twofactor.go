@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+type TwoFactor struct {
+	UserUUID  uuid.UUID `db:"tf.user_uuid"`
+	Secret    string    `db:"tf.secret"`
+	Enabled   bool      `db:"tf.enabled"`
+	LastStep  int64     `db:"tf.last_step"`
+	CreatedAt time.Time `db:"tf.created_at"`
+}
+
+type RecoveryCode struct {
+	UserUUID  uuid.UUID `db:"rc.user_uuid"`
+	Hash      string    `db:"rc.hash"`
+	Used      bool      `db:"rc.used"`
+	CreatedAt time.Time `db:"rc.created_at"`
+}
+
+func (d *DB) UpsertTwoFactor(ctx context.Context, e sq.ExecerContext, tf TwoFactor) error {
+	b := sq.Replace("two_factor").SetMap(map[string]interface{}{
+		"user_uuid":  tf.UserUUID,
+		"secret":     tf.Secret,
+		"enabled":    tf.Enabled,
+		"last_step":  tf.LastStep,
+		"created_at": tf.CreatedAt,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+// UpdateTwoFactorStep records the time step of the accepted code unless a
+// later one was recorded, so that the same code cannot be accepted twice.
+func (d *DB) UpdateTwoFactorStep(ctx context.Context, e sq.ExecerContext, tf TwoFactor) (bool, error) {
+	b := sq.Update("two_factor").
+		Set("last_step", tf.LastStep).
+		Where(sq.And{
+			sq.Eq{"user_uuid": tf.UserUUID},
+			sq.Lt{"last_step": tf.LastStep},
+		})
+
+	res, err := sq.ExecContextWith(ctx, e, b)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (d *DB) DeleteTwoFactor(ctx context.Context, e sq.ExecerContext, userUUID uuid.UUID) error {
+	b := sq.Delete("two_factor").Where(sq.Eq{"user_uuid": userUUID})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) FetchTwoFactor(ctx context.Context, q sq.QueryerContext, userUUID uuid.UUID) (TwoFactor, bool, error) {
+	b := sq.Select()
+
+	b = twoFactorQuery(b, "tf").From("two_factor AS tf").Where(sq.Eq{"tf.user_uuid": userUUID})
+	qr, args := b.MustSql()
+
+	var tf TwoFactor
+
+	err := d.d.GetContext(ctx, &tf, qr, args...)
+	switch err {
+	case nil:
+		return tf, true, nil
+	case sql.ErrNoRows:
+		return TwoFactor{}, false, nil
+	default:
+		return TwoFactor{}, false, err
+	}
+}
+
+func (d *DB) InsertRecoveryCode(ctx context.Context, e sq.ExecerContext, rc RecoveryCode) error {
+	b := sq.Insert("recovery_code").SetMap(map[string]interface{}{
+		"hash":       rc.Hash,
+		"user_uuid":  rc.UserUUID,
+		"used":       rc.Used,
+		"created_at": rc.CreatedAt,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+// UseRecoveryCode marks the unused recovery code of the user used,
+// reporting false if there is no such code.
+func (d *DB) UseRecoveryCode(ctx context.Context, e sq.ExecerContext, userUUID uuid.UUID, hash string) (bool, error) {
+	b := sq.Update("recovery_code").
+		Set("used", true).
+		Where(sq.Eq{"user_uuid": userUUID, "hash": hash, "used": false})
+
+	res, err := sq.ExecContextWith(ctx, e, b)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (d *DB) DeleteRecoveryCodes(ctx context.Context, e sq.ExecerContext, userUUID uuid.UUID) error {
+	b := sq.Delete("recovery_code").Where(sq.Eq{"user_uuid": userUUID})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func twoFactorQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "user_uuid"),
+		column(prefix, "secret"),
+		column(prefix, "enabled"),
+		column(prefix, "last_step"),
+		column(prefix, "created_at"),
+	)
+}
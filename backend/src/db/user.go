@@ -23,6 +23,19 @@ type BetUser struct {
 	User
 	IdentityVerified bool            `db:"betusr.identity_verified"`
 	Balance          decimal.Decimal `db:"betusr.balance"`
+	Currency         string          `db:"betusr.currency"`
+	BonusBalance     decimal.Decimal `db:"betusr.bonus_balance"`
+	ExclusionKind    string          `db:"betusr.exclusion_kind"`
+	ExcludedUntil    time.Time       `db:"betusr.excluded_until"`
+}
+
+type Exclusion struct {
+	UUID      uuid.UUID `db:"excl.uuid"`
+	UserUUID  uuid.UUID `db:"excl.user_uuid"`
+	Kind      string    `db:"excl.kind"`
+	Period    string    `db:"excl.period"`
+	EndsAt    time.Time `db:"excl.ends_at"`
+	CreatedAt time.Time `db:"excl.created_at"`
 }
 
 type AdminUser struct {
@@ -53,7 +66,18 @@ type IdentityVerification struct {
 type EmailVerification struct {
 	UserUUID  uuid.UUID `db:"emailver.user_uuid"`
 	Token     string    `db:"emailver.token"`
+	Email     string    `db:"emailver.email"`
 	Activated bool      `db:"emailver.activated"`
+	ExpiresAt time.Time `db:"emailver.expires_at"`
+	CreatedAt time.Time `db:"emailver.created_at"`
+}
+
+type PasswordReset struct {
+	UserUUID  uuid.UUID `db:"pwreset.user_uuid"`
+	Token     string    `db:"pwreset.token"`
+	Used      bool      `db:"pwreset.used"`
+	ExpiresAt time.Time `db:"pwreset.expires_at"`
+	CreatedAt time.Time `db:"pwreset.created_at"`
 }
 
 type fetchUserCriteria func(b sq.SelectBuilder, prefix string) sq.SelectBuilder
@@ -89,25 +113,70 @@ func (d *DB) InsertBetUser(ctx context.Context, e sq.ExecerContext, u BetUser) e
 		"user_uuid":         u.UUID,
 		"identity_verified": u.IdentityVerified,
 		"balance":           u.Balance,
+		"currency":          u.Currency,
+		"bonus_balance":     u.BonusBalance,
 	})
 
 	_, err := sq.ExecContextWith(ctx, e, b)
 	return err
 }
 
-func (d *DB) UpdateBetUser(ctx context.Context, e sq.ExecerContext, u BetUser) error {
+// UpdateBetUserBalance stores the balances of the user. They are only
+// changed along with the journal entries that move them, which are checked
+// against the balances in the same transaction.
+func (d *DB) UpdateBetUserBalance(ctx context.Context, e sq.ExecerContext, u BetUser) error {
+	b := sq.Update("bet_user").SetMap(map[string]interface{}{
+		"balance":       u.Balance,
+		"bonus_balance": u.BonusBalance,
+	}).Where(sq.Eq{"user_uuid": u.UUID})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) UpdateBetUserIdentity(ctx context.Context, e sq.ExecerContext, u BetUser) error {
 	b := sq.Update("bet_user").SetMap(map[string]interface{}{
 		"identity_verified": u.IdentityVerified,
-		"balance":           u.Balance,
 	}).Where(sq.Eq{"user_uuid": u.UUID})
 
 	_, err := sq.ExecContextWith(ctx, e, b)
 	return err
 }
 
+// UpdateBetUserExclusion stores the exclusion of the user. Like the other
+// bet user updates it only writes its own columns, so that users read
+// before the exclusion cannot overwrite it.
+func (d *DB) UpdateBetUserExclusion(ctx context.Context, e sq.ExecerContext, u BetUser) error {
+	b := sq.Update("bet_user").SetMap(map[string]interface{}{
+		"exclusion_kind": u.ExclusionKind,
+		"excluded_until": u.ExcludedUntil,
+	}).Where(sq.Eq{"user_uuid": u.UUID})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) InsertExclusion(ctx context.Context, e sq.ExecerContext, ex Exclusion) error {
+	b := sq.Insert("exclusion").SetMap(map[string]interface{}{
+		"uuid":       ex.UUID,
+		"user_uuid":  ex.UserUUID,
+		"kind":       ex.Kind,
+		"period":     ex.Period,
+		"ends_at":    ex.EndsAt,
+		"created_at": ex.CreatedAt,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
 func (d *DB) UpdateUser(ctx context.Context, e sq.ExecerContext, u User) error {
 	b := sq.Update("user").SetMap(map[string]interface{}{
+		"email":          u.Email,
+		"first_name":     u.FirstName,
+		"last_name":      u.LastName,
 		"email_verified": u.EmailVerified,
+		"password_hash":  u.PasswordHash,
 	}).Where(sq.Eq{"uuid": u.UUID})
 
 	_, err := sq.ExecContextWith(ctx, e, b)
@@ -248,11 +317,43 @@ func (d *DB) FetchIdentityVerifications(ctx context.Context, q sq.QueryerContext
 	return ids, nil
 }
 
+func (d *DB) FetchUserIdentityVerifications(ctx context.Context, q sq.QueryerContext, userUUID uuid.UUID) ([]IdentityVerification, error) {
+	b := sq.Select()
+
+	b = identityVerificatiosQuery(b, "idv").From("identity_verification AS idv").
+		Where(sq.Eq{"idv.user_uuid": userUUID}).
+		OrderBy("idv.created_at")
+	qr, args := b.MustSql()
+
+	var ids []IdentityVerification
+
+	if err := d.d.SelectContext(ctx, &ids, qr, args...); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// EraseIdentityVerifications removes the photos of the user's identity
+// verifications, keeping the record of their outcome.
+func (d *DB) EraseIdentityVerifications(ctx context.Context, e sq.ExecerContext, userUUID uuid.UUID) error {
+	b := sq.Update("identity_verification").SetMap(map[string]interface{}{
+		"id_photo_base64":       "",
+		"portrait_photo_base64": "",
+	}).Where(sq.Eq{"user_uuid": userUUID})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
 func (d *DB) InsertEmailVerification(ctx context.Context, e sq.ExecerContext, ve EmailVerification) error {
 	b := sq.Insert("email_verification_token").SetMap(map[string]interface{}{
-		"user_uuid": ve.UserUUID,
-		"token":     ve.Token,
-		"activated": ve.Activated,
+		"user_uuid":  ve.UserUUID,
+		"token":      ve.Token,
+		"email":      ve.Email,
+		"activated":  ve.Activated,
+		"expires_at": ve.ExpiresAt,
+		"created_at": ve.CreatedAt,
 	})
 
 	_, err := sq.ExecContextWith(ctx, e, b)
@@ -262,7 +363,7 @@ func (d *DB) InsertEmailVerification(ctx context.Context, e sq.ExecerContext, ve
 func (d *DB) UpdateEmailVerification(ctx context.Context, e sq.ExecerContext, ve EmailVerification) error {
 	b := sq.Update("email_verification_token").SetMap(map[string]interface{}{
 		"activated": ve.Activated,
-	})
+	}).Where(sq.Eq{"token": ve.Token})
 
 	_, err := sq.ExecContextWith(ctx, e, b)
 	return err
@@ -288,6 +389,123 @@ func (d *DB) FetchEmailVerification(ctx context.Context, q sq.QueryerContext, to
 	}
 }
 
+// FetchLatestEmailVerification returns the token issued to the user last.
+func (d *DB) FetchLatestEmailVerification(ctx context.Context, q sq.QueryerContext, userUUID uuid.UUID) (EmailVerification, bool, error) {
+	b := sq.Select()
+
+	b = emailVerificationQuery(b, "emailver").From("email_verification_token AS emailver").
+		Where(sq.Eq{"emailver.user_uuid": userUUID}).
+		OrderBy("emailver.created_at DESC").
+		Limit(1)
+
+	qr, args := b.MustSql()
+
+	var ver EmailVerification
+
+	err := d.d.GetContext(ctx, &ver, qr, args...)
+	switch err {
+	case nil:
+		return ver, true, nil
+	case sql.ErrNoRows:
+		return EmailVerification{}, false, nil
+	default:
+		return EmailVerification{}, false, err
+	}
+}
+
+// ExpireEmailVerifications expires the unused tokens of the user at t.
+func (d *DB) ExpireEmailVerifications(ctx context.Context, e sq.ExecerContext, userUUID uuid.UUID, t time.Time) error {
+	b := sq.Update("email_verification_token").SetMap(map[string]interface{}{
+		"expires_at": t,
+	}).Where(sq.And{
+		sq.Eq{"user_uuid": userUUID},
+		sq.Eq{"activated": false},
+		sq.Gt{"expires_at": t},
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+// DeleteExpiredEmailVerifications deletes the unused tokens that expired
+// by t. Activated tokens are kept as a record of the verified addresses.
+func (d *DB) DeleteExpiredEmailVerifications(ctx context.Context, e sq.ExecerContext, t time.Time) error {
+	b := sq.Delete("email_verification_token").Where(sq.And{
+		sq.Eq{"activated": false},
+		sq.LtOrEq{"expires_at": t},
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) DeleteEmailVerifications(ctx context.Context, e sq.ExecerContext, userUUID uuid.UUID) error {
+	b := sq.Delete("email_verification_token").Where(sq.Eq{"user_uuid": userUUID})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) InsertPasswordReset(ctx context.Context, e sq.ExecerContext, pr PasswordReset) error {
+	b := sq.Insert("password_reset_token").SetMap(map[string]interface{}{
+		"token":      pr.Token,
+		"user_uuid":  pr.UserUUID,
+		"used":       pr.Used,
+		"expires_at": pr.ExpiresAt,
+		"created_at": pr.CreatedAt,
+	})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+// UsePasswordReset marks the token used unless it already was, so that
+// concurrent resets cannot both use it.
+func (d *DB) UsePasswordReset(ctx context.Context, e sq.ExecerContext, token string) (bool, error) {
+	b := sq.Update("password_reset_token").
+		Set("used", true).
+		Where(sq.Eq{"token": token, "used": false})
+
+	res, err := sq.ExecContextWith(ctx, e, b)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (d *DB) DeletePasswordResets(ctx context.Context, e sq.ExecerContext, userUUID uuid.UUID) error {
+	b := sq.Delete("password_reset_token").Where(sq.Eq{"user_uuid": userUUID})
+
+	_, err := sq.ExecContextWith(ctx, e, b)
+	return err
+}
+
+func (d *DB) FetchPasswordReset(ctx context.Context, q sq.QueryerContext, token string) (PasswordReset, bool, error) {
+	b := sq.Select()
+
+	b = passwordResetQuery(b, "pwreset").From("password_reset_token AS pwreset").Where(sq.Eq{"pwreset.token": token})
+
+	qr, args := b.MustSql()
+
+	var pr PasswordReset
+
+	err := d.d.GetContext(ctx, &pr, qr, args...)
+	switch err {
+	case nil:
+		return pr, true, nil
+	case sql.ErrNoRows:
+		return PasswordReset{}, false, nil
+	default:
+		return PasswordReset{}, false, err
+	}
+}
+
 func (d *DB) InsertAdminLog(ctx context.Context, e sq.ExecerContext, lg AdminLog) error {
 	b := sq.Insert("admin_log").SetMap(map[string]interface{}{
 		"uuid":       lg.UUID,
@@ -359,6 +577,10 @@ func betUserQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
 	return b.Columns(
 		column(prefix, "identity_verified"),
 		column(prefix, "balance"),
+		column(prefix, "currency"),
+		column(prefix, "bonus_balance"),
+		column(prefix, "exclusion_kind"),
+		column(prefix, "excluded_until"),
 	)
 }
 
@@ -372,6 +594,19 @@ func emailVerificationQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder
 	return b.Columns(
 		column(prefix, "token"),
 		column(prefix, "user_uuid"),
+		column(prefix, "email"),
 		column(prefix, "activated"),
+		column(prefix, "expires_at"),
+		column(prefix, "created_at"),
+	)
+}
+
+func passwordResetQuery(b sq.SelectBuilder, prefix string) sq.SelectBuilder {
+	return b.Columns(
+		column(prefix, "token"),
+		column(prefix, "user_uuid"),
+		column(prefix, "used"),
+		column(prefix, "expires_at"),
+		column(prefix, "created_at"),
 	)
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"time"
@@ -8,9 +9,9 @@ import (
 	"github.com/ramasauskas/ispbet/autobet"
 	"github.com/ramasauskas/ispbet/autoreport"
 	"github.com/ramasauskas/ispbet/db"
+	"github.com/ramasauskas/ispbet/purse"
 	"github.com/ramasauskas/ispbet/server"
 	"github.com/rs/zerolog"
-	"github.com/swithek/sessionup/memstore"
 )
 
 func main() {
@@ -18,6 +19,12 @@ func main() {
 
 	mainLog := log.With().Str("goroutine", "main").Logger()
 
+	cfg, err := loadConfig()
+	if err != nil {
+		mainLog.Fatal().Err(err).Msg("cannot load config")
+		return
+	}
+
 	dbLog := log.With().Str("goroutine", "db").Logger()
 	database, err := db.NewDB("test.sql", dbLog)
 	if err != nil {
@@ -25,12 +32,18 @@ func main() {
 		return
 	}
 
-	sessionStore := memstore.New(time.Minute)
+	if err = database.BackfillCurrency(context.Background(), string(cfg.BaseCurrency)); err != nil {
+		mainLog.Fatal().Err(err).Msg("cannot backfill currencies")
+		return
+	}
+
+	sessionStore := newSessionStore(database, time.Minute, log.With().Str("goroutine", "sessions").Logger())
 
 	mainLog.Info().Msg("started session store")
 
 	dbAdapter := &serverDBAdapter{
-		db: database,
+		db:           database,
+		baseCurrency: cfg.BaseCurrency,
 	}
 
 	betDBAdapter := &betDBAdapter{
@@ -38,7 +51,8 @@ func main() {
 	}
 
 	better := &better{
-		db: betDBAdapter,
+		db:            betDBAdapter,
+		cashOutMargin: cfg.CashOutMargin,
 	}
 
 	betSrv := serverBetAdapter{
@@ -46,7 +60,8 @@ func main() {
 	}
 
 	reportDB := &reportDB{
-		db: database,
+		db:           database,
+		baseCurrency: cfg.BaseCurrency,
 	}
 
 	dummyEm := &dummyEmail{
@@ -68,6 +83,22 @@ func main() {
 
 	mainLog.Info().Msg("started auto odds worker")
 
+	reconcileDB := &reconcileDB{
+		db: database,
+	}
+
+	reconciler := newReconciler(reconcileDB, dummyEm, cfg.ReconciliationEmail, cfg.ReconciliationInterval, log.With().Str("goroutine", "reconciliation").Logger())
+
+	go reconciler.work()
+
+	mainLog.Info().Msg("started reconciliation worker")
+
+	tokenCleaner := newTokenCleaner(database, time.Hour, log.With().Str("goroutine", "token_cleanup").Logger())
+
+	go tokenCleaner.work()
+
+	mainLog.Info().Msg("started token cleanup worker")
+
 	autoWorker := autobet.NewWorker(autoBetDB, autoBetDB, log.With().Str("goroutine", "autobet").Logger())
 
 	go autoWorker.Work()
@@ -81,8 +112,22 @@ func main() {
 
 	mainLog.Info().Msg("started report worked")
 
+	simPayments := purse.NewSimulatedProvider(
+		cfg.SimulatedPaymentSecret,
+		cfg.SimulatedPaymentCallbackURL,
+		cfg.SimulatedPaymentDelay,
+		log.With().Str("goroutine", "simulated_payments").Logger(),
+	)
+
 	srvLog := log.With().Str("goroutine", "server").Logger()
-	srv := server.NewServer(8080, sessionStore, &betSrv, &betSrv, dummyEm, dbAdapter, srvLog)
+	srvCfg := server.Config{
+		FourEyesWithdrawalAmount: cfg.FourEyesWithdrawalAmount,
+		BaseCurrency:             cfg.BaseCurrency,
+		AdminTwoFactorRequired:   cfg.AdminTwoFactorRequired,
+		GamblingLimitCoolingOff:  cfg.GamblingLimitCoolingOff,
+	}
+
+	srv := server.NewServer(8080, srvCfg, sessionStore, &betSrv, &betSrv, dummyEm, []purse.PaymentProvider{simPayments}, dbAdapter, srvLog)
 
 	doneCh := make(chan struct{}, 1)
 	interCh := make(chan os.Signal, 1)
@@ -107,6 +152,10 @@ func main() {
 		mainLog.Error().Err(err).Msg("cannot close server")
 	}
 
+	simPayments.Close()
+
+	mainLog.Info().Msg("stopped simulated payment provider")
+
 	sessionStore.StopCleanup()
 
 	mainLog.Info().Msg("stopped session store")
@@ -123,5 +172,13 @@ func main() {
 
 	mainLog.Info().Msg("stopped auto odds worker")
 
+	reconciler.stop()
+
+	mainLog.Info().Msg("stopped reconciliation worker")
+
+	tokenCleaner.stop()
+
+	mainLog.Info().Msg("stopped token cleanup worker")
+
 	mainLog.Info().Msg("application gracefully closed")
 }
@@ -0,0 +1,59 @@
+package purse
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Currency is an ISO 4217 currency code.
+type Currency string
+
+func (c Currency) Validate() error {
+	if len(c) != 3 {
+		return errors.New("currency must be a three letter code")
+	}
+
+	for _, r := range c {
+		if r < 'A' || r > 'Z' {
+			return errors.New("currency must be a three letter code")
+		}
+	}
+
+	return nil
+}
+
+// ExchangeRate is the value of one unit of the currency in the base
+// currency, in effect from ValidFrom until the next rate of the currency.
+// Rates are never changed, a new rate is added instead, so that amounts
+// can be converted at the rate in effect when they were transacted.
+type ExchangeRate struct {
+	UUID      uuid.UUID
+	Currency  Currency
+	Rate      decimal.Decimal
+	ValidFrom time.Time
+	AdminUUID uuid.UUID
+}
+
+func (er ExchangeRate) Validate(base Currency) error {
+	if err := er.Currency.Validate(); err != nil {
+		return err
+	}
+
+	if er.Currency == base {
+		return errors.New("base currency rate cannot be changed")
+	}
+
+	if !er.Rate.IsPositive() {
+		return errors.New("exchange rate must be positive")
+	}
+
+	return nil
+}
+
+// Convert returns the amount in the base currency.
+func (er ExchangeRate) Convert(amount decimal.Decimal) decimal.Decimal {
+	return amount.Mul(er.Rate)
+}
@@ -0,0 +1,215 @@
+package purse
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Account is a ledger account money is moved between. Every bet user has
+// a wallet account, the house account holds stakes and funds payouts and
+// the cash account stands for money outside of the system. Bonus funds
+// are given out of the promotions account.
+type Account string
+
+const (
+	AccountHouse      Account = "house"
+	AccountCash       Account = "cash"
+	AccountOpening    Account = "opening"
+	AccountPromotions Account = "promotions"
+)
+
+// UserAccount returns the wallet account of the bet user.
+func UserAccount(id uuid.UUID) Account {
+	return Account("user:" + id.String())
+}
+
+// ReservedAccount returns the account holding the bet user's funds
+// reserved for requested withdrawals.
+func ReservedAccount(id uuid.UUID) Account {
+	return Account("reserved:" + id.String())
+}
+
+// BonusAccount returns the account holding the bet user's bonus funds
+// until they are released to the wallet.
+func BonusAccount(id uuid.UUID) Account {
+	return Account("bonus:" + id.String())
+}
+
+type EntryType string
+
+const (
+	EntryTypeOpening      EntryType = "opening"
+	EntryTypeDeposit      EntryType = "deposit"
+	EntryTypeWithdrawal   EntryType = "withdrawal"
+	EntryTypeReserve      EntryType = "withdrawal_reserve"
+	EntryTypeRelease      EntryType = "withdrawal_release"
+	EntryTypeStake        EntryType = "stake"
+	EntryTypePayout       EntryType = "payout"
+	EntryTypeRefund       EntryType = "refund"
+	EntryTypeCashOut      EntryType = "cash_out"
+	EntryTypeAdjustment   EntryType = "adjustment"
+	EntryTypeBonusGrant   EntryType = "bonus_grant"
+	EntryTypeBonusRelease EntryType = "bonus_release"
+)
+
+// Entry is an immutable journal entry moving an amount from one account to
+// another, so that every entry is recorded on both sides at once.
+// Reference points to the deposit, withdrawal, bet or adjustment that
+// caused it.
+type Entry struct {
+	UUID      uuid.UUID
+	Type      EntryType
+	From      Account
+	To        Account
+	Amount    decimal.Decimal
+	UserUUID  uuid.UUID
+	Reference uuid.UUID
+	Timestamp time.Time
+}
+
+func (e Entry) Validate() error {
+	if !e.Amount.IsPositive() {
+		return errors.New("entry amount must be positive")
+	}
+
+	if e.From == e.To {
+		return errors.New("entry must move money between different accounts")
+	}
+
+	return nil
+}
+
+func newEntry(typ EntryType, from, to Account, amount decimal.Decimal, userUUID, ref uuid.UUID, ts time.Time) Entry {
+	return Entry{
+		UUID:      uuid.New(),
+		Type:      typ,
+		From:      from,
+		To:        to,
+		Amount:    amount,
+		UserUUID:  userUUID,
+		Reference: ref,
+		Timestamp: ts,
+	}
+}
+
+// DepositEntry credits the confirmed deposit to the user's wallet.
+func DepositEntry(d Deposit) Entry {
+	return newEntry(EntryTypeDeposit, AccountCash, UserAccount(d.UserUUID), d.Amount, d.UserUUID, d.UUID, d.UpdatedAt)
+}
+
+// WithdrawalEntry pays the withdrawal straight out of the user's wallet.
+func WithdrawalEntry(wd Withdrawal) Entry {
+	return newEntry(EntryTypeWithdrawal, UserAccount(wd.UserUUID), AccountCash, wd.Amount, wd.UserUUID, wd.UUID, wd.Timestamp)
+}
+
+// ReserveEntry holds the funds of a requested withdrawal back from the
+// user's wallet.
+func ReserveEntry(wd Withdrawal) Entry {
+	return newEntry(EntryTypeReserve, UserAccount(wd.UserUUID), ReservedAccount(wd.UserUUID), wd.Amount, wd.UserUUID, wd.UUID, wd.Timestamp)
+}
+
+// ReleaseEntry returns the reserved funds of a rejected or cancelled
+// withdrawal to the user's wallet.
+func ReleaseEntry(wd Withdrawal) Entry {
+	return newEntry(EntryTypeRelease, ReservedAccount(wd.UserUUID), UserAccount(wd.UserUUID), wd.Amount, wd.UserUUID, wd.UUID, wd.UpdatedAt)
+}
+
+// PaidEntry pays a requested withdrawal out of the reserved funds.
+func PaidEntry(wd Withdrawal) Entry {
+	return newEntry(EntryTypeWithdrawal, ReservedAccount(wd.UserUUID), AccountCash, wd.Amount, wd.UserUUID, wd.UUID, wd.UpdatedAt)
+}
+
+// PayoutFailedEntry returns the funds of a withdrawal whose payout failed
+// to the user's wallet.
+func PayoutFailedEntry(wd Withdrawal) Entry {
+	return newEntry(EntryTypeRelease, AccountCash, UserAccount(wd.UserUUID), wd.Amount, wd.UserUUID, wd.UUID, wd.UpdatedAt)
+}
+
+// StakeEntry moves the stake of a bet or accumulator to the house.
+func StakeEntry(userUUID, betUUID uuid.UUID, stake decimal.Decimal, ts time.Time) Entry {
+	return newEntry(EntryTypeStake, UserAccount(userUUID), AccountHouse, stake, userUUID, betUUID, ts)
+}
+
+// SettlementEntry pays the user out of the house for a won bet, or refunds
+// the stake of a voided one.
+func SettlementEntry(typ EntryType, userUUID, betUUID uuid.UUID, amount decimal.Decimal, ts time.Time) Entry {
+	return newEntry(typ, AccountHouse, UserAccount(userUUID), amount, userUUID, betUUID, ts)
+}
+
+// AdjustmentEntry records an admin correction, moving money from the
+// user back to the house when the adjustment is negative.
+func AdjustmentEntry(ba BalanceAdjustment) Entry {
+	if ba.Amount.IsNegative() {
+		return newEntry(EntryTypeAdjustment, UserAccount(ba.UserUUID), AccountHouse, ba.Amount.Neg(), ba.UserUUID, ba.UUID, ba.Timestamp)
+	}
+
+	return newEntry(EntryTypeAdjustment, AccountHouse, UserAccount(ba.UserUUID), ba.Amount, ba.UserUUID, ba.UUID, ba.Timestamp)
+}
+
+// BonusEntry gives the user bonus funds out of the promotions account.
+func BonusEntry(userUUID, grantUUID uuid.UUID, amount decimal.Decimal, ts time.Time) Entry {
+	return newEntry(EntryTypeBonusGrant, AccountPromotions, BonusAccount(userUUID), amount, userUUID, grantUUID, ts)
+}
+
+// BonusReleaseEntry moves the bonus funds of the user to the wallet once
+// the wagering requirement of the referenced grant is met.
+func BonusReleaseEntry(userUUID, grantUUID uuid.UUID, amount decimal.Decimal, ts time.Time) Entry {
+	return newEntry(EntryTypeBonusRelease, BonusAccount(userUUID), UserAccount(userUUID), amount, userUUID, grantUUID, ts)
+}
+
+// Balance derives the balance of the account from the journal entries.
+func Balance(acc Account, ee []Entry) decimal.Decimal {
+	bal := decimal.Zero
+
+	for _, e := range ee {
+		if e.To == acc {
+			bal = bal.Add(e.Amount)
+		}
+
+		if e.From == acc {
+			bal = bal.Sub(e.Amount)
+		}
+	}
+
+	return bal
+}
+
+// StatementLine is a journal entry seen from an account, with the amount
+// signed by direction and the balance of the account after it.
+type StatementLine struct {
+	Entry   Entry
+	Amount  decimal.Decimal
+	Balance decimal.Decimal
+}
+
+// Statement lists the entries of the account in the given order with a
+// running balance.
+func Statement(acc Account, ee []Entry) []StatementLine {
+	lines := make([]StatementLine, 0, len(ee))
+	bal := decimal.Zero
+
+	for _, e := range ee {
+		amount := decimal.Zero
+
+		if e.To == acc {
+			amount = amount.Add(e.Amount)
+		}
+
+		if e.From == acc {
+			amount = amount.Sub(e.Amount)
+		}
+
+		bal = bal.Add(amount)
+
+		lines = append(lines, StatementLine{
+			Entry:   e,
+			Amount:  amount,
+			Balance: bal,
+		})
+	}
+
+	return lines
+}
@@ -0,0 +1,90 @@
+package purse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidSignature is returned by payment providers for callbacks that
+// were not signed by them.
+var ErrInvalidSignature = errors.New("invalid callback signature")
+
+type PaymentKind string
+
+const (
+	PaymentKindDeposit PaymentKind = "deposit"
+	PaymentKindPayout  PaymentKind = "payout"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentStatusConfirmed PaymentStatus = "confirmed"
+	PaymentStatusFailed    PaymentStatus = "failed"
+)
+
+// PaymentIntent is the provider's answer to an initiated payment.
+type PaymentIntent struct {
+	// Reference identifies the payment in the provider's callbacks.
+	Reference string
+	// RedirectURL is where the user completes the payment, empty if the
+	// provider does not need the user to do anything.
+	RedirectURL string
+}
+
+// PaymentEvent is the outcome of a payment reported by a provider's
+// callback.
+type PaymentEvent struct {
+	Kind      PaymentKind
+	Reference string
+	Status    PaymentStatus
+	Amount    decimal.Decimal
+}
+
+func (e PaymentEvent) Validate() error {
+	switch e.Kind {
+	case PaymentKindDeposit, PaymentKindPayout:
+	default:
+		return errors.New("invalid payment kind")
+	}
+
+	switch e.Status {
+	case PaymentStatusConfirmed, PaymentStatusFailed:
+	default:
+		return errors.New("invalid payment status")
+	}
+
+	if e.Reference == "" {
+		return errors.New("payment reference must be set")
+	}
+
+	if !e.Amount.IsPositive() {
+		return errors.New("payment amount must be positive")
+	}
+
+	return nil
+}
+
+// PaymentProvider is a gateway moving money in and out of the system.
+// Payments are completed asynchronously, the provider reporting their
+// outcome by calling back the server.
+type PaymentProvider interface {
+	// Name identifies the provider in deposits, withdrawals and callback
+	// routes.
+	Name() string
+
+	// InitiateDeposit starts collecting the pending deposit.
+	InitiateDeposit(context.Context, Deposit) (PaymentIntent, error)
+
+	// InitiatePayout starts paying the withdrawal out. Initiating the
+	// payout of the same withdrawal twice must not pay it twice.
+	InitiatePayout(context.Context, Withdrawal) (PaymentIntent, error)
+
+	// HandleCallback verifies the callback sent by the provider and returns
+	// the payment event it reports. ErrInvalidSignature is returned if the
+	// callback was not sent by the provider.
+	HandleCallback(ctx context.Context, header http.Header, body []byte) (PaymentEvent, error)
+}
@@ -1,22 +1,273 @@
 package purse
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
+type DepositStatus string
+
+const (
+	DepositStatusPending   DepositStatus = "pending"
+	DepositStatusConfirmed DepositStatus = "confirmed"
+	DepositStatusFailed    DepositStatus = "failed"
+)
+
+// Deposit moves money into a bet user's wallet. Deposits entered by admins
+// are confirmed at once, while the ones made through a payment provider
+// stay pending until the provider reports their outcome.
 type Deposit struct {
 	UUID      uuid.UUID
 	Amount    decimal.Decimal
 	Timestamp time.Time
 	UserUUID  uuid.UUID
+	Status    DepositStatus
+
+	// Provider is the name of the payment provider handling the deposit
+	// and Reference its identifier of the payment, both empty for the
+	// deposits entered by admins.
+	Provider  string
+	Reference string
+	UpdatedAt time.Time
+	Currency  Currency
+}
+
+func (d *Deposit) Confirm() error {
+	if d.Status != DepositStatusPending {
+		return errors.New("deposit is " + string(d.Status))
+	}
+
+	d.Status = DepositStatusConfirmed
+
+	return nil
+}
+
+func (d *Deposit) Fail() error {
+	if d.Status != DepositStatusPending {
+		return errors.New("deposit is " + string(d.Status))
+	}
+
+	d.Status = DepositStatusFailed
+
+	return nil
+}
+
+// Deposited sums the deposits made from since on, including the pending
+// ones.
+func Deposited(dd []Deposit, since time.Time) decimal.Decimal {
+	sum := decimal.Zero
+
+	for _, d := range dd {
+		if d.Status != DepositStatusFailed && !d.Timestamp.Before(since) {
+			sum = sum.Add(d.Amount)
+		}
+	}
+
+	return sum
 }
 
+type WithdrawalStatus string
+
+const (
+	WithdrawalStatusPending   WithdrawalStatus = "pending"
+	WithdrawalStatusApproved  WithdrawalStatus = "approved"
+	WithdrawalStatusRejected  WithdrawalStatus = "rejected"
+	WithdrawalStatusCancelled WithdrawalStatus = "cancelled"
+	WithdrawalStatusPaid      WithdrawalStatus = "paid"
+	// WithdrawalStatusProcessing is a withdrawal being paid out by its
+	// payment provider, stored before the payout is initiated so that a
+	// payout is never started for a withdrawal that could still change.
+	WithdrawalStatusProcessing WithdrawalStatus = "processing"
+	// WithdrawalStatusFailed is a paid withdrawal whose payout the
+	// provider reported as failed, with the funds returned to the user.
+	WithdrawalStatusFailed WithdrawalStatus = "failed"
+)
+
+func (s WithdrawalStatus) Validate() error {
+	switch s {
+	case WithdrawalStatusPending, WithdrawalStatusApproved, WithdrawalStatusRejected,
+		WithdrawalStatusCancelled, WithdrawalStatusPaid, WithdrawalStatusProcessing, WithdrawalStatusFailed:
+		return nil
+	default:
+		return errors.New("invalid withdrawal status")
+	}
+}
+
+// Withdrawal moves money out of a bet user's wallet. Withdrawals made by
+// admins are paid at once, while the ones requested by users keep the
+// funds reserved until they are paid out or released.
 type Withdrawal struct {
 	UUID      uuid.UUID
 	Amount    decimal.Decimal
 	Timestamp time.Time
 	UserUUID  uuid.UUID
+	Status    WithdrawalStatus
+
+	// ApprovedBy and SecondApprovedBy hold the admins that approved the
+	// withdrawal, the second one only being needed above the four-eyes
+	// amount. ResolvedBy is the admin that rejected, cancelled or paid it.
+	ApprovedBy       uuid.UUID
+	SecondApprovedBy uuid.UUID
+	ResolvedBy       uuid.UUID
+	UpdatedAt        time.Time
+
+	// Provider is the name of the payment provider paying the withdrawal
+	// out and Reference its identifier of the payout, both empty for the
+	// withdrawals paid out by hand.
+	Provider  string
+	Reference string
+	Currency  Currency
+}
+
+// Reserved reports whether the funds of the withdrawal are held back from
+// the user's wallet without being paid out yet.
+func (wd Withdrawal) Reserved() bool {
+	switch wd.Status {
+	case WithdrawalStatusPending, WithdrawalStatusApproved, WithdrawalStatusProcessing:
+		return true
+	default:
+		return false
+	}
+}
+
+// Approve records the approval of the admin. Withdrawals above the
+// four-eyes amount stay pending until a second admin approves them, a zero
+// amount disables the second approval.
+func (wd *Withdrawal) Approve(admin uuid.UUID, fourEyesAmount decimal.Decimal) error {
+	if wd.Status != WithdrawalStatusPending {
+		return errors.New("withdrawal is " + string(wd.Status))
+	}
+
+	if wd.ApprovedBy == admin {
+		return errors.New("withdrawal already approved by this admin")
+	}
+
+	if wd.ApprovedBy == uuid.Nil {
+		wd.ApprovedBy = admin
+
+		if fourEyesAmount.IsPositive() && wd.Amount.GreaterThan(fourEyesAmount) {
+			return nil
+		}
+	} else {
+		wd.SecondApprovedBy = admin
+	}
+
+	wd.Status = WithdrawalStatusApproved
+
+	return nil
+}
+
+// AwaitsSecondApproval reports whether the withdrawal was approved once but
+// still needs another admin to approve it.
+func (wd Withdrawal) AwaitsSecondApproval() bool {
+	return wd.Status == WithdrawalStatusPending && wd.ApprovedBy != uuid.Nil
+}
+
+func (wd *Withdrawal) Reject(admin uuid.UUID) error {
+	if wd.Status != WithdrawalStatusPending {
+		return errors.New("withdrawal is " + string(wd.Status))
+	}
+
+	wd.Status = WithdrawalStatusRejected
+	wd.ResolvedBy = admin
+
+	return nil
+}
+
+// Cancel withdraws the request before it is paid out. Users can only
+// cancel pending requests, admins may also cancel approved ones.
+func (wd *Withdrawal) Cancel(admin uuid.UUID) error {
+	if (wd.Status != WithdrawalStatusPending && wd.Status != WithdrawalStatusApproved) ||
+		(admin == uuid.Nil && wd.Status != WithdrawalStatusPending) {
+		return errors.New("withdrawal is " + string(wd.Status))
+	}
+
+	wd.Status = WithdrawalStatusCancelled
+	wd.ResolvedBy = admin
+
+	return nil
+}
+
+// Pay pays the approved withdrawal out. Withdrawals paid by a payment
+// provider only become processing until the payout is initiated, paying a
+// processing one again retries the initiation.
+func (wd *Withdrawal) Pay(admin uuid.UUID) error {
+	if wd.Status != WithdrawalStatusApproved && (wd.Status != WithdrawalStatusProcessing || wd.Provider == "") {
+		return errors.New("withdrawal is " + string(wd.Status))
+	}
+
+	wd.Status = WithdrawalStatusPaid
+	if wd.Provider != "" {
+		wd.Status = WithdrawalStatusProcessing
+	}
+
+	wd.ResolvedBy = admin
+
+	return nil
+}
+
+// Initiated marks the processing withdrawal paid with the reference of
+// the payout initiated by its provider.
+func (wd *Withdrawal) Initiated(ref string) error {
+	if wd.Status != WithdrawalStatusProcessing {
+		return errors.New("withdrawal is " + string(wd.Status))
+	}
+
+	wd.Status = WithdrawalStatusPaid
+	wd.Reference = ref
+
+	return nil
+}
+
+// Fail marks the payout of the paid withdrawal failed, so that its funds
+// go back to the user.
+func (wd *Withdrawal) Fail() error {
+	if wd.Status != WithdrawalStatusPaid || wd.Provider == "" {
+		return errors.New("withdrawal is " + string(wd.Status))
+	}
+
+	wd.Status = WithdrawalStatusFailed
+
+	return nil
+}
+
+type AdjustmentReason string
+
+const (
+	AdjustmentReasonUnsettle AdjustmentReason = "unsettle"
+	AdjustmentReasonResettle AdjustmentReason = "resettle"
+)
+
+// BalanceAdjustment records a correction of a bet user's balance made by
+// an admin. Amount is negative for debits and Balance holds the balance
+// after the change.
+type BalanceAdjustment struct {
+	UUID          uuid.UUID
+	UserUUID      uuid.UUID
+	AdminUUID     uuid.UUID
+	BetUUID       uuid.UUID
+	SelectionUUID uuid.UUID
+	Amount        decimal.Decimal
+	Balance       decimal.Decimal
+	Reason        AdjustmentReason
+	Timestamp     time.Time
+}
+
+// Discrepancy records a bet user's balance that differs from the one
+// derived from the user's deposits, withdrawals, bets and bonuses.
+type Discrepancy struct {
+	UUID       uuid.UUID
+	UserUUID   uuid.UUID
+	Expected   decimal.Decimal
+	Actual     decimal.Decimal
+	Currency   Currency
+	DetectedAt time.Time
+}
+
+// Difference is how much more the user holds than expected.
+func (d Discrepancy) Difference() decimal.Decimal {
+	return d.Actual.Sub(d.Expected)
 }
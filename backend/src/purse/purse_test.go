@@ -0,0 +1,188 @@
+package purse
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestWithdrawalTransitions(t *testing.T) {
+	var (
+		first  = uuid.New()
+		second = uuid.New()
+	)
+
+	type action struct {
+		name  string
+		admin uuid.UUID
+		err   bool
+	}
+
+	tests := map[string]struct {
+		amount   string
+		provider string
+		actions  []action
+		status   WithdrawalStatus
+	}{
+		"approved and paid": {
+			amount: "100",
+			actions: []action{
+				{name: "approve", admin: first},
+				{name: "pay", admin: first},
+			},
+			status: WithdrawalStatusPaid,
+		},
+		"four-eyes approval": {
+			amount: "1000",
+			actions: []action{
+				{name: "approve", admin: first},
+				{name: "pay", admin: first, err: true},
+				{name: "approve", admin: second},
+				{name: "pay", admin: second},
+			},
+			status: WithdrawalStatusPaid,
+		},
+		"four-eyes approval by the same admin": {
+			amount: "1000",
+			actions: []action{
+				{name: "approve", admin: first},
+				{name: "approve", admin: first, err: true},
+			},
+			status: WithdrawalStatusPending,
+		},
+		"rejected": {
+			amount: "100",
+			actions: []action{
+				{name: "reject", admin: first},
+				{name: "approve", admin: second, err: true},
+			},
+			status: WithdrawalStatusRejected,
+		},
+		"approved cannot be rejected": {
+			amount: "100",
+			actions: []action{
+				{name: "approve", admin: first},
+				{name: "reject", admin: second, err: true},
+			},
+			status: WithdrawalStatusApproved,
+		},
+		"cancelled by the user": {
+			amount: "100",
+			actions: []action{
+				{name: "cancel"},
+				{name: "approve", admin: first, err: true},
+			},
+			status: WithdrawalStatusCancelled,
+		},
+		"approved cannot be cancelled by the user": {
+			amount: "100",
+			actions: []action{
+				{name: "approve", admin: first},
+				{name: "cancel", err: true},
+			},
+			status: WithdrawalStatusApproved,
+		},
+		"approved cancelled by an admin": {
+			amount: "100",
+			actions: []action{
+				{name: "approve", admin: first},
+				{name: "cancel", admin: second},
+			},
+			status: WithdrawalStatusCancelled,
+		},
+		"paid cannot be cancelled": {
+			amount: "100",
+			actions: []action{
+				{name: "approve", admin: first},
+				{name: "pay", admin: first},
+				{name: "cancel", admin: first, err: true},
+			},
+			status: WithdrawalStatusPaid,
+		},
+		"paid by a provider": {
+			amount:   "100",
+			provider: "simulated",
+			actions: []action{
+				{name: "approve", admin: first},
+				{name: "pay", admin: first},
+				{name: "initiated"},
+			},
+			status: WithdrawalStatusPaid,
+		},
+		"processing paid again": {
+			amount:   "100",
+			provider: "simulated",
+			actions: []action{
+				{name: "approve", admin: first},
+				{name: "pay", admin: first},
+				{name: "pay", admin: second},
+				{name: "cancel", admin: first, err: true},
+			},
+			status: WithdrawalStatusProcessing,
+		},
+		"paid by hand cannot be initiated": {
+			amount: "100",
+			actions: []action{
+				{name: "approve", admin: first},
+				{name: "pay", admin: first},
+				{name: "initiated", err: true},
+				{name: "pay", admin: first, err: true},
+			},
+			status: WithdrawalStatusPaid,
+		},
+		"pending cannot be paid": {
+			amount: "100",
+			actions: []action{
+				{name: "pay", admin: first, err: true},
+			},
+			status: WithdrawalStatusPending,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			wd := Withdrawal{
+				Amount:   decimal.RequireFromString(test.amount),
+				Status:   WithdrawalStatusPending,
+				Provider: test.provider,
+			}
+
+			for i, a := range test.actions {
+				var err error
+
+				switch a.name {
+				case "approve":
+					err = wd.Approve(a.admin, decimal.NewFromInt(500))
+				case "reject":
+					err = wd.Reject(a.admin)
+				case "cancel":
+					err = wd.Cancel(a.admin)
+				case "pay":
+					err = wd.Pay(a.admin)
+				case "initiated":
+					err = wd.Initiated("ref")
+				}
+
+				if a.err && err == nil {
+					t.Fatalf("action %d (%s): want error, got nil", i, a.name)
+				}
+
+				if !a.err && err != nil {
+					t.Fatalf("action %d (%s): want no error, got %v", i, a.name, err)
+				}
+			}
+
+			if wd.Status != test.status {
+				t.Errorf("want status %s, got %s", test.status, wd.Status)
+			}
+
+			reserved := test.status == WithdrawalStatusPending || test.status == WithdrawalStatusApproved ||
+				test.status == WithdrawalStatusProcessing
+
+			if wd.Reserved() != reserved {
+				t.Errorf("want reserved %t, got %t", reserved, wd.Reserved())
+			}
+		})
+	}
+}
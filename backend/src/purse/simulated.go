@@ -0,0 +1,176 @@
+package purse
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/shopspring/decimal"
+)
+
+// SimulatedSignatureHeader holds the hex encoded HMAC-SHA256 of the
+// simulated provider's callback body.
+const SimulatedSignatureHeader = "X-Simulated-Signature"
+
+type simulatedCallback struct {
+	Kind      PaymentKind     `json:"kind"`
+	Reference string          `json:"reference"`
+	Status    PaymentStatus   `json:"status"`
+	Amount    decimal.Decimal `json:"amount"`
+}
+
+// SimulatedProvider is a local payment provider confirming every payment
+// after a delay by sending a signed callback to the server, so that the
+// whole payment flow can be run offline.
+type SimulatedProvider struct {
+	secret      []byte
+	callbackURL string
+	delay       time.Duration
+	client      *http.Client
+	log         zerolog.Logger
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewSimulatedProvider(secret []byte, callbackURL string, delay time.Duration, log zerolog.Logger) *SimulatedProvider {
+	return &SimulatedProvider{
+		secret:      secret,
+		callbackURL: callbackURL,
+		delay:       delay,
+		client:      &http.Client{Timeout: time.Second * 10},
+		log:         log,
+		stopCh:      make(chan struct{}),
+	}
+}
+
+func (p *SimulatedProvider) Name() string {
+	return "simulated"
+}
+
+// InitiateDeposit derives the reference from the deposit, the same as
+// InitiatePayout does from the withdrawal, so that initiating a payment
+// again does not move the money twice.
+func (p *SimulatedProvider) InitiateDeposit(_ context.Context, d Deposit) (PaymentIntent, error) {
+	ref := "sim_dep_" + d.UUID.String()
+
+	p.notify(simulatedCallback{
+		Kind:      PaymentKindDeposit,
+		Reference: ref,
+		Status:    PaymentStatusConfirmed,
+		Amount:    d.Amount,
+	})
+
+	return PaymentIntent{Reference: ref}, nil
+}
+
+func (p *SimulatedProvider) InitiatePayout(_ context.Context, wd Withdrawal) (PaymentIntent, error) {
+	ref := "sim_pay_" + wd.UUID.String()
+
+	p.notify(simulatedCallback{
+		Kind:      PaymentKindPayout,
+		Reference: ref,
+		Status:    PaymentStatusConfirmed,
+		Amount:    wd.Amount,
+	})
+
+	return PaymentIntent{Reference: ref}, nil
+}
+
+func (p *SimulatedProvider) HandleCallback(_ context.Context, header http.Header, body []byte) (PaymentEvent, error) {
+	sig, err := hex.DecodeString(header.Get(SimulatedSignatureHeader))
+	if err != nil || !hmac.Equal(sig, p.sign(body)) {
+		return PaymentEvent{}, ErrInvalidSignature
+	}
+
+	var cb simulatedCallback
+
+	if err = json.Unmarshal(body, &cb); err != nil {
+		return PaymentEvent{}, err
+	}
+
+	ev := PaymentEvent{
+		Kind:      cb.Kind,
+		Reference: cb.Reference,
+		Status:    cb.Status,
+		Amount:    cb.Amount,
+	}
+
+	if err = ev.Validate(); err != nil {
+		return PaymentEvent{}, err
+	}
+
+	return ev, nil
+}
+
+// Sign returns the signature header value of the callback body, letting
+// callbacks with other outcomes be sent by hand.
+func (p *SimulatedProvider) Sign(body []byte) string {
+	return hex.EncodeToString(p.sign(body))
+}
+
+// Close stops sending callbacks, dropping the ones not sent yet.
+func (p *SimulatedProvider) Close() {
+	close(p.stopCh)
+	p.wg.Wait()
+}
+
+func (p *SimulatedProvider) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(body)
+
+	return mac.Sum(nil)
+}
+
+func (p *SimulatedProvider) notify(cb simulatedCallback) {
+	p.wg.Add(1)
+
+	go func() {
+		defer p.wg.Done()
+
+		select {
+		case <-p.stopCh:
+			return
+		case <-time.After(p.delay):
+		}
+
+		log := p.log.With().Str("reference", cb.Reference).Logger()
+
+		body, err := json.Marshal(cb)
+		if err != nil {
+			log.Error().Err(err).Msg("cannot encode callback")
+			return
+		}
+
+		req, err := http.NewRequest(http.MethodPost, p.callbackURL, bytes.NewReader(body))
+		if err != nil {
+			log.Error().Err(err).Msg("cannot create callback request")
+			return
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(SimulatedSignatureHeader, p.Sign(body))
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			log.Error().Err(err).Msg("cannot send callback")
+			return
+		}
+
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			log.Error().Int("status", resp.StatusCode).Msg("callback was not accepted")
+			return
+		}
+
+		log.Info().Msg("sent callback")
+	}()
+}
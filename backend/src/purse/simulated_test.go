@@ -0,0 +1,239 @@
+package purse
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/shopspring/decimal"
+)
+
+func TestSimulatedProviderHandleCallback(t *testing.T) {
+	p := NewSimulatedProvider([]byte("secret"), "", 0, zerolog.Nop())
+	other := NewSimulatedProvider([]byte("other"), "", 0, zerolog.Nop())
+
+	const body = `{"kind":"payout","reference":"sim_pay_1","status":"failed","amount":"10"}`
+
+	tests := map[string]struct {
+		body      string
+		signature string
+		event     PaymentEvent
+		err       error
+	}{
+		"signed callback": {
+			body:      body,
+			signature: p.Sign([]byte(body)),
+			event: PaymentEvent{
+				Kind:      PaymentKindPayout,
+				Reference: "sim_pay_1",
+				Status:    PaymentStatusFailed,
+				Amount:    decimal.NewFromInt(10),
+			},
+		},
+		"missing signature": {
+			body: body,
+			err:  ErrInvalidSignature,
+		},
+		"malformed signature": {
+			body:      body,
+			signature: "not hex",
+			err:       ErrInvalidSignature,
+		},
+		"signed with another secret": {
+			body:      body,
+			signature: other.Sign([]byte(body)),
+			err:       ErrInvalidSignature,
+		},
+		"body changed after signing": {
+			body:      `{"kind":"payout","reference":"sim_pay_1","status":"confirmed","amount":"10"}`,
+			signature: p.Sign([]byte(body)),
+			err:       ErrInvalidSignature,
+		},
+		"invalid status": {
+			body:      `{"kind":"payout","reference":"sim_pay_1","status":"lost","amount":"10"}`,
+			signature: p.Sign([]byte(`{"kind":"payout","reference":"sim_pay_1","status":"lost","amount":"10"}`)),
+			err:       errors.New("invalid payment status"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			header := http.Header{}
+			if test.signature != "" {
+				header.Set(SimulatedSignatureHeader, test.signature)
+			}
+
+			ev, err := p.HandleCallback(context.Background(), header, []byte(test.body))
+			if test.err != nil {
+				if err == nil || err.Error() != test.err.Error() {
+					t.Fatalf("want error %v, got %v", test.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+
+			if ev.Kind != test.event.Kind || ev.Reference != test.event.Reference || ev.Status != test.event.Status || !ev.Amount.Equal(test.event.Amount) {
+				t.Errorf("want event %+v, got %+v", test.event, ev)
+			}
+		})
+	}
+}
+
+func TestSimulatedProviderCallbacks(t *testing.T) {
+	id := uuid.New()
+
+	tests := map[string]struct {
+		initiate  func(*SimulatedProvider) (PaymentIntent, error)
+		kind      PaymentKind
+		reference string
+	}{
+		"deposit": {
+			initiate: func(p *SimulatedProvider) (PaymentIntent, error) {
+				return p.InitiateDeposit(context.Background(), Deposit{UUID: id, Amount: decimal.NewFromInt(10)})
+			},
+			kind:      PaymentKindDeposit,
+			reference: "sim_dep_" + id.String(),
+		},
+		"payout": {
+			initiate: func(p *SimulatedProvider) (PaymentIntent, error) {
+				return p.InitiatePayout(context.Background(), Withdrawal{UUID: id, Amount: decimal.NewFromInt(10)})
+			},
+			kind:      PaymentKindPayout,
+			reference: "sim_pay_" + id.String(),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var p *SimulatedProvider
+
+			events := make(chan PaymentEvent, 1)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Errorf("cannot read callback: %v", err)
+					return
+				}
+
+				ev, err := p.HandleCallback(r.Context(), r.Header, body)
+				if err != nil {
+					t.Errorf("want callback accepted, got %v", err)
+					w.WriteHeader(http.StatusBadRequest)
+
+					return
+				}
+
+				events <- ev
+			}))
+			defer srv.Close()
+
+			p = NewSimulatedProvider([]byte("secret"), srv.URL, 0, zerolog.Nop())
+			defer p.Close()
+
+			intent, err := test.initiate(p)
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+
+			if intent.Reference != test.reference {
+				t.Errorf("want reference %s, got %s", test.reference, intent.Reference)
+			}
+
+			select {
+			case ev := <-events:
+				if ev.Kind != test.kind || ev.Reference != test.reference || ev.Status != PaymentStatusConfirmed || !ev.Amount.Equal(decimal.NewFromInt(10)) {
+					t.Errorf("want confirmed %s callback for %s, got %+v", test.kind, test.reference, ev)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no callback received")
+			}
+		})
+	}
+}
+
+func TestDepositTransitions(t *testing.T) {
+	tests := map[string]struct {
+		status  DepositStatus
+		confirm bool
+		want    DepositStatus
+		err     bool
+	}{
+		"pending confirmed":     {status: DepositStatusPending, confirm: true, want: DepositStatusConfirmed},
+		"pending failed":        {status: DepositStatusPending, want: DepositStatusFailed},
+		"confirmed again":       {status: DepositStatusConfirmed, confirm: true, want: DepositStatusConfirmed, err: true},
+		"confirmed then failed": {status: DepositStatusConfirmed, want: DepositStatusConfirmed, err: true},
+		"failed then confirmed": {status: DepositStatusFailed, confirm: true, want: DepositStatusFailed, err: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := Deposit{Status: test.status}
+
+			var err error
+			if test.confirm {
+				err = d.Confirm()
+			} else {
+				err = d.Fail()
+			}
+
+			if test.err != (err != nil) {
+				t.Fatalf("want error %t, got %v", test.err, err)
+			}
+
+			if d.Status != test.want {
+				t.Errorf("want status %s, got %s", test.want, d.Status)
+			}
+		})
+	}
+}
+
+func TestWithdrawalFail(t *testing.T) {
+	tests := map[string]struct {
+		status   WithdrawalStatus
+		provider string
+		err      bool
+	}{
+		"paid by a provider": {status: WithdrawalStatusPaid, provider: "simulated"},
+		"paid by hand":       {status: WithdrawalStatusPaid, err: true},
+		"approved":           {status: WithdrawalStatusApproved, provider: "simulated", err: true},
+		"already failed":     {status: WithdrawalStatusFailed, provider: "simulated", err: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			wd := Withdrawal{Status: test.status, Provider: test.provider}
+
+			err := wd.Fail()
+			if test.err {
+				if err == nil {
+					t.Fatal("want error, got nil")
+				}
+
+				if wd.Status != test.status {
+					t.Errorf("want status %s kept, got %s", test.status, wd.Status)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+
+			if wd.Status != WithdrawalStatusFailed {
+				t.Errorf("want status %s, got %s", WithdrawalStatusFailed, wd.Status)
+			}
+		})
+	}
+}
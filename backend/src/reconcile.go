@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ramasauskas/ispbet/bet"
+	"github.com/ramasauskas/ispbet/bonus"
+	"github.com/ramasauskas/ispbet/purse"
+	"github.com/ramasauskas/ispbet/user"
+	"github.com/rs/zerolog"
+	"github.com/shopspring/decimal"
+)
+
+// reconciler periodically recomputes the balance of every bet user from
+// the user's history and records the balances that do not match it.
+type reconciler struct {
+	doneCh   chan struct{}
+	db       ReconcileDB
+	sender   EmailSender
+	sendTo   string
+	interval time.Duration
+	log      zerolog.Logger
+}
+
+func newReconciler(db ReconcileDB, sender EmailSender, sendTo string, interval time.Duration, log zerolog.Logger) *reconciler {
+	return &reconciler{
+		doneCh:   make(chan struct{}, 1),
+		db:       db,
+		sender:   sender,
+		sendTo:   sendTo,
+		interval: interval,
+		log:      log,
+	}
+}
+
+func (r *reconciler) work() {
+	tick := time.NewTicker(r.interval)
+
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-r.doneCh:
+			return
+		case <-tick.C:
+			if err := r.reconcile(context.Background()); err != nil {
+				r.log.Error().Err(err).Msg("cannot reconcile balances")
+			}
+		}
+	}
+}
+
+func (r *reconciler) stop() {
+	close(r.doneCh)
+}
+
+func (r *reconciler) reconcile(ctx context.Context) error {
+	uu, err := r.db.FetchBetUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	var found []purse.Discrepancy
+
+	for _, u := range uu {
+		d, ok, err := r.check(ctx, u)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			continue
+		}
+
+		last, ok, err := r.db.FetchLatestDiscrepancy(ctx, u.UUID)
+		if err != nil {
+			return err
+		}
+
+		// the same discrepancy is reported once, not on every run
+		if ok && last.Expected.Equal(d.Expected) && last.Actual.Equal(d.Actual) {
+			continue
+		}
+
+		if err := r.db.InsertDiscrepancy(ctx, d); err != nil {
+			return err
+		}
+
+		r.log.Warn().
+			Str("user", u.UUID.String()).
+			Str("expected", d.Expected.String()).
+			Str("actual", d.Actual.String()).
+			Msg("balance discrepancy")
+
+		found = append(found, d)
+	}
+
+	r.log.Info().Int("users", len(uu)).Int("discrepancies", len(found)).Msg("reconciled balances")
+
+	if len(found) == 0 || r.sendTo == "" {
+		return nil
+	}
+
+	msg := fmt.Sprintf("Balance discrepancies: %d", len(found))
+	for _, d := range found {
+		msg += fmt.Sprintf("\n %s: expected %s, actual %s %s", d.UserUUID, d.Expected, d.Actual, d.Currency)
+	}
+
+	return r.sender.SendEmail(ctx, r.sendTo, msg)
+}
+
+// check compares the balance of the user with the one derived from the
+// user's history. The history is not read in a single transaction, so a
+// mismatch is checked again and dropped if the balance moved in between;
+// such a user is left for the next run.
+func (r *reconciler) check(ctx context.Context, u user.BetUser) (purse.Discrepancy, bool, error) {
+	expected, err := r.expected(ctx, u.UUID)
+	if err != nil {
+		return purse.Discrepancy{}, false, err
+	}
+
+	actual := u.Balance.Add(u.BonusBalance)
+
+	if expected.Equal(actual) {
+		return purse.Discrepancy{}, false, nil
+	}
+
+	again, ok, err := r.db.FetchBetUserByUUID(ctx, u.UUID)
+	if err != nil || !ok {
+		return purse.Discrepancy{}, false, err
+	}
+
+	if !again.Balance.Add(again.BonusBalance).Equal(actual) {
+		return purse.Discrepancy{}, false, nil
+	}
+
+	if expected, err = r.expected(ctx, u.UUID); err != nil {
+		return purse.Discrepancy{}, false, err
+	}
+
+	if expected.Equal(actual) {
+		return purse.Discrepancy{}, false, nil
+	}
+
+	return purse.Discrepancy{
+		UUID:       uuid.New(),
+		UserUUID:   u.UUID,
+		Expected:   expected,
+		Actual:     actual,
+		Currency:   u.Currency,
+		DetectedAt: time.Now(),
+	}, true, nil
+}
+
+func (r *reconciler) expected(ctx context.Context, id uuid.UUID) (decimal.Decimal, error) {
+	h, err := r.db.FetchBalanceHistory(ctx, id)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	return h.Balance(), nil
+}
+
+// balanceHistory holds everything that moved money in or out of the bet
+// user's wallet and bonus balance.
+type balanceHistory struct {
+	// Opening is the balance carried over from before the ledger existed.
+	Opening      decimal.Decimal
+	Deposits     []purse.Deposit
+	Withdrawals  []purse.Withdrawal
+	Bets         []bet.Bet
+	Accumulators []bet.Accumulator
+	Grants       []bonus.Grant
+}
+
+// Balance derives the wallet and bonus balance of the user. Adjustments
+// made by resettling are left out as the bets already hold their current
+// outcome, and released bonuses only move funds between the two balances.
+func (h balanceHistory) Balance() decimal.Decimal {
+	bal := h.Opening
+
+	for _, d := range h.Deposits {
+		if d.Status == purse.DepositStatusConfirmed {
+			bal = bal.Add(d.Amount)
+		}
+	}
+
+	for _, wd := range h.Withdrawals {
+		switch wd.Status {
+		case purse.WithdrawalStatusRejected, purse.WithdrawalStatusCancelled, purse.WithdrawalStatusFailed:
+		default:
+			bal = bal.Sub(wd.Amount)
+		}
+	}
+
+	for _, b := range h.Bets {
+		if !b.FreeBet {
+			bal = bal.Sub(b.Stake)
+		}
+
+		if b.State == bet.BetStateCashedOut {
+			bal = bal.Add(b.CashOutAmount)
+			continue
+		}
+
+		bal = bal.Add(b.Payout())
+	}
+
+	for _, acc := range h.Accumulators {
+		bal = bal.Sub(acc.Stake).Add(acc.Payout())
+	}
+
+	for _, g := range h.Grants {
+		bal = bal.Add(g.Amount)
+	}
+
+	return bal
+}
+
+type ReconcileDB interface {
+	FetchBetUsers(context.Context) ([]user.BetUser, error)
+	FetchBetUserByUUID(context.Context, uuid.UUID) (user.BetUser, bool, error)
+	FetchBalanceHistory(context.Context, uuid.UUID) (balanceHistory, error)
+	FetchLatestDiscrepancy(context.Context, uuid.UUID) (purse.Discrepancy, bool, error)
+	InsertDiscrepancy(context.Context, purse.Discrepancy) error
+}
+
+type EmailSender interface {
+	SendEmail(ctx context.Context, to, msg string) error
+}
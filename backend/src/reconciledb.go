@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/ramasauskas/ispbet/db"
+	"github.com/ramasauskas/ispbet/purse"
+	"github.com/ramasauskas/ispbet/user"
+)
+
+type reconcileDB struct {
+	db *db.DB
+}
+
+func (r *reconcileDB) FetchBetUsers(ctx context.Context) ([]user.BetUser, error) {
+	uu, err := r.db.FetchBetUsers(ctx, r.db.NoTX())
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded []user.BetUser
+
+	for _, u := range uu {
+		decoded = append(decoded, decodeBetUser(u))
+	}
+
+	return decoded, nil
+}
+
+func (r *reconcileDB) FetchBetUserByUUID(ctx context.Context, id uuid.UUID) (user.BetUser, bool, error) {
+	u, ok, err := r.db.FetchBetUser(ctx, r.db.NoTX(), db.FetchUserByUUID(id))
+	if err != nil || !ok {
+		return user.BetUser{}, ok, err
+	}
+
+	return decodeBetUser(u), true, nil
+}
+
+func (r *reconcileDB) FetchBalanceHistory(ctx context.Context, id uuid.UUID) (balanceHistory, error) {
+	var h balanceHistory
+
+	ee, err := r.db.FetchJournalEntries(ctx, r.db.NoTX(), db.AccountJournalEntries(string(purse.UserAccount(id))))
+	if err != nil {
+		return balanceHistory{}, err
+	}
+
+	var opening []purse.Entry
+
+	for _, e := range ee {
+		if e.Type == string(purse.EntryTypeOpening) {
+			opening = append(opening, decodeEntry(e))
+		}
+	}
+
+	h.Opening = purse.Balance(purse.UserAccount(id), opening)
+
+	dd, err := r.db.FetchDeposits(ctx, r.db.NoTX(), db.UserDeposits(id))
+	if err != nil {
+		return balanceHistory{}, err
+	}
+
+	for _, d := range dd {
+		h.Deposits = append(h.Deposits, decodeDeposit(d))
+	}
+
+	ww, err := r.db.FetchWithdrawals(ctx, r.db.NoTX(), db.UserWithdrawals(id))
+	if err != nil {
+		return balanceHistory{}, err
+	}
+
+	for _, wd := range ww {
+		h.Withdrawals = append(h.Withdrawals, decodeWithdrawal(wd))
+	}
+
+	bb, err := r.db.FetchBets(ctx, r.db.NoTX(), db.UserBets(id))
+	if err != nil {
+		return balanceHistory{}, err
+	}
+
+	for _, b := range bb {
+		h.Bets = append(h.Bets, decodeBet(b))
+	}
+
+	accs, err := r.db.FetchAccumulators(ctx, r.db.NoTX(), db.UserAccumulators(id))
+	if err != nil {
+		return balanceHistory{}, err
+	}
+
+	for _, acc := range accs {
+		filled, err := fillAccumulator(ctx, r.db, r.db.NoTX(), acc)
+		if err != nil {
+			return balanceHistory{}, err
+		}
+
+		h.Accumulators = append(h.Accumulators, filled)
+	}
+
+	gg, err := r.db.FetchBonusGrants(ctx, r.db.NoTX(), db.UserBonusGrants(id))
+	if err != nil {
+		return balanceHistory{}, err
+	}
+
+	for _, g := range gg {
+		h.Grants = append(h.Grants, decodeBonusGrant(g))
+	}
+
+	return h, nil
+}
+
+func (r *reconcileDB) FetchLatestDiscrepancy(ctx context.Context, id uuid.UUID) (purse.Discrepancy, bool, error) {
+	d, ok, err := r.db.FetchLatestBalanceDiscrepancy(ctx, r.db.NoTX(), id)
+	if err != nil || !ok {
+		return purse.Discrepancy{}, ok, err
+	}
+
+	return decodeDiscrepancy(d), true, nil
+}
+
+func (r *reconcileDB) InsertDiscrepancy(ctx context.Context, d purse.Discrepancy) error {
+	return r.db.InsertBalanceDiscrepancy(ctx, r.db.NoTX(), encodeDiscrepancy(d))
+}
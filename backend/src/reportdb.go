@@ -6,12 +6,13 @@ import (
 
 	"github.com/ramasauskas/ispbet/autoreport"
 	"github.com/ramasauskas/ispbet/db"
+	"github.com/ramasauskas/ispbet/purse"
 	"github.com/ramasauskas/ispbet/report"
-	"github.com/shopspring/decimal"
 )
 
 type reportDB struct {
-	db *db.DB
+	db           *db.DB
+	baseCurrency purse.Currency
 }
 
 func (r *reportDB) FetchAutoReports(ctx context.Context) ([]report.AutoReport, error) {
@@ -33,22 +34,59 @@ func (r *reportDB) FetchAutoReports(ctx context.Context) ([]report.AutoReport, e
 	return rrd, nil
 }
 
-func (r *reportDB) FetchTotalDeposits(ctx context.Context, from, to time.Time) (decimal.Decimal, error) {
-	return r.db.FetchTotalDeposits(ctx, from, to)
+func (r *reportDB) FetchTotalDeposits(ctx context.Context, from, to time.Time) (autoreport.DepositReport, error) {
+	tt, err := r.db.FetchTotalDeposits(ctx, from, to, string(r.baseCurrency))
+	if err != nil {
+		return autoreport.DepositReport{}, err
+	}
+
+	dr := autoreport.DepositReport{
+		Currency: string(r.baseCurrency),
+	}
+
+	for _, t := range tt {
+		if err = missingRatesErr(t.Currency, t.MissingRates); err != nil {
+			return autoreport.DepositReport{}, err
+		}
+
+		dr.Total = dr.Total.Add(t.BaseAmount)
+		dr.ByCurrency = append(dr.ByCurrency, autoreport.CurrencyAmount{
+			Currency: t.Currency,
+			Amount:   t.Amount,
+		})
+	}
+
+	return dr, nil
 }
 
 func (r *reportDB) FetchProfitReport(ctx context.Context, from, to time.Time) (autoreport.ProfitReport, error) {
-	pr, err := r.db.ProfitReport(ctx, db.ProfitOpts{
-		From: from,
-		To:   to,
+	rr, err := r.db.ProfitReport(ctx, db.ProfitOpts{
+		From:         from,
+		To:           to,
+		BaseCurrency: string(r.baseCurrency),
 	})
 	if err != nil {
 		return autoreport.ProfitReport{}, err
 	}
 
-	return autoreport.ProfitReport{
-		Profit: pr.Profit,
-		Loss:   pr.Loss,
-		Final:  pr.Final,
-	}, nil
+	pr := autoreport.ProfitReport{
+		Currency: string(r.baseCurrency),
+	}
+
+	for _, rep := range rr {
+		if err = missingRatesErr(rep.Currency, rep.MissingRates); err != nil {
+			return autoreport.ProfitReport{}, err
+		}
+
+		pr.Profit = pr.Profit.Add(rep.BaseProfit)
+		pr.Loss = pr.Loss.Add(rep.BaseLoss)
+		pr.ByCurrency = append(pr.ByCurrency, autoreport.CurrencyAmount{
+			Currency: rep.Currency,
+			Amount:   rep.Profit.Sub(rep.Loss),
+		})
+	}
+
+	pr.Final = pr.Profit.Sub(pr.Loss)
+
+	return pr, nil
 }
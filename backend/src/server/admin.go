@@ -52,16 +52,83 @@ func adminUserView(u user.AdminUser) adminUser {
 	}
 }
 
+type betEventOutcome struct {
+	Winner bet.Winner      `json:"winner"`
+	Odds   decimal.Decimal `json:"odds"`
+}
+
+type newBetEventSelection struct {
+	Name     string              `json:"name"`
+	AutoOdds bool                `json:"auto_odds"`
+	Type     string              `json:"type"`
+	Period   string              `json:"period"`
+	Line     decimal.NullDecimal `json:"line"`
+	Outcomes []betEventOutcome   `json:"outcomes"`
+	OddsHome decimal.Decimal     `json:"odds_home"`
+	OddsAway decimal.Decimal     `json:"odds_away"`
+	OddsDraw decimal.Decimal     `json:"odds_draw"`
+	HasDraw  bool                `json:"has_draw"`
+}
+
+// outcomes falls back to the home/away/draw odds fields for match winner
+// selections that do not list their outcomes.
+func (ns newBetEventSelection) outcomes() []bet.Outcome {
+	var oo []bet.Outcome
+
+	for _, o := range ns.Outcomes {
+		oo = append(oo, bet.Outcome{
+			Winner: o.Winner,
+			Odds:   o.Odds,
+		})
+	}
+
+	if len(oo) > 0 {
+		return oo
+	}
+
+	oo = []bet.Outcome{
+		{Winner: bet.WinnerHome, Odds: ns.OddsHome},
+		{Winner: bet.WinnerAway, Odds: ns.OddsAway},
+	}
+
+	if ns.HasDraw {
+		oo = append(oo, bet.Outcome{Winner: bet.WinnerDraw, Odds: ns.OddsDraw})
+	}
+
+	return oo
+}
+
+func (ns newBetEventSelection) materialize() bet.EventSelection {
+	typ := bet.MarketType(ns.Type)
+	if typ == "" {
+		typ = bet.MarketTypeMatchWinner
+	}
+
+	period := bet.Period(ns.Period)
+	if period == "" {
+		period = bet.PeriodFullTime
+	}
+
+	return bet.EventSelection{
+		UUID:     uuid.New(),
+		Name:     ns.Name,
+		Type:     typ,
+		Period:   period,
+		Status:   bet.MarketStatusOpen,
+		Line:     ns.Line,
+		Outcomes: ns.outcomes(),
+		AutoOdds: ns.AutoOdds,
+		Winner:   bet.WinnerTBD,
+	}
+}
+
 type updateBetEvent struct {
 	UUID       uuid.UUID `json:"uuid"`
 	Name       string    `json:"name"`
 	Sport      string    `json:"sport"`
 	Selections []struct {
-		UUID     uuid.UUID       `json:"uuid"`
-		Name     string          `json:"name"`
-		AutoOdds bool            `json:"auto_odds"`
-		OddsHome decimal.Decimal `json:"odds_home"`
-		OddsAway decimal.Decimal `json:"odds_away"`
+		UUID uuid.UUID `json:"uuid"`
+		newBetEventSelection
 	} `json:"selections"`
 	BeginsAt time.Time `json:"begins_at"`
 }
@@ -71,15 +138,10 @@ func (ud updateBetEvent) validate() error {
 }
 
 type newBetEvent struct {
-	Name       string `json:"name"`
-	Sport      string `json:"sport"`
-	Selections []struct {
-		Name     string          `json:"name"`
-		AutoOdds bool            `json:"auto_odds"`
-		OddsHome decimal.Decimal `json:"odds_home"`
-		OddsAway decimal.Decimal `json:"odds_away"`
-	} `json:"selections"`
-	AwayTeam struct {
+	Name       string                 `json:"name"`
+	Sport      string                 `json:"sport"`
+	Selections []newBetEventSelection `json:"selections"`
+	AwayTeam   struct {
 		Name    string   `json:"name"`
 		Players []string `json:"players"`
 	} `json:"away_team"`
@@ -95,6 +157,12 @@ func (be newBetEvent) validate() error {
 		return errors.New("no selections provided")
 	}
 
+	for _, s := range be.Selections {
+		if err := s.materialize().Validate(); err != nil {
+			return err
+		}
+	}
+
 	if len(be.AwayTeam.Players) == 0 {
 		return errors.New("away team has no players")
 	}
@@ -124,17 +192,50 @@ type betEvent struct {
 	Selections []betEventSelection `json:"selections"`
 	Sport      string              `json:"sport"`
 	BeginsAt   time.Time           `json:"begins_at"`
+	Status     bet.EventStatus     `json:"status"`
 	Finished   bool                `json:"finished"`
 	HomeTeam   betEventTeam        `json:"home_team"`
 	AwayTeam   betEventTeam        `json:"away_team"`
+	Score      *betEventScore      `json:"score,omitempty"`
+	HalfTime   *betEventScore      `json:"half_time_score,omitempty"`
+}
+
+type betEventScore struct {
+	Home int `json:"home"`
+	Away int `json:"away"`
+}
+
+func betEventScoreView(s *bet.Score) *betEventScore {
+	if s == nil {
+		return nil
+	}
+
+	return &betEventScore{
+		Home: s.Home,
+		Away: s.Away,
+	}
 }
 
 func betEventSelectionView(s bet.EventSelection) betEventSelection {
+	outcomes := make([]betEventOutcome, 0, len(s.Outcomes))
+
+	for _, o := range s.Outcomes {
+		outcomes = append(outcomes, betEventOutcome{
+			Winner: o.Winner,
+			Odds:   o.Odds,
+		})
+	}
+
 	return betEventSelection{
 		UUID:     s.UUID,
 		Name:     s.Name,
-		OddsHome: s.OddsHome,
-		OddsAway: s.OddsAway,
+		Type:     s.Type,
+		Period:   s.Period,
+		Status:   s.Status,
+		Line:     s.Line,
+		Outcomes: outcomes,
+		OddsHome: s.Odds(bet.WinnerHome),
+		OddsAway: s.Odds(bet.WinnerAway),
 		Winner:   s.Winner,
 		AutoOdds: s.AutoOdds,
 	}
@@ -184,9 +285,12 @@ func betEventView(e bet.Event) betEvent {
 		Selections: selections,
 		Sport:      string(e.Sport),
 		BeginsAt:   e.BeginsAt,
+		Status:     e.Status,
 		Finished:   e.Finished(),
 		HomeTeam:   home,
 		AwayTeam:   away,
+		Score:      betEventScoreView(e.FinalScore),
+		HalfTime:   betEventScoreView(e.HalfTimeScore),
 	}
 }
 
@@ -203,12 +307,17 @@ type betEventPlayer struct {
 }
 
 type betEventSelection struct {
-	UUID     uuid.UUID       `json:"uuid"`
-	Name     string          `json:"name"`
-	OddsHome decimal.Decimal `json:"odds_home"`
-	OddsAway decimal.Decimal `json:"odds_away"`
-	AutoOdds bool            `json:"auto_ods"`
-	Winner   bet.Winner      `json:"winner"`
+	UUID     uuid.UUID           `json:"uuid"`
+	Name     string              `json:"name"`
+	Type     bet.MarketType      `json:"type"`
+	Period   bet.Period          `json:"period"`
+	Status   bet.MarketStatus    `json:"status"`
+	Line     decimal.NullDecimal `json:"line"`
+	Outcomes []betEventOutcome   `json:"outcomes"`
+	OddsHome decimal.Decimal     `json:"odds_home"`
+	OddsAway decimal.Decimal     `json:"odds_away"`
+	AutoOdds bool                `json:"auto_ods"`
+	Winner   bet.Winner          `json:"winner"`
 }
 
 type newDeposit struct {
@@ -224,23 +333,54 @@ type newWithdrawal struct {
 type deposit struct {
 	UUID      uuid.UUID       `json:"uuid"`
 	Amount    decimal.Decimal `json:"amount"`
+	Currency  purse.Currency  `json:"currency"`
 	Timestamp time.Time       `json:"timestamp"`
 	UserUUID  uuid.UUID       `json:"user_uuid"`
+	Status    string          `json:"status"`
+	Provider  string          `json:"provider,omitempty"`
+	Reference string          `json:"reference,omitempty"`
+	UpdatedAt time.Time       `json:"updated_at"`
 }
 
 type withdrawal struct {
-	UUID      uuid.UUID       `json:"uuid"`
-	Amount    decimal.Decimal `json:"amount"`
-	Timestamp time.Time       `json:"timestamp"`
-	UserUUID  uuid.UUID       `json:"user_uuid"`
+	UUID                 uuid.UUID       `json:"uuid"`
+	Amount               decimal.Decimal `json:"amount"`
+	Currency             purse.Currency  `json:"currency"`
+	Timestamp            time.Time       `json:"timestamp"`
+	UserUUID             uuid.UUID       `json:"user_uuid"`
+	Status               string          `json:"status"`
+	AwaitsSecondApproval bool            `json:"awaits_second_approval"`
+	ApprovedBy           *uuid.UUID      `json:"approved_by,omitempty"`
+	SecondApprovedBy     *uuid.UUID      `json:"second_approved_by,omitempty"`
+	ResolvedBy           *uuid.UUID      `json:"resolved_by,omitempty"`
+	UpdatedAt            time.Time       `json:"updated_at"`
+	Provider             string          `json:"provider,omitempty"`
+	Reference            string          `json:"reference,omitempty"`
 }
 
 func withdrawalView(w purse.Withdrawal) withdrawal {
+	admin := func(id uuid.UUID) *uuid.UUID {
+		if id == uuid.Nil {
+			return nil
+		}
+
+		return &id
+	}
+
 	return withdrawal{
-		UUID:      w.UUID,
-		Amount:    w.Amount,
-		Timestamp: w.Timestamp,
-		UserUUID:  w.UserUUID,
+		UUID:                 w.UUID,
+		Amount:               w.Amount,
+		Currency:             w.Currency,
+		Timestamp:            w.Timestamp,
+		UserUUID:             w.UserUUID,
+		Status:               string(w.Status),
+		AwaitsSecondApproval: w.AwaitsSecondApproval(),
+		ApprovedBy:           admin(w.ApprovedBy),
+		SecondApprovedBy:     admin(w.SecondApprovedBy),
+		ResolvedBy:           admin(w.ResolvedBy),
+		UpdatedAt:            w.UpdatedAt,
+		Provider:             w.Provider,
+		Reference:            w.Reference,
 	}
 }
 
@@ -248,9 +388,209 @@ func depositView(d purse.Deposit) deposit {
 	return deposit{
 		UUID:      d.UUID,
 		Amount:    d.Amount,
+		Currency:  d.Currency,
 		Timestamp: d.Timestamp,
 		UserUUID:  d.UserUUID,
+		Status:    string(d.Status),
+		Provider:  d.Provider,
+		Reference: d.Reference,
+		UpdatedAt: d.UpdatedAt,
+	}
+}
+
+type betLimits struct {
+	Scope        bet.LimitScope  `json:"scope"`
+	ScopeUUID    uuid.UUID       `json:"scope_uuid"`
+	MinStake     decimal.Decimal `json:"min_stake"`
+	MaxStake     decimal.Decimal `json:"max_stake"`
+	MaxPayout    decimal.Decimal `json:"max_payout"`
+	MaxLiability decimal.Decimal `json:"max_liability"`
+}
+
+func (bl betLimits) materialize() bet.Limits {
+	return bet.Limits{
+		Scope:        bl.Scope,
+		ScopeUUID:    bl.ScopeUUID,
+		MinStake:     bl.MinStake,
+		MaxStake:     bl.MaxStake,
+		MaxPayout:    bl.MaxPayout,
+		MaxLiability: bl.MaxLiability,
+	}
+}
+
+func betLimitsView(l bet.Limits) betLimits {
+	return betLimits{
+		Scope:        l.Scope,
+		ScopeUUID:    l.ScopeUUID,
+		MinStake:     l.MinStake,
+		MaxStake:     l.MaxStake,
+		MaxPayout:    l.MaxPayout,
+		MaxLiability: l.MaxLiability,
+	}
+}
+
+type newEventStatus struct {
+	EventUUID uuid.UUID       `json:"event_uuid"`
+	Status    bet.EventStatus `json:"status"`
+	BeginsAt  *time.Time      `json:"begins_at"`
+}
+
+func (ns newEventStatus) validate() error {
+	if err := ns.Status.Validate(); err != nil {
+		return err
+	}
+
+	if ns.BeginsAt != nil && ns.Status != bet.EventStatusScheduled {
+		return errors.New("begins at can only be changed when rescheduling")
+	}
+
+	if ns.BeginsAt != nil && ns.BeginsAt.Before(time.Now()) {
+		return errors.New("event cannot begin in the past")
+	}
+
+	return nil
+}
+
+type newEventResult struct {
+	EventUUID uuid.UUID      `json:"event_uuid"`
+	Score     betEventScore  `json:"score"`
+	HalfTime  *betEventScore `json:"half_time_score"`
+	DryRun    bool           `json:"dry_run"`
+}
+
+func (nr newEventResult) scores() (bet.Score, *bet.Score) {
+	final := bet.Score{
+		Home: nr.Score.Home,
+		Away: nr.Score.Away,
+	}
+
+	if nr.HalfTime == nil {
+		return final, nil
+	}
+
+	return final, &bet.Score{
+		Home: nr.HalfTime.Home,
+		Away: nr.HalfTime.Away,
+	}
+}
+
+type eventResultPayout struct {
+	BetUUID  uuid.UUID       `json:"bet_uuid"`
+	UserUUID uuid.UUID       `json:"user_uuid"`
+	Stake    decimal.Decimal `json:"stake"`
+	State    bet.BetState    `json:"state"`
+	Amount   decimal.Decimal `json:"amount"`
+}
+
+type eventResult struct {
+	DryRun      bool                `json:"dry_run"`
+	Event       betEvent            `json:"event"`
+	Payouts     []eventResultPayout `json:"payouts"`
+	TotalStake  decimal.Decimal     `json:"total_stake"`
+	TotalPayout decimal.Decimal     `json:"total_payout"`
+}
+
+func eventResultView(st Settlement, dryRun bool) eventResult {
+	res := eventResult{
+		DryRun:      dryRun,
+		Event:       betEventView(st.Event),
+		Payouts:     make([]eventResultPayout, 0, len(st.Payouts)),
+		TotalStake:  decimal.Zero,
+		TotalPayout: decimal.Zero,
+	}
+
+	for _, p := range st.Payouts {
+		res.Payouts = append(res.Payouts, eventResultPayout{
+			BetUUID:  p.BetUUID,
+			UserUUID: p.UserUUID,
+			Stake:    p.Stake,
+			State:    p.State,
+			Amount:   p.Amount,
+		})
+
+		res.TotalStake = res.TotalStake.Add(p.Stake)
+		res.TotalPayout = res.TotalPayout.Add(p.Amount)
+	}
+
+	return res
+}
+
+type newResettlement struct {
+	SelectionUUID        uuid.UUID  `json:"selection_uuid"`
+	Winner               bet.Winner `json:"winner"`
+	AllowNegativeBalance bool       `json:"allow_negative_balance"`
+	DryRun               bool       `json:"dry_run"`
+}
+
+type balanceAdjustment struct {
+	UUID          uuid.UUID       `json:"uuid"`
+	UserUUID      uuid.UUID       `json:"user_uuid"`
+	AdminUUID     uuid.UUID       `json:"admin_uuid"`
+	BetUUID       uuid.UUID       `json:"bet_uuid"`
+	SelectionUUID uuid.UUID       `json:"selection_uuid"`
+	Amount        decimal.Decimal `json:"amount"`
+	Balance       decimal.Decimal `json:"balance"`
+	Reason        string          `json:"reason"`
+	Timestamp     time.Time       `json:"timestamp"`
+}
+
+func balanceAdjustmentView(ba purse.BalanceAdjustment) balanceAdjustment {
+	return balanceAdjustment{
+		UUID:          ba.UUID,
+		UserUUID:      ba.UserUUID,
+		AdminUUID:     ba.AdminUUID,
+		BetUUID:       ba.BetUUID,
+		SelectionUUID: ba.SelectionUUID,
+		Amount:        ba.Amount,
+		Balance:       ba.Balance,
+		Reason:        string(ba.Reason),
+		Timestamp:     ba.Timestamp,
+	}
+}
+
+type discrepancy struct {
+	UUID       uuid.UUID       `json:"uuid"`
+	UserUUID   uuid.UUID       `json:"user_uuid"`
+	Expected   decimal.Decimal `json:"expected"`
+	Actual     decimal.Decimal `json:"actual"`
+	Difference decimal.Decimal `json:"difference"`
+	Currency   purse.Currency  `json:"currency"`
+	DetectedAt time.Time       `json:"detected_at"`
+}
+
+func discrepancyView(d purse.Discrepancy) discrepancy {
+	return discrepancy{
+		UUID:       d.UUID,
+		UserUUID:   d.UserUUID,
+		Expected:   d.Expected,
+		Actual:     d.Actual,
+		Difference: d.Difference(),
+		Currency:   d.Currency,
+		DetectedAt: d.DetectedAt,
+	}
+}
+
+type resettlement struct {
+	DryRun      bool                `json:"dry_run"`
+	Event       betEvent            `json:"event"`
+	Adjustments []balanceAdjustment `json:"adjustments"`
+	Total       decimal.Decimal     `json:"total"`
+}
+
+func resettlementView(st Settlement, dryRun bool) resettlement {
+	res := resettlement{
+		DryRun:      dryRun,
+		Event:       betEventView(st.Event),
+		Adjustments: make([]balanceAdjustment, 0, len(st.Adjustments)),
+		Total:       decimal.Zero,
+	}
+
+	for _, ba := range st.Adjustments {
+		res.Adjustments = append(res.Adjustments, balanceAdjustmentView(ba))
+		res.Total = res.Total.Add(ba.Amount)
 	}
+
+	return res
 }
 
 func (d *newDeposit) validate() error {
@@ -273,11 +613,16 @@ func (s *Server) adminRouter() http.Handler {
 	r := chi.NewRouter()
 
 	r.Post("/login", s.adminLogin)
+	r.Post("/login/two-factor", s.adminLoginTwoFactor)
 
 	r.Group(func(r chi.Router) {
 		r.Use(s.sessions.Auth)
 		r.Get("/bet-users", s.betUsers)
+		r.Get("/bet-users/{uuid}/limit-changes", s.authorizeAdmin(user.RoleUsers, "view-limit-changes", s.betUserGamblingLimitChanges))
+		r.Get("/bet-users/{uuid}/export", s.authorizeAdmin(user.RoleUsers, "export-user-data", s.exportBetUserData))
+		r.Post("/bet-users/{uuid}/erase", s.authorizeAdmin(user.RoleUsers, "erase-user", s.eraseBetUserData))
 		r.Get("/admin-logs", s.adminsLogs)
+		r.Get("/lockouts", s.authorizeAdmin(user.RoleUsers, "view-lockouts", s.lockoutEvents))
 		r.Get("/identity-verifications", s.identityVerifications)
 
 		r.Post("/auto-report", s.createAutoReport)
@@ -285,16 +630,36 @@ func (s *Server) adminRouter() http.Handler {
 		r.Post("/finalize-identity-verification", s.authorizeAdmin(user.RoleUsers, "finalize-identity", s.finalizeIdentityVerification))
 		r.Post("/deposit", s.authorizeAdmin(user.RoleUsers, "deposit", s.createDeposit))
 		r.Post("/withdraw", s.authorizeAdmin(user.RoleUsers, "withdraw", s.createWithdrawal))
+		r.Get("/withdrawals", s.withdrawalQueue)
+		r.Post("/withdrawals/approve", s.authorizeAdmin(user.RoleUsers, "approve-withdrawal", s.reviewWithdrawal(approveWithdrawal)))
+		r.Post("/withdrawals/reject", s.authorizeAdmin(user.RoleUsers, "reject-withdrawal", s.reviewWithdrawal(rejectWithdrawal)))
+		r.Post("/withdrawals/cancel", s.authorizeAdmin(user.RoleUsers, "cancel-withdrawal", s.reviewWithdrawal(cancelWithdrawal)))
+		r.Post("/withdrawals/pay", s.authorizeAdmin(user.RoleUsers, "pay-withdrawal", s.reviewWithdrawal(payWithdrawal)))
 		r.Post("/event", s.authorizeAdmin(user.RoleMatches, "create-event", s.createEvent))
 		r.Put("/event", s.authorizeAdmin(user.RoleMatches, "update-event", s.updateEvent))
 		r.Post("/resolve", s.authorizeAdmin(user.RoleMatches, "resolve-event", s.resolveEventSelection))
+		r.Post("/result", s.authorizeAdmin(user.RoleMatches, "event-result", s.resolveEventResult))
+		r.Post("/resettle", s.authorizeAdmin(user.RoleMatches, "resettle-selection", s.resettleSelection))
+		r.Get("/limits", s.betLimits)
+		r.Put("/limits", s.authorizeAdmin(user.RoleMatches, "update-limits", s.updateBetLimits))
+		r.Delete("/limits", s.authorizeAdmin(user.RoleMatches, "delete-limits", s.deleteBetLimits))
+		r.Post("/event/status", s.authorizeAdmin(user.RoleMatches, "event-status", s.updateEventStatus))
+		r.Post("/selection/status", s.authorizeAdmin(user.RoleMatches, "selection-status", s.updateSelectionStatus))
+
+		r.Get("/exchange-rates", s.exchangeRates)
+		r.Post("/exchange-rates", s.authorizeAdmin(user.RoleSales, "set-exchange-rate", s.setExchangeRate))
+		r.Get("/bonus-campaigns", s.bonusCampaigns)
+		r.Post("/bonus-campaigns", s.authorizeAdmin(user.RoleSales, "create-bonus-campaign", s.createBonusCampaign))
 
 		r.Route("/report", func(r chi.Router) {
 			r.Post("/profit", s.profitReport)
+			r.Post("/deposits", s.depositReport)
 			r.Post("/admins", s.admins)
 			r.Post("/admin-logs/{uuid}", s.adminLogs)
 			r.Post("/user-bets/{uuid}", s.userBets)
 			r.Post("/bets", s.betReport)
+			r.Post("/balance-adjustments", s.balanceAdjustments)
+			r.Post("/discrepancies", s.discrepancyReport)
 		})
 	})
 
@@ -312,6 +677,10 @@ func (s *Server) adminLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.checkLoginThrottle(w, r, input.Email) {
+		return
+	}
+
 	ctx := r.Context()
 	log := s.logger("loginAdmin")
 
@@ -324,23 +693,57 @@ func (s *Server) adminLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !ok {
-		respondErr(w, notFoundErr())
+		dummyUser.Login(input.Password)
+	}
+
+	if !ok || !u.Login(input.Password) {
+		if err = s.loginFailed(ctx, r, input.Email, u.UUID); err != nil {
+			log.Error().Err(err).Msg("cannot record failed login")
+			respondErr(w, internalErr())
+
+			return
+		}
+
+		respondErr(w, invalidLoginErr())
+
 		return
 	}
 
-	if !u.Login(input.Password) {
-		respondErr(w, badRequestErr(errors.New("invalid password")))
+	s.startLogin(w, r, u.User, s.cfg.AdminTwoFactorRequired, adminUserView(u))
+}
+
+// adminLoginTwoFactor finishes the login of the admin with the second
+// factor. The recovery codes are returned when the login enrolled the
+// admin.
+func (s *Server) adminLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	id, codes, ok := s.passLoginChallenge(w, r)
+	if !ok {
 		return
 	}
 
-	if err = s.sessions.Init(w, r, u.UUID.String()); err != nil {
-		log.Error().Err(err).Msg("cannot initialize session")
+	ctx := r.Context()
+	log := s.logger("adminLoginTwoFactor")
+
+	u, ok, err := s.db.FetchAdminUserByUUID(ctx, id)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch admin user")
 		respondErr(w, internalErr())
 
 		return
 	}
 
-	respondJSON(w, http.StatusOK, adminUserView(u))
+	if !ok {
+		respondErr(w, notFoundErr())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, struct {
+		adminUser
+		RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	}{
+		adminUser:     adminUserView(u),
+		RecoveryCodes: codes,
+	})
 }
 
 func (s *Server) identityVerifications(w http.ResponseWriter, r *http.Request) {
@@ -483,11 +886,15 @@ func (s *Server) createDeposit(w http.ResponseWriter, r *http.Request, _ user.Ad
 		return
 	}
 
+	now := time.Now()
+
 	d := purse.Deposit{
 		UUID:      uuid.New(),
 		Amount:    nd.Amount,
-		Timestamp: time.Now(),
+		Timestamp: now,
 		UserUUID:  nd.UserUUID,
+		Status:    purse.DepositStatusConfirmed,
+		UpdatedAt: now,
 	}
 
 	u, ok, err := s.db.FetchBetUserByUUID(ctx, d.UserUUID)
@@ -503,12 +910,24 @@ func (s *Server) createDeposit(w http.ResponseWriter, r *http.Request, _ user.Ad
 		return
 	}
 
+	if u.Excluded(now) {
+		respondErr(w, badRequestErr(errExcluded))
+		return
+	}
+
+	d.Currency = u.Currency
+
 	if err = u.Credit(d.Amount); err != nil {
 		respondErr(w, badRequestErr(err))
 		return
 	}
 
 	if err = s.db.InsertDeposit(ctx, u, d); err != nil {
+		if errors.Is(err, user.ErrGamblingLimitReached) {
+			respondErr(w, gamblingLimitErr(err))
+			return
+		}
+
 		log.Error().Err(err).Msg("cannot insert deposit")
 		respondErr(w, internalErr())
 
@@ -518,7 +937,7 @@ func (s *Server) createDeposit(w http.ResponseWriter, r *http.Request, _ user.Ad
 	respondJSON(w, http.StatusCreated, depositView(d))
 }
 
-func (s *Server) createWithdrawal(w http.ResponseWriter, r *http.Request, _ user.AdminUser) {
+func (s *Server) createWithdrawal(w http.ResponseWriter, r *http.Request, au user.AdminUser) {
 	var nw newWithdrawal
 
 	if err := json.NewDecoder(r.Body).Decode(&nw); err != nil {
@@ -534,11 +953,16 @@ func (s *Server) createWithdrawal(w http.ResponseWriter, r *http.Request, _ user
 		return
 	}
 
+	now := time.Now()
+
 	wd := purse.Withdrawal{
-		UUID:      uuid.New(),
-		Amount:    nw.Amount,
-		Timestamp: time.Now(),
-		UserUUID:  nw.UserUUID,
+		UUID:       uuid.New(),
+		Amount:     nw.Amount,
+		Timestamp:  now,
+		UserUUID:   nw.UserUUID,
+		Status:     purse.WithdrawalStatusPaid,
+		ResolvedBy: au.UUID,
+		UpdatedAt:  now,
 	}
 
 	u, ok, err := s.db.FetchBetUserByUUID(ctx, wd.UserUUID)
@@ -554,6 +978,8 @@ func (s *Server) createWithdrawal(w http.ResponseWriter, r *http.Request, _ user
 		return
 	}
 
+	wd.Currency = u.Currency
+
 	if err = u.Debit(wd.Amount); err != nil {
 		respondErr(w, badRequestErr(err))
 		return
@@ -569,100 +995,110 @@ func (s *Server) createWithdrawal(w http.ResponseWriter, r *http.Request, _ user
 	respondJSON(w, http.StatusCreated, withdrawalView(wd))
 }
 
-func (s *Server) createEvent(w http.ResponseWriter, r *http.Request, _ user.AdminUser) {
-	var newEvent newBetEvent
-
-	if err := json.NewDecoder(r.Body).Decode(&newEvent); err != nil {
-		respondErr(w, badRequestErr(err))
-		return
+// withdrawalQueue lists the withdrawals with the given status, or the
+// ones waiting to be approved or paid out if no status is given.
+func (s *Server) withdrawalQueue(w http.ResponseWriter, r *http.Request) {
+	statuses := []purse.WithdrawalStatus{
+		purse.WithdrawalStatusPending, purse.WithdrawalStatusApproved, purse.WithdrawalStatusProcessing,
 	}
 
-	if err := newEvent.validate(); err != nil {
-		respondErr(w, badRequestErr(err))
-		return
-	}
+	if v := r.URL.Query().Get("status"); v != "" {
+		st := purse.WithdrawalStatus(v)
 
-	ev := bet.Event{
-		UUID:     uuid.New(),
-		Name:     newEvent.Name,
-		Sport:    bet.Sport(newEvent.Sport),
-		BeginsAt: newEvent.BeginsAt,
-	}
+		if err := st.Validate(); err != nil {
+			respondErr(w, badRequestErr(err))
+			return
+		}
 
-	for _, s := range newEvent.Selections {
-		ev.Selections = append(ev.Selections, bet.EventSelection{
-			UUID:     uuid.New(),
-			Name:     s.Name,
-			OddsHome: s.OddsHome,
-			AutoOdds: s.AutoOdds,
-			OddsAway: s.OddsAway,
-			Winner:   bet.WinnerTBD,
-		})
+		statuses = []purse.WithdrawalStatus{st}
 	}
 
-	var (
-		homePlayers []bet.Player
-		awayPlayers []bet.Player
-	)
+	ctx := r.Context()
+	log := s.logger("withdrawalQueue")
 
-	for _, p := range newEvent.HomeTeam.Players {
-		homePlayers = append(homePlayers, bet.Player{
-			UUID: uuid.New(),
-			Name: p,
-		})
-	}
+	ww, err := s.db.FetchWithdrawalsWithStatus(ctx, statuses...)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch withdrawals")
+		respondErr(w, internalErr())
 
-	for _, p := range newEvent.AwayTeam.Players {
-		awayPlayers = append(awayPlayers, bet.Player{
-			UUID: uuid.New(),
-			Name: p,
-		})
+		return
 	}
 
-	ev.AwayTeam = bet.Team{
-		UUID:    uuid.New(),
-		Name:    newEvent.AwayTeam.Name,
-		Players: awayPlayers,
-	}
+	views := make([]withdrawal, 0, len(ww))
 
-	ev.HomeTeam = bet.Team{
-		UUID:    uuid.New(),
-		Name:    newEvent.HomeTeam.Name,
-		Players: homePlayers,
+	for _, wd := range ww {
+		views = append(views, withdrawalView(wd))
 	}
 
-	ctx := r.Context()
-	log := s.logger("createEvent")
+	respondJSON(w, http.StatusOK, views)
+}
 
-	if err := s.db.InsertEvent(ctx, ev); err != nil {
-		log.Error().Err(err).Msg("cannot insert event")
-		respondErr(w, internalErr())
+type withdrawalReview func(wd *purse.Withdrawal, au user.AdminUser, fourEyesAmount decimal.Decimal) error
 
-		return
-	}
+func approveWithdrawal(wd *purse.Withdrawal, au user.AdminUser, fourEyesAmount decimal.Decimal) error {
+	return wd.Approve(au.UUID, fourEyesAmount)
+}
 
-	respondJSON(w, http.StatusOK, betEventView(ev))
+func rejectWithdrawal(wd *purse.Withdrawal, au user.AdminUser, _ decimal.Decimal) error {
+	return wd.Reject(au.UUID)
 }
 
-func (s *Server) updateEvent(w http.ResponseWriter, r *http.Request, _ user.AdminUser) {
-	var updateEvent updateBetEvent
+func cancelWithdrawal(wd *purse.Withdrawal, au user.AdminUser, _ decimal.Decimal) error {
+	return wd.Cancel(au.UUID)
+}
 
-	if err := json.NewDecoder(r.Body).Decode(&updateEvent); err != nil {
-		respondErr(w, badRequestErr(err))
-		return
-	}
+func payWithdrawal(wd *purse.Withdrawal, au user.AdminUser, _ decimal.Decimal) error {
+	return wd.Pay(au.UUID)
+}
 
-	if err := updateEvent.validate(); err != nil {
-		respondErr(w, badRequestErr(err))
-		return
+func (s *Server) reviewWithdrawal(review withdrawalReview) adminHandler {
+	return func(w http.ResponseWriter, r *http.Request, au user.AdminUser) {
+		var input struct {
+			WithdrawalUUID uuid.UUID `json:"withdrawal_uuid"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			respondErr(w, badRequestErr(err))
+			return
+		}
+
+		ctx := r.Context()
+		log := s.logger("reviewWithdrawal")
+
+		wd, ok, err := s.db.FetchWithdrawal(ctx, input.WithdrawalUUID)
+		if err != nil {
+			log.Error().Err(err).Msg("cannot fetch withdrawal")
+			respondErr(w, internalErr())
+
+			return
+		}
+
+		if !ok {
+			respondErr(w, notFoundErr())
+			return
+		}
+
+		from := wd.Status
+
+		if err := review(&wd, au, s.cfg.FourEyesWithdrawalAmount); err != nil {
+			respondErr(w, badRequestErr(err))
+			return
+		}
+
+		s.saveWithdrawal(w, r, wd, from)
 	}
+}
 
+// saveWithdrawal stores the reviewed withdrawal, crediting the reserved
+// funds back to the user if it was rejected or cancelled and initiating
+// the payout with its payment provider if it is being paid by one.
+func (s *Server) saveWithdrawal(w http.ResponseWriter, r *http.Request, wd purse.Withdrawal, from purse.WithdrawalStatus) {
 	ctx := r.Context()
-	log := s.logger("updateEvent")
+	log := s.logger("saveWithdrawal")
 
-	ev, ok, err := s.db.FetchEvent(ctx, updateEvent.UUID)
+	u, ok, err := s.db.FetchBetUserByUUID(ctx, wd.UserUUID)
 	if err != nil {
-		log.Error().Err(err).Msg("cannot fetch event")
+		log.Error().Err(err).Msg("cannot fetch bet user")
 		respondErr(w, internalErr())
 
 		return
@@ -673,40 +1109,442 @@ func (s *Server) updateEvent(w http.ResponseWriter, r *http.Request, _ user.Admi
 		return
 	}
 
-	for i := range ev.Selections {
-		for _, sel := range updateEvent.Selections {
-			if sel.UUID.String() == ev.Selections[i].EventUUID.String() {
-				sell := bet.EventSelection{
-					UUID:      sel.UUID,
-					EventUUID: ev.UUID,
-					Name:      sel.Name,
-					AutoOdds:  sel.AutoOdds,
-					OddsHome:  sel.OddsHome,
-					OddsAway:  sel.OddsAway,
-				}
-
-				if err := s.db.UpdateSelection(ctx, sell); err != nil {
-					log.Error().Err(err).Msg("cannot update selection")
-					respondErr(w, internalErr())
-
-					return
-				}
-
-				ev.Selections[i] = sell
-			}
+	if !wd.Reserved() && wd.Status != purse.WithdrawalStatusPaid {
+		if err := u.Credit(wd.Amount); err != nil {
+			respondErr(w, badRequestErr(err))
+			return
 		}
 	}
 
-	ev.Name = updateEvent.Name
+	if wd.Status == purse.WithdrawalStatusProcessing {
+		ref, ok := s.initiatePayout(w, r, wd, from, u)
+		if !ok {
+			return
+		}
 
-	if err := s.db.UpdateEvent(ctx, ev); err != nil {
-		log.Error().Err(err).Msg("cannot insert event")
-		respondErr(w, internalErr())
+		from = wd.Status
 
-		return
-	}
+		if err := wd.Initiated(ref); err != nil {
+			respondErr(w, badRequestErr(err))
+			return
+		}
+	}
+
+	wd.UpdatedAt = time.Now()
+
+	ok, err = s.db.UpdateWithdrawal(ctx, wd, from, u)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot update withdrawal")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, badRequestErr(errors.New("withdrawal was changed in the meantime, try again")))
+		return
+	}
+
+	respondJSON(w, http.StatusOK, withdrawalView(wd))
+}
+
+// initiatePayout stores the withdrawal as processing before asking its
+// payment provider to pay it out, so that no payout is started for a
+// withdrawal that was changed in the meantime. If the payout cannot be
+// initiated the withdrawal stays processing and paying it again retries,
+// which providers must not pay twice. It responds by itself and returns
+// false if the payout was not initiated.
+func (s *Server) initiatePayout(w http.ResponseWriter, r *http.Request, wd purse.Withdrawal, from purse.WithdrawalStatus, u user.BetUser) (string, bool) {
+	ctx := r.Context()
+	log := s.logger("initiatePayout").With().Str("provider", wd.Provider).Logger()
+
+	p, ok := s.payments[wd.Provider]
+	if !ok {
+		respondErr(w, badRequestErr(errors.New("unknown payment provider")))
+		return "", false
+	}
+
+	wd.UpdatedAt = time.Now()
+
+	ok, err := s.db.UpdateWithdrawal(ctx, wd, from, u)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot update withdrawal")
+		respondErr(w, internalErr())
+
+		return "", false
+	}
+
+	if !ok {
+		respondErr(w, badRequestErr(errors.New("withdrawal was changed in the meantime, try again")))
+		return "", false
+	}
+
+	intent, err := p.InitiatePayout(ctx, wd)
+	if err != nil {
+		log.Error().Err(err).Str("withdrawal_uuid", wd.UUID.String()).Msg("cannot initiate payout")
+		respondErr(w, internalErr())
+
+		return "", false
+	}
+
+	return intent.Reference, true
+}
+
+func (s *Server) createEvent(w http.ResponseWriter, r *http.Request, _ user.AdminUser) {
+	var newEvent newBetEvent
+
+	if err := json.NewDecoder(r.Body).Decode(&newEvent); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if err := newEvent.validate(); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ev := bet.Event{
+		UUID:     uuid.New(),
+		Name:     newEvent.Name,
+		Sport:    bet.Sport(newEvent.Sport),
+		BeginsAt: newEvent.BeginsAt,
+		Status:   bet.EventStatusScheduled,
+	}
+
+	for _, s := range newEvent.Selections {
+		sel := s.materialize()
+		sel.EventUUID = ev.UUID
+
+		ev.Selections = append(ev.Selections, sel)
+	}
+
+	var (
+		homePlayers []bet.Player
+		awayPlayers []bet.Player
+	)
+
+	for _, p := range newEvent.HomeTeam.Players {
+		homePlayers = append(homePlayers, bet.Player{
+			UUID: uuid.New(),
+			Name: p,
+		})
+	}
+
+	for _, p := range newEvent.AwayTeam.Players {
+		awayPlayers = append(awayPlayers, bet.Player{
+			UUID: uuid.New(),
+			Name: p,
+		})
+	}
+
+	ev.AwayTeam = bet.Team{
+		UUID:    uuid.New(),
+		Name:    newEvent.AwayTeam.Name,
+		Players: awayPlayers,
+	}
+
+	ev.HomeTeam = bet.Team{
+		UUID:    uuid.New(),
+		Name:    newEvent.HomeTeam.Name,
+		Players: homePlayers,
+	}
+
+	ctx := r.Context()
+	log := s.logger("createEvent")
+
+	if err := s.db.InsertEvent(ctx, ev); err != nil {
+		log.Error().Err(err).Msg("cannot insert event")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondJSON(w, http.StatusOK, betEventView(ev))
+}
+
+func (s *Server) updateEvent(w http.ResponseWriter, r *http.Request, _ user.AdminUser) {
+	var updateEvent updateBetEvent
+
+	if err := json.NewDecoder(r.Body).Decode(&updateEvent); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if err := updateEvent.validate(); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("updateEvent")
+
+	ev, ok, err := s.db.FetchEvent(ctx, updateEvent.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch event")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, notFoundErr())
+		return
+	}
+
+	for i := range ev.Selections {
+		for _, sel := range updateEvent.Selections {
+			if sel.UUID != ev.Selections[i].UUID {
+				continue
+			}
+
+			sell := ev.Selections[i]
+			sell.Name = sel.Name
+			sell.AutoOdds = sel.AutoOdds
+
+			for _, o := range sel.outcomes() {
+				sell.SetOdds(o.Winner, o.Odds)
+			}
+
+			if err := sell.Validate(); err != nil {
+				respondErr(w, badRequestErr(err))
+				return
+			}
+
+			if err := s.db.UpdateSelection(ctx, sell); err != nil {
+				log.Error().Err(err).Msg("cannot update selection")
+				respondErr(w, internalErr())
+
+				return
+			}
+
+			ev.Selections[i] = sell
+		}
+	}
+
+	ev.Name = updateEvent.Name
+
+	if err := s.db.UpdateEvent(ctx, ev); err != nil {
+		log.Error().Err(err).Msg("cannot insert event")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondJSON(w, http.StatusOK, betEventView(ev))
+}
+
+func (s *Server) betLimits(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := s.logger("betLimits")
+
+	ll, err := s.db.FetchBetLimits(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch bet limits")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	views := make([]betLimits, 0, len(ll))
+
+	for _, l := range ll {
+		views = append(views, betLimitsView(l))
+	}
+
+	respondJSON(w, http.StatusOK, views)
+}
+
+func (s *Server) updateBetLimits(w http.ResponseWriter, r *http.Request, _ user.AdminUser) {
+	var input betLimits
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	limits := input.materialize()
+
+	if err := limits.Validate(); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("updateBetLimits")
+
+	ok, err := s.limitScopeExists(ctx, limits.Scope, limits.ScopeUUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch limit scope")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, notFoundErr())
+		return
+	}
+
+	if err := s.db.UpsertBetLimits(ctx, limits); err != nil {
+		log.Error().Err(err).Msg("cannot update bet limits")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondJSON(w, http.StatusOK, betLimitsView(limits))
+}
+
+func (s *Server) deleteBetLimits(w http.ResponseWriter, r *http.Request, _ user.AdminUser) {
+	var input struct {
+		Scope     bet.LimitScope `json:"scope"`
+		ScopeUUID uuid.UUID      `json:"scope_uuid"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if input.Scope != bet.LimitScopeEvent && input.Scope != bet.LimitScopeSelection {
+		respondErr(w, badRequestErr(errors.New("only event and selection limits can be deleted")))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("deleteBetLimits")
+
+	if err := s.db.DeleteBetLimits(ctx, input.Scope, input.ScopeUUID); err != nil {
+		log.Error().Err(err).Msg("cannot delete bet limits")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondOK(w)
+}
+
+func (s *Server) limitScopeExists(ctx context.Context, scope bet.LimitScope, id uuid.UUID) (bool, error) {
+	switch scope {
+	case bet.LimitScopeEvent:
+		_, ok, err := s.db.FetchEvent(ctx, id)
+		return ok, err
+	case bet.LimitScopeSelection:
+		_, ok, err := s.db.FetchSelection(ctx, id)
+		return ok, err
+	default:
+		return true, nil
+	}
+}
+
+func (s *Server) updateEventStatus(w http.ResponseWriter, r *http.Request, _ user.AdminUser) {
+	var input newEventStatus
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if err := input.validate(); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("updateEventStatus")
+
+	if input.Status == bet.EventStatusCancelled {
+		resp, err := s.resolver.CancelEvent(ctx, input.EventUUID)
+		if err != nil {
+			log.Error().Err(err).Msg("cannot cancel event")
+			respondErr(w, internalErr())
+
+			return
+		}
+
+		if !resp.Ok {
+			respondErr(w, betResponseErr(resp))
+			return
+		}
+	}
+
+	ev, ok, err := s.db.FetchEvent(ctx, input.EventUUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch event")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, notFoundErr())
+		return
+	}
+
+	if input.Status == bet.EventStatusCancelled {
+		respondJSON(w, http.StatusOK, betEventView(ev))
+		return
+	}
+
+	if err := ev.Transition(input.Status); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if input.BeginsAt != nil {
+		ev.BeginsAt = *input.BeginsAt
+	}
+
+	if err := s.db.UpdateEvent(ctx, ev); err != nil {
+		log.Error().Err(err).Msg("cannot update event")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondJSON(w, http.StatusOK, betEventView(ev))
+}
+
+func (s *Server) updateSelectionStatus(w http.ResponseWriter, r *http.Request, _ user.AdminUser) {
+	var input struct {
+		SelectionUUID uuid.UUID        `json:"selection_uuid"`
+		Status        bet.MarketStatus `json:"status"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("updateSelectionStatus")
+
+	sel, ok, err := s.db.FetchSelection(ctx, input.SelectionUUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch event selection")
+		respondErr(w, internalErr())
 
-	respondJSON(w, http.StatusOK, betEventView(ev))
+		return
+	}
+
+	if !ok {
+		respondErr(w, notFoundErr())
+		return
+	}
+
+	if err := sel.Transition(input.Status); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if err := s.db.UpdateSelection(ctx, sel); err != nil {
+		log.Error().Err(err).Msg("cannot update selection")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondJSON(w, http.StatusOK, betEventSelectionView(sel))
 }
 
 func (s *Server) resolveEventSelection(w http.ResponseWriter, r *http.Request, _ user.AdminUser) {
@@ -720,11 +1558,6 @@ func (s *Server) resolveEventSelection(w http.ResponseWriter, r *http.Request, _
 		return
 	}
 
-	if err := input.Winner.Validate(); err != nil {
-		respondErr(w, badRequestErr(err))
-		return
-	}
-
 	if input.Winner == bet.WinnerTBD {
 		respondErr(w, badRequestErr(errors.New("winner cannot be tbd")))
 		return
@@ -747,22 +1580,186 @@ func (s *Server) resolveEventSelection(w http.ResponseWriter, r *http.Request, _
 	}
 
 	if sel.Winner.Finalized() {
-		respondOK(w)
+		respondErr(w, badRequestErr(errors.New("selection already settled, resettle it to change the winner")))
 		return
 	}
 
-	sel.Winner = input.Winner
+	if err := sel.CanResolve(input.Winner); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
 
-	if err := s.resolver.Resolve(ctx, sel); err != nil {
+	resp, err := s.resolver.Resolve(ctx, sel.UUID, input.Winner)
+	if err != nil {
 		log.Error().Err(err).Msg("cannot resolve")
 		respondErr(w, internalErr())
 
 		return
 	}
 
+	if !resp.Ok {
+		respondErr(w, betResponseErr(resp))
+		return
+	}
+
 	respondOK(w)
 }
 
+func (s *Server) resolveEventResult(w http.ResponseWriter, r *http.Request, _ user.AdminUser) {
+	var input newEventResult
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("resolveEventResult")
+
+	ev, ok, err := s.db.FetchEvent(ctx, input.EventUUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch event")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, notFoundErr())
+		return
+	}
+
+	final, halfTime := input.scores()
+
+	if err := ev.RecordScore(final, halfTime); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	st, err := s.resolver.ResolveEvent(ctx, ev.UUID, final, halfTime, input.DryRun)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot resolve event result")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondJSON(w, http.StatusOK, eventResultView(st, input.DryRun))
+}
+
+func (s *Server) resettleSelection(w http.ResponseWriter, r *http.Request, au user.AdminUser) {
+	var input newResettlement
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if input.Winner == bet.WinnerTBD || !input.Winner.Finalized() {
+		respondErr(w, badRequestErr(errors.New("winner must be settled")))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("resettleSelection")
+
+	sel, ok, err := s.db.FetchSelection(ctx, input.SelectionUUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch event selection")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, notFoundErr())
+		return
+	}
+
+	if err := sel.CanResolve(input.Winner); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	st, resp, err := s.resolver.ResettleSelection(ctx, sel.UUID, input.Winner, input.AllowNegativeBalance, input.DryRun, au.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot resettle selection")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !resp.Ok {
+		respondErr(w, betResponseErr(resp))
+		return
+	}
+
+	respondJSON(w, http.StatusOK, resettlementView(st, input.DryRun))
+}
+
+func (s *Server) balanceAdjustments(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		UserUUID uuid.UUID `json:"user_uuid"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("balanceAdjustments")
+
+	aa, err := s.db.FetchBalanceAdjustments(ctx, input.UserUUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch balance adjustments")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	views := make([]balanceAdjustment, 0, len(aa))
+
+	for _, ba := range aa {
+		views = append(views, balanceAdjustmentView(ba))
+	}
+
+	respondJSON(w, http.StatusOK, views)
+}
+
+// discrepancyReport lists the balance discrepancies found by the
+// reconciliation between the given times.
+func (s *Server) discrepancyReport(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("discrepancyReport")
+
+	dd, err := s.db.FetchDiscrepancyReport(ctx, input.From, input.To)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch balance discrepancies")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	views := make([]discrepancy, 0, len(dd))
+
+	for _, d := range dd {
+		views = append(views, discrepancyView(d))
+	}
+
+	respondJSON(w, http.StatusOK, views)
+}
+
 func (s *Server) createAutoReport(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := s.logger("createAutoReport")
@@ -852,6 +1849,128 @@ func (s *Server) profitReport(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, profit)
 }
 
+func (s *Server) depositReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := s.logger("depositReport")
+
+	var input struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	report, err := s.db.FetchDepositReport(ctx, input.From, input.To)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch deposit report")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+type exchangeRate struct {
+	UUID      uuid.UUID       `json:"uuid"`
+	Currency  purse.Currency  `json:"currency"`
+	Rate      decimal.Decimal `json:"rate"`
+	ValidFrom time.Time       `json:"valid_from"`
+	AdminUUID uuid.UUID       `json:"admin_uuid"`
+}
+
+func exchangeRateView(er purse.ExchangeRate) exchangeRate {
+	return exchangeRate{
+		UUID:      er.UUID,
+		Currency:  er.Currency,
+		Rate:      er.Rate,
+		ValidFrom: er.ValidFrom,
+		AdminUUID: er.AdminUUID,
+	}
+}
+
+// exchangeRates lists the rates of the currency given by the currency
+// query parameter, or of every currency, in the order they took effect.
+func (s *Server) exchangeRates(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := s.logger("exchangeRates")
+
+	rr, err := s.db.FetchExchangeRates(ctx, purse.Currency(r.URL.Query().Get("currency")))
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch exchange rates")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	views := make([]exchangeRate, 0, len(rr))
+
+	for _, er := range rr {
+		views = append(views, exchangeRateView(er))
+	}
+
+	respondJSON(w, http.StatusOK, struct {
+		BaseCurrency purse.Currency `json:"base_currency"`
+		Rates        []exchangeRate `json:"rates"`
+	}{
+		BaseCurrency: s.cfg.BaseCurrency,
+		Rates:        views,
+	})
+}
+
+// setExchangeRate adds a rate of the currency taking effect at once, or at
+// the given time if it is in the future. Rates already in effect are never
+// changed, so that past transactions keep their rates.
+func (s *Server) setExchangeRate(w http.ResponseWriter, r *http.Request, au user.AdminUser) {
+	var input struct {
+		Currency  purse.Currency  `json:"currency"`
+		Rate      decimal.Decimal `json:"rate"`
+		ValidFrom *time.Time      `json:"valid_from"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	er := purse.ExchangeRate{
+		UUID:      uuid.New(),
+		Currency:  input.Currency,
+		Rate:      input.Rate,
+		ValidFrom: time.Now(),
+		AdminUUID: au.UUID,
+	}
+
+	if input.ValidFrom != nil {
+		if input.ValidFrom.Before(er.ValidFrom) {
+			respondErr(w, badRequestErr(errors.New("exchange rate cannot take effect in the past")))
+			return
+		}
+
+		er.ValidFrom = *input.ValidFrom
+	}
+
+	if err := er.Validate(s.cfg.BaseCurrency); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("setExchangeRate")
+
+	if err := s.db.InsertExchangeRate(ctx, er); err != nil {
+		log.Error().Err(err).Msg("cannot insert exchange rate")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, exchangeRateView(er))
+}
+
 func (s *Server) admins(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := s.logger("admins")
@@ -945,6 +2064,30 @@ func (s *Server) userBets(w http.ResponseWriter, r *http.Request) {
 		views = append(views, betView)
 	}
 
+	accs, err := s.db.FetchUserAccumulators(ctx, id)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch user accumulators")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	for _, acc := range accs {
+		view, ok, err := s.accumulatorView(ctx, acc)
+		if err != nil {
+			log.Error().Err(err).Msg("cannot fetch accumulator legs")
+			respondErr(w, internalErr())
+
+			return
+		}
+
+		if !ok {
+			continue
+		}
+
+		views = append(views, view)
+	}
+
 	respondJSON(w, http.StatusOK, views)
 }
 
@@ -999,9 +2142,54 @@ func (s *Server) betReport(w http.ResponseWriter, r *http.Request) {
 		views = append(views, betView)
 	}
 
+	accs, err := s.db.FetchAccumulatorReport(ctx, input.From, input.To)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch accumulator report")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	for _, acc := range accs {
+		view, ok, err := s.accumulatorView(ctx, acc)
+		if err != nil {
+			log.Error().Err(err).Msg("cannot fetch accumulator legs")
+			respondErr(w, internalErr())
+
+			return
+		}
+
+		if !ok {
+			continue
+		}
+
+		views = append(views, view)
+	}
+
 	respondJSON(w, http.StatusOK, views)
 }
 
 type Resolver interface {
-	Resolve(context.Context, bet.EventSelection) error
+	// Resolve settles the selection with the winner unless it is already
+	// settled.
+	Resolve(ctx context.Context, id uuid.UUID, w bet.Winner) (BetResponse, error)
+	ResolveEvent(context.Context, uuid.UUID, bet.Score, *bet.Score, bool) (Settlement, error)
+	CancelEvent(context.Context, uuid.UUID) (BetResponse, error)
+	ResettleSelection(ctx context.Context, id uuid.UUID, w bet.Winner, allowNegative, dryRun bool, admin uuid.UUID) (Settlement, BetResponse, error)
+}
+
+// Settlement is the outcome of resolving an event from its score or of
+// correcting the winner of a selection.
+type Settlement struct {
+	Event       bet.Event
+	Payouts     []Payout
+	Adjustments []purse.BalanceAdjustment
+}
+
+type Payout struct {
+	BetUUID  uuid.UUID
+	UserUUID uuid.UUID
+	Stake    decimal.Decimal
+	State    bet.BetState
+	Amount   decimal.Decimal
 }
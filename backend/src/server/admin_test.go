@@ -0,0 +1,273 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ramasauskas/ispbet/purse"
+	"github.com/ramasauskas/ispbet/user"
+	"github.com/rs/zerolog"
+	"github.com/shopspring/decimal"
+)
+
+// withdrawalDB keeps a single withdrawal and records the statuses it was
+// updated to.
+type withdrawalDB struct {
+	DB
+
+	wd      purse.Withdrawal
+	updates []purse.WithdrawalStatus
+}
+
+func (d *withdrawalDB) FetchWithdrawal(_ context.Context, id uuid.UUID) (purse.Withdrawal, bool, error) {
+	return d.wd, d.wd.UUID == id, nil
+}
+
+func (d *withdrawalDB) FetchBetUserByUUID(_ context.Context, id uuid.UUID) (user.BetUser, bool, error) {
+	var u user.BetUser
+	u.UUID = id
+
+	return u, true, nil
+}
+
+func (d *withdrawalDB) UpdateWithdrawal(_ context.Context, wd purse.Withdrawal, from purse.WithdrawalStatus, _ user.BetUser) (bool, error) {
+	if d.wd.Status != from {
+		return false, nil
+	}
+
+	d.wd = wd
+	d.updates = append(d.updates, wd.Status)
+
+	return true, nil
+}
+
+// payoutProvider fails to initiate payouts while err is set and records
+// the status the withdrawal had when the payout was initiated.
+type payoutProvider struct {
+	purse.PaymentProvider
+
+	db        *withdrawalDB
+	err       error
+	initiated []purse.WithdrawalStatus
+}
+
+func (p *payoutProvider) Name() string {
+	return "simulated"
+}
+
+func (p *payoutProvider) InitiatePayout(_ context.Context, wd purse.Withdrawal) (purse.PaymentIntent, error) {
+	p.initiated = append(p.initiated, p.db.wd.Status)
+
+	if p.err != nil {
+		return purse.PaymentIntent{}, p.err
+	}
+
+	return purse.PaymentIntent{Reference: "ref_" + wd.UUID.String()}, nil
+}
+
+func TestPayWithdrawalByProvider(t *testing.T) {
+	wd := purse.Withdrawal{
+		UUID:     uuid.New(),
+		Amount:   decimal.NewFromInt(100),
+		UserUUID: uuid.New(),
+		Status:   purse.WithdrawalStatusApproved,
+		Provider: "simulated",
+	}
+
+	tests := map[string]struct {
+		status    purse.WithdrawalStatus
+		errs      []error
+		code      int
+		updates   []purse.WithdrawalStatus
+		initiated []purse.WithdrawalStatus
+		want      purse.WithdrawalStatus
+	}{
+		"payout initiated": {
+			status:    purse.WithdrawalStatusApproved,
+			errs:      []error{nil},
+			code:      http.StatusOK,
+			updates:   []purse.WithdrawalStatus{purse.WithdrawalStatusProcessing, purse.WithdrawalStatusPaid},
+			initiated: []purse.WithdrawalStatus{purse.WithdrawalStatusProcessing},
+			want:      purse.WithdrawalStatusPaid,
+		},
+		"payout failed to initiate": {
+			status:    purse.WithdrawalStatusApproved,
+			errs:      []error{errors.New("provider unavailable")},
+			code:      http.StatusInternalServerError,
+			updates:   []purse.WithdrawalStatus{purse.WithdrawalStatusProcessing},
+			initiated: []purse.WithdrawalStatus{purse.WithdrawalStatusProcessing},
+			want:      purse.WithdrawalStatusProcessing,
+		},
+		"payout retried": {
+			status: purse.WithdrawalStatusApproved,
+			errs:   []error{errors.New("provider unavailable"), nil},
+			code:   http.StatusOK,
+			updates: []purse.WithdrawalStatus{
+				purse.WithdrawalStatusProcessing, purse.WithdrawalStatusProcessing, purse.WithdrawalStatusPaid,
+			},
+			initiated: []purse.WithdrawalStatus{purse.WithdrawalStatusProcessing, purse.WithdrawalStatusProcessing},
+			want:      purse.WithdrawalStatusPaid,
+		},
+		"withdrawal not approved": {
+			status: purse.WithdrawalStatusPending,
+			errs:   []error{nil},
+			code:   http.StatusBadRequest,
+			want:   purse.WithdrawalStatusPending,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := &withdrawalDB{wd: wd}
+			d.wd.Status = test.status
+
+			p := &payoutProvider{db: d}
+
+			s := &Server{
+				db:       d,
+				log:      zerolog.Nop(),
+				payments: map[string]purse.PaymentProvider{p.Name(): p},
+			}
+
+			var au user.AdminUser
+			au.UUID = uuid.New()
+
+			var code int
+
+			for _, err := range test.errs {
+				p.err = err
+
+				body := strings.NewReader(`{"withdrawal_uuid":"` + wd.UUID.String() + `"}`)
+				rec := httptest.NewRecorder()
+
+				s.reviewWithdrawal(payWithdrawal)(rec, httptest.NewRequest(http.MethodPost, "/", body), au)
+
+				code = rec.Code
+			}
+
+			if code != test.code {
+				t.Errorf("want code %d, got %d", test.code, code)
+			}
+
+			if !equalStatuses(d.updates, test.updates) {
+				t.Errorf("want updates %v, got %v", test.updates, d.updates)
+			}
+
+			if !equalStatuses(p.initiated, test.initiated) {
+				t.Errorf("want payouts initiated with statuses %v, got %v", test.initiated, p.initiated)
+			}
+
+			if d.wd.Status != test.want {
+				t.Errorf("want status %s, got %s", test.want, d.wd.Status)
+			}
+
+			if test.want == purse.WithdrawalStatusPaid && d.wd.Reference != "ref_"+wd.UUID.String() {
+				t.Errorf("want reference stored, got %q", d.wd.Reference)
+			}
+		})
+	}
+}
+
+func equalStatuses(a, b []purse.WithdrawalStatus) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// walletDB keeps a single bet user and records the balance it had when
+// the deposits and withdrawals were stored along with their entries.
+type walletDB struct {
+	DB
+
+	u        user.BetUser
+	balances []decimal.Decimal
+}
+
+func (d *walletDB) FetchBetUserByUUID(_ context.Context, id uuid.UUID) (user.BetUser, bool, error) {
+	return d.u, d.u.UUID == id, nil
+}
+
+func (d *walletDB) InsertDeposit(_ context.Context, u user.BetUser, _ purse.Deposit) error {
+	d.balances = append(d.balances, u.Balance)
+	return nil
+}
+
+func (d *walletDB) InsertWithdrawal(_ context.Context, u user.BetUser, _ purse.Withdrawal) error {
+	d.balances = append(d.balances, u.Balance)
+	return nil
+}
+
+func TestAdminWalletPosting(t *testing.T) {
+	var u user.BetUser
+	u.UUID = uuid.New()
+	u.Balance = decimal.NewFromInt(40)
+	u.Currency = "EUR"
+
+	tests := map[string]struct {
+		withdraw bool
+		amount   string
+		code     int
+		balances []decimal.Decimal
+	}{
+		"deposit": {
+			amount:   "60",
+			code:     http.StatusCreated,
+			balances: []decimal.Decimal{decimal.NewFromInt(100)},
+		},
+		"withdrawal": {
+			withdraw: true,
+			amount:   "15",
+			code:     http.StatusCreated,
+			balances: []decimal.Decimal{decimal.NewFromInt(25)},
+		},
+		"withdrawal above the balance": {
+			withdraw: true,
+			amount:   "50",
+			code:     http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := &walletDB{u: u}
+			s := &Server{db: d, log: zerolog.Nop()}
+
+			handle := s.createDeposit
+			if test.withdraw {
+				handle = s.createWithdrawal
+			}
+
+			body := strings.NewReader(`{"amount":"` + test.amount + `","user_uuid":"` + u.UUID.String() + `"}`)
+			rec := httptest.NewRecorder()
+
+			handle(rec, httptest.NewRequest(http.MethodPost, "/", body), user.AdminUser{})
+
+			if rec.Code != test.code {
+				t.Fatalf("want code %d, got %d", test.code, rec.Code)
+			}
+
+			if len(d.balances) != len(test.balances) {
+				t.Fatalf("want %d stored balances, got %v", len(test.balances), d.balances)
+			}
+
+			for i := range d.balances {
+				if !d.balances[i].Equal(test.balances[i]) {
+					t.Errorf("want balance %s stored, got %s", test.balances[i], d.balances[i])
+				}
+			}
+		})
+	}
+}
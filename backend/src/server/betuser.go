@@ -2,15 +2,20 @@ package server
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/ramasauskas/ispbet/autobet"
 	"github.com/ramasauskas/ispbet/bet"
+	"github.com/ramasauskas/ispbet/purse"
 	"github.com/ramasauskas/ispbet/user"
 	"github.com/shopspring/decimal"
 )
@@ -48,6 +53,12 @@ type newUserBet struct {
 	SelectionUUID uuid.UUID       `json:"selection_uuid"`
 	Stake         decimal.Decimal `json:"stake"`
 	Winner        string          `json:"winner"`
+	// Odds are the odds the user saw when placing the bet.
+	Odds       decimal.NullDecimal `json:"odds"`
+	OddsPolicy string              `json:"odds_policy"`
+	// TokenUUID is the bonus token to place the bet with. Free bets take
+	// the stake of the token.
+	TokenUUID uuid.UUID `json:"token_uuid"`
 }
 
 func (nb newUserBet) validate() error {
@@ -55,42 +66,178 @@ func (nb newUserBet) validate() error {
 		return errors.New("selection not provided")
 	}
 
-	if nb.Stake.LessThanOrEqual(decimal.Zero) {
+	if nb.Stake.IsNegative() || (nb.TokenUUID == uuid.Nil && nb.Stake.IsZero()) {
 		return errors.New("stake cannot be less than or equal to 0")
 	}
 
+	if nb.Odds.Valid && nb.Odds.Decimal.LessThanOrEqual(decimal.NewFromInt(1)) {
+		return errors.New("odds must be greater than 1")
+	}
+
+	if nb.OddsPolicy != "" {
+		if !nb.Odds.Valid {
+			return errors.New("odds policy requires odds")
+		}
+
+		if err := bet.OddsPolicy(nb.OddsPolicy).Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// policy returns the odds policy of the bet. Bets placed with odds must
+// match them exactly unless told otherwise, bets without odds take the
+// current price.
+func (nb newUserBet) policy() bet.OddsPolicy {
+	if !nb.Odds.Valid {
+		return bet.OddsPolicyAny
+	}
+
+	if nb.OddsPolicy == "" {
+		return bet.OddsPolicyExact
+	}
+
+	return bet.OddsPolicy(nb.OddsPolicy)
+}
+
+type newAccumulatorBet struct {
+	Stake decimal.Decimal `json:"stake"`
+	Legs  []struct {
+		SelectionUUID uuid.UUID `json:"selection_uuid"`
+		Winner        string    `json:"winner"`
+	} `json:"legs"`
+}
+
+func (na newAccumulatorBet) validate() error {
+	if len(na.Legs) < 2 {
+		return errors.New("at least 2 legs must be provided")
+	}
+
+	for _, l := range na.Legs {
+		if l.SelectionUUID == uuid.Nil {
+			return errors.New("selection not provided")
+		}
+	}
+
+	if na.Stake.LessThanOrEqual(decimal.Zero) {
+		return errors.New("stake cannot be less than or equal to 0")
+	}
+
+	return nil
+}
+
+type newCashOut struct {
+	BetUUID uuid.UUID       `json:"bet_uuid"`
+	Amount  decimal.Decimal `json:"amount"`
+}
+
+func (nc newCashOut) validate() error {
+	if nc.BetUUID == uuid.Nil {
+		return errors.New("bet not provided")
+	}
+
 	return nil
 }
 
+type cashOutQuote struct {
+	BetUUID uuid.UUID       `json:"bet_uuid"`
+	Amount  decimal.Decimal `json:"amount"`
+}
+
+type userBetType string
+
+const (
+	userBetTypeSingle      userBetType = "single"
+	userBetTypeAccumulator userBetType = "accumulator"
+)
+
 type userBet struct {
-	UUID      uuid.UUID         `json:"uuid"`
-	Stake     decimal.Decimal   `json:"stake"`
-	Odds      decimal.Decimal   `json:"odds"`
-	State     string            `json:"status"`
-	Selection betEventSelection `json:"selection"`
-	Winner    string            `json:"winner"`
-	Event     betEvent          `json:"event"`
-	Timestamp time.Time         `json:"timestamp"`
+	UUID      uuid.UUID          `json:"uuid"`
+	Type      userBetType        `json:"type"`
+	Stake     decimal.Decimal    `json:"stake"`
+	Odds      decimal.Decimal    `json:"odds"`
+	State     string             `json:"status"`
+	Selection *betEventSelection `json:"selection,omitempty"`
+	Winner    string             `json:"winner,omitempty"`
+	Event     *betEvent          `json:"event,omitempty"`
+	Legs      []userBetLeg       `json:"legs,omitempty"`
+	CashOut   *decimal.Decimal   `json:"cash_out,omitempty"`
+	Currency  purse.Currency     `json:"currency"`
+	TokenUUID *uuid.UUID         `json:"token_uuid,omitempty"`
+	FreeBet   bool               `json:"free_bet,omitempty"`
+	Timestamp time.Time          `json:"timestamp"`
 }
 
 func userBetView(b bet.Bet, ev betEvent, sel betEventSelection) userBet {
+	var cashOut *decimal.Decimal
+	if b.State == bet.BetStateCashedOut {
+		cashOut = &b.CashOutAmount
+	}
+
+	var tokenUUID *uuid.UUID
+	if b.TokenUUID != uuid.Nil {
+		tokenUUID = &b.TokenUUID
+	}
+
 	return userBet{
 		UUID:      b.UUID,
+		Type:      userBetTypeSingle,
 		Stake:     b.Stake,
 		Odds:      b.Odds,
 		State:     string(b.State),
-		Event:     ev,
-		Selection: sel,
+		Event:     &ev,
+		Selection: &sel,
 		Winner:    string(b.SelectionWinner),
+		CashOut:   cashOut,
+		Currency:  b.Currency,
+		TokenUUID: tokenUUID,
+		FreeBet:   b.FreeBet,
 		Timestamp: b.Timestamp,
 	}
 }
 
+type userBetLeg struct {
+	UUID      uuid.UUID         `json:"uuid"`
+	Odds      decimal.Decimal   `json:"odds"`
+	State     string            `json:"status"`
+	Selection betEventSelection `json:"selection"`
+	Winner    string            `json:"winner"`
+	Event     betEvent          `json:"event"`
+}
+
+func userAccumulatorView(acc bet.Accumulator, legs []userBetLeg) userBet {
+	return userBet{
+		UUID:      acc.UUID,
+		Type:      userBetTypeAccumulator,
+		Stake:     acc.Stake,
+		Odds:      acc.Odds,
+		State:     string(acc.State),
+		Legs:      legs,
+		Currency:  acc.Currency,
+		Timestamp: acc.Timestamp,
+	}
+}
+
+func userBetLegView(l bet.AccumulatorLeg, ev betEvent, sel betEventSelection) userBetLeg {
+	return userBetLeg{
+		UUID:      l.UUID,
+		Odds:      l.Odds,
+		State:     string(l.State),
+		Selection: sel,
+		Winner:    string(l.SelectionWinner),
+		Event:     ev,
+	}
+}
+
 type newBetUser struct {
 	Email     string `json:"email"`
 	Password  string `json:"password"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
+	// Currency of the user's wallet, the base currency if empty.
+	Currency purse.Currency `json:"currency"`
 }
 
 func (bu newBetUser) Materialize() (user.BetUser, error) {
@@ -110,6 +257,10 @@ func (bu newBetUser) Materialize() (user.BetUser, error) {
 		return user.BetUser{}, errors.New("password not provided")
 	}
 
+	if err := bu.Currency.Validate(); err != nil {
+		return user.BetUser{}, err
+	}
+
 	u := user.BetUser{
 		User: user.User{
 			UUID:      uuid.New(),
@@ -117,6 +268,7 @@ func (bu newBetUser) Materialize() (user.BetUser, error) {
 			FirstName: bu.FirstName,
 			LastName:  bu.LastName,
 		},
+		Currency: bu.Currency,
 	}
 
 	if err := u.SetPassword(bu.Password); err != nil {
@@ -133,19 +285,37 @@ type betUser struct {
 	LastName           string          `json:"last_name"`
 	EmailVerified      bool            `json:"email_verified"`
 	Balance            decimal.Decimal `json:"balance"`
+	BonusBalance       decimal.Decimal `json:"bonus_balance"`
+	Currency           purse.Currency  `json:"currency"`
 	IdentitityVerified bool            `json:"identitity_verified"`
+	Excluded           bool            `json:"excluded"`
+	ExclusionKind      string          `json:"exclusion_kind,omitempty"`
+	ExcludedUntil      *time.Time      `json:"excluded_until,omitempty"`
 }
 
 func betUserView(u user.BetUser) betUser {
-	return betUser{
+	v := betUser{
 		UUID:               u.UUID,
 		Email:              u.Email,
 		Balance:            u.Balance,
+		BonusBalance:       u.BonusBalance,
+		Currency:           u.Currency,
 		FirstName:          u.FirstName,
 		LastName:           u.LastName,
 		EmailVerified:      u.EmailVerified,
 		IdentitityVerified: u.IdentityVerified,
 	}
+
+	if u.Excluded(time.Now()) {
+		v.Excluded = true
+		v.ExclusionKind = string(u.ExclusionKind)
+
+		if !u.ExcludedUntil.IsZero() {
+			v.ExcludedUntil = &u.ExcludedUntil
+		}
+	}
+
+	return v
 }
 
 type newIdentityVerification struct {
@@ -187,20 +357,139 @@ func identityVerificationView(id user.IdentityVerification, bu betUser) identity
 	}
 }
 
+const (
+	defaultTransactionsPerPage = 50
+	maxTransactionsPerPage     = 200
+)
+
+// statementQuery filters the statement of a bet user. From is inclusive
+// and To is exclusive, zero times leave the range open.
+type statementQuery struct {
+	From    time.Time
+	To      time.Time
+	Page    int
+	PerPage int
+}
+
+func parseStatementQuery(v url.Values) (statementQuery, error) {
+	q := statementQuery{
+		Page:    1,
+		PerPage: defaultTransactionsPerPage,
+	}
+
+	for name, t := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if v.Get(name) == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, v.Get(name))
+		if err != nil {
+			return statementQuery{}, errors.New(name + " must be an RFC 3339 time")
+		}
+
+		*t = parsed
+	}
+
+	for name, n := range map[string]*int{"page": &q.Page, "per_page": &q.PerPage} {
+		if v.Get(name) == "" {
+			continue
+		}
+
+		parsed, err := strconv.Atoi(v.Get(name))
+		if err != nil || parsed < 1 {
+			return statementQuery{}, errors.New(name + " must be a positive number")
+		}
+
+		*n = parsed
+	}
+
+	if q.PerPage > maxTransactionsPerPage {
+		return statementQuery{}, fmt.Errorf("per_page cannot be greater than %d", maxTransactionsPerPage)
+	}
+
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return statementQuery{}, errors.New("from must be before to")
+	}
+
+	return q, nil
+}
+
+func (q statementQuery) includes(t time.Time) bool {
+	if !q.From.IsZero() && t.Before(q.From) {
+		return false
+	}
+
+	if !q.To.IsZero() && !t.Before(q.To) {
+		return false
+	}
+
+	return true
+}
+
+type transaction struct {
+	UUID          uuid.UUID       `json:"uuid"`
+	Type          string          `json:"type"`
+	ReferenceUUID uuid.UUID       `json:"reference_uuid"`
+	Amount        decimal.Decimal `json:"amount"`
+	Balance       decimal.Decimal `json:"balance"`
+	Timestamp     time.Time       `json:"timestamp"`
+}
+
+func transactionView(l purse.StatementLine) transaction {
+	return transaction{
+		UUID:          l.Entry.UUID,
+		Type:          string(l.Entry.Type),
+		ReferenceUUID: l.Entry.Reference,
+		Amount:        l.Amount,
+		Balance:       l.Balance,
+		Timestamp:     l.Entry.Timestamp,
+	}
+}
+
+type transactionPage struct {
+	Transactions []transaction `json:"transactions"`
+	Page         int           `json:"page"`
+	PerPage      int           `json:"per_page"`
+	Total        int           `json:"total"`
+}
+
 func (s *Server) betUserRouter() http.Handler {
 	r := chi.NewRouter()
 
 	r.Post("/register", s.registerBetUser)
 	r.Post("/login", s.loginBetUser)
+	r.Post("/login/two-factor", s.loginBetUserTwoFactor)
 	r.Post("/logout", s.logout)
 
 	r.Group(func(r chi.Router) {
 		r.Use(s.sessions.Auth)
 
 		r.Get("/me", s.withBetUser(s.betUserMe))
+		r.Put("/me/password", s.withBetUser(s.changePassword))
+		r.Put("/me/name", s.withBetUser(s.changeName))
+		r.Put("/me/email", s.withBetUser(s.changeEmail))
 		r.Get("/bets", s.withBetUser(s.bets))
+		r.Get("/transactions", s.withBetUser(s.transactions))
+		r.Get("/deposits", s.withBetUser(s.userDeposits))
+		r.Post("/deposits", s.withBetUser(s.initiateDeposit))
+		r.Get("/withdrawals", s.withBetUser(s.userWithdrawals))
+		r.Post("/withdrawals", s.withBetUser(s.requestWithdrawal))
+		r.Post("/withdrawals/{uuid}/cancel", s.withBetUser(s.cancelUserWithdrawal))
+		r.Get("/transactions/csv", s.withBetUser(s.transactionsCSV))
 		r.Post("/identity-verification", s.withBetUser(s.createVerificationRequest))
 		r.Post("/bet", s.withBetUser(s.bet))
+		r.Post("/bet/accumulator", s.withBetUser(s.accumulatorBet))
+		r.Post("/bet/cash-out/quote", s.withBetUser(s.quoteCashOut))
+		r.Post("/bet/cash-out", s.withBetUser(s.cashOut))
+		r.Get("/bonuses", s.withBetUser(s.userBonuses))
+		r.Get("/bonuses/campaigns", s.withBetUser(s.runningBonusCampaigns))
+		r.Post("/bonuses/claim", s.withBetUser(s.claimBonus))
+		r.Get("/limits", s.withBetUser(s.gamblingLimits))
+		r.Put("/limits", s.withBetUser(s.setGamblingLimit))
+		r.Get("/limits/history", s.withBetUser(s.gamblingLimitHistory))
+		r.Post("/exclusion", s.withBetUser(s.exclude))
+		r.Get("/export", s.withBetUser(s.exportUserData))
+		r.Post("/close", s.withBetUser(s.closeAccount))
 	})
 
 	r.Route("/autobet", func(r chi.Router) {
@@ -218,6 +507,137 @@ func (s *Server) betUserMe(w http.ResponseWriter, r *http.Request, bu user.BetUs
 	respondJSON(w, http.StatusOK, betUserView(bu))
 }
 
+// changePassword sets a new password of the user and signs the user out
+// of every other session.
+func (s *Server) changePassword(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	var input struct {
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if err := u.ChangePassword(input.OldPassword, input.NewPassword); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("changePassword")
+
+	if err := s.db.UpdateUser(ctx, u.User); err != nil {
+		log.Error().Err(err).Msg("cannot update user")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if err := s.sessions.RevokeOther(ctx); err != nil {
+		log.Error().Err(err).Msg("cannot revoke sessions")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondOK(w)
+}
+
+func (s *Server) changeName(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	var input struct {
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if err := u.Rename(input.FirstName, input.LastName); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("changeName")
+
+	if err := s.db.UpdateUser(ctx, u.User); err != nil {
+		log.Error().Err(err).Msg("cannot update user")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondJSON(w, http.StatusOK, betUserView(u))
+}
+
+// changeEmail sends a verification to the new email of the user. The
+// user cannot bet until it is verified, and keeps the old email until
+// then.
+func (s *Server) changeEmail(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	var input struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if !u.Login(input.Password) {
+		respondErr(w, badRequestErr(errors.New("invalid password")))
+		return
+	}
+
+	if err := u.ChangeEmail(input.Email); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("changeEmail")
+
+	_, ok, err := s.db.FetchUserByEmail(ctx, input.Email)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch user")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if ok {
+		respondErr(w, badRequestErr(errors.New("user already exists with provided email")))
+		return
+	}
+
+	if err = s.db.UpdateUser(ctx, u.User); err != nil {
+		log.Error().Err(err).Msg("cannot update user")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+
+		log = s.logger("changeEmail:defer")
+
+		if err := s.sendEmailVerification(ctx, u.User, input.Email); err != nil {
+			log.Err(err).Msg("cannot send email verification")
+		}
+	}()
+
+	respondJSON(w, http.StatusOK, betUserView(u))
+}
+
 func (s *Server) registerBetUser(w http.ResponseWriter, r *http.Request) {
 	var newUser newBetUser
 
@@ -226,6 +646,10 @@ func (s *Server) registerBetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if newUser.Currency == "" {
+		newUser.Currency = s.cfg.BaseCurrency
+	}
+
 	u, err := newUser.Materialize()
 	if err != nil {
 		respondErr(w, badRequestErr(err))
@@ -235,6 +659,21 @@ func (s *Server) registerBetUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := s.logger("registerBetUser")
 
+	if u.Currency != s.cfg.BaseCurrency {
+		rr, err := s.db.FetchExchangeRates(ctx, u.Currency)
+		if err != nil {
+			log.Error().Err(err).Msg("cannot fetch exchange rates")
+			respondErr(w, internalErr())
+
+			return
+		}
+
+		if len(rr) == 0 {
+			respondErr(w, badRequestErr(errors.New("currency is not supported")))
+			return
+		}
+	}
+
 	_, ok, err := s.db.FetchBetUserByEmail(ctx, u.Email)
 	if err != nil {
 		log.Error().Err(err).Msg("cannot fetch bet user")
@@ -264,7 +703,7 @@ func (s *Server) registerBetUser(w http.ResponseWriter, r *http.Request) {
 
 		log = s.logger("registerBetUser:defer")
 
-		if err := s.sendEmailVerification(ctx, u.User); err != nil {
+		if err := s.sendEmailVerification(ctx, u.User, u.Email); err != nil {
 			log.Err(err).Msg("cannot send email verification")
 		}
 	}()
@@ -283,6 +722,10 @@ func (s *Server) loginBetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.checkLoginThrottle(w, r, input.Email) {
+		return
+	}
+
 	ctx := r.Context()
 	log := s.logger("loginBetUser")
 
@@ -295,22 +738,49 @@ func (s *Server) loginBetUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !ok {
-		respondErr(w, notFoundErr())
+		dummyUser.Login(input.Password)
+	}
+
+	if !ok || !u.Login(input.Password) {
+		if err = s.loginFailed(ctx, r, input.Email, u.UUID); err != nil {
+			log.Error().Err(err).Msg("cannot record failed login")
+			respondErr(w, internalErr())
+
+			return
+		}
+
+		respondErr(w, invalidLoginErr())
+
 		return
 	}
 
-	if !u.Login(input.Password) {
-		respondErr(w, badRequestErr(errors.New("invalid password")))
+	s.startLogin(w, r, u.User, false, betUserView(u))
+}
+
+// loginBetUserTwoFactor finishes the login of the bet user with the
+// second factor.
+func (s *Server) loginBetUserTwoFactor(w http.ResponseWriter, r *http.Request) {
+	id, _, ok := s.passLoginChallenge(w, r)
+	if !ok {
 		return
 	}
 
-	if err = s.sessions.Init(w, r, u.UUID.String()); err != nil {
-		log.Error().Err(err).Msg("cannot initialize session")
+	ctx := r.Context()
+	log := s.logger("loginBetUserTwoFactor")
+
+	u, ok, err := s.db.FetchBetUserByUUID(ctx, id)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch bet user")
 		respondErr(w, internalErr())
 
 		return
 	}
 
+	if !ok {
+		respondErr(w, notFoundErr())
+		return
+	}
+
 	respondJSON(w, http.StatusOK, betUserView(u))
 }
 
@@ -365,8 +835,10 @@ func (s *Server) bet(w http.ResponseWriter, r *http.Request, u user.BetUser) {
 		SelectionUUID:   nb.SelectionUUID,
 		SelectionWinner: bet.Winner(nb.Winner),
 		Stake:           nb.Stake,
+		Odds:            nb.Odds.Decimal,
 		State:           bet.BetStateTBD,
 		Timestamp:       time.Now(),
+		TokenUUID:       nb.TokenUUID,
 	}
 
 	ctx := r.Context()
@@ -385,12 +857,6 @@ func (s *Server) bet(w http.ResponseWriter, r *http.Request, u user.BetUser) {
 		return
 	}
 
-	if b.SelectionWinner == bet.WinnerAway {
-		b.Odds = sel.OddsAway
-	} else if b.SelectionWinner == bet.WinnerHome {
-		b.Odds = sel.OddsHome
-	}
-
 	ev, ok, err := s.db.FetchEventBySelection(ctx, sel.UUID)
 	if err != nil {
 		log.Error().Err(err).Msg("cannot fetch event")
@@ -407,7 +873,7 @@ func (s *Server) bet(w http.ResponseWriter, r *http.Request, u user.BetUser) {
 	evView := betEventView(ev)
 	selView := betEventSelectionView(sel)
 
-	resp, err := s.better.Bet(ctx, &b, &u)
+	resp, err := s.better.Bet(ctx, &b, nb.policy(), &u)
 	if err != nil {
 		log.Error().Err(err).Msg("cannot place bet")
 		respondErr(w, internalErr())
@@ -415,19 +881,525 @@ func (s *Server) bet(w http.ResponseWriter, r *http.Request, u user.BetUser) {
 		return
 	}
 
+	if !resp.Ok && resp.Odds.IsZero() {
+		respondErr(w, betResponseErr(resp))
+		return
+	}
+
+	// the odds changed and the policy did not allow the new price, so it
+	// is returned for the user to bet again
 	if !resp.Ok {
-		respondErr(w, badRequestErr(errors.New(resp.ErrorMessage)))
+		respondJSON(w, http.StatusConflict, struct {
+			Message string          `json:"message"`
+			Code    string          `json:"code"`
+			Odds    decimal.Decimal `json:"odds"`
+		}{
+			Message: resp.ErrorMessage,
+			Code:    resp.ErrorCode,
+			Odds:    resp.Odds,
+		})
+
 		return
 	}
 
 	respondJSON(w, http.StatusCreated, userBetView(b, evView, selView))
 }
 
-func (s *Server) bets(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+func (s *Server) quoteCashOut(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	var input newCashOut
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if err := input.validate(); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
 	ctx := r.Context()
+	log := s.logger("quoteCashOut")
+
+	amount, resp, err := s.better.QuoteCashOut(ctx, input.BetUUID, u)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot quote cash-out")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !resp.Ok {
+		respondErr(w, betResponseErr(resp))
+		return
+	}
+
+	respondJSON(w, http.StatusOK, cashOutQuote{
+		BetUUID: input.BetUUID,
+		Amount:  amount,
+	})
+}
+
+func (s *Server) cashOut(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	var input newCashOut
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if err := input.validate(); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("cashOut")
+
+	amount, resp, err := s.better.CashOut(ctx, input.BetUUID, input.Amount, &u)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot cash out")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !resp.Ok && amount.IsZero() {
+		respondErr(w, betResponseErr(resp))
+		return
+	}
+
+	// the price moved since the quote, so the new one is returned for the
+	// user to accept
+	if !resp.Ok {
+		respondJSON(w, http.StatusConflict, struct {
+			Message string          `json:"message"`
+			Code    string          `json:"code"`
+			Amount  decimal.Decimal `json:"amount"`
+		}{
+			Message: resp.ErrorMessage,
+			Code:    resp.ErrorCode,
+			Amount:  amount,
+		})
+
+		return
+	}
+
+	respondJSON(w, http.StatusOK, cashOutQuote{
+		BetUUID: input.BetUUID,
+		Amount:  amount,
+	})
+}
+
+func (s *Server) accumulatorBet(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	var na newAccumulatorBet
+
+	if err := json.NewDecoder(r.Body).Decode(&na); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if err := na.validate(); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	acc := bet.Accumulator{
+		UUID:      uuid.New(),
+		UserUUID:  u.UUID,
+		Stake:     na.Stake,
+		State:     bet.BetStateTBD,
+		Timestamp: time.Now(),
+	}
+
+	for _, l := range na.Legs {
+		acc.Legs = append(acc.Legs, bet.AccumulatorLeg{
+			UUID:            uuid.New(),
+			SelectionUUID:   l.SelectionUUID,
+			SelectionWinner: bet.Winner(l.Winner),
+			State:           bet.BetStateTBD,
+		})
+	}
+
+	ctx := r.Context()
+	log := s.logger("accumulatorBet")
+
+	resp, err := s.better.Accumulate(ctx, &acc, &u)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot place accumulator")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !resp.Ok {
+		respondErr(w, betResponseErr(resp))
+		return
+	}
+
+	view, ok, err := s.accumulatorView(ctx, acc)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch accumulator legs")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, notFoundErr())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, view)
+}
+
+// accumulatorView resolves the selection and event of every leg. False
+// is returned if any of them no longer exists.
+func (s *Server) accumulatorView(ctx context.Context, acc bet.Accumulator) (userBet, bool, error) {
+	var legs []userBetLeg
+
+	for _, l := range acc.Legs {
+		sel, ok, err := s.db.FetchSelection(ctx, l.SelectionUUID)
+		if err != nil {
+			return userBet{}, false, err
+		}
+
+		if !ok {
+			return userBet{}, false, nil
+		}
+
+		ev, ok, err := s.db.FetchEvent(ctx, sel.EventUUID)
+		if err != nil {
+			return userBet{}, false, err
+		}
+
+		if !ok {
+			return userBet{}, false, nil
+		}
+
+		legs = append(legs, userBetLegView(l, betEventView(ev), betEventSelectionView(sel)))
+	}
+
+	return userAccumulatorView(acc, legs), true, nil
+}
+
+// statement returns the wallet statement of the user within the range of
+// the query. The running balance covers the whole history, so it stays
+// correct for any range.
+func (s *Server) statement(ctx context.Context, u user.BetUser, q statementQuery) ([]purse.StatementLine, error) {
+	entries, err := s.db.FetchUserEntries(ctx, u.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []purse.StatementLine
+
+	for _, l := range purse.Statement(purse.UserAccount(u.UUID), entries) {
+		if q.includes(l.Entry.Timestamp) {
+			lines = append(lines, l)
+		}
+	}
+
+	return lines, nil
+}
+
+func (s *Server) transactions(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	q, err := parseStatementQuery(r.URL.Query())
+	if err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("transactions")
+
+	lines, err := s.statement(ctx, u, q)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch statement")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	page := transactionPage{
+		Transactions: make([]transaction, 0, q.PerPage),
+		Page:         q.Page,
+		PerPage:      q.PerPage,
+		Total:        len(lines),
+	}
+
+	start := (q.Page - 1) * q.PerPage
+
+	for i := start; i < len(lines) && i < start+q.PerPage; i++ {
+		page.Transactions = append(page.Transactions, transactionView(lines[i]))
+	}
+
+	respondJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) transactionsCSV(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	q, err := parseStatementQuery(r.URL.Query())
+	if err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("transactionsCSV")
+
+	lines, err := s.statement(ctx, u, q)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch statement")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	w.Header().Add("Content-type", "text/csv")
+	w.Header().Add("Content-Disposition", `attachment; filename="transactions.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"timestamp", "type", "uuid", "reference_uuid", "amount", "balance"})
+
+	for _, l := range lines {
+		cw.Write([]string{
+			l.Entry.Timestamp.Format(time.RFC3339),
+			string(l.Entry.Type),
+			l.Entry.UUID.String(),
+			l.Entry.Reference.String(),
+			l.Amount.String(),
+			l.Balance.String(),
+		})
+	}
+
+	cw.Flush()
+
+	if err := cw.Error(); err != nil {
+		log.Error().Err(err).Msg("cannot write statement")
+	}
+}
+
+func (s *Server) userDeposits(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	ctx := r.Context()
+	log := s.logger("userDeposits")
+
+	dd, err := s.db.FetchUserDeposits(ctx, u.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch deposits")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	views := make([]deposit, 0, len(dd))
+
+	for _, d := range dd {
+		views = append(views, depositView(d))
+	}
+
+	respondJSON(w, http.StatusOK, views)
+}
+
+// initiateDeposit creates a pending deposit collected by the payment
+// provider. The deposit is credited once the provider confirms it.
+func (s *Server) initiateDeposit(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	var input struct {
+		Amount   decimal.Decimal `json:"amount"`
+		Provider string          `json:"provider"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if input.Amount.LessThanOrEqual(decimal.Zero) {
+		respondErr(w, badRequestErr(errors.New("amount cannot be less than or equal to 0")))
+		return
+	}
+
+	p, ok := s.payments[input.Provider]
+	if !ok {
+		respondErr(w, badRequestErr(errors.New("unknown payment provider")))
+		return
+	}
+
+	if u.Excluded(time.Now()) {
+		respondErr(w, badRequestErr(errExcluded))
+		return
+	}
+
+	if !s.checkDepositLimits(w, r, u.UUID, input.Amount) {
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("initiateDeposit")
+
+	now := time.Now()
+
+	d := purse.Deposit{
+		UUID:      uuid.New(),
+		Amount:    input.Amount,
+		Timestamp: now,
+		UserUUID:  u.UUID,
+		Status:    purse.DepositStatusPending,
+		Provider:  p.Name(),
+		UpdatedAt: now,
+		Currency:  u.Currency,
+	}
+
+	intent, err := p.InitiateDeposit(ctx, d)
+	if err != nil {
+		log.Error().Err(err).Str("provider", p.Name()).Msg("cannot initiate deposit")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	d.Reference = intent.Reference
+
+	if err = s.db.InsertDeposit(ctx, u, d); err != nil {
+		if errors.Is(err, user.ErrGamblingLimitReached) {
+			respondErr(w, gamblingLimitErr(err))
+			return
+		}
+
+		log.Error().Err(err).Msg("cannot insert deposit")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, struct {
+		deposit
+		RedirectURL string `json:"redirect_url,omitempty"`
+	}{
+		deposit:     depositView(d),
+		RedirectURL: intent.RedirectURL,
+	})
+}
+
+func (s *Server) userWithdrawals(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	ctx := r.Context()
+	log := s.logger("userWithdrawals")
+
+	ww, err := s.db.FetchUserWithdrawals(ctx, u.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch withdrawals")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	views := make([]withdrawal, 0, len(ww))
+
+	for _, wd := range ww {
+		views = append(views, withdrawalView(wd))
+	}
+
+	respondJSON(w, http.StatusOK, views)
+}
+
+// requestWithdrawal creates a pending withdrawal, reserving its amount so
+// that it cannot be spent on bets until the withdrawal is reviewed.
+func (s *Server) requestWithdrawal(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	var input struct {
+		Amount decimal.Decimal `json:"amount"`
+		// Provider pays the withdrawal out, the admins pay it by hand if
+		// it is empty.
+		Provider string `json:"provider"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if input.Amount.LessThanOrEqual(decimal.Zero) {
+		respondErr(w, badRequestErr(errors.New("amount cannot be less than or equal to 0")))
+		return
+	}
+
+	if _, ok := s.payments[input.Provider]; input.Provider != "" && !ok {
+		respondErr(w, badRequestErr(errors.New("unknown payment provider")))
+		return
+	}
+
+	if !u.IdentityVerified {
+		respondErr(w, badRequestErr(errors.New("identity must be verified to withdraw")))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("requestWithdrawal")
+
+	if err := u.Debit(input.Amount); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	now := time.Now()
+
+	wd := purse.Withdrawal{
+		UUID:      uuid.New(),
+		Amount:    input.Amount,
+		Timestamp: now,
+		UserUUID:  u.UUID,
+		Status:    purse.WithdrawalStatusPending,
+		UpdatedAt: now,
+		Provider:  input.Provider,
+		Currency:  u.Currency,
+	}
+
+	if err := s.db.InsertWithdrawalRequest(ctx, u, wd); err != nil {
+		log.Error().Err(err).Msg("cannot insert withdrawal request")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, withdrawalView(wd))
+}
+
+func (s *Server) cancelUserWithdrawal(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	id, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("cancelUserWithdrawal")
+
+	wd, ok, err := s.db.FetchWithdrawal(ctx, id)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch withdrawal")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok || wd.UserUUID != u.UUID {
+		respondErr(w, notFoundErr())
+		return
+	}
+
+	from := wd.Status
+
+	if err := wd.Cancel(uuid.Nil); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	s.saveWithdrawal(w, r, wd, from)
+}
+
+func (s *Server) bets(w http.ResponseWriter, r *http.Request, u user.BetUser) {
 	log := s.logger("bets")
 
-	bets, err := s.db.FetchUserBets(ctx, u.UUID)
+	betViews, err := s.userBetViews(r.Context(), u.UUID)
 	if err != nil {
 		log.Error().Err(err).Msg("cannot fetch bets")
 		respondErr(w, internalErr())
@@ -435,13 +1407,23 @@ func (s *Server) bets(w http.ResponseWriter, r *http.Request, u user.BetUser) {
 		return
 	}
 
+	respondJSON(w, http.StatusOK, betViews)
+}
+
+// userBetViews returns the bets and accumulators of the user. Bets on
+// selections or events that no longer exist are left out.
+func (s *Server) userBetViews(ctx context.Context, userUUID uuid.UUID) ([]userBet, error) {
+	bets, err := s.db.FetchUserBets(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
 	betViews := make([]userBet, 0)
 
 	for _, b := range bets {
 		sel, ok, err := s.db.FetchSelection(ctx, b.SelectionUUID)
 		if err != nil {
-			log.Error().Err(err).Msg("cannot fetch event")
-			continue
+			return nil, err
 		}
 
 		if !ok {
@@ -450,10 +1432,7 @@ func (s *Server) bets(w http.ResponseWriter, r *http.Request, u user.BetUser) {
 
 		ev, ok, err := s.db.FetchEvent(ctx, sel.EventUUID)
 		if err != nil {
-			log.Error().Err(err).Msg("cannot fetch event")
-			respondErr(w, internalErr())
-
-			return
+			return nil, err
 		}
 
 		if !ok {
@@ -464,7 +1443,25 @@ func (s *Server) bets(w http.ResponseWriter, r *http.Request, u user.BetUser) {
 		betViews = append(betViews, betView)
 	}
 
-	respondJSON(w, http.StatusOK, betViews)
+	accs, err := s.db.FetchUserAccumulators(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, acc := range accs {
+		view, ok, err := s.accumulatorView(ctx, acc)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		betViews = append(betViews, view)
+	}
+
+	return betViews, nil
 }
 
 func (s *Server) autoBets(w http.ResponseWriter, r *http.Request, u user.BetUser) {
@@ -542,9 +1539,14 @@ func (s *Server) deleteAutoBet(w http.ResponseWriter, r *http.Request, u user.Be
 
 type BetResponse struct {
 	Ok           bool
+	ErrorCode    string
 	ErrorMessage string
+	Odds         decimal.Decimal
 }
 
 type Better interface {
-	Bet(context.Context, *bet.Bet, *user.BetUser) (BetResponse, error)
+	Bet(context.Context, *bet.Bet, bet.OddsPolicy, *user.BetUser) (BetResponse, error)
+	Accumulate(context.Context, *bet.Accumulator, *user.BetUser) (BetResponse, error)
+	QuoteCashOut(context.Context, uuid.UUID, user.BetUser) (decimal.Decimal, BetResponse, error)
+	CashOut(context.Context, uuid.UUID, decimal.Decimal, *user.BetUser) (decimal.Decimal, BetResponse, error)
 }
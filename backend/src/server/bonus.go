@@ -0,0 +1,369 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ramasauskas/ispbet/bonus"
+	"github.com/ramasauskas/ispbet/purse"
+	"github.com/ramasauskas/ispbet/user"
+	"github.com/shopspring/decimal"
+)
+
+type bonusCampaign struct {
+	UUID               uuid.UUID          `json:"uuid"`
+	Name               string             `json:"name"`
+	Type               bonus.CampaignType `json:"type"`
+	Currency           purse.Currency     `json:"currency"`
+	MatchRate          decimal.Decimal    `json:"match_rate"`
+	MaxAmount          decimal.Decimal    `json:"max_amount"`
+	WageringMultiplier decimal.Decimal    `json:"wagering_multiplier"`
+	MinOdds            decimal.Decimal    `json:"min_odds"`
+	FreeBetAmount      decimal.Decimal    `json:"free_bet_amount"`
+	OddsBoost          decimal.Decimal    `json:"odds_boost"`
+	StartsAt           time.Time          `json:"starts_at"`
+	EndsAt             time.Time          `json:"ends_at"`
+}
+
+func bonusCampaignView(c bonus.Campaign) bonusCampaign {
+	return bonusCampaign{
+		UUID:               c.UUID,
+		Name:               c.Name,
+		Type:               c.Type,
+		Currency:           c.Currency,
+		MatchRate:          c.MatchRate,
+		MaxAmount:          c.MaxAmount,
+		WageringMultiplier: c.WageringMultiplier,
+		MinOdds:            c.MinOdds,
+		FreeBetAmount:      c.FreeBetAmount,
+		OddsBoost:          c.OddsBoost,
+		StartsAt:           c.StartsAt,
+		EndsAt:             c.EndsAt,
+	}
+}
+
+type bonusGrant struct {
+	UUID             uuid.UUID         `json:"uuid"`
+	CampaignUUID     uuid.UUID         `json:"campaign_uuid"`
+	DepositUUID      uuid.UUID         `json:"deposit_uuid"`
+	Amount           decimal.Decimal   `json:"amount"`
+	WageringRequired decimal.Decimal   `json:"wagering_required"`
+	Wagered          decimal.Decimal   `json:"wagered"`
+	MinOdds          decimal.Decimal   `json:"min_odds"`
+	Status           bonus.GrantStatus `json:"status"`
+	CreatedAt        time.Time         `json:"created_at"`
+}
+
+func bonusGrantView(g bonus.Grant) bonusGrant {
+	return bonusGrant{
+		UUID:             g.UUID,
+		CampaignUUID:     g.CampaignUUID,
+		DepositUUID:      g.DepositUUID,
+		Amount:           g.Amount,
+		WageringRequired: g.WageringRequired,
+		Wagered:          g.Wagered,
+		MinOdds:          g.MinOdds,
+		Status:           g.Status,
+		CreatedAt:        g.CreatedAt,
+	}
+}
+
+type bonusToken struct {
+	UUID         uuid.UUID          `json:"uuid"`
+	CampaignUUID uuid.UUID          `json:"campaign_uuid"`
+	Type         bonus.CampaignType `json:"type"`
+	Amount       decimal.Decimal    `json:"amount"`
+	Boost        decimal.Decimal    `json:"boost"`
+	Status       bonus.TokenStatus  `json:"status"`
+	BetUUID      *uuid.UUID         `json:"bet_uuid,omitempty"`
+	ExpiresAt    time.Time          `json:"expires_at"`
+}
+
+func bonusTokenView(t bonus.Token) bonusToken {
+	var betUUID *uuid.UUID
+	if t.BetUUID != uuid.Nil {
+		betUUID = &t.BetUUID
+	}
+
+	return bonusToken{
+		UUID:         t.UUID,
+		CampaignUUID: t.CampaignUUID,
+		Type:         t.Type,
+		Amount:       t.Amount,
+		Boost:        t.Boost,
+		Status:       t.Status,
+		BetUUID:      betUUID,
+		ExpiresAt:    t.ExpiresAt,
+	}
+}
+
+func (s *Server) bonusCampaigns(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := s.logger("bonusCampaigns")
+
+	cc, err := s.db.FetchBonusCampaigns(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch bonus campaigns")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	views := make([]bonusCampaign, 0, len(cc))
+
+	for _, c := range cc {
+		views = append(views, bonusCampaignView(c))
+	}
+
+	respondJSON(w, http.StatusOK, views)
+}
+
+// createBonusCampaign defines a campaign starting at once, or at the given
+// time. The campaign is in the base currency unless told otherwise.
+func (s *Server) createBonusCampaign(w http.ResponseWriter, r *http.Request, au user.AdminUser) {
+	var input struct {
+		Name               string             `json:"name"`
+		Type               bonus.CampaignType `json:"type"`
+		Currency           purse.Currency     `json:"currency"`
+		MatchRate          decimal.Decimal    `json:"match_rate"`
+		MaxAmount          decimal.Decimal    `json:"max_amount"`
+		WageringMultiplier decimal.Decimal    `json:"wagering_multiplier"`
+		MinOdds            decimal.Decimal    `json:"min_odds"`
+		FreeBetAmount      decimal.Decimal    `json:"free_bet_amount"`
+		OddsBoost          decimal.Decimal    `json:"odds_boost"`
+		StartsAt           *time.Time         `json:"starts_at"`
+		EndsAt             time.Time          `json:"ends_at"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	now := time.Now()
+
+	c := bonus.Campaign{
+		UUID:               uuid.New(),
+		Name:               input.Name,
+		Type:               input.Type,
+		Currency:           input.Currency,
+		MatchRate:          input.MatchRate,
+		MaxAmount:          input.MaxAmount,
+		WageringMultiplier: input.WageringMultiplier,
+		MinOdds:            input.MinOdds,
+		FreeBetAmount:      input.FreeBetAmount,
+		OddsBoost:          input.OddsBoost,
+		StartsAt:           now,
+		EndsAt:             input.EndsAt,
+		CreatedBy:          au.UUID,
+		CreatedAt:          now,
+	}
+
+	if c.Currency == "" {
+		c.Currency = s.cfg.BaseCurrency
+	}
+
+	if input.StartsAt != nil {
+		c.StartsAt = *input.StartsAt
+	}
+
+	if err := c.Validate(); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("createBonusCampaign")
+
+	if err := s.db.InsertBonusCampaign(ctx, c); err != nil {
+		log.Error().Err(err).Msg("cannot insert bonus campaign")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, bonusCampaignView(c))
+}
+
+// runningBonusCampaigns lists the campaigns the user can claim now.
+func (s *Server) runningBonusCampaigns(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	ctx := r.Context()
+	log := s.logger("runningBonusCampaigns")
+
+	cc, err := s.db.FetchRunningBonusCampaigns(ctx, u.Currency, time.Now())
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch bonus campaigns")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	views := make([]bonusCampaign, 0, len(cc))
+
+	for _, c := range cc {
+		views = append(views, bonusCampaignView(c))
+	}
+
+	respondJSON(w, http.StatusOK, views)
+}
+
+func (s *Server) userBonuses(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	ctx := r.Context()
+	log := s.logger("userBonuses")
+
+	gg, err := s.db.FetchUserBonusGrants(ctx, u.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch bonus grants")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	tt, err := s.db.FetchUserBonusTokens(ctx, u.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch bonus tokens")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	grants := make([]bonusGrant, 0, len(gg))
+
+	for _, g := range gg {
+		grants = append(grants, bonusGrantView(g))
+	}
+
+	tokens := make([]bonusToken, 0, len(tt))
+
+	for _, t := range tt {
+		tokens = append(tokens, bonusTokenView(t))
+	}
+
+	respondJSON(w, http.StatusOK, struct {
+		BonusBalance decimal.Decimal `json:"bonus_balance"`
+		Currency     purse.Currency  `json:"currency"`
+		Grants       []bonusGrant    `json:"grants"`
+		Tokens       []bonusToken    `json:"tokens"`
+	}{
+		BonusBalance: u.BonusBalance,
+		Currency:     u.Currency,
+		Grants:       grants,
+		Tokens:       tokens,
+	})
+}
+
+// claimBonus gives the user the bonus of a running campaign. Deposit match
+// campaigns credit the bonus balance for the given deposit, the other
+// campaigns issue a token to bet with.
+func (s *Server) claimBonus(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	var input struct {
+		CampaignUUID uuid.UUID `json:"campaign_uuid"`
+		DepositUUID  uuid.UUID `json:"deposit_uuid"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("claimBonus")
+
+	c, ok, err := s.db.FetchBonusCampaign(ctx, input.CampaignUUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch bonus campaign")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, notFoundErr())
+		return
+	}
+
+	claimed, err := s.db.BonusCampaignClaimed(ctx, c.UUID, u.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot check claimed bonuses")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if claimed {
+		respondErr(w, badRequestErr(errors.New("campaign already claimed")))
+		return
+	}
+
+	now := time.Now()
+
+	if c.Type != bonus.CampaignTypeDepositMatch {
+		t, err := c.IssueToken(u.UUID, u.Currency, now)
+		if err != nil {
+			respondErr(w, badRequestErr(err))
+			return
+		}
+
+		if err = s.db.InsertBonusToken(ctx, t); err != nil {
+			log.Error().Err(err).Msg("cannot insert bonus token")
+			respondErr(w, internalErr())
+
+			return
+		}
+
+		respondJSON(w, http.StatusCreated, bonusTokenView(t))
+
+		return
+	}
+
+	d, ok, err := s.db.FetchDeposit(ctx, input.DepositUUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch deposit")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok || d.UserUUID != u.UUID {
+		respondErr(w, notFoundErr())
+		return
+	}
+
+	gg, err := s.db.FetchUserBonusGrants(ctx, u.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch bonus grants")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	for _, g := range gg {
+		if g.DepositUUID == d.UUID {
+			respondErr(w, badRequestErr(errors.New("deposit already matched")))
+			return
+		}
+	}
+
+	g, err := c.GrantDeposit(d, now)
+	if err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if err = u.CreditBonus(g.Amount); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if err = s.db.InsertBonusGrant(ctx, u, g); err != nil {
+		log.Error().Err(err).Msg("cannot insert bonus grant")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, bonusGrantView(g))
+}
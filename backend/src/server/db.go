@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/ramasauskas/ispbet/autobet"
 	"github.com/ramasauskas/ispbet/bet"
+	"github.com/ramasauskas/ispbet/bonus"
 	"github.com/ramasauskas/ispbet/purse"
 	"github.com/ramasauskas/ispbet/report"
 	"github.com/ramasauskas/ispbet/user"
@@ -16,10 +17,15 @@ import (
 type DB interface {
 	UserDB
 	EmailVerificationDB
+	PasswordResetDB
+	TwoFactorDB
+	LoginThrottleDB
+	GamblingLimitDB
 	PurseDB
 	BetDB
 	AdminDB
 	ReportDB
+	BonusDB
 }
 
 type UserDB interface {
@@ -27,8 +33,17 @@ type UserDB interface {
 	FetchBetUserByUUID(context.Context, uuid.UUID) (user.BetUser, bool, error)
 	FetchBetUsers(context.Context) ([]user.BetUser, error)
 	InsertBetUser(context.Context, user.BetUser) error
+	// ExcludeBetUser stores the exclusion of the user along with the
+	// record of it.
+	ExcludeBetUser(context.Context, user.BetUser, user.Exclusion) error
+	// EraseBetUser stores the erased user, removing the photos of the
+	// user's identity verifications and the tokens, two-factor and auto
+	// bets of the user. Financial records are kept.
+	EraseBetUser(context.Context, user.BetUser) error
 
 	FetchUserByUUID(context.Context, uuid.UUID) (user.User, bool, error)
+	FetchUserByEmail(context.Context, string) (user.User, bool, error)
+	UpdateUser(context.Context, user.User) error
 
 	FetchAdminUserByUUID(context.Context, uuid.UUID) (user.AdminUser, bool, error)
 	FetchAdminUserByEmail(context.Context, string) (user.AdminUser, bool, error)
@@ -36,18 +51,88 @@ type UserDB interface {
 	InsertBetUserIdentityVerification(context.Context, user.IdentityVerification) error
 	FetchIdentityVerification(context.Context, uuid.UUID) (user.IdentityVerification, bool, error)
 	FetchIdentityVerifications(context.Context) ([]user.IdentityVerification, error)
+	FetchUserIdentityVerifications(context.Context, uuid.UUID) ([]user.IdentityVerification, error)
 	InsertIdentityVerificationUpdate(context.Context, user.BetUser, user.IdentityVerification) error
 }
 
 type EmailVerificationDB interface {
+	// InsertEmailVerification stores the new token of the user, expiring
+	// the older unused ones.
 	InsertEmailVerification(context.Context, user.EmailVerification) error
 	FetchEmailVerification(context.Context, string) (user.EmailVerification, bool, error)
+	FetchLatestEmailVerification(context.Context, uuid.UUID) (user.EmailVerification, bool, error)
 	InsertUserVerification(context.Context, user.User, user.EmailVerification) error
 }
 
+type PasswordResetDB interface {
+	InsertPasswordReset(context.Context, user.PasswordReset) error
+	FetchPasswordReset(context.Context, string) (user.PasswordReset, bool, error)
+	// ResetPassword stores the new password of the user and uses up the
+	// token, reporting false if the token was used in the meantime.
+	ResetPassword(context.Context, user.User, user.PasswordReset) (bool, error)
+}
+
+type TwoFactorDB interface {
+	FetchTwoFactor(context.Context, uuid.UUID) (user.TwoFactor, bool, error)
+	UpsertTwoFactor(context.Context, user.TwoFactor) error
+	// EnableTwoFactor stores the enabled two-factor of the user, replacing
+	// the recovery codes of the user with the given ones.
+	EnableTwoFactor(context.Context, user.TwoFactor, []user.RecoveryCode) error
+	// UpdateTwoFactorStep records the step of the accepted code, reporting
+	// false if the code was used in the meantime.
+	UpdateTwoFactorStep(context.Context, user.TwoFactor) (bool, error)
+	UseRecoveryCode(ctx context.Context, userUUID uuid.UUID, hash string) (bool, error)
+	DeleteTwoFactor(context.Context, uuid.UUID) error
+}
+
+type LoginThrottleDB interface {
+	FetchLoginThrottle(ctx context.Context, scope user.ThrottleScope, key string) (user.LoginThrottle, bool, error)
+	UpsertLoginThrottle(context.Context, user.LoginThrottle) error
+	DeleteLoginThrottle(ctx context.Context, scope user.ThrottleScope, key string) error
+	InsertLockoutEvent(context.Context, user.LockoutEvent) error
+	FetchLockoutEvents(context.Context) ([]user.LockoutEvent, error)
+}
+
+type GamblingLimitDB interface {
+	FetchGamblingLimits(context.Context, uuid.UUID) ([]user.GamblingLimit, error)
+	// UpdateGamblingLimit stores the limit along with the record of its
+	// change.
+	UpdateGamblingLimit(context.Context, user.GamblingLimit, user.GamblingLimitChange) error
+	// FetchGamblingLimitChanges returns the limit changes of the user,
+	// newest first.
+	FetchGamblingLimitChanges(context.Context, uuid.UUID) ([]user.GamblingLimitChange, error)
+}
+
 type PurseDB interface {
+	// InsertDeposit returns an error wrapping user.ErrGamblingLimitReached
+	// if the deposit does not fit in the deposit limits of the user.
 	InsertDeposit(context.Context, user.BetUser, purse.Deposit) error
+	// UpdateDeposit returns false if the deposit no longer has the given
+	// status.
+	UpdateDeposit(ctx context.Context, d purse.Deposit, from purse.DepositStatus, u user.BetUser) (bool, error)
+	FetchDeposit(context.Context, uuid.UUID) (purse.Deposit, bool, error)
+	FetchDepositByReference(ctx context.Context, provider, ref string) (purse.Deposit, bool, error)
+	FetchUserDeposits(context.Context, uuid.UUID) ([]purse.Deposit, error)
 	InsertWithdrawal(context.Context, user.BetUser, purse.Withdrawal) error
+	InsertWithdrawalRequest(context.Context, user.BetUser, purse.Withdrawal) error
+	// UpdateWithdrawal returns false if the withdrawal no longer has the
+	// given status.
+	UpdateWithdrawal(ctx context.Context, wd purse.Withdrawal, from purse.WithdrawalStatus, u user.BetUser) (bool, error)
+	FetchWithdrawal(context.Context, uuid.UUID) (purse.Withdrawal, bool, error)
+	FetchWithdrawalByReference(ctx context.Context, provider, ref string) (purse.Withdrawal, bool, error)
+	FetchUserWithdrawals(context.Context, uuid.UUID) ([]purse.Withdrawal, error)
+	FetchWithdrawalsWithStatus(context.Context, ...purse.WithdrawalStatus) ([]purse.Withdrawal, error)
+	// FetchBalanceAdjustments returns the adjustments of the user, or of
+	// every user if the uuid is nil.
+	FetchBalanceAdjustments(context.Context, uuid.UUID) ([]purse.BalanceAdjustment, error)
+	// FetchUserEntries returns the journal entries of the user's wallet in
+	// chronological order.
+	FetchUserEntries(context.Context, uuid.UUID) ([]purse.Entry, error)
+
+	InsertExchangeRate(context.Context, purse.ExchangeRate) error
+	// FetchExchangeRates returns the rates of the currency, or of every
+	// currency if it is empty, in the order they took effect.
+	FetchExchangeRates(context.Context, purse.Currency) ([]purse.ExchangeRate, error)
 }
 
 type BetDB interface {
@@ -57,9 +142,14 @@ type BetDB interface {
 	FetchEvent(context.Context, uuid.UUID) (bet.Event, bool, error)
 	FetchEventBySelection(context.Context, uuid.UUID) (bet.Event, bool, error)
 	FetchUserBets(context.Context, uuid.UUID) ([]bet.Bet, error)
+	FetchUserAccumulators(context.Context, uuid.UUID) ([]bet.Accumulator, error)
 	UpdateSelection(context.Context, bet.EventSelection) error
 	UpdateEvent(context.Context, bet.Event) error
 
+	FetchBetLimits(context.Context) ([]bet.Limits, error)
+	UpsertBetLimits(context.Context, bet.Limits) error
+	DeleteBetLimits(context.Context, bet.LimitScope, uuid.UUID) error
+
 	InsertAutoBet(context.Context, autobet.AutoBet) error
 	DeleteAutoBet(context.Context, uuid.UUID) error
 	FetchUserAutoBets(context.Context, uuid.UUID) ([]autobet.AutoBet, error)
@@ -72,19 +162,66 @@ type AdminDB interface {
 	FetchAdminLogs(context.Context, uuid.UUID) ([]user.AdminLog, error)
 }
 
+type BonusDB interface {
+	InsertBonusCampaign(context.Context, bonus.Campaign) error
+	FetchBonusCampaign(context.Context, uuid.UUID) (bonus.Campaign, bool, error)
+	FetchBonusCampaigns(context.Context) ([]bonus.Campaign, error)
+	// FetchRunningBonusCampaigns returns the campaigns in the currency that
+	// can be claimed at the given time.
+	FetchRunningBonusCampaigns(context.Context, purse.Currency, time.Time) ([]bonus.Campaign, error)
+	// BonusCampaignClaimed reports whether the user already claimed the
+	// campaign.
+	BonusCampaignClaimed(ctx context.Context, campaignUUID, userUUID uuid.UUID) (bool, error)
+	// InsertBonusGrant stores the grant along with the user, whose bonus
+	// balance was credited with it.
+	InsertBonusGrant(context.Context, user.BetUser, bonus.Grant) error
+	InsertBonusToken(context.Context, bonus.Token) error
+	FetchUserBonusGrants(context.Context, uuid.UUID) ([]bonus.Grant, error)
+	FetchUserBonusTokens(context.Context, uuid.UUID) ([]bonus.Token, error)
+}
+
 type ProfitOpts struct {
 	From time.Time
 	To   time.Time
 }
 
+// ProfitReport holds the profit converted to the base currency at the
+// rates in effect when the bets were placed, and broken down by the
+// currency of the bets.
 type ProfitReport struct {
-	Profit decimal.Decimal `json:"profit"`
-	Loss   decimal.Decimal `json:"loss"`
-	Final  decimal.Decimal `json:"final"`
+	Currency   purse.Currency   `json:"currency"`
+	Profit     decimal.Decimal  `json:"profit"`
+	Loss       decimal.Decimal  `json:"loss"`
+	Final      decimal.Decimal  `json:"final"`
+	ByCurrency []CurrencyProfit `json:"by_currency"`
+}
+
+type CurrencyProfit struct {
+	Currency purse.Currency  `json:"currency"`
+	Profit   decimal.Decimal `json:"profit"`
+	Loss     decimal.Decimal `json:"loss"`
+	Final    decimal.Decimal `json:"final"`
+}
+
+// DepositReport holds the confirmed deposits converted to the base
+// currency at the rates in effect when they were made, and broken down by
+// their currency.
+type DepositReport struct {
+	Currency   purse.Currency   `json:"currency"`
+	Total      decimal.Decimal  `json:"total"`
+	ByCurrency []CurrencyAmount `json:"by_currency"`
+}
+
+type CurrencyAmount struct {
+	Currency purse.Currency  `json:"currency"`
+	Amount   decimal.Decimal `json:"amount"`
 }
 
 type ReportDB interface {
 	InsertAutoReport(context.Context, report.AutoReport) error
 	FetchProfit(context.Context, ProfitOpts) (ProfitReport, error)
+	FetchDepositReport(ctx context.Context, from, to time.Time) (DepositReport, error)
 	FetchBetReport(ctx context.Context, from, to time.Time) ([]bet.Bet, error)
+	FetchAccumulatorReport(ctx context.Context, from, to time.Time) ([]bet.Accumulator, error)
+	FetchDiscrepancyReport(ctx context.Context, from, to time.Time) ([]purse.Discrepancy, error)
 }
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/ramasauskas/ispbet/user"
+	"github.com/shopspring/decimal"
+)
+
+type gamblingLimit struct {
+	Type          user.GamblingLimitType   `json:"type"`
+	Period        user.GamblingLimitPeriod `json:"period"`
+	Amount        decimal.Decimal          `json:"amount"`
+	PendingAmount *decimal.Decimal         `json:"pending_amount,omitempty"`
+	PendingAt     *time.Time               `json:"pending_at,omitempty"`
+	UpdatedAt     time.Time                `json:"updated_at"`
+}
+
+func gamblingLimitView(l user.GamblingLimit) gamblingLimit {
+	v := gamblingLimit{
+		Type:      l.Type,
+		Period:    l.Period,
+		Amount:    l.Amount,
+		UpdatedAt: l.UpdatedAt,
+	}
+
+	if l.Pending {
+		v.PendingAmount = &l.PendingAmount
+		v.PendingAt = &l.PendingAt
+	}
+
+	return v
+}
+
+type gamblingLimitChange struct {
+	UUID        uuid.UUID                `json:"uuid"`
+	UserUUID    uuid.UUID                `json:"user_uuid"`
+	Type        user.GamblingLimitType   `json:"type"`
+	Period      user.GamblingLimitPeriod `json:"period"`
+	OldAmount   decimal.Decimal          `json:"old_amount"`
+	NewAmount   decimal.Decimal          `json:"new_amount"`
+	EffectiveAt time.Time                `json:"effective_at"`
+	CreatedAt   time.Time                `json:"created_at"`
+}
+
+func gamblingLimitChangeView(c user.GamblingLimitChange) gamblingLimitChange {
+	return gamblingLimitChange{
+		UUID:        c.UUID,
+		UserUUID:    c.UserUUID,
+		Type:        c.Type,
+		Period:      c.Period,
+		OldAmount:   c.OldAmount,
+		NewAmount:   c.NewAmount,
+		EffectiveAt: c.EffectiveAt,
+		CreatedAt:   c.CreatedAt,
+	}
+}
+
+var errExcluded = errors.New("user is excluded from betting and depositing")
+
+func gamblingLimitErr(err error) serverErr {
+	return serverErr{
+		Code:      http.StatusBadRequest,
+		Message:   err.Error(),
+		ErrorCode: "gambling_limit_reached",
+	}
+}
+
+func (s *Server) gamblingLimits(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	ctx := r.Context()
+	log := s.logger("gamblingLimits")
+
+	ll, err := s.db.FetchGamblingLimits(ctx, u.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch gambling limits")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	now := time.Now()
+	views := make([]gamblingLimit, 0, len(ll))
+
+	for _, l := range ll {
+		views = append(views, gamblingLimitView(l.Effective(now)))
+	}
+
+	respondJSON(w, http.StatusOK, views)
+}
+
+// setGamblingLimit changes a limit of the user. Stricter limits apply at
+// once, looser ones after the cooling-off period. Every change is recorded.
+func (s *Server) setGamblingLimit(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	var input struct {
+		Type   user.GamblingLimitType   `json:"type"`
+		Period user.GamblingLimitPeriod `json:"period"`
+		Amount decimal.Decimal          `json:"amount"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	l, err := user.NewGamblingLimit(u.UUID, input.Type, input.Period)
+	if err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("setGamblingLimit")
+
+	ll, err := s.db.FetchGamblingLimits(ctx, u.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch gambling limits")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	for _, existing := range ll {
+		if existing.Type == l.Type && existing.Period == l.Period {
+			l = existing
+		}
+	}
+
+	now := time.Now()
+	old := l.Effective(now).Amount
+
+	effective, err := l.Change(input.Amount, s.cfg.GamblingLimitCoolingOff, now)
+	if err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	c := user.GamblingLimitChange{
+		UUID:        uuid.New(),
+		UserUUID:    u.UUID,
+		Type:        l.Type,
+		Period:      l.Period,
+		OldAmount:   old,
+		NewAmount:   input.Amount,
+		EffectiveAt: effective,
+		CreatedAt:   now,
+	}
+
+	if err = s.db.UpdateGamblingLimit(ctx, l, c); err != nil {
+		log.Error().Err(err).Msg("cannot update gambling limit")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondJSON(w, http.StatusOK, gamblingLimitView(l))
+}
+
+func (s *Server) gamblingLimitHistory(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	s.respondGamblingLimitChanges(w, r, u.UUID)
+}
+
+// betUserGamblingLimitChanges lets admins review the limit changes of a
+// bet user.
+func (s *Server) betUserGamblingLimitChanges(w http.ResponseWriter, r *http.Request, _ user.AdminUser) {
+	id, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		respondErr(w, badRequestErr(errors.New("invalid uuid")))
+		return
+	}
+
+	s.respondGamblingLimitChanges(w, r, id)
+}
+
+func (s *Server) respondGamblingLimitChanges(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	ctx := r.Context()
+	log := s.logger("respondGamblingLimitChanges")
+
+	cc, err := s.db.FetchGamblingLimitChanges(ctx, id)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch gambling limit changes")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	views := make([]gamblingLimitChange, 0, len(cc))
+
+	for _, c := range cc {
+		views = append(views, gamblingLimitChangeView(c))
+	}
+
+	respondJSON(w, http.StatusOK, views)
+}
+
+// checkDepositLimits checks the deposit against the deposit limits of the
+// user, over the deposits the user made in each limit's period. It
+// responds by itself and returns false if the deposit is not allowed.
+// The limits are checked again when the deposit is stored, this only
+// spares starting a payment that cannot be accepted.
+func (s *Server) checkDepositLimits(w http.ResponseWriter, r *http.Request, userUUID uuid.UUID, amount decimal.Decimal) bool {
+	ctx := r.Context()
+	log := s.logger("checkDepositLimits")
+
+	ll, err := s.db.FetchGamblingLimits(ctx, userUUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch gambling limits")
+		respondErr(w, internalErr())
+
+		return false
+	}
+
+	dd, err := s.db.FetchUserDeposits(ctx, userUUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch deposits")
+		respondErr(w, internalErr())
+
+		return false
+	}
+
+	if err = user.CheckDepositLimits(ll, dd, amount, time.Now()); err != nil {
+		respondErr(w, gamblingLimitErr(err))
+		return false
+	}
+
+	return true
+}
+
+// exclude blocks the user from betting and depositing for the chosen
+// period. An exclusion in force can only be extended.
+func (s *Server) exclude(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	var input struct {
+		Kind   user.ExclusionKind   `json:"kind"`
+		Period user.ExclusionPeriod `json:"period"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	e, err := user.NewExclusion(u.UUID, input.Kind, input.Period, time.Now())
+	if err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if err = u.Exclude(e); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("exclude")
+
+	if err = s.db.ExcludeBetUser(ctx, u, e); err != nil {
+		log.Error().Err(err).Msg("cannot exclude bet user")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondJSON(w, http.StatusOK, betUserView(u))
+}
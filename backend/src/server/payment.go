@@ -0,0 +1,205 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ramasauskas/ispbet/purse"
+)
+
+// maxCallbackSize limits the body of payment provider callbacks.
+const maxCallbackSize = 1 << 20
+
+func (s *Server) paymentRouter() http.Handler {
+	r := chi.NewRouter()
+
+	r.Post("/callback/{provider}", s.paymentCallback)
+
+	return r
+}
+
+// paymentCallback handles the outcome of a payment reported by its
+// provider. Callbacks for payments that were already handled are
+// acknowledged without changes, as providers repeat them until they
+// succeed.
+func (s *Server) paymentCallback(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+
+	p, ok := s.payments[name]
+	if !ok {
+		respondErr(w, notFoundErr())
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCallbackSize))
+	if err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	log := s.logger("paymentCallback").With().Str("provider", name).Logger()
+
+	ev, err := p.HandleCallback(r.Context(), r.Header, body)
+	if err != nil {
+		if errors.Is(err, purse.ErrInvalidSignature) {
+			log.Warn().Msg("received callback with invalid signature")
+			respondErr(w, unauthorizedErr())
+
+			return
+		}
+
+		respondErr(w, badRequestErr(err))
+
+		return
+	}
+
+	switch ev.Kind {
+	case purse.PaymentKindDeposit:
+		s.depositCallback(w, r, name, ev)
+	case purse.PaymentKindPayout:
+		s.payoutCallback(w, r, name, ev)
+	}
+}
+
+func (s *Server) depositCallback(w http.ResponseWriter, r *http.Request, provider string, ev purse.PaymentEvent) {
+	ctx := r.Context()
+	log := s.logger("depositCallback").With().Str("provider", provider).Str("reference", ev.Reference).Logger()
+
+	d, ok, err := s.db.FetchDepositByReference(ctx, provider, ev.Reference)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch deposit")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, notFoundErr())
+		return
+	}
+
+	if d.Status != purse.DepositStatusPending {
+		respondOK(w)
+		return
+	}
+
+	if !ev.Amount.Equal(d.Amount) {
+		log.Error().Str("amount", ev.Amount.String()).Str("expected", d.Amount.String()).Msg("deposit amount does not match")
+		respondErr(w, badRequestErr(errors.New("deposit amount does not match")))
+
+		return
+	}
+
+	u, ok, err := s.db.FetchBetUserByUUID(ctx, d.UserUUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch bet user")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, notFoundErr())
+		return
+	}
+
+	if ev.Status == purse.PaymentStatusConfirmed {
+		err = d.Confirm()
+		if err == nil {
+			err = u.Credit(d.Amount)
+		}
+	} else {
+		err = d.Fail()
+	}
+
+	if err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	d.UpdatedAt = time.Now()
+
+	// a concurrent callback might have handled the deposit already, which
+	// is acknowledged the same way.
+	if _, err = s.db.UpdateDeposit(ctx, d, purse.DepositStatusPending, u); err != nil {
+		log.Error().Err(err).Msg("cannot update deposit")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondOK(w)
+}
+
+// payoutCallback handles the outcome of a withdrawal payout. The funds of
+// a paid withdrawal already left the user's wallet, so they are credited
+// back if the payout failed.
+func (s *Server) payoutCallback(w http.ResponseWriter, r *http.Request, provider string, ev purse.PaymentEvent) {
+	ctx := r.Context()
+	log := s.logger("payoutCallback").With().Str("provider", provider).Str("reference", ev.Reference).Logger()
+
+	wd, ok, err := s.db.FetchWithdrawalByReference(ctx, provider, ev.Reference)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch withdrawal")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, notFoundErr())
+		return
+	}
+
+	if ev.Status != purse.PaymentStatusFailed {
+		log.Info().Str("withdrawal_uuid", wd.UUID.String()).Msg("withdrawal payout confirmed")
+		respondOK(w)
+
+		return
+	}
+
+	if wd.Status != purse.WithdrawalStatusPaid {
+		respondOK(w)
+		return
+	}
+
+	u, ok, err := s.db.FetchBetUserByUUID(ctx, wd.UserUUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch bet user")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, notFoundErr())
+		return
+	}
+
+	if err = wd.Fail(); err == nil {
+		err = u.Credit(wd.Amount)
+	}
+
+	if err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	wd.UpdatedAt = time.Now()
+
+	// a concurrent callback might have handled the payout already, which
+	// is acknowledged the same way.
+	if _, err = s.db.UpdateWithdrawal(ctx, wd, purse.WithdrawalStatusPaid, u); err != nil {
+		log.Error().Err(err).Msg("cannot update withdrawal")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	log.Warn().Str("withdrawal_uuid", wd.UUID.String()).Msg("withdrawal payout failed, funds returned")
+
+	respondOK(w)
+}
@@ -0,0 +1,352 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/ramasauskas/ispbet/bet"
+	"github.com/ramasauskas/ispbet/purse"
+	"github.com/ramasauskas/ispbet/user"
+)
+
+// userData holds everything stored about a bet user, as handed out on
+// request of the user.
+type userData struct {
+	User                  betUser                `json:"user"`
+	IdentityVerifications []identityVerification `json:"identity_verifications"`
+	Bets                  []userBet              `json:"bets"`
+	Deposits              []deposit              `json:"deposits"`
+	Withdrawals           []withdrawal           `json:"withdrawals"`
+	AutoBets              []autoBet              `json:"auto_bets"`
+	ExportedAt            time.Time              `json:"exported_at"`
+}
+
+func (s *Server) userData(ctx context.Context, bu user.BetUser) (userData, error) {
+	data := userData{
+		User:                  betUserView(bu),
+		IdentityVerifications: make([]identityVerification, 0),
+		Deposits:              make([]deposit, 0),
+		Withdrawals:           make([]withdrawal, 0),
+		AutoBets:              make([]autoBet, 0),
+		ExportedAt:            time.Now(),
+	}
+
+	vv, err := s.db.FetchUserIdentityVerifications(ctx, bu.UUID)
+	if err != nil {
+		return userData{}, err
+	}
+
+	for _, v := range vv {
+		data.IdentityVerifications = append(data.IdentityVerifications, identityVerificationView(v, data.User))
+	}
+
+	if data.Bets, err = s.userBetViews(ctx, bu.UUID); err != nil {
+		return userData{}, err
+	}
+
+	dd, err := s.db.FetchUserDeposits(ctx, bu.UUID)
+	if err != nil {
+		return userData{}, err
+	}
+
+	for _, d := range dd {
+		data.Deposits = append(data.Deposits, depositView(d))
+	}
+
+	ww, err := s.db.FetchUserWithdrawals(ctx, bu.UUID)
+	if err != nil {
+		return userData{}, err
+	}
+
+	for _, wd := range ww {
+		data.Withdrawals = append(data.Withdrawals, withdrawalView(wd))
+	}
+
+	bb, err := s.db.FetchUserAutoBets(ctx, bu.UUID)
+	if err != nil {
+		return userData{}, err
+	}
+
+	for _, b := range bb {
+		data.AutoBets = append(data.AutoBets, autoBetView(b))
+	}
+
+	return data, nil
+}
+
+// zip archives the data with a JSON file for each of its parts.
+func (d userData) zip() ([]byte, error) {
+	files := []struct {
+		name string
+		data any
+	}{
+		{"user.json", d.User},
+		{"identity_verifications.json", d.IdentityVerifications},
+		{"bets.json", d.Bets},
+		{"deposits.json", d.Deposits},
+		{"withdrawals.json", d.Withdrawals},
+		{"auto_bets.json", d.AutoBets},
+	}
+
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: d.ExportedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "\t")
+
+		if err = enc.Encode(f.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *Server) exportUserData(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	s.respondUserData(w, r, u)
+}
+
+// exportBetUserData lets admins answer the data requests of bet users.
+func (s *Server) exportBetUserData(w http.ResponseWriter, r *http.Request, _ user.AdminUser) {
+	bu, ok := s.betUserParam(w, r)
+	if !ok {
+		return
+	}
+
+	s.respondUserData(w, r, bu)
+}
+
+// respondUserData responds with the data of the user as a JSON file, or as
+// a ZIP archive if the zip format is asked for.
+func (s *Server) respondUserData(w http.ResponseWriter, r *http.Request, bu user.BetUser) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		respondErr(w, badRequestErr(errors.New("invalid format")))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("respondUserData")
+
+	data, err := s.userData(ctx, bu)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch user data")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if format != "zip" {
+		w.Header().Add("Content-Disposition", `attachment; filename="user-data.json"`)
+		respondJSON(w, http.StatusOK, data)
+
+		return
+	}
+
+	archive, err := data.zip()
+	if err != nil {
+		log.Error().Err(err).Msg("cannot archive user data")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	w.Header().Add("Content-type", "application/zip")
+	w.Header().Add("Content-Disposition", `attachment; filename="user-data.zip"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
+
+// closeAccount erases the personal data of the user, who has to confirm it
+// with the password.
+func (s *Server) closeAccount(w http.ResponseWriter, r *http.Request, u user.BetUser) {
+	var input struct {
+		Password string `json:"password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	if !u.Login(input.Password) {
+		respondErr(w, badRequestErr(errors.New("invalid password")))
+		return
+	}
+
+	if _, ok := s.eraseBetUser(w, r, u); !ok {
+		return
+	}
+
+	respondOK(w)
+}
+
+// eraseBetUserData lets admins answer the erasure requests of bet users.
+func (s *Server) eraseBetUserData(w http.ResponseWriter, r *http.Request, _ user.AdminUser) {
+	bu, ok := s.betUserParam(w, r)
+	if !ok {
+		return
+	}
+
+	bu, ok = s.eraseBetUser(w, r, bu)
+	if !ok {
+		return
+	}
+
+	respondJSON(w, http.StatusOK, betUserView(bu))
+}
+
+// eraseBetUser anonymizes the user and signs the user out everywhere. Bets,
+// payments and the wallet journal are kept for legal retention, so the user
+// must have no money or bets outstanding. It returns the erased user, or
+// responds by itself and returns false on failure.
+func (s *Server) eraseBetUser(w http.ResponseWriter, r *http.Request, bu user.BetUser) (user.BetUser, bool) {
+	ctx := r.Context()
+	log := s.logger("eraseBetUser")
+
+	bb, err := s.db.FetchUserBets(ctx, bu.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch bets")
+		respondErr(w, internalErr())
+
+		return user.BetUser{}, false
+	}
+
+	accs, err := s.db.FetchUserAccumulators(ctx, bu.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch accumulators")
+		respondErr(w, internalErr())
+
+		return user.BetUser{}, false
+	}
+
+	ww, err := s.db.FetchUserWithdrawals(ctx, bu.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch withdrawals")
+		respondErr(w, internalErr())
+
+		return user.BetUser{}, false
+	}
+
+	dd, err := s.db.FetchUserDeposits(ctx, bu.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch deposits")
+		respondErr(w, internalErr())
+
+		return user.BetUser{}, false
+	}
+
+	if err = outstanding(bb, accs, ww, dd); err != nil {
+		respondErr(w, badRequestErr(err))
+		return user.BetUser{}, false
+	}
+
+	// The account lockout of the user is keyed by the address, which is
+	// gone once the user is erased.
+	throttleKey := loginEmailKey(bu.Email)
+
+	if err = bu.Erase(); err != nil {
+		respondErr(w, badRequestErr(err))
+		return user.BetUser{}, false
+	}
+
+	if err = s.db.EraseBetUser(ctx, bu); err != nil {
+		log.Error().Err(err).Msg("cannot erase bet user")
+		respondErr(w, internalErr())
+
+		return user.BetUser{}, false
+	}
+
+	if err = s.db.DeleteLoginThrottle(ctx, user.ThrottleScopeAccount, throttleKey); err != nil {
+		log.Error().Err(err).Msg("cannot delete login throttle")
+	}
+
+	if err = s.sessions.RevokeByUserKey(ctx, bu.UUID.String()); err != nil {
+		log.Error().Err(err).Msg("cannot revoke sessions")
+		respondErr(w, internalErr())
+
+		return user.BetUser{}, false
+	}
+
+	return bu, true
+}
+
+// outstanding returns an error if any of the bets is not settled yet or
+// any of the withdrawals or deposits is not finished.
+func outstanding(bb []bet.Bet, accs []bet.Accumulator, ww []purse.Withdrawal, dd []purse.Deposit) error {
+	for _, b := range bb {
+		if b.State == bet.BetStateTBD {
+			return errors.New("account has open bets")
+		}
+	}
+
+	for _, acc := range accs {
+		if acc.State == bet.BetStateTBD {
+			return errors.New("account has open bets")
+		}
+	}
+
+	for _, wd := range ww {
+		if wd.Reserved() {
+			return errors.New("account has withdrawals in progress")
+		}
+	}
+
+	for _, d := range dd {
+		if d.Status == purse.DepositStatusPending {
+			return errors.New("account has deposits in progress")
+		}
+	}
+
+	return nil
+}
+
+// betUserParam fetches the bet user of the uuid in the URL. It responds by
+// itself and returns false if there is no such user.
+func (s *Server) betUserParam(w http.ResponseWriter, r *http.Request) (user.BetUser, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		respondErr(w, badRequestErr(errors.New("invalid uuid")))
+		return user.BetUser{}, false
+	}
+
+	ctx := r.Context()
+	log := s.logger("betUserParam")
+
+	bu, ok, err := s.db.FetchBetUserByUUID(ctx, id)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch bet user")
+		respondErr(w, internalErr())
+
+		return user.BetUser{}, false
+	}
+
+	if !ok {
+		respondErr(w, notFoundErr())
+		return user.BetUser{}, false
+	}
+
+	return bu, true
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/ramasauskas/ispbet/bet"
+	"github.com/ramasauskas/ispbet/purse"
+)
+
+func TestOutstanding(t *testing.T) {
+	tests := map[string]struct {
+		bets        []bet.Bet
+		accs        []bet.Accumulator
+		withdrawals []purse.Withdrawal
+		deposits    []purse.Deposit
+		err         string
+	}{
+		"nothing outstanding": {
+			bets:        []bet.Bet{{State: bet.BetStateWon}},
+			accs:        []bet.Accumulator{{State: bet.BetStateLost}},
+			withdrawals: []purse.Withdrawal{{Status: purse.WithdrawalStatusPaid}},
+			deposits:    []purse.Deposit{{Status: purse.DepositStatusConfirmed}, {Status: purse.DepositStatusFailed}},
+		},
+		"open bet": {
+			bets: []bet.Bet{{State: bet.BetStateTBD}},
+			err:  "account has open bets",
+		},
+		"open accumulator": {
+			accs: []bet.Accumulator{{State: bet.BetStateTBD}},
+			err:  "account has open bets",
+		},
+		"withdrawal being paid out": {
+			withdrawals: []purse.Withdrawal{{Status: purse.WithdrawalStatusProcessing}},
+			err:         "account has withdrawals in progress",
+		},
+		"pending deposit": {
+			deposits: []purse.Deposit{{Status: purse.DepositStatusPending}},
+			err:      "account has deposits in progress",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := outstanding(test.bets, test.accs, test.withdrawals, test.deposits)
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("want no error, got %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || err.Error() != test.err {
+				t.Fatalf("want error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
@@ -10,16 +10,36 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
+	"github.com/ramasauskas/ispbet/purse"
 	"github.com/rs/zerolog"
+	"github.com/shopspring/decimal"
 	"github.com/swithek/sessionup"
 )
 
 type serverErr struct {
-	Code    int    `json:"-"`
-	Message string `json:"message"`
+	Code      int    `json:"-"`
+	Message   string `json:"message"`
+	ErrorCode string `json:"code,omitempty"`
+}
+
+// Config holds the settings of the server.
+type Config struct {
+	// FourEyesWithdrawalAmount is the amount above which withdrawals
+	// need the approval of two admins. Zero disables the second approval.
+	FourEyesWithdrawalAmount decimal.Decimal
+	// BaseCurrency is the currency of new wallets unless users choose
+	// another one, reports are converted to it.
+	BaseCurrency purse.Currency
+	// AdminTwoFactorRequired makes admins enroll in and always pass
+	// two-factor authentication when logging in.
+	AdminTwoFactorRequired bool
+	// GamblingLimitCoolingOff is how long users wait for their gambling
+	// limits to be raised or removed.
+	GamblingLimitCoolingOff time.Duration
 }
 
 type Server struct {
+	cfg      Config
 	srv      *http.Server
 	db       DB
 	log      zerolog.Logger
@@ -27,16 +47,23 @@ type Server struct {
 	resolver Resolver
 	sessions *sessionup.Manager
 	email    EmailSender
+	payments map[string]purse.PaymentProvider
+
+	challengesMu sync.Mutex
+	challenges   map[string]loginChallenge
+	throttleMu   sync.Mutex
 
 	wg sync.WaitGroup
 }
 
 func NewServer(
 	port uint,
+	cfg Config,
 	sessionStore sessionup.Store,
 	better Better,
 	resolver Resolver,
 	email EmailSender,
+	payments []purse.PaymentProvider,
 	db DB,
 	log zerolog.Logger,
 ) *Server {
@@ -46,14 +73,22 @@ func NewServer(
 
 	sessions := sessionup.NewManager(sessionStore)
 
+	providers := make(map[string]purse.PaymentProvider, len(payments))
+	for _, p := range payments {
+		providers[p.Name()] = p
+	}
+
 	return &Server{
-		srv:      srv,
-		log:      log,
-		db:       db,
-		sessions: sessions,
-		email:    email,
-		better:   better,
-		resolver: resolver,
+		cfg:        cfg,
+		srv:        srv,
+		log:        log,
+		db:         db,
+		sessions:   sessions,
+		email:      email,
+		payments:   providers,
+		challenges: make(map[string]loginChallenge),
+		better:     better,
+		resolver:   resolver,
 	}
 }
 
@@ -75,6 +110,7 @@ func (s *Server) Run() error {
 	r.Mount("/user", s.userRouter())
 	r.Mount("/admin", s.adminRouter())
 	r.Mount("/betting", s.betRouter())
+	r.Mount("/payments", s.paymentRouter())
 
 	s.srv.Handler = r
 
@@ -144,6 +180,14 @@ func badRequestErr(err error) serverErr {
 	}
 }
 
+func betResponseErr(resp BetResponse) serverErr {
+	return serverErr{
+		Code:      http.StatusBadRequest,
+		Message:   resp.ErrorMessage,
+		ErrorCode: resp.ErrorCode,
+	}
+}
+
 func unauthorizedErr() serverErr {
 	return serverErr{
 		Code:    http.StatusUnauthorized,
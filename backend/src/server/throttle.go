@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ramasauskas/ispbet/user"
+)
+
+var (
+	accountThrottle = user.ThrottlePolicy{
+		MaxFailures:     5,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutDuration: time.Minute * 15,
+	}
+	// ipThrottle only locks out, after more failures, as many users may
+	// share an address.
+	ipThrottle = user.ThrottlePolicy{
+		MaxFailures:     20,
+		LockoutDuration: time.Minute * 15,
+	}
+)
+
+// dummyUser is checked against when the email is unknown, so that the
+// response takes as long as it does for a wrong password.
+var dummyUser = func() user.User {
+	var u user.User
+	u.SetPassword("password")
+
+	return u
+}()
+
+type lockoutEvent struct {
+	UUID        uuid.UUID          `json:"uuid"`
+	Scope       user.ThrottleScope `json:"scope"`
+	Key         string             `json:"key"`
+	UserUUID    *uuid.UUID         `json:"user_uuid,omitempty"`
+	IP          string             `json:"ip"`
+	LockedUntil time.Time          `json:"locked_until"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+func lockoutEventView(le user.LockoutEvent) lockoutEvent {
+	var userUUID *uuid.UUID
+	if le.UserUUID != uuid.Nil {
+		userUUID = &le.UserUUID
+	}
+
+	return lockoutEvent{
+		UUID:        le.UUID,
+		Scope:       le.Scope,
+		Key:         le.Key,
+		UserUUID:    userUUID,
+		IP:          le.IP,
+		LockedUntil: le.LockedUntil,
+		CreatedAt:   le.CreatedAt,
+	}
+}
+
+// invalidLoginErr is the same for unknown emails and wrong passwords, so
+// that logins cannot be used to find out registered emails.
+func invalidLoginErr() serverErr {
+	return badRequestErr(errors.New("invalid email or password"))
+}
+
+func throttledErr() serverErr {
+	return serverErr{
+		Code:    http.StatusTooManyRequests,
+		Message: "too many failed logins, try again later",
+	}
+}
+
+func loginEmailKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// checkLoginThrottle responds and reports false if the email or the
+// address of the request has to wait before trying to log in again.
+func (s *Server) checkLoginThrottle(w http.ResponseWriter, r *http.Request, email string) bool {
+	ctx := r.Context()
+	log := s.logger("checkLoginThrottle")
+	now := time.Now()
+
+	var wait time.Duration
+
+	for _, c := range []struct {
+		scope  user.ThrottleScope
+		key    string
+		policy user.ThrottlePolicy
+	}{
+		{user.ThrottleScopeAccount, loginEmailKey(email), accountThrottle},
+		{user.ThrottleScopeIP, remoteIP(r), ipThrottle},
+	} {
+		th, ok, err := s.db.FetchLoginThrottle(ctx, c.scope, c.key)
+		if err != nil {
+			log.Error().Err(err).Msg("cannot fetch login throttle")
+			respondErr(w, internalErr())
+
+			return false
+		}
+
+		if !ok {
+			continue
+		}
+
+		if d := c.policy.Wait(th, now); d > wait {
+			wait = d
+		}
+	}
+
+	if wait == 0 {
+		return true
+	}
+
+	w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+	respondErr(w, throttledErr())
+
+	return false
+}
+
+// loginFailed counts the failed login against the email and the address
+// of the request, recording a lockout event for each one it locks out.
+func (s *Server) loginFailed(ctx context.Context, r *http.Request, email string, userUUID uuid.UUID) error {
+	s.throttleMu.Lock()
+	defer s.throttleMu.Unlock()
+
+	now := time.Now()
+	ip := remoteIP(r)
+
+	for _, c := range []struct {
+		scope  user.ThrottleScope
+		key    string
+		policy user.ThrottlePolicy
+	}{
+		{user.ThrottleScopeAccount, loginEmailKey(email), accountThrottle},
+		{user.ThrottleScopeIP, ip, ipThrottle},
+	} {
+		th, ok, err := s.db.FetchLoginThrottle(ctx, c.scope, c.key)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			th = user.LoginThrottle{
+				Scope: c.scope,
+				Key:   c.key,
+			}
+		}
+
+		locked := c.policy.Fail(&th, now)
+
+		if err = s.db.UpsertLoginThrottle(ctx, th); err != nil {
+			return err
+		}
+
+		if !locked {
+			continue
+		}
+
+		le := user.LockoutEvent{
+			UUID:        uuid.New(),
+			Scope:       c.scope,
+			Key:         c.key,
+			IP:          ip,
+			LockedUntil: th.LockedUntil,
+			CreatedAt:   now,
+		}
+
+		if c.scope == user.ThrottleScopeAccount {
+			le.UserUUID = userUUID
+		}
+
+		if err = s.db.InsertLockoutEvent(ctx, le); err != nil {
+			return err
+		}
+
+		log := s.logger("loginFailed")
+		log.Warn().Str("scope", string(c.scope)).Str("key", c.key).Msg("locked out")
+	}
+
+	return nil
+}
+
+// loginSucceeded forgets the failed logins of the email. Those of the
+// address are kept, so that one known password cannot clear them.
+func (s *Server) loginSucceeded(ctx context.Context, email string) error {
+	return s.db.DeleteLoginThrottle(ctx, user.ThrottleScopeAccount, loginEmailKey(email))
+}
+
+func (s *Server) lockoutEvents(w http.ResponseWriter, r *http.Request, _ user.AdminUser) {
+	ctx := r.Context()
+	log := s.logger("lockoutEvents")
+
+	ee, err := s.db.FetchLockoutEvents(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch lockout events")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	views := make([]lockoutEvent, 0, len(ee))
+
+	for _, le := range ee {
+		views = append(views, lockoutEventView(le))
+	}
+
+	respondJSON(w, http.StatusOK, views)
+}
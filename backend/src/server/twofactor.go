@@ -0,0 +1,433 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ramasauskas/ispbet/user"
+)
+
+const (
+	twoFactorIssuer = "ispbet"
+	// loginChallengeTTL is how long a login that passed the password
+	// check waits for the second factor.
+	loginChallengeTTL = time.Minute * 5
+	// loginChallengeAttempts is how many wrong codes a login challenge
+	// takes before it is dropped.
+	loginChallengeAttempts = 5
+	recoveryCodeCount      = 10
+)
+
+// loginChallenge is a login that passed the password check and waits for
+// the second factor.
+type loginChallenge struct {
+	userUUID  uuid.UUID
+	email     string
+	attempts  int
+	expiresAt time.Time
+}
+
+type twoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+func twoFactorEnrollmentView(tf user.TwoFactor, email string) twoFactorEnrollment {
+	return twoFactorEnrollment{
+		Secret: tf.Secret,
+		URI:    tf.URI(twoFactorIssuer, email),
+	}
+}
+
+type twoFactorChallenge struct {
+	Token      string               `json:"two_factor_token"`
+	Enrollment *twoFactorEnrollment `json:"enrollment,omitempty"`
+}
+
+func (s *Server) newLoginChallenge(u user.User) (string, error) {
+	tok, err := randomTextToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	s.challengesMu.Lock()
+	defer s.challengesMu.Unlock()
+
+	for t, c := range s.challenges {
+		if !now.Before(c.expiresAt) {
+			delete(s.challenges, t)
+		}
+	}
+
+	s.challenges[tok] = loginChallenge{
+		userUUID:  u.UUID,
+		email:     u.Email,
+		expiresAt: now.Add(loginChallengeTTL),
+	}
+
+	return tok, nil
+}
+
+// startLogin signs in the user that passed the password check, unless
+// the user has to pass the second factor first. In that case a challenge
+// to finish the login with is returned instead, along with a new secret
+// if the user has to enroll first.
+func (s *Server) startLogin(w http.ResponseWriter, r *http.Request, u user.User, required bool, view any) {
+	ctx := r.Context()
+	log := s.logger("startLogin")
+
+	tf, ok, err := s.db.FetchTwoFactor(ctx, u.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch two-factor")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	enabled := ok && tf.Enabled
+
+	if !enabled && !required {
+		if err = s.loginSucceeded(ctx, u.Email); err != nil {
+			log.Error().Err(err).Msg("cannot reset login throttle")
+			respondErr(w, internalErr())
+
+			return
+		}
+
+		if err = s.sessions.Init(w, r, u.UUID.String()); err != nil {
+			log.Error().Err(err).Msg("cannot initialize session")
+			respondErr(w, internalErr())
+
+			return
+		}
+
+		respondJSON(w, http.StatusOK, view)
+
+		return
+	}
+
+	var ch twoFactorChallenge
+
+	if !enabled {
+		if tf, err = user.NewTwoFactor(u.UUID, time.Now()); err != nil {
+			log.Error().Err(err).Msg("cannot create two-factor")
+			respondErr(w, internalErr())
+
+			return
+		}
+
+		if err = s.db.UpsertTwoFactor(ctx, tf); err != nil {
+			log.Error().Err(err).Msg("cannot upsert two-factor")
+			respondErr(w, internalErr())
+
+			return
+		}
+
+		enr := twoFactorEnrollmentView(tf, u.Email)
+		ch.Enrollment = &enr
+	}
+
+	if ch.Token, err = s.newLoginChallenge(u); err != nil {
+		log.Error().Err(err).Msg("cannot create login challenge")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, ch)
+}
+
+// passLoginChallenge checks the second factor of a challenged login and
+// initializes the session. A login that enrolled the user returns the
+// new recovery codes. It responds by itself on failure.
+func (s *Server) passLoginChallenge(w http.ResponseWriter, r *http.Request) (uuid.UUID, []string, bool) {
+	var input struct {
+		Token string `json:"two_factor_token"`
+		Code  string `json:"code"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return uuid.Nil, nil, false
+	}
+
+	ctx := r.Context()
+	log := s.logger("passLoginChallenge")
+
+	s.challengesMu.Lock()
+	ch, ok := s.challenges[input.Token]
+	if ok && (!time.Now().Before(ch.expiresAt) || ch.attempts >= loginChallengeAttempts) {
+		delete(s.challenges, input.Token)
+		ok = false
+	}
+	s.challengesMu.Unlock()
+
+	if !ok {
+		respondErr(w, unauthorizedErr())
+		return uuid.Nil, nil, false
+	}
+
+	tf, ok, err := s.db.FetchTwoFactor(ctx, ch.userUUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch two-factor")
+		respondErr(w, internalErr())
+
+		return uuid.Nil, nil, false
+	}
+
+	if !ok {
+		respondErr(w, unauthorizedErr())
+		return uuid.Nil, nil, false
+	}
+
+	var codes []string
+
+	if tf.Enabled {
+		ok, err = s.checkSecondFactor(ctx, tf, input.Code)
+	} else {
+		codes, ok, err = s.enableTwoFactor(ctx, tf, input.Code)
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg("cannot check second factor")
+		respondErr(w, internalErr())
+
+		return uuid.Nil, nil, false
+	}
+
+	s.challengesMu.Lock()
+	if ok {
+		delete(s.challenges, input.Token)
+	} else if c, found := s.challenges[input.Token]; found {
+		c.attempts++
+		s.challenges[input.Token] = c
+	}
+	s.challengesMu.Unlock()
+
+	if !ok {
+		// wrong codes count as failed logins, so that the second factor
+		// cannot be guessed over many challenges
+		if err = s.loginFailed(ctx, r, ch.email, ch.userUUID); err != nil {
+			log.Error().Err(err).Msg("cannot record failed login")
+			respondErr(w, internalErr())
+
+			return uuid.Nil, nil, false
+		}
+
+		respondErr(w, badRequestErr(errors.New("invalid code")))
+
+		return uuid.Nil, nil, false
+	}
+
+	if err = s.loginSucceeded(ctx, ch.email); err != nil {
+		log.Error().Err(err).Msg("cannot reset login throttle")
+		respondErr(w, internalErr())
+
+		return uuid.Nil, nil, false
+	}
+
+	if err = s.sessions.Init(w, r, ch.userUUID.String()); err != nil {
+		log.Error().Err(err).Msg("cannot initialize session")
+		respondErr(w, internalErr())
+
+		return uuid.Nil, nil, false
+	}
+
+	return ch.userUUID, codes, true
+}
+
+// checkSecondFactor accepts a TOTP code or an unused recovery code of the
+// user with enabled two-factor authentication.
+func (s *Server) checkSecondFactor(ctx context.Context, tf user.TwoFactor, code string) (bool, error) {
+	if err := tf.Verify(code, time.Now()); err == nil {
+		return s.db.UpdateTwoFactorStep(ctx, tf)
+	}
+
+	return s.db.UseRecoveryCode(ctx, tf.UserUUID, user.HashRecoveryCode(code))
+}
+
+// enableTwoFactor confirms the secret of the user with the code and
+// issues new recovery codes, reporting false if the code is wrong.
+func (s *Server) enableTwoFactor(ctx context.Context, tf user.TwoFactor, code string) ([]string, bool, error) {
+	now := time.Now()
+
+	if err := tf.Enable(code, now); err != nil {
+		return nil, false, nil
+	}
+
+	codes, hashed, err := user.NewRecoveryCodes(tf.UserUUID, recoveryCodeCount, now)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err = s.db.EnableTwoFactor(ctx, tf, hashed); err != nil {
+		return nil, false, err
+	}
+
+	return codes, true, nil
+}
+
+// enrollTwoFactor creates a new secret for the user to add to an
+// authenticator app. It takes effect once confirmed with a code.
+func (s *Server) enrollTwoFactor(w http.ResponseWriter, r *http.Request, u user.User) {
+	ctx := r.Context()
+	log := s.logger("enrollTwoFactor")
+
+	tf, ok, err := s.db.FetchTwoFactor(ctx, u.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch two-factor")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if ok && tf.Enabled {
+		respondErr(w, badRequestErr(errors.New("two-factor authentication already enabled")))
+		return
+	}
+
+	if tf, err = user.NewTwoFactor(u.UUID, time.Now()); err != nil {
+		log.Error().Err(err).Msg("cannot create two-factor")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if err = s.db.UpsertTwoFactor(ctx, tf); err != nil {
+		log.Error().Err(err).Msg("cannot upsert two-factor")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, twoFactorEnrollmentView(tf, u.Email))
+}
+
+func (s *Server) confirmTwoFactor(w http.ResponseWriter, r *http.Request, u user.User) {
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("confirmTwoFactor")
+
+	tf, ok, err := s.db.FetchTwoFactor(ctx, u.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch two-factor")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, notFoundErr())
+		return
+	}
+
+	if tf.Enabled {
+		respondErr(w, badRequestErr(errors.New("two-factor authentication already enabled")))
+		return
+	}
+
+	codes, ok, err := s.enableTwoFactor(ctx, tf, input.Code)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot enable two-factor")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, badRequestErr(errors.New("invalid code")))
+		return
+	}
+
+	respondJSON(w, http.StatusOK, struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{
+		RecoveryCodes: codes,
+	})
+}
+
+// disableTwoFactor turns two-factor authentication off, which admins
+// cannot do when it is mandatory for them.
+func (s *Server) disableTwoFactor(w http.ResponseWriter, r *http.Request, u user.User) {
+	var input struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("disableTwoFactor")
+
+	if s.cfg.AdminTwoFactorRequired {
+		_, admin, err := s.db.FetchAdminUserByUUID(ctx, u.UUID)
+		if err != nil {
+			log.Error().Err(err).Msg("cannot fetch admin user")
+			respondErr(w, internalErr())
+
+			return
+		}
+
+		if admin {
+			respondErr(w, badRequestErr(errors.New("two-factor authentication is mandatory for admins")))
+			return
+		}
+	}
+
+	if !u.Login(input.Password) {
+		respondErr(w, badRequestErr(errors.New("invalid password")))
+		return
+	}
+
+	tf, ok, err := s.db.FetchTwoFactor(ctx, u.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch two-factor")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok || !tf.Enabled {
+		respondErr(w, badRequestErr(errors.New("two-factor authentication not enabled")))
+		return
+	}
+
+	ok, err = s.checkSecondFactor(ctx, tf, input.Code)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot check second factor")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, badRequestErr(errors.New("invalid code")))
+		return
+	}
+
+	if err = s.db.DeleteTwoFactor(ctx, u.UUID); err != nil {
+		log.Error().Err(err).Msg("cannot delete two-factor")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondOK(w)
+}
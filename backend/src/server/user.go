@@ -3,40 +3,79 @@ package server
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/ramasauskas/ispbet/user"
+	"github.com/swithek/sessionup"
 )
 
 func (s *Server) userRouter() http.Handler {
 	r := chi.NewRouter()
 
-	r.Use(s.sessions.Auth)
+	r.Post("/forgot-password", s.requestPasswordReset)
+	r.Post("/reset-password", s.resetPassword)
 
-	r.Post("/logout", s.logout)
-	r.Post("/verify-email/{token}", s.withUser(s.confirmEmail))
+	r.Group(func(r chi.Router) {
+		r.Use(s.sessions.Auth)
+
+		r.Post("/logout", s.logout)
+		r.Get("/sessions", s.userSessions)
+		r.Delete("/sessions/{handle}", s.revokeSession)
+		r.Post("/verify-email/{token}", s.withUser(s.confirmEmail))
+		r.Post("/resend-verification", s.withUser(s.resendEmailVerification))
+		r.Post("/two-factor", s.withUser(s.enrollTwoFactor))
+		r.Post("/two-factor/confirm", s.withUser(s.confirmTwoFactor))
+		r.Delete("/two-factor", s.withUser(s.disableTwoFactor))
+	})
 
 	return r
 }
 
-func (s *Server) sendEmailVerification(ctx context.Context, u user.User) error {
-	tok, err := randomTextToken(6)
+const (
+	// passwordResetTTL is how long an emailed password reset token can be
+	// used for.
+	passwordResetTTL = time.Hour
+	// emailVerificationTTL is how long an emailed verification token can be
+	// used for.
+	emailVerificationTTL = time.Hour * 24
+	// emailVerificationResendInterval is how long users wait before
+	// another verification token can be sent to them.
+	emailVerificationResendInterval = time.Minute * 2
+)
+
+// sendEmailVerification sends a verification token to the email, which
+// becomes the user's address once verified. The older tokens of the user
+// can no longer be used.
+func (s *Server) sendEmailVerification(ctx context.Context, u user.User, email string) error {
+	tok, err := randomTextToken(32)
 	if err != nil {
 		return err
 	}
 
+	now := time.Now()
+
 	ver := user.EmailVerification{
-		UserUUID: u.UUID,
-		Token:    tok,
+		UserUUID:  u.UUID,
+		Token:     tok,
+		Email:     email,
+		ExpiresAt: now.Add(emailVerificationTTL),
+		CreatedAt: now,
 	}
 
 	if err := s.db.InsertEmailVerification(ctx, ver); err != nil {
 		return err
 	}
 
-	return s.email.SendEmail(context.Background(), u.Email, tok)
+	return s.email.SendEmail(context.Background(), email, tok)
 }
 
 func (s *Server) confirmEmail(w http.ResponseWriter, r *http.Request, u user.User) {
@@ -62,11 +101,23 @@ func (s *Server) confirmEmail(w http.ResponseWriter, r *http.Request, u user.Use
 		return
 	}
 
-	if err := user.VerifyUserEmail(&u, &ve); err != nil {
+	if err := user.VerifyUserEmail(&u, &ve, time.Now()); err != nil {
 		respondErr(w, badRequestErr(err))
 		return
 	}
 
+	other, ok, err := s.db.FetchUserByEmail(ctx, u.Email)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch user")
+		respondErr(w, internalErr())
+		return
+	}
+
+	if ok && other.UUID != u.UUID {
+		respondErr(w, badRequestErr(errors.New("user already exists with provided email")))
+		return
+	}
+
 	if err := s.db.InsertUserVerification(ctx, u, ve); err != nil {
 		log.Error().Err(err).Msg("cannot insert user verification")
 		respondErr(w, internalErr())
@@ -76,6 +127,272 @@ func (s *Server) confirmEmail(w http.ResponseWriter, r *http.Request, u user.Use
 	respondOK(w)
 }
 
+// resendEmailVerification sends a new verification token to the address
+// the user is verifying, at most once per emailVerificationResendInterval.
+func (s *Server) resendEmailVerification(w http.ResponseWriter, r *http.Request, u user.User) {
+	ctx := r.Context()
+	log := s.logger("resendEmailVerification")
+
+	if u.EmailVerified {
+		respondErr(w, badRequestErr(errors.New("email already verified")))
+		return
+	}
+
+	email := u.Email
+
+	last, ok, err := s.db.FetchLatestEmailVerification(ctx, u.UUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch email verification")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if ok {
+		if wait := time.Until(last.CreatedAt.Add(emailVerificationResendInterval)); wait > 0 {
+			w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+			respondErr(w, serverErr{
+				Code:    http.StatusTooManyRequests,
+				Message: "verification email sent recently, try again later",
+			})
+
+			return
+		}
+
+		if last.Email != "" {
+			email = last.Email
+		}
+	}
+
+	if err = s.sendEmailVerification(ctx, u, email); err != nil {
+		log.Error().Err(err).Msg("cannot send email verification")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondOK(w)
+}
+
+// requestPasswordReset emails a password reset token to the user. It
+// responds the same whether the user exists or not, so that it cannot be
+// used to find out registered emails.
+func (s *Server) requestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+
+		log := s.logger("requestPasswordReset:defer")
+
+		if err := s.sendPasswordReset(ctx, input.Email); err != nil {
+			log.Err(err).Msg("cannot send password reset")
+		}
+	}()
+
+	respondOK(w)
+}
+
+func (s *Server) sendPasswordReset(ctx context.Context, email string) error {
+	u, ok, err := s.db.FetchUserByEmail(ctx, email)
+	if err != nil || !ok {
+		return err
+	}
+
+	tok, err := randomTextToken(32)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	pr := user.PasswordReset{
+		UserUUID:  u.UUID,
+		Token:     tok,
+		ExpiresAt: now.Add(passwordResetTTL),
+		CreatedAt: now,
+	}
+
+	if err := s.db.InsertPasswordReset(ctx, pr); err != nil {
+		return err
+	}
+
+	return s.email.SendEmail(ctx, u.Email, tok)
+}
+
+// resetPassword sets a new password with an emailed token and signs the
+// user out everywhere.
+func (s *Server) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ctx := r.Context()
+	log := s.logger("resetPassword")
+
+	pr, ok, err := s.db.FetchPasswordReset(ctx, input.Token)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch password reset")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, badRequestErr(errors.New("invalid token")))
+		return
+	}
+
+	u, ok, err := s.db.FetchUserByUUID(ctx, pr.UserUUID)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch user")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, badRequestErr(errors.New("invalid token")))
+		return
+	}
+
+	if err = user.ResetPassword(&u, &pr, input.Password, time.Now()); err != nil {
+		respondErr(w, badRequestErr(err))
+		return
+	}
+
+	ok, err = s.db.ResetPassword(ctx, u, pr)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot reset password")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	if !ok {
+		respondErr(w, badRequestErr(errors.New("token already used")))
+		return
+	}
+
+	if err = s.sessions.RevokeByUserKey(ctx, u.UUID.String()); err != nil {
+		log.Error().Err(err).Msg("cannot revoke sessions")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	respondOK(w)
+}
+
+type session struct {
+	Handle    string    `json:"handle"`
+	Current   bool      `json:"current"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	IP        string    `json:"ip"`
+	OS        string    `json:"os"`
+	Browser   string    `json:"browser"`
+}
+
+// sessionHandle identifies the session to the user. The session ID is the
+// value of the session cookie, so it is never handed out.
+func sessionHandle(ses sessionup.Session) string {
+	sum := sha256.Sum256([]byte(ses.ID))
+	return hex.EncodeToString(sum[:])
+}
+
+func sessionView(ses sessionup.Session) session {
+	var ip string
+	if ses.IP != nil {
+		ip = ses.IP.String()
+	}
+
+	return session{
+		Handle:    sessionHandle(ses),
+		Current:   ses.Current,
+		CreatedAt: ses.CreatedAt,
+		ExpiresAt: ses.ExpiresAt,
+		IP:        ip,
+		OS:        ses.Agent.OS,
+		Browser:   ses.Agent.Browser,
+	}
+}
+
+// userSessions lists the active sessions of the current user.
+func (s *Server) userSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := s.logger("userSessions")
+
+	ss, err := s.sessions.FetchAll(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch sessions")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	views := make([]session, 0, len(ss))
+
+	for _, ses := range ss {
+		views = append(views, sessionView(ses))
+	}
+
+	respondJSON(w, http.StatusOK, views)
+}
+
+// revokeSession signs the current user out of one of the user's sessions,
+// found by its handle.
+func (s *Server) revokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := s.logger("revokeSession")
+
+	ss, err := s.sessions.FetchAll(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot fetch sessions")
+		respondErr(w, internalErr())
+
+		return
+	}
+
+	handle := chi.URLParam(r, "handle")
+
+	for _, ses := range ss {
+		if subtle.ConstantTimeCompare([]byte(sessionHandle(ses)), []byte(handle)) != 1 {
+			continue
+		}
+
+		if err = s.sessions.RevokeByIDExt(ctx, ses.ID); err != nil {
+			log.Error().Err(err).Msg("cannot revoke session")
+			respondErr(w, internalErr())
+
+			return
+		}
+
+		respondOK(w)
+
+		return
+	}
+
+	respondErr(w, notFoundErr())
+}
+
 func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
 	s.sessions.RevokeAll(r.Context(), w)
 }
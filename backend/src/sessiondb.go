@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/ramasauskas/ispbet/db"
+	"github.com/rs/zerolog"
+	"github.com/swithek/sessionup"
+)
+
+// sessionStore keeps the sessions in the database, so that they outlive
+// restarts, and deletes the expired ones periodically.
+type sessionStore struct {
+	doneCh chan struct{}
+	db     *db.DB
+	log    zerolog.Logger
+}
+
+func newSessionStore(db *db.DB, cleanup time.Duration, log zerolog.Logger) *sessionStore {
+	s := &sessionStore{
+		doneCh: make(chan struct{}, 1),
+		db:     db,
+		log:    log,
+	}
+
+	go s.cleanup(cleanup)
+
+	return s
+}
+
+func (s *sessionStore) cleanup(d time.Duration) {
+	tick := time.NewTicker(d)
+
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-s.doneCh:
+			return
+		case <-tick.C:
+			if err := s.db.DeleteExpiredSessions(context.Background(), s.db.NoTX(), time.Now()); err != nil {
+				s.log.Error().Err(err).Msg("cannot delete expired sessions")
+			}
+		}
+	}
+}
+
+func (s *sessionStore) StopCleanup() {
+	close(s.doneCh)
+}
+
+func (s *sessionStore) Create(ctx context.Context, ses sessionup.Session) error {
+	enc, err := encodeSession(ses)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.db.InsertSession(ctx, s.db.NoTX(), enc)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return sessionup.ErrDuplicateID
+	}
+
+	return nil
+}
+
+func (s *sessionStore) FetchByID(ctx context.Context, id string) (sessionup.Session, bool, error) {
+	ses, ok, err := s.db.FetchSession(ctx, s.db.NoTX(), id, time.Now())
+	if err != nil || !ok {
+		return sessionup.Session{}, false, err
+	}
+
+	dec, err := decodeSession(ses)
+	if err != nil {
+		return sessionup.Session{}, false, err
+	}
+
+	return dec, true, nil
+}
+
+func (s *sessionStore) FetchByUserKey(ctx context.Context, key string) ([]sessionup.Session, error) {
+	ss, err := s.db.FetchUserSessions(ctx, s.db.NoTX(), key, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded []sessionup.Session
+
+	for _, ses := range ss {
+		dec, err := decodeSession(ses)
+		if err != nil {
+			return nil, err
+		}
+
+		decoded = append(decoded, dec)
+	}
+
+	return decoded, nil
+}
+
+func (s *sessionStore) DeleteByID(ctx context.Context, id string) error {
+	return s.db.DeleteSession(ctx, s.db.NoTX(), id)
+}
+
+func (s *sessionStore) DeleteByUserKey(ctx context.Context, key string, expID ...string) error {
+	return s.db.DeleteUserSessions(ctx, s.db.NoTX(), key, expID)
+}
+
+func encodeSession(s sessionup.Session) (db.Session, error) {
+	meta, err := json.Marshal(s.Meta)
+	if err != nil {
+		return db.Session{}, err
+	}
+
+	var ip string
+	if s.IP != nil {
+		ip = s.IP.String()
+	}
+
+	return db.Session{
+		ID:           s.ID,
+		UserKey:      s.UserKey,
+		CreatedAt:    s.CreatedAt,
+		ExpiresAt:    s.ExpiresAt,
+		IP:           ip,
+		AgentOS:      s.Agent.OS,
+		AgentBrowser: s.Agent.Browser,
+		Meta:         string(meta),
+	}, nil
+}
+
+func decodeSession(s db.Session) (sessionup.Session, error) {
+	ses := sessionup.Session{
+		ID:        s.ID,
+		UserKey:   s.UserKey,
+		CreatedAt: s.CreatedAt,
+		ExpiresAt: s.ExpiresAt,
+		IP:        net.ParseIP(s.IP),
+	}
+
+	ses.Agent.OS = s.AgentOS
+	ses.Agent.Browser = s.AgentBrowser
+
+	if err := json.Unmarshal([]byte(s.Meta), &ses.Meta); err != nil {
+		return sessionup.Session{}, err
+	}
+
+	return ses, nil
+}
@@ -3,27 +3,134 @@ package main
 import (
 	"context"
 
+	"github.com/google/uuid"
 	"github.com/ramasauskas/ispbet/bet"
 	"github.com/ramasauskas/ispbet/server"
 	"github.com/ramasauskas/ispbet/user"
+	"github.com/shopspring/decimal"
 )
 
 type serverBetAdapter struct {
 	better *better
 }
 
-func (adp *serverBetAdapter) Bet(ctx context.Context, b *bet.Bet, au *user.BetUser) (server.BetResponse, error) {
-	resp, err := adp.better.Bet(ctx, b, au)
+func (adp *serverBetAdapter) Bet(ctx context.Context, b *bet.Bet, policy bet.OddsPolicy, au *user.BetUser) (server.BetResponse, error) {
+	resp, err := adp.better.Bet(ctx, b, policy, au)
 	if err != nil {
 		return server.BetResponse{}, err
 	}
 
 	return server.BetResponse{
 		Ok:           resp.Ok,
+		ErrorCode:    string(resp.ErrorCode),
 		ErrorMessage: resp.ErrorMessage,
+		Odds:         resp.Odds,
 	}, nil
 }
 
-func (adp *serverBetAdapter) Resolve(ctx context.Context, sel bet.EventSelection) error {
-	return adp.better.ResolveEventSelection(ctx, sel)
+func (adp *serverBetAdapter) Accumulate(ctx context.Context, acc *bet.Accumulator, au *user.BetUser) (server.BetResponse, error) {
+	resp, err := adp.better.Accumulate(ctx, acc, au)
+	if err != nil {
+		return server.BetResponse{}, err
+	}
+
+	return server.BetResponse{
+		Ok:           resp.Ok,
+		ErrorCode:    string(resp.ErrorCode),
+		ErrorMessage: resp.ErrorMessage,
+	}, nil
+}
+
+func (adp *serverBetAdapter) QuoteCashOut(ctx context.Context, id uuid.UUID, au user.BetUser) (decimal.Decimal, server.BetResponse, error) {
+	amount, resp, err := adp.better.QuoteCashOut(ctx, id, au)
+	if err != nil {
+		return decimal.Zero, server.BetResponse{}, err
+	}
+
+	return amount, server.BetResponse{
+		Ok:           resp.Ok,
+		ErrorCode:    string(resp.ErrorCode),
+		ErrorMessage: resp.ErrorMessage,
+	}, nil
+}
+
+func (adp *serverBetAdapter) CashOut(ctx context.Context, id uuid.UUID, quoted decimal.Decimal, au *user.BetUser) (decimal.Decimal, server.BetResponse, error) {
+	amount, resp, err := adp.better.CashOut(ctx, id, quoted, au)
+	if err != nil {
+		return decimal.Zero, server.BetResponse{}, err
+	}
+
+	return amount, server.BetResponse{
+		Ok:           resp.Ok,
+		ErrorCode:    string(resp.ErrorCode),
+		ErrorMessage: resp.ErrorMessage,
+	}, nil
+}
+
+func (adp *serverBetAdapter) Resolve(ctx context.Context, id uuid.UUID, w bet.Winner) (server.BetResponse, error) {
+	resp, err := adp.better.ResolveEventSelection(ctx, id, w)
+	if err != nil {
+		return server.BetResponse{}, err
+	}
+
+	return server.BetResponse{
+		Ok:           resp.Ok,
+		ErrorCode:    string(resp.ErrorCode),
+		ErrorMessage: resp.ErrorMessage,
+	}, nil
+}
+
+func (adp *serverBetAdapter) CancelEvent(ctx context.Context, id uuid.UUID) (server.BetResponse, error) {
+	resp, err := adp.better.CancelEvent(ctx, id)
+	if err != nil {
+		return server.BetResponse{}, err
+	}
+
+	return server.BetResponse{
+		Ok:           resp.Ok,
+		ErrorCode:    string(resp.ErrorCode),
+		ErrorMessage: resp.ErrorMessage,
+	}, nil
+}
+
+func (adp *serverBetAdapter) ResolveEvent(ctx context.Context, id uuid.UUID, final bet.Score, halfTime *bet.Score, dryRun bool) (server.Settlement, error) {
+	st, err := adp.better.ResolveEvent(ctx, id, final, halfTime, dryRun)
+	if err != nil {
+		return server.Settlement{}, err
+	}
+
+	var payouts []server.Payout
+
+	for _, p := range st.Payouts {
+		payouts = append(payouts, server.Payout{
+			BetUUID:  p.BetUUID,
+			UserUUID: p.UserUUID,
+			Stake:    p.Stake,
+			State:    p.State,
+			Amount:   p.Amount,
+		})
+	}
+
+	return server.Settlement{
+		Event:   st.Event,
+		Payouts: payouts,
+	}, nil
+}
+
+func (adp *serverBetAdapter) ResettleSelection(ctx context.Context, id uuid.UUID, w bet.Winner, allowNegative, dryRun bool, admin uuid.UUID) (server.Settlement, server.BetResponse, error) {
+	st, resp, err := adp.better.ResettleSelection(ctx, id, w, allowNegative, dryRun, admin)
+	if err != nil {
+		return server.Settlement{}, server.BetResponse{}, err
+	}
+
+	settlement := server.Settlement{
+		Event:       st.Event,
+		Adjustments: st.Adjustments,
+	}
+
+	return settlement, server.BetResponse{
+		Ok:           resp.Ok,
+		ErrorCode:    string(resp.ErrorCode),
+		ErrorMessage: resp.ErrorMessage,
+	}, nil
 }
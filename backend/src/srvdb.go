@@ -2,21 +2,25 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/ramasauskas/ispbet/autobet"
 	"github.com/ramasauskas/ispbet/bet"
+	"github.com/ramasauskas/ispbet/bonus"
 	"github.com/ramasauskas/ispbet/db"
 	"github.com/ramasauskas/ispbet/purse"
 	"github.com/ramasauskas/ispbet/report"
 	"github.com/ramasauskas/ispbet/server"
 	"github.com/ramasauskas/ispbet/user"
+	"github.com/shopspring/decimal"
 )
 
 type serverDBAdapter struct {
-	db *db.DB
+	db           *db.DB
+	baseCurrency purse.Currency
 }
 
 func (a *serverDBAdapter) FetchBetUserByEmail(ctx context.Context, email string) (user.BetUser, bool, error) {
@@ -112,6 +116,21 @@ func (a *serverDBAdapter) FetchIdentityVerifications(ctx context.Context) ([]use
 	return vv, nil
 }
 
+func (a *serverDBAdapter) FetchUserIdentityVerifications(ctx context.Context, id uuid.UUID) ([]user.IdentityVerification, error) {
+	verifs, err := a.db.FetchUserIdentityVerifications(ctx, a.db.NoTX(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	vv := make([]user.IdentityVerification, len(verifs))
+
+	for i := range verifs {
+		vv[i] = decodeIdentityVerification(verifs[i])
+	}
+
+	return vv, nil
+}
+
 func (a *serverDBAdapter) InsertIdentityVerificationUpdate(ctx context.Context, u user.BetUser, ver user.IdentityVerification) error {
 	tx, err := a.db.NewTX(ctx)
 	if err != nil {
@@ -120,7 +139,7 @@ func (a *serverDBAdapter) InsertIdentityVerificationUpdate(ctx context.Context,
 
 	defer tx.Rollback()
 
-	if err = a.db.UpdateBetUser(ctx, tx, encodeBetUser(u)); err != nil {
+	if err = a.db.UpdateBetUserIdentity(ctx, tx, encodeBetUser(u)); err != nil {
 		return err
 	}
 
@@ -139,6 +158,10 @@ func (a *serverDBAdapter) InsertEmailVerification(ctx context.Context, ve user.E
 
 	defer tx.Rollback()
 
+	if err = a.db.ExpireEmailVerifications(ctx, tx, ve.UserUUID, ve.CreatedAt); err != nil {
+		return err
+	}
+
 	if err = a.db.InsertEmailVerification(ctx, tx, encodeEmailVerification(ve)); err != nil {
 		return err
 	}
@@ -159,6 +182,19 @@ func (a *serverDBAdapter) FetchEmailVerification(ctx context.Context, token stri
 	return decodeEmailVerifcation(vee), true, nil
 }
 
+func (a *serverDBAdapter) FetchLatestEmailVerification(ctx context.Context, userUUID uuid.UUID) (user.EmailVerification, bool, error) {
+	vee, ok, err := a.db.FetchLatestEmailVerification(ctx, a.db.NoTX(), userUUID)
+	if err != nil {
+		return user.EmailVerification{}, false, err
+	}
+
+	if !ok {
+		return user.EmailVerification{}, false, nil
+	}
+
+	return decodeEmailVerifcation(vee), true, nil
+}
+
 func (a *serverDBAdapter) InsertUserVerification(ctx context.Context, u user.User, ve user.EmailVerification) error {
 	tx, err := a.db.NewTX(ctx)
 	if err != nil {
@@ -191,6 +227,156 @@ func (a *serverDBAdapter) FetchUserByUUID(ctx context.Context, uuid uuid.UUID) (
 	return decodeUser(u), true, nil
 }
 
+func (a *serverDBAdapter) FetchUserByEmail(ctx context.Context, email string) (user.User, bool, error) {
+	u, ok, err := a.db.FetchUser(ctx, a.db.NoTX(), db.FetchUserByEmail(email))
+	if err != nil || !ok {
+		return user.User{}, ok, err
+	}
+
+	return decodeUser(u), true, nil
+}
+
+func (a *serverDBAdapter) UpdateUser(ctx context.Context, u user.User) error {
+	return a.db.UpdateUser(ctx, a.db.NoTX(), encodeUser(u))
+}
+
+func (a *serverDBAdapter) InsertPasswordReset(ctx context.Context, pr user.PasswordReset) error {
+	return a.db.InsertPasswordReset(ctx, a.db.NoTX(), encodePasswordReset(pr))
+}
+
+func (a *serverDBAdapter) FetchPasswordReset(ctx context.Context, token string) (user.PasswordReset, bool, error) {
+	pr, ok, err := a.db.FetchPasswordReset(ctx, a.db.NoTX(), token)
+	if err != nil || !ok {
+		return user.PasswordReset{}, ok, err
+	}
+
+	return decodePasswordReset(pr), true, nil
+}
+
+func (a *serverDBAdapter) ResetPassword(ctx context.Context, u user.User, pr user.PasswordReset) (bool, error) {
+	tx, err := a.db.NewTX(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	ok, err := a.db.UsePasswordReset(ctx, tx, pr.Token)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if err = a.db.UpdateUser(ctx, tx, encodeUser(u)); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (a *serverDBAdapter) FetchTwoFactor(ctx context.Context, id uuid.UUID) (user.TwoFactor, bool, error) {
+	tf, ok, err := a.db.FetchTwoFactor(ctx, a.db.NoTX(), id)
+	if err != nil || !ok {
+		return user.TwoFactor{}, ok, err
+	}
+
+	return decodeTwoFactor(tf), true, nil
+}
+
+func (a *serverDBAdapter) UpsertTwoFactor(ctx context.Context, tf user.TwoFactor) error {
+	return a.db.UpsertTwoFactor(ctx, a.db.NoTX(), encodeTwoFactor(tf))
+}
+
+func (a *serverDBAdapter) EnableTwoFactor(ctx context.Context, tf user.TwoFactor, rr []user.RecoveryCode) error {
+	tx, err := a.db.NewTX(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err = a.db.UpsertTwoFactor(ctx, tx, encodeTwoFactor(tf)); err != nil {
+		return err
+	}
+
+	if err = a.db.DeleteRecoveryCodes(ctx, tx, tf.UserUUID); err != nil {
+		return err
+	}
+
+	for _, rc := range rr {
+		if err = a.db.InsertRecoveryCode(ctx, tx, encodeRecoveryCode(rc)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (a *serverDBAdapter) UpdateTwoFactorStep(ctx context.Context, tf user.TwoFactor) (bool, error) {
+	return a.db.UpdateTwoFactorStep(ctx, a.db.NoTX(), encodeTwoFactor(tf))
+}
+
+func (a *serverDBAdapter) UseRecoveryCode(ctx context.Context, id uuid.UUID, hash string) (bool, error) {
+	return a.db.UseRecoveryCode(ctx, a.db.NoTX(), id, hash)
+}
+
+func (a *serverDBAdapter) DeleteTwoFactor(ctx context.Context, id uuid.UUID) error {
+	tx, err := a.db.NewTX(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err = a.db.DeleteTwoFactor(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err = a.db.DeleteRecoveryCodes(ctx, tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (a *serverDBAdapter) FetchLoginThrottle(ctx context.Context, scope user.ThrottleScope, key string) (user.LoginThrottle, bool, error) {
+	lt, ok, err := a.db.FetchLoginThrottle(ctx, a.db.NoTX(), string(scope), key)
+	if err != nil || !ok {
+		return user.LoginThrottle{}, ok, err
+	}
+
+	return decodeLoginThrottle(lt), true, nil
+}
+
+func (a *serverDBAdapter) UpsertLoginThrottle(ctx context.Context, lt user.LoginThrottle) error {
+	return a.db.UpsertLoginThrottle(ctx, a.db.NoTX(), encodeLoginThrottle(lt))
+}
+
+func (a *serverDBAdapter) DeleteLoginThrottle(ctx context.Context, scope user.ThrottleScope, key string) error {
+	return a.db.DeleteLoginThrottle(ctx, a.db.NoTX(), string(scope), key)
+}
+
+func (a *serverDBAdapter) InsertLockoutEvent(ctx context.Context, le user.LockoutEvent) error {
+	return a.db.InsertLockoutEvent(ctx, a.db.NoTX(), encodeLockoutEvent(le))
+}
+
+func (a *serverDBAdapter) FetchLockoutEvents(ctx context.Context) ([]user.LockoutEvent, error) {
+	ee, err := a.db.FetchLockoutEvents(ctx, a.db.NoTX())
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded []user.LockoutEvent
+
+	for _, le := range ee {
+		decoded = append(decoded, decodeLockoutEvent(le))
+	}
+
+	return decoded, nil
+}
+
+// InsertDeposit stores the deposit if it fits in the deposit limits of the
+// user, which are checked in the same transaction so that concurrent
+// deposits cannot pass them together.
 func (a *serverDBAdapter) InsertDeposit(ctx context.Context, u user.BetUser, d purse.Deposit) error {
 	tx, err := a.db.NewTX(ctx)
 	if err != nil {
@@ -199,7 +385,29 @@ func (a *serverDBAdapter) InsertDeposit(ctx context.Context, u user.BetUser, d p
 
 	defer tx.Rollback()
 
-	if err = a.db.UpdateBetUser(ctx, tx, encodeBetUser(u)); err != nil {
+	ll, err := a.db.FetchGamblingLimits(ctx, tx, d.UserUUID)
+	if err != nil {
+		return err
+	}
+
+	dd, err := a.db.FetchDeposits(ctx, tx, db.UserDeposits(d.UserUUID))
+	if err != nil {
+		return err
+	}
+
+	decodedLimits := make([]user.GamblingLimit, 0, len(ll))
+
+	for _, l := range ll {
+		decodedLimits = append(decodedLimits, decodeGamblingLimit(l))
+	}
+
+	decodedDeposits := make([]purse.Deposit, 0, len(dd))
+
+	for _, dep := range dd {
+		decodedDeposits = append(decodedDeposits, decodeDeposit(dep))
+	}
+
+	if err = user.CheckDepositLimits(decodedLimits, decodedDeposits, d.Amount, d.Timestamp); err != nil {
 		return err
 	}
 
@@ -207,9 +415,81 @@ func (a *serverDBAdapter) InsertDeposit(ctx context.Context, u user.BetUser, d p
 		return err
 	}
 
+	if d.Status == purse.DepositStatusConfirmed {
+		if err = postEntries(ctx, a.db, tx, []purse.Entry{purse.DepositEntry(d)}, u); err != nil {
+			return err
+		}
+	}
+
 	return tx.Commit()
 }
 
+// UpdateDeposit stores the deposit reported by its payment provider,
+// crediting it to the user if it was confirmed. False is returned if the
+// deposit no longer had the given status.
+func (a *serverDBAdapter) UpdateDeposit(ctx context.Context, d purse.Deposit, from purse.DepositStatus, u user.BetUser) (bool, error) {
+	tx, err := a.db.NewTX(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	ok, err := a.db.UpdateDeposit(ctx, tx, encodeDeposit(d), string(from))
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if d.Status == purse.DepositStatusConfirmed {
+		if err = postEntries(ctx, a.db, tx, []purse.Entry{purse.DepositEntry(d)}, u); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+func (a *serverDBAdapter) FetchDeposit(ctx context.Context, id uuid.UUID) (purse.Deposit, bool, error) {
+	d, ok, err := a.db.FetchDeposit(ctx, a.db.NoTX(), db.DepositByUUID(id))
+	if err != nil {
+		return purse.Deposit{}, false, err
+	}
+
+	if !ok {
+		return purse.Deposit{}, false, nil
+	}
+
+	return decodeDeposit(d), true, nil
+}
+
+func (a *serverDBAdapter) FetchDepositByReference(ctx context.Context, provider, ref string) (purse.Deposit, bool, error) {
+	d, ok, err := a.db.FetchDeposit(ctx, a.db.NoTX(), db.DepositByReference(provider, ref))
+	if err != nil {
+		return purse.Deposit{}, false, err
+	}
+
+	if !ok {
+		return purse.Deposit{}, false, nil
+	}
+
+	return decodeDeposit(d), true, nil
+}
+
+func (a *serverDBAdapter) FetchUserDeposits(ctx context.Context, id uuid.UUID) ([]purse.Deposit, error) {
+	dd, err := a.db.FetchDeposits(ctx, a.db.NoTX(), db.UserDeposits(id))
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := make([]purse.Deposit, 0, len(dd))
+
+	for _, d := range dd {
+		decoded = append(decoded, decodeDeposit(d))
+	}
+
+	return decoded, nil
+}
+
 func (a *serverDBAdapter) InsertWithdrawal(ctx context.Context, u user.BetUser, wd purse.Withdrawal) error {
 	tx, err := a.db.NewTX(ctx)
 	if err != nil {
@@ -218,17 +498,168 @@ func (a *serverDBAdapter) InsertWithdrawal(ctx context.Context, u user.BetUser,
 
 	defer tx.Rollback()
 
-	if err = a.db.UpdateBetUser(ctx, tx, encodeBetUser(u)); err != nil {
+	if err = a.db.InsertWithdrawal(ctx, tx, encodeWithdrawal(wd)); err != nil {
+		return err
+	}
+
+	if err = postEntries(ctx, a.db, tx, []purse.Entry{purse.WithdrawalEntry(wd)}, u); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// InsertWithdrawalRequest stores the requested withdrawal and reserves its
+// funds.
+func (a *serverDBAdapter) InsertWithdrawalRequest(ctx context.Context, u user.BetUser, wd purse.Withdrawal) error {
+	tx, err := a.db.NewTX(ctx)
+	if err != nil {
 		return err
 	}
 
+	defer tx.Rollback()
+
 	if err = a.db.InsertWithdrawal(ctx, tx, encodeWithdrawal(wd)); err != nil {
 		return err
 	}
 
+	if err = postEntries(ctx, a.db, tx, []purse.Entry{purse.ReserveEntry(wd)}, u); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
+// UpdateWithdrawal stores the reviewed withdrawal, releasing its funds back
+// to the user if it was rejected, cancelled or its payout failed and paying
+// them out if it was paid. False is returned if the withdrawal no longer had the given
+// status.
+func (a *serverDBAdapter) UpdateWithdrawal(ctx context.Context, wd purse.Withdrawal, from purse.WithdrawalStatus, u user.BetUser) (bool, error) {
+	tx, err := a.db.NewTX(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	ok, err := a.db.UpdateWithdrawal(ctx, tx, encodeWithdrawal(wd), string(from))
+	if err != nil || !ok {
+		return false, err
+	}
+
+	switch wd.Status {
+	case purse.WithdrawalStatusRejected, purse.WithdrawalStatusCancelled:
+		err = postEntries(ctx, a.db, tx, []purse.Entry{purse.ReleaseEntry(wd)}, u)
+	case purse.WithdrawalStatusPaid:
+		err = postEntries(ctx, a.db, tx, []purse.Entry{purse.PaidEntry(wd)})
+	case purse.WithdrawalStatusFailed:
+		err = postEntries(ctx, a.db, tx, []purse.Entry{purse.PayoutFailedEntry(wd)}, u)
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (a *serverDBAdapter) FetchWithdrawal(ctx context.Context, id uuid.UUID) (purse.Withdrawal, bool, error) {
+	wd, ok, err := a.db.FetchWithdrawal(ctx, a.db.NoTX(), id)
+	if err != nil {
+		return purse.Withdrawal{}, false, err
+	}
+
+	if !ok {
+		return purse.Withdrawal{}, false, nil
+	}
+
+	return decodeWithdrawal(wd), true, nil
+}
+
+func (a *serverDBAdapter) FetchWithdrawalByReference(ctx context.Context, provider, ref string) (purse.Withdrawal, bool, error) {
+	ww, err := a.db.FetchWithdrawals(ctx, a.db.NoTX(), db.WithdrawalByReference(provider, ref))
+	if err != nil {
+		return purse.Withdrawal{}, false, err
+	}
+
+	if len(ww) == 0 {
+		return purse.Withdrawal{}, false, nil
+	}
+
+	return decodeWithdrawal(ww[0]), true, nil
+}
+
+func (a *serverDBAdapter) FetchUserWithdrawals(ctx context.Context, id uuid.UUID) ([]purse.Withdrawal, error) {
+	ww, err := a.db.FetchWithdrawals(ctx, a.db.NoTX(), db.UserWithdrawals(id))
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := make([]purse.Withdrawal, 0, len(ww))
+
+	for _, wd := range ww {
+		decoded = append(decoded, decodeWithdrawal(wd))
+	}
+
+	return decoded, nil
+}
+
+func (a *serverDBAdapter) FetchWithdrawalsWithStatus(ctx context.Context, ss ...purse.WithdrawalStatus) ([]purse.Withdrawal, error) {
+	statuses := make([]string, 0, len(ss))
+
+	for _, st := range ss {
+		statuses = append(statuses, string(st))
+	}
+
+	ww, err := a.db.FetchWithdrawals(ctx, a.db.NoTX(), db.WithdrawalsWithStatus(statuses...))
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := make([]purse.Withdrawal, 0, len(ww))
+
+	for _, wd := range ww {
+		decoded = append(decoded, decodeWithdrawal(wd))
+	}
+
+	return decoded, nil
+}
+
+func (a *serverDBAdapter) FetchUserEntries(ctx context.Context, id uuid.UUID) ([]purse.Entry, error) {
+	ee, err := a.db.FetchJournalEntries(ctx, a.db.NoTX(), db.AccountJournalEntries(string(purse.UserAccount(id))))
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := make([]purse.Entry, 0, len(ee))
+
+	for _, e := range ee {
+		decoded = append(decoded, decodeEntry(e))
+	}
+
+	return decoded, nil
+}
+
+func (a *serverDBAdapter) FetchBalanceAdjustments(ctx context.Context, id uuid.UUID) ([]purse.BalanceAdjustment, error) {
+	c := db.AllBalanceAdjustments()
+	if id != uuid.Nil {
+		c = db.UserBalanceAdjustments(id)
+	}
+
+	aa, err := a.db.FetchBalanceAdjustments(ctx, a.db.NoTX(), c)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded []purse.BalanceAdjustment
+
+	for _, ba := range aa {
+		decoded = append(decoded, decodeBalanceAdjustment(ba))
+	}
+
+	return decoded, nil
+}
+
 func (a *serverDBAdapter) FetchBetUsers(ctx context.Context) ([]user.BetUser, error) {
 	uu, err := a.db.FetchBetUsers(ctx, a.db.NoTX())
 	if err != nil {
@@ -244,6 +675,68 @@ func (a *serverDBAdapter) FetchBetUsers(ctx context.Context) ([]user.BetUser, er
 	return uut, nil
 }
 
+func (a *serverDBAdapter) ExcludeBetUser(ctx context.Context, u user.BetUser, e user.Exclusion) error {
+	tx, err := a.db.NewTX(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err = a.db.UpdateBetUserExclusion(ctx, tx, encodeBetUser(u)); err != nil {
+		return err
+	}
+
+	if err = a.db.InsertExclusion(ctx, tx, encodeExclusion(e)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (a *serverDBAdapter) EraseBetUser(ctx context.Context, u user.BetUser) error {
+	tx, err := a.db.NewTX(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err = a.db.UpdateUser(ctx, tx, encodeUser(u.User)); err != nil {
+		return err
+	}
+
+	if err = a.db.EraseIdentityVerifications(ctx, tx, u.UUID); err != nil {
+		return err
+	}
+
+	if err = a.db.DeleteEmailVerifications(ctx, tx, u.UUID); err != nil {
+		return err
+	}
+
+	if err = a.db.DeletePasswordResets(ctx, tx, u.UUID); err != nil {
+		return err
+	}
+
+	if err = a.db.DeleteTwoFactor(ctx, tx, u.UUID); err != nil {
+		return err
+	}
+
+	if err = a.db.DeleteRecoveryCodes(ctx, tx, u.UUID); err != nil {
+		return err
+	}
+
+	if err = a.db.DeleteUserAutoBets(ctx, tx, u.UUID); err != nil {
+		return err
+	}
+
+	if err = a.db.AnonymizeLockoutEvents(ctx, tx, u.UUID, u.Email); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (a *serverDBAdapter) InsertEvent(ctx context.Context, ev bet.Event) error {
 	homeTeam := encodeTeam(ev.HomeTeam)
 	awayTeam := encodeTeam(ev.AwayTeam)
@@ -254,6 +747,7 @@ func (a *serverDBAdapter) InsertEvent(ctx context.Context, ev bet.Event) error {
 		awayPlayers []db.TeamPlayer
 		homePlayers []db.TeamPlayer
 		selections  []db.EventSelection
+		outcomes    []db.SelectionOutcome
 	)
 
 	for _, p := range ev.HomeTeam.Players {
@@ -266,6 +760,7 @@ func (a *serverDBAdapter) InsertEvent(ctx context.Context, ev bet.Event) error {
 
 	for _, s := range ev.Selections {
 		selections = append(selections, encodeSelection(s, ev.UUID))
+		outcomes = append(outcomes, encodeSelectionOutcomes(s)...)
 	}
 
 	tx, err := a.db.NewTX(ctx)
@@ -305,6 +800,12 @@ func (a *serverDBAdapter) InsertEvent(ctx context.Context, ev bet.Event) error {
 		}
 	}
 
+	for _, o := range outcomes {
+		if err := a.db.InsertSelectionOutcome(ctx, tx, o); err != nil {
+			return err
+		}
+	}
+
 	return tx.Commit()
 }
 
@@ -313,7 +814,7 @@ func (a *serverDBAdapter) UpdateEvent(ctx context.Context, ev bet.Event) error {
 }
 
 func (a *serverDBAdapter) UpdateSelection(ctx context.Context, sel bet.EventSelection) error {
-	return a.db.UpdateSelection(ctx, a.db.NoTX(), encodeSelection(sel, sel.EventUUID))
+	return updateSelection(ctx, a.db, sel)
 }
 
 func (a *serverDBAdapter) FetchEvents(ctx context.Context) ([]bet.Event, error) {
@@ -346,7 +847,12 @@ func (a *serverDBAdapter) FetchSelection(ctx context.Context, id uuid.UUID) (bet
 		return bet.EventSelection{}, false, nil
 	}
 
-	return decodeSelection(sel), true, nil
+	decoded, err := fillSelection(ctx, a.db, a.db.NoTX(), sel)
+	if err != nil {
+		return bet.EventSelection{}, false, err
+	}
+
+	return decoded, true, nil
 }
 
 func (a *serverDBAdapter) FetchAdminUserByEmail(ctx context.Context, email string) (user.AdminUser, bool, error) {
@@ -409,6 +915,26 @@ func (a *serverDBAdapter) FetchUserBets(ctx context.Context, id uuid.UUID) ([]be
 	return bb, nil
 }
 
+func (a *serverDBAdapter) FetchUserAccumulators(ctx context.Context, id uuid.UUID) ([]bet.Accumulator, error) {
+	accs, err := a.db.FetchAccumulators(ctx, a.db.NoTX(), db.UserAccumulators(id))
+	if err != nil {
+		return nil, err
+	}
+
+	var aa []bet.Accumulator
+
+	for _, acc := range accs {
+		filled, err := fillAccumulator(ctx, a.db, a.db.NoTX(), acc)
+		if err != nil {
+			return nil, err
+		}
+
+		aa = append(aa, filled)
+	}
+
+	return aa, nil
+}
+
 func (a *serverDBAdapter) FetchEvent(ctx context.Context, id uuid.UUID) (bet.Event, bool, error) {
 	ev, ok, err := a.db.FetchEvent(ctx, a.db.NoTX(), id)
 	if err != nil {
@@ -446,19 +972,196 @@ func (a *serverDBAdapter) FetchEventBySelection(ctx context.Context, id uuid.UUI
 }
 
 func (a *serverDBAdapter) FetchProfit(ctx context.Context, po server.ProfitOpts) (server.ProfitReport, error) {
-	pr, err := a.db.ProfitReport(ctx, db.ProfitOpts{
-		From: po.From,
-		To:   po.To,
+	rr, err := a.db.ProfitReport(ctx, db.ProfitOpts{
+		From:         po.From,
+		To:           po.To,
+		BaseCurrency: string(a.baseCurrency),
 	})
 	if err != nil {
 		return server.ProfitReport{}, err
 	}
 
-	return server.ProfitReport{
-		Profit: pr.Profit,
-		Loss:   pr.Loss,
-		Final:  pr.Final,
-	}, nil
+	pr := server.ProfitReport{
+		Currency:   a.baseCurrency,
+		ByCurrency: make([]server.CurrencyProfit, 0, len(rr)),
+	}
+
+	for _, r := range rr {
+		if err = missingRatesErr(r.Currency, r.MissingRates); err != nil {
+			return server.ProfitReport{}, err
+		}
+
+		pr.Profit = pr.Profit.Add(r.BaseProfit)
+		pr.Loss = pr.Loss.Add(r.BaseLoss)
+		pr.ByCurrency = append(pr.ByCurrency, server.CurrencyProfit{
+			Currency: purse.Currency(r.Currency),
+			Profit:   r.Profit,
+			Loss:     r.Loss,
+			Final:    r.Profit.Sub(r.Loss),
+		})
+	}
+
+	pr.Final = pr.Profit.Sub(pr.Loss)
+
+	return pr, nil
+}
+
+func (a *serverDBAdapter) FetchDepositReport(ctx context.Context, from, to time.Time) (server.DepositReport, error) {
+	tt, err := a.db.FetchTotalDeposits(ctx, from, to, string(a.baseCurrency))
+	if err != nil {
+		return server.DepositReport{}, err
+	}
+
+	dr := server.DepositReport{
+		Currency:   a.baseCurrency,
+		ByCurrency: make([]server.CurrencyAmount, 0, len(tt)),
+	}
+
+	for _, t := range tt {
+		if err = missingRatesErr(t.Currency, t.MissingRates); err != nil {
+			return server.DepositReport{}, err
+		}
+
+		dr.Total = dr.Total.Add(t.BaseAmount)
+		dr.ByCurrency = append(dr.ByCurrency, server.CurrencyAmount{
+			Currency: purse.Currency(t.Currency),
+			Amount:   t.Amount,
+		})
+	}
+
+	return dr, nil
+}
+
+func (a *serverDBAdapter) InsertExchangeRate(ctx context.Context, er purse.ExchangeRate) error {
+	return a.db.InsertExchangeRate(ctx, a.db.NoTX(), encodeExchangeRate(er))
+}
+
+// FetchExchangeRates returns the rates of the currency, or of every
+// currency if it is empty, in the order they took effect.
+func (a *serverDBAdapter) FetchExchangeRates(ctx context.Context, c purse.Currency) ([]purse.ExchangeRate, error) {
+	crit := db.AllExchangeRates()
+	if c != "" {
+		crit = db.CurrencyExchangeRates(string(c))
+	}
+
+	rr, err := a.db.FetchExchangeRates(ctx, a.db.NoTX(), crit)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := make([]purse.ExchangeRate, 0, len(rr))
+
+	for _, er := range rr {
+		decoded = append(decoded, decodeExchangeRate(er))
+	}
+
+	return decoded, nil
+}
+
+func (a *serverDBAdapter) InsertBonusCampaign(ctx context.Context, c bonus.Campaign) error {
+	return a.db.InsertBonusCampaign(ctx, a.db.NoTX(), encodeBonusCampaign(c))
+}
+
+func (a *serverDBAdapter) FetchBonusCampaign(ctx context.Context, id uuid.UUID) (bonus.Campaign, bool, error) {
+	c, ok, err := a.db.FetchBonusCampaign(ctx, a.db.NoTX(), id)
+	if err != nil {
+		return bonus.Campaign{}, false, err
+	}
+
+	if !ok {
+		return bonus.Campaign{}, false, nil
+	}
+
+	return decodeBonusCampaign(c), true, nil
+}
+
+func (a *serverDBAdapter) FetchBonusCampaigns(ctx context.Context) ([]bonus.Campaign, error) {
+	cc, err := a.db.FetchBonusCampaigns(ctx, a.db.NoTX(), db.AllBonusCampaigns())
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := make([]bonus.Campaign, 0, len(cc))
+
+	for _, c := range cc {
+		decoded = append(decoded, decodeBonusCampaign(c))
+	}
+
+	return decoded, nil
+}
+
+func (a *serverDBAdapter) FetchRunningBonusCampaigns(ctx context.Context, cur purse.Currency, t time.Time) ([]bonus.Campaign, error) {
+	cc, err := a.db.FetchBonusCampaigns(ctx, a.db.NoTX(), db.RunningBonusCampaigns(string(cur), t))
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := make([]bonus.Campaign, 0, len(cc))
+
+	for _, c := range cc {
+		decoded = append(decoded, decodeBonusCampaign(c))
+	}
+
+	return decoded, nil
+}
+
+func (a *serverDBAdapter) BonusCampaignClaimed(ctx context.Context, campaignUUID, userUUID uuid.UUID) (bool, error) {
+	return a.db.UserClaimedCampaign(ctx, a.db.NoTX(), campaignUUID, userUUID)
+}
+
+func (a *serverDBAdapter) InsertBonusGrant(ctx context.Context, u user.BetUser, g bonus.Grant) error {
+	tx, err := a.db.NewTX(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err = a.db.InsertBonusGrant(ctx, tx, encodeBonusGrant(g)); err != nil {
+		return err
+	}
+
+	entry := purse.BonusEntry(g.UserUUID, g.UUID, g.Amount, g.CreatedAt)
+
+	if err = postEntries(ctx, a.db, tx, []purse.Entry{entry}, u); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (a *serverDBAdapter) InsertBonusToken(ctx context.Context, t bonus.Token) error {
+	return a.db.InsertBonusToken(ctx, a.db.NoTX(), encodeBonusToken(t))
+}
+
+func (a *serverDBAdapter) FetchUserBonusGrants(ctx context.Context, id uuid.UUID) ([]bonus.Grant, error) {
+	gg, err := a.db.FetchBonusGrants(ctx, a.db.NoTX(), db.UserBonusGrants(id))
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := make([]bonus.Grant, 0, len(gg))
+
+	for _, g := range gg {
+		decoded = append(decoded, decodeBonusGrant(g))
+	}
+
+	return decoded, nil
+}
+
+func (a *serverDBAdapter) FetchUserBonusTokens(ctx context.Context, id uuid.UUID) ([]bonus.Token, error) {
+	tt, err := a.db.FetchUserBonusTokens(ctx, a.db.NoTX(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := make([]bonus.Token, 0, len(tt))
+
+	for _, t := range tt {
+		decoded = append(decoded, decodeBonusToken(t))
+	}
+
+	return decoded, nil
 }
 
 func (a *serverDBAdapter) FetchAdminLogs(ctx context.Context, id uuid.UUID) ([]user.AdminLog, error) {
@@ -506,6 +1209,41 @@ func (a *serverDBAdapter) FetchBetReport(ctx context.Context, from, to time.Time
 	return bets, nil
 }
 
+func (a *serverDBAdapter) FetchAccumulatorReport(ctx context.Context, from, to time.Time) ([]bet.Accumulator, error) {
+	accs, err := a.db.FetchAccumulators(ctx, a.db.NoTX(), db.AccumulatorsBetween(from, to))
+	if err != nil {
+		return nil, err
+	}
+
+	var aa []bet.Accumulator
+
+	for _, acc := range accs {
+		filled, err := fillAccumulator(ctx, a.db, a.db.NoTX(), acc)
+		if err != nil {
+			return nil, err
+		}
+
+		aa = append(aa, filled)
+	}
+
+	return aa, nil
+}
+
+func (a *serverDBAdapter) FetchDiscrepancyReport(ctx context.Context, from, to time.Time) ([]purse.Discrepancy, error) {
+	dd, err := a.db.FetchBalanceDiscrepancies(ctx, a.db.NoTX(), db.BalanceDiscrepanciesBetween(from, to))
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded []purse.Discrepancy
+
+	for _, d := range dd {
+		decoded = append(decoded, decodeDiscrepancy(d))
+	}
+
+	return decoded, nil
+}
+
 func (a *serverDBAdapter) InsertAutoReport(ctx context.Context, r report.AutoReport) error {
 	return a.db.InsertAutoReport(ctx, a.db.NoTX(), db.AutoReport{
 		UUID:   r.UUID,
@@ -523,6 +1261,78 @@ func (a *serverDBAdapter) InsertAutoBet(ctx context.Context, au autobet.AutoBet)
 	})
 }
 
+func (a *serverDBAdapter) FetchBetLimits(ctx context.Context) ([]bet.Limits, error) {
+	ll, err := a.db.FetchBetLimits(ctx, a.db.NoTX())
+	if err != nil {
+		return nil, err
+	}
+
+	var limits []bet.Limits
+
+	for _, l := range ll {
+		limits = append(limits, decodeBetLimit(l))
+	}
+
+	return limits, nil
+}
+
+func (a *serverDBAdapter) UpsertBetLimits(ctx context.Context, l bet.Limits) error {
+	return a.db.UpsertBetLimit(ctx, a.db.NoTX(), encodeBetLimit(l))
+}
+
+func (a *serverDBAdapter) DeleteBetLimits(ctx context.Context, scope bet.LimitScope, id uuid.UUID) error {
+	return a.db.DeleteBetLimit(ctx, a.db.NoTX(), string(scope), id)
+}
+
+func (a *serverDBAdapter) FetchGamblingLimits(ctx context.Context, id uuid.UUID) ([]user.GamblingLimit, error) {
+	ll, err := a.db.FetchGamblingLimits(ctx, a.db.NoTX(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	var limits []user.GamblingLimit
+
+	for _, l := range ll {
+		limits = append(limits, decodeGamblingLimit(l))
+	}
+
+	return limits, nil
+}
+
+func (a *serverDBAdapter) UpdateGamblingLimit(ctx context.Context, l user.GamblingLimit, c user.GamblingLimitChange) error {
+	tx, err := a.db.NewTX(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err = a.db.UpsertGamblingLimit(ctx, tx, encodeGamblingLimit(l)); err != nil {
+		return err
+	}
+
+	if err = a.db.InsertGamblingLimitChange(ctx, tx, encodeGamblingLimitChange(c)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (a *serverDBAdapter) FetchGamblingLimitChanges(ctx context.Context, id uuid.UUID) ([]user.GamblingLimitChange, error) {
+	cc, err := a.db.FetchGamblingLimitChanges(ctx, a.db.NoTX(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []user.GamblingLimitChange
+
+	for _, c := range cc {
+		changes = append(changes, decodeGamblingLimitChange(c))
+	}
+
+	return changes, nil
+}
+
 func (a *serverDBAdapter) DeleteAutoBet(ctx context.Context, id uuid.UUID) error {
 	return a.db.DeleteAutoBet(ctx, a.db.NoTX(), id)
 }
@@ -588,7 +1398,12 @@ func fillEvent(ctx context.Context, db *db.DB, tx db.TX, ev db.Event) (bet.Event
 	)
 
 	for _, sel := range sels {
-		decodedSels = append(decodedSels, decodeSelection(sel))
+		decoded, err := fillSelection(ctx, db, tx, sel)
+		if err != nil {
+			return bet.Event{}, err
+		}
+
+		decodedSels = append(decodedSels, decoded)
 	}
 
 	for _, p := range awayPlayers {
@@ -600,13 +1415,16 @@ func fillEvent(ctx context.Context, db *db.DB, tx db.TX, ev db.Event) (bet.Event
 	}
 
 	return bet.Event{
-		UUID:       ev.UUID,
-		Name:       ev.Name,
-		Sport:      bet.Sport(ev.Sport),
-		Selections: decodedSels,
-		BeginsAt:   ev.BeginsAt,
-		HomeTeam:   decodeTeam(home, decodedHome),
-		AwayTeam:   decodeTeam(away, decodedAway),
+		UUID:          ev.UUID,
+		Name:          ev.Name,
+		Sport:         bet.Sport(ev.Sport),
+		Selections:    decodedSels,
+		BeginsAt:      ev.BeginsAt,
+		Status:        bet.EventStatus(ev.Status),
+		HomeTeam:      decodeTeam(home, decodedHome),
+		AwayTeam:      decodeTeam(away, decodedAway),
+		FinalScore:    decodeScore(ev.HomeScore, ev.AwayScore),
+		HalfTimeScore: decodeScore(ev.HalfTimeHomeScore, ev.HalfTimeAwayScore),
 	}, nil
 }
 
@@ -652,6 +1470,10 @@ func decodeBetUser(u db.BetUser) user.BetUser {
 		User:             decodeUser(u.User),
 		IdentityVerified: u.IdentityVerified,
 		Balance:          u.Balance,
+		Currency:         purse.Currency(u.Currency),
+		BonusBalance:     u.BonusBalance,
+		ExclusionKind:    user.ExclusionKind(u.ExclusionKind),
+		ExcludedUntil:    u.ExcludedUntil,
 	}
 }
 
@@ -672,6 +1494,21 @@ func encodeBetUser(u user.BetUser) db.BetUser {
 		User:             encodeUser(u.User),
 		IdentityVerified: u.IdentityVerified,
 		Balance:          u.Balance,
+		Currency:         string(u.Currency),
+		BonusBalance:     u.BonusBalance,
+		ExclusionKind:    string(u.ExclusionKind),
+		ExcludedUntil:    u.ExcludedUntil,
+	}
+}
+
+func encodeExclusion(e user.Exclusion) db.Exclusion {
+	return db.Exclusion{
+		UUID:      e.UUID,
+		UserUUID:  e.UserUUID,
+		Kind:      string(e.Kind),
+		Period:    string(e.Period),
+		EndsAt:    e.EndsAt,
+		CreatedAt: e.CreatedAt,
 	}
 }
 
@@ -679,7 +1516,10 @@ func encodeEmailVerification(ev user.EmailVerification) db.EmailVerification {
 	return db.EmailVerification{
 		UserUUID:  ev.UserUUID,
 		Token:     ev.Token,
+		Email:     ev.Email,
 		Activated: ev.Activated,
+		ExpiresAt: ev.ExpiresAt,
+		CreatedAt: ev.CreatedAt,
 	}
 }
 
@@ -687,7 +1527,103 @@ func decodeEmailVerifcation(ev db.EmailVerification) user.EmailVerification {
 	return user.EmailVerification{
 		UserUUID:  ev.UserUUID,
 		Token:     ev.Token,
+		Email:     ev.Email,
 		Activated: ev.Activated,
+		ExpiresAt: ev.ExpiresAt,
+		CreatedAt: ev.CreatedAt,
+	}
+}
+
+func encodePasswordReset(pr user.PasswordReset) db.PasswordReset {
+	return db.PasswordReset{
+		UserUUID:  pr.UserUUID,
+		Token:     pr.Token,
+		Used:      pr.Used,
+		ExpiresAt: pr.ExpiresAt,
+		CreatedAt: pr.CreatedAt,
+	}
+}
+
+func decodePasswordReset(pr db.PasswordReset) user.PasswordReset {
+	return user.PasswordReset{
+		UserUUID:  pr.UserUUID,
+		Token:     pr.Token,
+		Used:      pr.Used,
+		ExpiresAt: pr.ExpiresAt,
+		CreatedAt: pr.CreatedAt,
+	}
+}
+
+func encodeTwoFactor(tf user.TwoFactor) db.TwoFactor {
+	return db.TwoFactor{
+		UserUUID:  tf.UserUUID,
+		Secret:    tf.Secret,
+		Enabled:   tf.Enabled,
+		LastStep:  tf.LastStep,
+		CreatedAt: tf.CreatedAt,
+	}
+}
+
+func decodeTwoFactor(tf db.TwoFactor) user.TwoFactor {
+	return user.TwoFactor{
+		UserUUID:  tf.UserUUID,
+		Secret:    tf.Secret,
+		Enabled:   tf.Enabled,
+		LastStep:  tf.LastStep,
+		CreatedAt: tf.CreatedAt,
+	}
+}
+
+func encodeRecoveryCode(rc user.RecoveryCode) db.RecoveryCode {
+	return db.RecoveryCode{
+		UserUUID:  rc.UserUUID,
+		Hash:      rc.Hash,
+		Used:      rc.Used,
+		CreatedAt: rc.CreatedAt,
+	}
+}
+
+func encodeLoginThrottle(lt user.LoginThrottle) db.LoginThrottle {
+	return db.LoginThrottle{
+		Scope:         string(lt.Scope),
+		Key:           lt.Key,
+		Failures:      lt.Failures,
+		LastFailureAt: lt.LastFailureAt,
+		LockedUntil:   lt.LockedUntil,
+	}
+}
+
+func decodeLoginThrottle(lt db.LoginThrottle) user.LoginThrottle {
+	return user.LoginThrottle{
+		Scope:         user.ThrottleScope(lt.Scope),
+		Key:           lt.Key,
+		Failures:      lt.Failures,
+		LastFailureAt: lt.LastFailureAt,
+		LockedUntil:   lt.LockedUntil,
+	}
+}
+
+func encodeLockoutEvent(le user.LockoutEvent) db.LockoutEvent {
+	return db.LockoutEvent{
+		UUID:        le.UUID,
+		Scope:       string(le.Scope),
+		Key:         le.Key,
+		UserUUID:    le.UserUUID,
+		IP:          le.IP,
+		LockedUntil: le.LockedUntil,
+		CreatedAt:   le.CreatedAt,
+	}
+}
+
+func decodeLockoutEvent(le db.LockoutEvent) user.LockoutEvent {
+	return user.LockoutEvent{
+		UUID:        le.UUID,
+		Scope:       user.ThrottleScope(le.Scope),
+		Key:         le.Key,
+		UserUUID:    le.UserUUID,
+		IP:          le.IP,
+		LockedUntil: le.LockedUntil,
+		CreatedAt:   le.CreatedAt,
 	}
 }
 
@@ -721,6 +1657,11 @@ func encodeDeposit(d purse.Deposit) db.Deposit {
 		Amount:    d.Amount,
 		Timestamp: d.Timestamp,
 		UserUUID:  d.UserUUID,
+		Status:    string(d.Status),
+		Provider:  d.Provider,
+		Reference: d.Reference,
+		UpdatedAt: d.UpdatedAt,
+		Currency:  string(d.Currency),
 	}
 }
 
@@ -730,36 +1671,290 @@ func decodeDeposit(d db.Deposit) purse.Deposit {
 		Amount:    d.Amount,
 		Timestamp: d.Timestamp,
 		UserUUID:  d.UserUUID,
+		Status:    purse.DepositStatus(d.Status),
+		Provider:  d.Provider,
+		Reference: d.Reference,
+		UpdatedAt: d.UpdatedAt,
+		Currency:  purse.Currency(d.Currency),
 	}
 }
 
 func encodeWithdrawal(wd purse.Withdrawal) db.Withdrawal {
 	return db.Withdrawal{
-		UUID:      wd.UUID,
-		Amount:    wd.Amount,
-		Timestamp: wd.Timestamp,
-		UserUUID:  wd.UserUUID,
+		UUID:             wd.UUID,
+		Amount:           wd.Amount,
+		Timestamp:        wd.Timestamp,
+		UserUUID:         wd.UserUUID,
+		Status:           string(wd.Status),
+		ApprovedBy:       wd.ApprovedBy,
+		SecondApprovedBy: wd.SecondApprovedBy,
+		ResolvedBy:       wd.ResolvedBy,
+		UpdatedAt:        wd.UpdatedAt,
+		Provider:         wd.Provider,
+		Reference:        wd.Reference,
+		Currency:         string(wd.Currency),
 	}
 }
 
 func decodeWithdrawal(wd db.Withdrawal) purse.Withdrawal {
 	return purse.Withdrawal{
-		UUID:      wd.UUID,
-		Amount:    wd.Amount,
-		Timestamp: wd.Timestamp,
-		UserUUID:  wd.UserUUID,
+		UUID:             wd.UUID,
+		Amount:           wd.Amount,
+		Timestamp:        wd.Timestamp,
+		UserUUID:         wd.UserUUID,
+		Status:           purse.WithdrawalStatus(wd.Status),
+		ApprovedBy:       wd.ApprovedBy,
+		SecondApprovedBy: wd.SecondApprovedBy,
+		ResolvedBy:       wd.ResolvedBy,
+		UpdatedAt:        wd.UpdatedAt,
+		Provider:         wd.Provider,
+		Reference:        wd.Reference,
+		Currency:         purse.Currency(wd.Currency),
+	}
+}
+
+func encodeExchangeRate(er purse.ExchangeRate) db.ExchangeRate {
+	return db.ExchangeRate{
+		UUID:      er.UUID,
+		Currency:  string(er.Currency),
+		Rate:      er.Rate,
+		ValidFrom: er.ValidFrom,
+		AdminUUID: er.AdminUUID,
+	}
+}
+
+func decodeExchangeRate(er db.ExchangeRate) purse.ExchangeRate {
+	return purse.ExchangeRate{
+		UUID:      er.UUID,
+		Currency:  purse.Currency(er.Currency),
+		Rate:      er.Rate,
+		ValidFrom: er.ValidFrom,
+		AdminUUID: er.AdminUUID,
+	}
+}
+
+// missingRatesErr fails reports converting amounts of a currency that has
+// no exchange rate, as leaving them out would understate the totals.
+func missingRatesErr(currency string, missing int) error {
+	if missing == 0 {
+		return nil
+	}
+
+	return errors.New("no exchange rate for currency " + currency)
+}
+
+func encodeBonusCampaign(c bonus.Campaign) db.BonusCampaign {
+	return db.BonusCampaign{
+		UUID:               c.UUID,
+		Name:               c.Name,
+		Type:               string(c.Type),
+		Currency:           string(c.Currency),
+		MatchRate:          c.MatchRate,
+		MaxAmount:          c.MaxAmount,
+		WageringMultiplier: c.WageringMultiplier,
+		MinOdds:            c.MinOdds,
+		FreeBetAmount:      c.FreeBetAmount,
+		OddsBoost:          c.OddsBoost,
+		StartsAt:           c.StartsAt,
+		EndsAt:             c.EndsAt,
+		CreatedBy:          c.CreatedBy,
+		CreatedAt:          c.CreatedAt,
+	}
+}
+
+func decodeBonusCampaign(c db.BonusCampaign) bonus.Campaign {
+	return bonus.Campaign{
+		UUID:               c.UUID,
+		Name:               c.Name,
+		Type:               bonus.CampaignType(c.Type),
+		Currency:           purse.Currency(c.Currency),
+		MatchRate:          c.MatchRate,
+		MaxAmount:          c.MaxAmount,
+		WageringMultiplier: c.WageringMultiplier,
+		MinOdds:            c.MinOdds,
+		FreeBetAmount:      c.FreeBetAmount,
+		OddsBoost:          c.OddsBoost,
+		StartsAt:           c.StartsAt,
+		EndsAt:             c.EndsAt,
+		CreatedBy:          c.CreatedBy,
+		CreatedAt:          c.CreatedAt,
+	}
+}
+
+func encodeBonusGrant(g bonus.Grant) db.BonusGrant {
+	return db.BonusGrant{
+		UUID:             g.UUID,
+		CampaignUUID:     g.CampaignUUID,
+		UserUUID:         g.UserUUID,
+		DepositUUID:      g.DepositUUID,
+		Amount:           g.Amount,
+		WageringRequired: g.WageringRequired,
+		Wagered:          g.Wagered,
+		MinOdds:          g.MinOdds,
+		Status:           string(g.Status),
+		CreatedAt:        g.CreatedAt,
+		UpdatedAt:        g.UpdatedAt,
+	}
+}
+
+func decodeBonusGrant(g db.BonusGrant) bonus.Grant {
+	return bonus.Grant{
+		UUID:             g.UUID,
+		CampaignUUID:     g.CampaignUUID,
+		UserUUID:         g.UserUUID,
+		DepositUUID:      g.DepositUUID,
+		Amount:           g.Amount,
+		WageringRequired: g.WageringRequired,
+		Wagered:          g.Wagered,
+		MinOdds:          g.MinOdds,
+		Status:           bonus.GrantStatus(g.Status),
+		CreatedAt:        g.CreatedAt,
+		UpdatedAt:        g.UpdatedAt,
+	}
+}
+
+func encodeBonusToken(t bonus.Token) db.BonusToken {
+	return db.BonusToken{
+		UUID:         t.UUID,
+		CampaignUUID: t.CampaignUUID,
+		UserUUID:     t.UserUUID,
+		Type:         string(t.Type),
+		Amount:       t.Amount,
+		Boost:        t.Boost,
+		Status:       string(t.Status),
+		BetUUID:      t.BetUUID,
+		ExpiresAt:    t.ExpiresAt,
+		CreatedAt:    t.CreatedAt,
+	}
+}
+
+func decodeBonusToken(t db.BonusToken) bonus.Token {
+	return bonus.Token{
+		UUID:         t.UUID,
+		CampaignUUID: t.CampaignUUID,
+		UserUUID:     t.UserUUID,
+		Type:         bonus.CampaignType(t.Type),
+		Amount:       t.Amount,
+		Boost:        t.Boost,
+		Status:       bonus.TokenStatus(t.Status),
+		BetUUID:      t.BetUUID,
+		ExpiresAt:    t.ExpiresAt,
+		CreatedAt:    t.CreatedAt,
+	}
+}
+
+func encodeEntry(e purse.Entry) db.JournalEntry {
+	return db.JournalEntry{
+		UUID:          e.UUID,
+		Type:          string(e.Type),
+		FromAccount:   string(e.From),
+		ToAccount:     string(e.To),
+		Amount:        e.Amount,
+		UserUUID:      e.UserUUID,
+		ReferenceUUID: e.Reference,
+		Timestamp:     e.Timestamp,
+	}
+}
+
+func decodeEntry(e db.JournalEntry) purse.Entry {
+	return purse.Entry{
+		UUID:      e.UUID,
+		Type:      purse.EntryType(e.Type),
+		From:      purse.Account(e.FromAccount),
+		To:        purse.Account(e.ToAccount),
+		Amount:    e.Amount,
+		UserUUID:  e.UserUUID,
+		Reference: e.ReferenceUUID,
+		Timestamp: e.Timestamp,
+	}
+}
+
+func encodeBalanceAdjustment(ba purse.BalanceAdjustment) db.BalanceAdjustment {
+	return db.BalanceAdjustment{
+		UUID:          ba.UUID,
+		UserUUID:      ba.UserUUID,
+		AdminUUID:     ba.AdminUUID,
+		BetUUID:       ba.BetUUID,
+		SelectionUUID: ba.SelectionUUID,
+		Amount:        ba.Amount,
+		Balance:       ba.Balance,
+		Reason:        string(ba.Reason),
+		Timestamp:     ba.Timestamp,
+	}
+}
+
+func decodeBalanceAdjustment(ba db.BalanceAdjustment) purse.BalanceAdjustment {
+	return purse.BalanceAdjustment{
+		UUID:          ba.UUID,
+		UserUUID:      ba.UserUUID,
+		AdminUUID:     ba.AdminUUID,
+		BetUUID:       ba.BetUUID,
+		SelectionUUID: ba.SelectionUUID,
+		Amount:        ba.Amount,
+		Balance:       ba.Balance,
+		Reason:        purse.AdjustmentReason(ba.Reason),
+		Timestamp:     ba.Timestamp,
+	}
+}
+
+func encodeDiscrepancy(d purse.Discrepancy) db.BalanceDiscrepancy {
+	return db.BalanceDiscrepancy{
+		UUID:       d.UUID,
+		UserUUID:   d.UserUUID,
+		Expected:   d.Expected,
+		Actual:     d.Actual,
+		Currency:   string(d.Currency),
+		DetectedAt: d.DetectedAt,
+	}
+}
+
+func decodeDiscrepancy(d db.BalanceDiscrepancy) purse.Discrepancy {
+	return purse.Discrepancy{
+		UUID:       d.UUID,
+		UserUUID:   d.UserUUID,
+		Expected:   d.Expected,
+		Actual:     d.Actual,
+		Currency:   purse.Currency(d.Currency),
+		DetectedAt: d.DetectedAt,
 	}
 }
 
 func encodeEvent(ev bet.Event) db.Event {
+	homeScore, awayScore := encodeScore(ev.FinalScore)
+	halfTimeHomeScore, halfTimeAwayScore := encodeScore(ev.HalfTimeScore)
+
 	return db.Event{
-		UUID:         ev.UUID,
-		Name:         ev.Name,
-		Sport:        string(ev.Sport),
-		BeginsAt:     ev.BeginsAt,
-		Finished:     ev.Finished(),
-		HomeTeamUUID: ev.HomeTeam.UUID,
-		AwayTeamUUID: ev.AwayTeam.UUID,
+		UUID:              ev.UUID,
+		Name:              ev.Name,
+		Sport:             string(ev.Sport),
+		BeginsAt:          ev.BeginsAt,
+		Finished:          ev.Finished(),
+		Status:            string(ev.Status),
+		HomeTeamUUID:      ev.HomeTeam.UUID,
+		AwayTeamUUID:      ev.AwayTeam.UUID,
+		HomeScore:         homeScore,
+		AwayScore:         awayScore,
+		HalfTimeHomeScore: halfTimeHomeScore,
+		HalfTimeAwayScore: halfTimeAwayScore,
+	}
+}
+
+func encodeScore(s *bet.Score) (sql.NullInt64, sql.NullInt64) {
+	if s == nil {
+		return sql.NullInt64{}, sql.NullInt64{}
+	}
+
+	return sql.NullInt64{Int64: int64(s.Home), Valid: true}, sql.NullInt64{Int64: int64(s.Away), Valid: true}
+}
+
+func decodeScore(home, away sql.NullInt64) *bet.Score {
+	if !home.Valid || !away.Valid {
+		return nil
+	}
+
+	return &bet.Score{
+		Home: int(home.Int64),
+		Away: int(away.Int64),
 	}
 }
 
@@ -769,6 +1964,7 @@ func decodeEvent(ev db.Event, home bet.Team, away bet.Team) bet.Event {
 		Name:     ev.Name,
 		Sport:    bet.Sport(ev.Sport),
 		BeginsAt: ev.BeginsAt,
+		Status:   bet.EventStatus(ev.Status),
 		HomeTeam: home,
 		AwayTeam: away,
 	}
@@ -776,28 +1972,228 @@ func decodeEvent(ev db.Event, home bet.Team, away bet.Team) bet.Event {
 
 func encodeSelection(sel bet.EventSelection, eventUUID uuid.UUID) db.EventSelection {
 	return db.EventSelection{
-		UUID:      sel.UUID,
-		EventUUID: eventUUID,
-		Name:      sel.Name,
-		OddsHome:  sel.OddsHome,
-		AutoOdds:  sel.AutoOdds,
-		OddsAway:  sel.OddsAway,
-		Winner:    string(sel.Winner),
+		UUID:       sel.UUID,
+		EventUUID:  eventUUID,
+		Name:       sel.Name,
+		MarketType: string(sel.Type),
+		Period:     string(sel.Period),
+		Status:     string(sel.Status),
+		Line:       sel.Line,
+		AutoOdds:   sel.AutoOdds,
+		Winner:     string(sel.Winner),
+	}
+}
+
+func encodeSelectionOutcomes(sel bet.EventSelection) []db.SelectionOutcome {
+	var oo []db.SelectionOutcome
+
+	for _, o := range sel.Outcomes {
+		oo = append(oo, db.SelectionOutcome{
+			SelectionUUID: sel.UUID,
+			Winner:        string(o.Winner),
+			Odds:          o.Odds,
+		})
 	}
+
+	return oo
 }
 
-func decodeSelection(sel db.EventSelection) bet.EventSelection {
+func decodeSelection(sel db.EventSelection, oo []db.SelectionOutcome) bet.EventSelection {
+	var outcomes []bet.Outcome
+
+	for _, o := range oo {
+		outcomes = append(outcomes, bet.Outcome{
+			Winner: bet.Winner(o.Winner),
+			Odds:   o.Odds,
+		})
+	}
+
 	return bet.EventSelection{
 		UUID:      sel.UUID,
 		Name:      sel.Name,
-		OddsHome:  sel.OddsHome,
-		OddsAway:  sel.OddsAway,
+		Type:      bet.MarketType(sel.MarketType),
+		Period:    bet.Period(sel.Period),
+		Status:    bet.MarketStatus(sel.Status),
+		Line:      sel.Line,
+		Outcomes:  outcomes,
 		EventUUID: sel.EventUUID,
 		AutoOdds:  sel.AutoOdds,
 		Winner:    bet.Winner(sel.Winner),
 	}
 }
 
+func fillSelection(ctx context.Context, db *db.DB, tx db.TX, sel db.EventSelection) (bet.EventSelection, error) {
+	oo, err := db.FetchSelectionOutcomes(ctx, tx, sel.UUID)
+	if err != nil {
+		return bet.EventSelection{}, err
+	}
+
+	return decodeSelection(sel, oo), nil
+}
+
+// updateSelection stores the selection together with the current odds of
+// its outcomes.
+func updateSelection(ctx context.Context, d *db.DB, sel bet.EventSelection) error {
+	tx, err := d.NewTX(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err := saveSelection(ctx, d, tx, sel); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func saveSelection(ctx context.Context, d *db.DB, tx db.TX, sel bet.EventSelection) error {
+	if err := d.UpdateSelection(ctx, tx, encodeSelection(sel, sel.EventUUID)); err != nil {
+		return err
+	}
+
+	for _, o := range encodeSelectionOutcomes(sel) {
+		if err := d.UpdateSelectionOutcome(ctx, tx, o); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// postEntries stores the journal entries together with the users whose
+// balance they change. The stored balance of each user must match the
+// balance derived from the ledger, otherwise nothing is stored.
+func postEntries(ctx context.Context, d *db.DB, tx db.TX, ee []purse.Entry, uu ...user.BetUser) error {
+	for _, e := range ee {
+		if err := e.Validate(); err != nil {
+			return err
+		}
+
+		if err := d.InsertJournalEntry(ctx, tx, encodeEntry(e)); err != nil {
+			return err
+		}
+	}
+
+	for _, u := range uu {
+		ok, err := ledgerMatches(ctx, d, tx, purse.UserAccount(u.UUID), u.Balance)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return errors.New("balance of user " + u.UUID.String() + " does not match the ledger")
+		}
+
+		ok, err = ledgerMatches(ctx, d, tx, purse.BonusAccount(u.UUID), u.BonusBalance)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return errors.New("bonus balance of user " + u.UUID.String() + " does not match the ledger")
+		}
+
+		if err := d.UpdateBetUserBalance(ctx, tx, encodeBetUser(u)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ledgerMatches reports whether the balance of the account derived from
+// the journal equals the given one.
+func ledgerMatches(ctx context.Context, d *db.DB, tx db.TX, acc purse.Account, balance decimal.Decimal) (bool, error) {
+	entries, err := d.FetchJournalEntries(ctx, tx, db.AccountJournalEntries(string(acc)))
+	if err != nil {
+		return false, err
+	}
+
+	decoded := make([]purse.Entry, 0, len(entries))
+
+	for _, e := range entries {
+		decoded = append(decoded, decodeEntry(e))
+	}
+
+	return purse.Balance(acc, decoded).Equal(balance), nil
+}
+
+func encodeBetLimit(l bet.Limits) db.BetLimit {
+	return db.BetLimit{
+		Scope:        string(l.Scope),
+		ScopeUUID:    l.ScopeUUID,
+		MinStake:     l.MinStake,
+		MaxStake:     l.MaxStake,
+		MaxPayout:    l.MaxPayout,
+		MaxLiability: l.MaxLiability,
+	}
+}
+
+func decodeBetLimit(l db.BetLimit) bet.Limits {
+	return bet.Limits{
+		Scope:        bet.LimitScope(l.Scope),
+		ScopeUUID:    l.ScopeUUID,
+		MinStake:     l.MinStake,
+		MaxStake:     l.MaxStake,
+		MaxPayout:    l.MaxPayout,
+		MaxLiability: l.MaxLiability,
+	}
+}
+
+func encodeGamblingLimit(l user.GamblingLimit) db.GamblingLimit {
+	return db.GamblingLimit{
+		UserUUID:      l.UserUUID,
+		Type:          string(l.Type),
+		Period:        string(l.Period),
+		Amount:        l.Amount,
+		Pending:       l.Pending,
+		PendingAmount: l.PendingAmount,
+		PendingAt:     l.PendingAt,
+		UpdatedAt:     l.UpdatedAt,
+	}
+}
+
+func decodeGamblingLimit(l db.GamblingLimit) user.GamblingLimit {
+	return user.GamblingLimit{
+		UserUUID:      l.UserUUID,
+		Type:          user.GamblingLimitType(l.Type),
+		Period:        user.GamblingLimitPeriod(l.Period),
+		Amount:        l.Amount,
+		Pending:       l.Pending,
+		PendingAmount: l.PendingAmount,
+		PendingAt:     l.PendingAt,
+		UpdatedAt:     l.UpdatedAt,
+	}
+}
+
+func encodeGamblingLimitChange(c user.GamblingLimitChange) db.GamblingLimitChange {
+	return db.GamblingLimitChange{
+		UUID:        c.UUID,
+		UserUUID:    c.UserUUID,
+		Type:        string(c.Type),
+		Period:      string(c.Period),
+		OldAmount:   c.OldAmount,
+		NewAmount:   c.NewAmount,
+		EffectiveAt: c.EffectiveAt,
+		CreatedAt:   c.CreatedAt,
+	}
+}
+
+func decodeGamblingLimitChange(c db.GamblingLimitChange) user.GamblingLimitChange {
+	return user.GamblingLimitChange{
+		UUID:        c.UUID,
+		UserUUID:    c.UserUUID,
+		Type:        user.GamblingLimitType(c.Type),
+		Period:      user.GamblingLimitPeriod(c.Period),
+		OldAmount:   c.OldAmount,
+		NewAmount:   c.NewAmount,
+		EffectiveAt: c.EffectiveAt,
+		CreatedAt:   c.CreatedAt,
+	}
+}
+
 func encodeTeam(t bet.Team) db.Team {
 	return db.Team{
 		UUID: t.UUID,
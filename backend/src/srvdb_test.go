@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ramasauskas/ispbet/db"
+	"github.com/ramasauskas/ispbet/purse"
+	"github.com/ramasauskas/ispbet/user"
+	"github.com/rs/zerolog"
+	"github.com/shopspring/decimal"
+)
+
+func newTestDB(t *testing.T) *db.DB {
+	t.Helper()
+
+	d, err := db.NewDB(filepath.Join(t.TempDir(), "test.sql"), zerolog.Nop())
+	if err != nil {
+		t.Fatalf("cannot create database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		d.Close()
+	})
+
+	return d
+}
+
+func newTestAdapter(t *testing.T) *serverDBAdapter {
+	t.Helper()
+
+	return &serverDBAdapter{
+		db:           newTestDB(t),
+		baseCurrency: "EUR",
+	}
+}
+
+// newTestBetUser stores a new bet user with a zero balance.
+func newTestBetUser(t *testing.T, srvDB *serverDBAdapter) user.BetUser {
+	t.Helper()
+
+	u := user.BetUser{
+		User: user.User{
+			UUID:      uuid.New(),
+			Email:     uuid.NewString() + "@example.com",
+			FirstName: "First",
+			LastName:  "Last",
+		},
+		Currency: "EUR",
+	}
+
+	if err := srvDB.InsertBetUser(context.Background(), u); err != nil {
+		t.Fatalf("cannot insert bet user: %v", err)
+	}
+
+	return u
+}
+
+func TestPostEntries(t *testing.T) {
+	ctx := context.Background()
+	srvDB := newTestAdapter(t)
+
+	now := time.Now()
+
+	deposit := func(u user.BetUser) purse.Entry {
+		return purse.DepositEntry(purse.Deposit{
+			UUID:      uuid.New(),
+			UserUUID:  u.UUID,
+			Amount:    decimal.NewFromInt(50),
+			UpdatedAt: now,
+		})
+	}
+
+	bonus := func(u user.BetUser) purse.Entry {
+		return purse.BonusEntry(u.UUID, uuid.New(), decimal.NewFromInt(10), now)
+	}
+
+	stake := func(u user.BetUser) purse.Entry {
+		return purse.StakeEntry(u.UUID, uuid.New(), decimal.NewFromInt(20), now)
+	}
+
+	invalid := func(u user.BetUser) purse.Entry {
+		return purse.Entry{
+			UUID:   uuid.New(),
+			From:   purse.AccountHouse,
+			To:     purse.UserAccount(u.UUID),
+			Amount: decimal.Zero,
+		}
+	}
+
+	tests := map[string]struct {
+		entries []func(user.BetUser) purse.Entry
+		balance string
+		bonus   string
+		err     bool
+	}{
+		"balance matches": {
+			entries: []func(user.BetUser) purse.Entry{deposit},
+			balance: "50",
+			bonus:   "0",
+		},
+		"balance and bonus match": {
+			entries: []func(user.BetUser) purse.Entry{deposit, bonus, stake},
+			balance: "30",
+			bonus:   "10",
+		},
+		"balance does not match": {
+			entries: []func(user.BetUser) purse.Entry{deposit},
+			balance: "60",
+			bonus:   "0",
+			err:     true,
+		},
+		"balance not changed": {
+			entries: []func(user.BetUser) purse.Entry{deposit},
+			balance: "0",
+			bonus:   "0",
+			err:     true,
+		},
+		"bonus does not match": {
+			entries: []func(user.BetUser) purse.Entry{deposit, bonus},
+			balance: "50",
+			bonus:   "0",
+			err:     true,
+		},
+		"invalid entry": {
+			entries: []func(user.BetUser) purse.Entry{invalid},
+			balance: "0",
+			bonus:   "0",
+			err:     true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			u := newTestBetUser(t, srvDB)
+
+			ee := make([]purse.Entry, 0, len(test.entries))
+
+			for _, e := range test.entries {
+				ee = append(ee, e(u))
+			}
+
+			tx, err := srvDB.db.NewTX(ctx)
+			if err != nil {
+				t.Fatalf("cannot begin transaction: %v", err)
+			}
+
+			defer tx.Rollback()
+
+			u.Balance = decimal.RequireFromString(test.balance)
+			u.BonusBalance = decimal.RequireFromString(test.bonus)
+
+			err = postEntries(ctx, srvDB.db, tx, ee, u)
+			if test.err {
+				if err == nil {
+					t.Fatal("want error, got nil")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+
+			if err = tx.Commit(); err != nil {
+				t.Fatalf("cannot commit transaction: %v", err)
+			}
+
+			stored, ok, err := srvDB.FetchBetUserByUUID(ctx, u.UUID)
+			if err != nil || !ok {
+				t.Fatalf("cannot fetch bet user: %v", err)
+			}
+
+			if !stored.Balance.Equal(u.Balance) {
+				t.Errorf("want stored balance %s, got %s", u.Balance, stored.Balance)
+			}
+
+			if !stored.BonusBalance.Equal(u.BonusBalance) {
+				t.Errorf("want stored bonus balance %s, got %s", u.BonusBalance, stored.BonusBalance)
+			}
+		})
+	}
+}
+
+func TestInsertDepositLimits(t *testing.T) {
+	ctx := context.Background()
+	srvDB := newTestAdapter(t)
+
+	u := newTestBetUser(t, srvDB)
+
+	now := time.Now()
+
+	l, err := user.NewGamblingLimit(u.UUID, user.GamblingLimitDeposit, user.GamblingLimitDaily)
+	if err != nil {
+		t.Fatalf("cannot create limit: %v", err)
+	}
+
+	if _, err = l.Change(decimal.NewFromInt(100), time.Hour, now); err != nil {
+		t.Fatalf("cannot change limit: %v", err)
+	}
+
+	if err = srvDB.UpdateGamblingLimit(ctx, l, user.GamblingLimitChange{
+		UUID:        uuid.New(),
+		UserUUID:    u.UUID,
+		Type:        l.Type,
+		Period:      l.Period,
+		NewAmount:   l.Amount,
+		EffectiveAt: now,
+		CreatedAt:   now,
+	}); err != nil {
+		t.Fatalf("cannot store limit: %v", err)
+	}
+
+	tests := []struct {
+		amount string
+		err    bool
+	}{
+		{amount: "60"},
+		{amount: "50", err: true},
+		{amount: "40"},
+		{amount: "1", err: true},
+	}
+
+	for i, test := range tests {
+		dep := purse.Deposit{
+			UUID:      uuid.New(),
+			UserUUID:  u.UUID,
+			Amount:    decimal.RequireFromString(test.amount),
+			Status:    purse.DepositStatusPending,
+			Timestamp: now,
+			UpdatedAt: now,
+			Currency:  u.Currency,
+		}
+
+		err := srvDB.InsertDeposit(ctx, u, dep)
+		if test.err {
+			if !errors.Is(err, user.ErrGamblingLimitReached) {
+				t.Fatalf("deposit %d: want %v, got %v", i, user.ErrGamblingLimitReached, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("deposit %d: want no error, got %v", i, err)
+		}
+	}
+
+	dd, err := srvDB.FetchUserDeposits(ctx, u.UUID)
+	if err != nil {
+		t.Fatalf("cannot fetch deposits: %v", err)
+	}
+
+	if len(dd) != 2 {
+		t.Errorf("want 2 deposits stored, got %d", len(dd))
+	}
+}
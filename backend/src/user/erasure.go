@@ -0,0 +1,28 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Erase anonymizes the personal data of the user, who can no longer log in
+// afterwards. The wallet is kept along with the financial records of the
+// user, so the balance has to be settled first and the bonus balance
+// released into it.
+func (bu *BetUser) Erase() error {
+	if !bu.Balance.IsZero() {
+		return errors.New("balance must be withdrawn before the account is closed")
+	}
+
+	if !bu.BonusBalance.IsZero() {
+		return errors.New("bonus balance must be released before the account is closed")
+	}
+
+	bu.Email = fmt.Sprintf("erased-%s@invalid", bu.UUID)
+	bu.PasswordHash = ""
+	bu.FirstName = ""
+	bu.LastName = ""
+	bu.EmailVerified = false
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestBetUserErase(t *testing.T) {
+	tests := map[string]struct {
+		balance string
+		bonus   string
+		err     bool
+	}{
+		"settled wallet": {
+			balance: "0",
+			bonus:   "0",
+		},
+		"balance left": {
+			balance: "10",
+			bonus:   "0",
+			err:     true,
+		},
+		"bonus balance left": {
+			balance: "0",
+			bonus:   "5",
+			err:     true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			bu := BetUser{
+				User: User{
+					UUID:          uuid.New(),
+					Email:         "user@example.com",
+					PasswordHash:  "hash",
+					FirstName:     "First",
+					LastName:      "Last",
+					EmailVerified: true,
+				},
+				Balance:      decimal.RequireFromString(test.balance),
+				BonusBalance: decimal.RequireFromString(test.bonus),
+			}
+
+			err := bu.Erase()
+			if test.err {
+				if err == nil {
+					t.Fatal("want error, got nil")
+				}
+
+				if bu.Email != "user@example.com" {
+					t.Errorf("want user kept, got email %s", bu.Email)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+
+			if bu.Email != "erased-"+bu.UUID.String()+"@invalid" || bu.PasswordHash != "" || bu.FirstName != "" || bu.LastName != "" {
+				t.Errorf("want personal data erased, got %+v", bu.User)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package user
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ExclusionKind string
+
+const (
+	// ExclusionTimeOut is a short break from betting.
+	ExclusionTimeOut ExclusionKind = "time_out"
+	// ExclusionSelf blocks the user for months, years or for good.
+	ExclusionSelf ExclusionKind = "self_exclusion"
+)
+
+type ExclusionPeriod string
+
+const (
+	ExclusionPeriodDay       ExclusionPeriod = "day"
+	ExclusionPeriodWeek      ExclusionPeriod = "week"
+	ExclusionPeriodMonth     ExclusionPeriod = "month"
+	ExclusionPeriodSixMonths ExclusionPeriod = "six_months"
+	ExclusionPeriodYear      ExclusionPeriod = "year"
+	ExclusionPeriodFiveYears ExclusionPeriod = "five_years"
+	ExclusionPeriodPermanent ExclusionPeriod = "permanent"
+)
+
+// Exclusion blocks the bet user from betting and depositing until it ends.
+// It cannot be lifted or shortened by the user. EndsAt is zero for
+// permanent exclusions.
+type Exclusion struct {
+	UUID      uuid.UUID
+	UserUUID  uuid.UUID
+	Kind      ExclusionKind
+	Period    ExclusionPeriod
+	EndsAt    time.Time
+	CreatedAt time.Time
+}
+
+// NewExclusion starts the exclusion at t. Time-outs last a day, a week or a
+// month, self-exclusions six months, a year, five years or for good.
+func NewExclusion(userUUID uuid.UUID, kind ExclusionKind, period ExclusionPeriod, t time.Time) (Exclusion, error) {
+	var endsAt time.Time
+
+	switch {
+	case kind == ExclusionTimeOut && period == ExclusionPeriodDay:
+		endsAt = t.AddDate(0, 0, 1)
+	case kind == ExclusionTimeOut && period == ExclusionPeriodWeek:
+		endsAt = t.AddDate(0, 0, 7)
+	case kind == ExclusionTimeOut && period == ExclusionPeriodMonth:
+		endsAt = t.AddDate(0, 1, 0)
+	case kind == ExclusionSelf && period == ExclusionPeriodSixMonths:
+		endsAt = t.AddDate(0, 6, 0)
+	case kind == ExclusionSelf && period == ExclusionPeriodYear:
+		endsAt = t.AddDate(1, 0, 0)
+	case kind == ExclusionSelf && period == ExclusionPeriodFiveYears:
+		endsAt = t.AddDate(5, 0, 0)
+	case kind == ExclusionSelf && period == ExclusionPeriodPermanent:
+	default:
+		return Exclusion{}, errors.New("invalid exclusion kind or period")
+	}
+
+	return Exclusion{
+		UUID:      uuid.New(),
+		UserUUID:  userUUID,
+		Kind:      kind,
+		Period:    period,
+		EndsAt:    endsAt,
+		CreatedAt: t,
+	}, nil
+}
+
+// Excluded reports whether the user is excluded at t.
+func (bu BetUser) Excluded(t time.Time) bool {
+	if bu.ExclusionKind == "" {
+		return false
+	}
+
+	return bu.ExcludedUntil.IsZero() || t.Before(bu.ExcludedUntil)
+}
+
+// Exclude applies the exclusion to the user. An exclusion in force can only
+// be extended, so that the user cannot end it early.
+func (bu *BetUser) Exclude(e Exclusion) error {
+	if bu.Excluded(e.CreatedAt) {
+		if bu.ExcludedUntil.IsZero() || (!e.EndsAt.IsZero() && e.EndsAt.Before(bu.ExcludedUntil)) {
+			return errors.New("exclusion in force cannot be shortened")
+		}
+	}
+
+	bu.ExclusionKind = e.Kind
+	bu.ExcludedUntil = e.EndsAt
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestBetUserExclude(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	type exclusion struct {
+		kind   ExclusionKind
+		period ExclusionPeriod
+		// after is how long after now the exclusion is requested.
+		after time.Duration
+		err   bool
+	}
+
+	tests := map[string]struct {
+		exclusions []exclusion
+		// excluded tells whether the user is excluded at each of the
+		// checked times.
+		excluded map[time.Duration]bool
+	}{
+		"not excluded": {
+			excluded: map[time.Duration]bool{0: false},
+		},
+		"time-out": {
+			exclusions: []exclusion{{kind: ExclusionTimeOut, period: ExclusionPeriodDay}},
+			excluded: map[time.Duration]bool{
+				0:                          true,
+				24*time.Hour - time.Second: true,
+				24 * time.Hour:             false,
+			},
+		},
+		"permanent self-exclusion": {
+			exclusions: []exclusion{{kind: ExclusionSelf, period: ExclusionPeriodPermanent}},
+			excluded: map[time.Duration]bool{
+				0:                         true,
+				10 * 365 * 24 * time.Hour: true,
+			},
+		},
+		"time-out extended": {
+			exclusions: []exclusion{
+				{kind: ExclusionTimeOut, period: ExclusionPeriodDay},
+				{kind: ExclusionTimeOut, period: ExclusionPeriodWeek, after: time.Hour},
+			},
+			excluded: map[time.Duration]bool{
+				48 * time.Hour: true,
+			},
+		},
+		"exclusion cannot be shortened": {
+			exclusions: []exclusion{
+				{kind: ExclusionTimeOut, period: ExclusionPeriodWeek},
+				{kind: ExclusionTimeOut, period: ExclusionPeriodDay, after: time.Hour, err: true},
+			},
+			excluded: map[time.Duration]bool{
+				48 * time.Hour: true,
+			},
+		},
+		"permanent exclusion cannot be replaced": {
+			exclusions: []exclusion{
+				{kind: ExclusionSelf, period: ExclusionPeriodPermanent},
+				{kind: ExclusionSelf, period: ExclusionPeriodFiveYears, after: time.Hour, err: true},
+			},
+			excluded: map[time.Duration]bool{
+				6 * 365 * 24 * time.Hour: true,
+			},
+		},
+		"new exclusion after the last one ended": {
+			exclusions: []exclusion{
+				{kind: ExclusionTimeOut, period: ExclusionPeriodWeek},
+				{kind: ExclusionTimeOut, period: ExclusionPeriodDay, after: 8 * 24 * time.Hour},
+			},
+			excluded: map[time.Duration]bool{
+				8*24*time.Hour + time.Hour: true,
+				9*24*time.Hour + time.Hour: false,
+			},
+		},
+		"invalid period for the kind": {
+			exclusions: []exclusion{{kind: ExclusionTimeOut, period: ExclusionPeriodPermanent, err: true}},
+			excluded: map[time.Duration]bool{
+				0: false,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var bu BetUser
+
+			for i, ex := range test.exclusions {
+				e, err := NewExclusion(uuid.New(), ex.kind, ex.period, now.Add(ex.after))
+				if err == nil {
+					err = bu.Exclude(e)
+				}
+
+				if ex.err && err == nil {
+					t.Fatalf("exclusion %d: want error, got nil", i)
+				}
+
+				if !ex.err && err != nil {
+					t.Fatalf("exclusion %d: want no error, got %v", i, err)
+				}
+			}
+
+			for at, excluded := range test.excluded {
+				if got := bu.Excluded(now.Add(at)); got != excluded {
+					t.Errorf("at %s: want excluded %t, got %t", at, excluded, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ramasauskas/ispbet/purse"
+	"github.com/shopspring/decimal"
+)
+
+var ErrGamblingLimitReached = errors.New("gambling limit reached")
+
+type GamblingLimitType string
+
+const (
+	GamblingLimitDeposit GamblingLimitType = "deposit"
+	GamblingLimitLoss    GamblingLimitType = "loss"
+	GamblingLimitWager   GamblingLimitType = "wager"
+)
+
+func (t GamblingLimitType) Validate() error {
+	switch t {
+	case GamblingLimitDeposit, GamblingLimitLoss, GamblingLimitWager:
+		return nil
+	default:
+		return errors.New("invalid limit type")
+	}
+}
+
+type GamblingLimitPeriod string
+
+const (
+	GamblingLimitDaily   GamblingLimitPeriod = "daily"
+	GamblingLimitWeekly  GamblingLimitPeriod = "weekly"
+	GamblingLimitMonthly GamblingLimitPeriod = "monthly"
+)
+
+func (p GamblingLimitPeriod) Validate() error {
+	switch p {
+	case GamblingLimitDaily, GamblingLimitWeekly, GamblingLimitMonthly:
+		return nil
+	default:
+		return errors.New("invalid limit period")
+	}
+}
+
+// Start returns the start of the rolling period that ends at t.
+func (p GamblingLimitPeriod) Start(t time.Time) time.Time {
+	switch p {
+	case GamblingLimitWeekly:
+		return t.AddDate(0, 0, -7)
+	case GamblingLimitMonthly:
+		return t.AddDate(0, -1, 0)
+	default:
+		return t.AddDate(0, 0, -1)
+	}
+}
+
+// GamblingLimit caps the amount the user deposits, loses or wagers over a
+// rolling period. Zero amounts mean no limit. Lowering a limit takes
+// effect at once, while raising or removing it is only pending until the
+// cooling-off period passes.
+type GamblingLimit struct {
+	UserUUID uuid.UUID
+	Type     GamblingLimitType
+	Period   GamblingLimitPeriod
+	Amount   decimal.Decimal
+	// PendingAmount replaces Amount at PendingAt if Pending is set.
+	Pending       bool
+	PendingAmount decimal.Decimal
+	PendingAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func NewGamblingLimit(userUUID uuid.UUID, tp GamblingLimitType, p GamblingLimitPeriod) (GamblingLimit, error) {
+	if err := tp.Validate(); err != nil {
+		return GamblingLimit{}, err
+	}
+
+	if err := p.Validate(); err != nil {
+		return GamblingLimit{}, err
+	}
+
+	return GamblingLimit{
+		UserUUID: userUUID,
+		Type:     tp,
+		Period:   p,
+	}, nil
+}
+
+// Effective returns the limit as it stands at t, with the pending change
+// applied once it is due.
+func (l GamblingLimit) Effective(t time.Time) GamblingLimit {
+	if l.Pending && !t.Before(l.PendingAt) {
+		l.Amount = l.PendingAmount
+		l.Pending = false
+		l.PendingAmount = decimal.Zero
+		l.PendingAt = time.Time{}
+	}
+
+	return l
+}
+
+// Change sets a new amount of the limit and returns the time it takes
+// effect at. A stricter amount replaces a pending change.
+func (l *GamblingLimit) Change(amount decimal.Decimal, coolingOff time.Duration, t time.Time) (time.Time, error) {
+	if amount.IsNegative() {
+		return time.Time{}, errors.New("limit cannot be negative")
+	}
+
+	*l = l.Effective(t)
+	l.UpdatedAt = t
+
+	looser := l.Amount.IsPositive() && (amount.IsZero() || amount.GreaterThan(l.Amount))
+	if !looser {
+		l.Amount = amount
+		l.Pending = false
+		l.PendingAmount = decimal.Zero
+		l.PendingAt = time.Time{}
+
+		return t, nil
+	}
+
+	l.Pending = true
+	l.PendingAmount = amount
+	l.PendingAt = t.Add(coolingOff)
+
+	return l.PendingAt, nil
+}
+
+// Check returns an error if the amount does not fit in the limit at t,
+// given the amount already used in the period.
+func (l GamblingLimit) Check(used, amount decimal.Decimal, t time.Time) error {
+	l = l.Effective(t)
+
+	if l.Amount.IsPositive() && used.Add(amount).GreaterThan(l.Amount) {
+		return fmt.Errorf("%w: %s %s limit is %s", ErrGamblingLimitReached, l.Period, l.Type, l.Amount)
+	}
+
+	return nil
+}
+
+// CheckDepositLimits returns an error if the deposit amount does not fit
+// in the deposit limits at t, given the deposits the user already made.
+func CheckDepositLimits(ll []GamblingLimit, dd []purse.Deposit, amount decimal.Decimal, t time.Time) error {
+	for _, l := range ll {
+		if l.Type != GamblingLimitDeposit {
+			continue
+		}
+
+		if err := l.Check(purse.Deposited(dd, l.Period.Start(t)), amount, t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GamblingLimitChange records a change of a gambling limit requested by
+// the user.
+type GamblingLimitChange struct {
+	UUID     uuid.UUID
+	UserUUID uuid.UUID
+	Type     GamblingLimitType
+	Period   GamblingLimitPeriod
+	// OldAmount is the limit in effect when the change was requested.
+	OldAmount   decimal.Decimal
+	NewAmount   decimal.Decimal
+	EffectiveAt time.Time
+	CreatedAt   time.Time
+}
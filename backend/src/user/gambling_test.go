@@ -0,0 +1,195 @@
+package user
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestGamblingLimitChange(t *testing.T) {
+	var (
+		now        = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		coolingOff = 24 * time.Hour
+	)
+
+	limit := func(amount string) GamblingLimit {
+		return GamblingLimit{
+			Type:   GamblingLimitDeposit,
+			Period: GamblingLimitDaily,
+			Amount: decimal.RequireFromString(amount),
+		}
+	}
+
+	pending := func(amount, pendingAmount string, at time.Time) GamblingLimit {
+		l := limit(amount)
+		l.Pending = true
+		l.PendingAmount = decimal.RequireFromString(pendingAmount)
+		l.PendingAt = at
+
+		return l
+	}
+
+	tests := map[string]struct {
+		limit  GamblingLimit
+		amount string
+		// effective is the time the change takes effect at.
+		effective time.Time
+		// now and later are the amounts in effect right away and once the
+		// cooling-off period has passed.
+		now   string
+		later string
+	}{
+		"setting a first limit": {
+			limit:     limit("0"),
+			amount:    "100",
+			effective: now,
+			now:       "100",
+			later:     "100",
+		},
+		"lowering a limit": {
+			limit:     limit("100"),
+			amount:    "50",
+			effective: now,
+			now:       "50",
+			later:     "50",
+		},
+		"raising a limit": {
+			limit:     limit("100"),
+			amount:    "200",
+			effective: now.Add(coolingOff),
+			now:       "100",
+			later:     "200",
+		},
+		"removing a limit": {
+			limit:     limit("100"),
+			amount:    "0",
+			effective: now.Add(coolingOff),
+			now:       "100",
+			later:     "0",
+		},
+		"lowering a limit with a pending raise": {
+			limit:     pending("100", "200", now.Add(time.Hour)),
+			amount:    "80",
+			effective: now,
+			now:       "80",
+			later:     "80",
+		},
+		"raising a limit again restarts the cooling-off": {
+			limit:     pending("100", "200", now.Add(time.Hour)),
+			amount:    "300",
+			effective: now.Add(coolingOff),
+			now:       "100",
+			later:     "300",
+		},
+		"raising a limit after a due raise": {
+			limit:     pending("100", "200", now.Add(-time.Hour)),
+			amount:    "300",
+			effective: now.Add(coolingOff),
+			now:       "200",
+			later:     "300",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			l := test.limit
+
+			at, err := l.Change(decimal.RequireFromString(test.amount), coolingOff, now)
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+
+			if !at.Equal(test.effective) {
+				t.Errorf("want effective at %s, got %s", test.effective, at)
+			}
+
+			if amount := l.Effective(now).Amount; !amount.Equal(decimal.RequireFromString(test.now)) {
+				t.Errorf("want amount %s now, got %s", test.now, amount)
+			}
+
+			if amount := l.Effective(now.Add(coolingOff)).Amount; !amount.Equal(decimal.RequireFromString(test.later)) {
+				t.Errorf("want amount %s after cooling-off, got %s", test.later, amount)
+			}
+		})
+	}
+}
+
+func TestGamblingLimitChangeNegative(t *testing.T) {
+	l := GamblingLimit{Amount: decimal.NewFromInt(100)}
+
+	if _, err := l.Change(decimal.NewFromInt(-1), time.Hour, time.Now()); err == nil {
+		t.Fatal("want error, got nil")
+	}
+
+	if !l.Amount.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("want amount kept, got %s", l.Amount)
+	}
+}
+
+func TestGamblingLimitCheck(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		limit  GamblingLimit
+		used   string
+		amount string
+		err    bool
+	}{
+		"no limit": {
+			limit:  GamblingLimit{},
+			used:   "1000",
+			amount: "1000",
+		},
+		"within limit": {
+			limit:  GamblingLimit{Amount: decimal.NewFromInt(100)},
+			used:   "60",
+			amount: "40",
+		},
+		"over limit": {
+			limit:  GamblingLimit{Amount: decimal.NewFromInt(100)},
+			used:   "60",
+			amount: "41",
+			err:    true,
+		},
+		"pending raise not due": {
+			limit: GamblingLimit{
+				Amount:        decimal.NewFromInt(100),
+				Pending:       true,
+				PendingAmount: decimal.NewFromInt(200),
+				PendingAt:     now.Add(time.Minute),
+			},
+			used:   "100",
+			amount: "1",
+			err:    true,
+		},
+		"pending raise due": {
+			limit: GamblingLimit{
+				Amount:        decimal.NewFromInt(100),
+				Pending:       true,
+				PendingAmount: decimal.NewFromInt(200),
+				PendingAt:     now,
+			},
+			used:   "100",
+			amount: "100",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.limit.Check(decimal.RequireFromString(test.used), decimal.RequireFromString(test.amount), now)
+			if test.err {
+				if !errors.Is(err, ErrGamblingLimitReached) {
+					t.Fatalf("want %v, got %v", ErrGamblingLimitReached, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package user
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ThrottleScope string
+
+const (
+	ThrottleScopeAccount ThrottleScope = "account"
+	ThrottleScopeIP      ThrottleScope = "ip"
+)
+
+// LoginThrottle counts the failed logins of an account, keyed by email,
+// or of an IP address.
+type LoginThrottle struct {
+	Scope         ThrottleScope
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// ThrottlePolicy makes every failed login double the wait before the next
+// attempt, and locks the account or address out after MaxFailures.
+// Failures older than the lockout are forgotten.
+type ThrottlePolicy struct {
+	MaxFailures     int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutDuration time.Duration
+}
+
+// Wait returns how long until the next login attempt is allowed.
+func (p ThrottlePolicy) Wait(th LoginThrottle, t time.Time) time.Duration {
+	if t.Before(th.LockedUntil) {
+		return th.LockedUntil.Sub(t)
+	}
+
+	if th.Failures == 0 || p.stale(th, t) {
+		return 0
+	}
+
+	next := th.LastFailureAt.Add(p.delay(th.Failures))
+	if t.Before(next) {
+		return next.Sub(t)
+	}
+
+	return 0
+}
+
+// Fail records a failed login, reporting whether it locked the account or
+// address out.
+func (p ThrottlePolicy) Fail(th *LoginThrottle, t time.Time) bool {
+	if p.stale(*th, t) {
+		th.Failures = 0
+	}
+
+	th.Failures++
+	th.LastFailureAt = t
+
+	if th.Failures < p.MaxFailures {
+		return false
+	}
+
+	th.Failures = 0
+	th.LockedUntil = t.Add(p.LockoutDuration)
+
+	return true
+}
+
+func (p ThrottlePolicy) stale(th LoginThrottle, t time.Time) bool {
+	return t.Sub(th.LastFailureAt) >= p.LockoutDuration
+}
+
+func (p ThrottlePolicy) delay(failures int) time.Duration {
+	d := p.BaseDelay
+
+	for i := 1; i < failures && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	return d
+}
+
+// LockoutEvent records an account or address locked out after too many
+// failed logins. UserUUID is set if the email belongs to a user, and IP
+// is the address of the attempt that caused the lockout.
+type LockoutEvent struct {
+	UUID        uuid.UUID
+	Scope       ThrottleScope
+	Key         string
+	UserUUID    uuid.UUID
+	IP          string
+	LockedUntil time.Time
+	CreatedAt   time.Time
+}
@@ -0,0 +1,171 @@
+package user
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	// totpSkew is how many periods before and after the current one are
+	// accepted, to allow for clock drift.
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactor holds the RFC 6238 TOTP secret of a user. It is enabled once
+// the user confirms it with a code from an authenticator app. LastStep is
+// the time step of the last accepted code, so that a code cannot be used
+// twice.
+type TwoFactor struct {
+	UserUUID  uuid.UUID
+	Secret    string
+	Enabled   bool
+	LastStep  int64
+	CreatedAt time.Time
+}
+
+func NewTwoFactor(userUUID uuid.UUID, t time.Time) (TwoFactor, error) {
+	key := make([]byte, 20)
+	if _, err := rand.Read(key); err != nil {
+		return TwoFactor{}, err
+	}
+
+	return TwoFactor{
+		UserUUID:  userUUID,
+		Secret:    totpEncoding.EncodeToString(key),
+		CreatedAt: t,
+	}, nil
+}
+
+// URI returns the otpauth URI authenticator apps enroll the secret with.
+func (tf TwoFactor) URI(issuer, account string) string {
+	v := url.Values{}
+	v.Set("secret", tf.Secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(totpDigits))
+	v.Set("period", fmt.Sprint(totpPeriod))
+
+	label := url.PathEscape(issuer + ":" + account)
+
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
+
+// Verify checks the code against the time steps around t and records the
+// step it matched.
+func (tf *TwoFactor) Verify(code string, t time.Time) error {
+	key, err := totpEncoding.DecodeString(tf.Secret)
+	if err != nil {
+		return err
+	}
+
+	step := t.Unix() / totpPeriod
+
+	for s := step - totpSkew; s <= step+totpSkew; s++ {
+		if s <= tf.LastStep {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare([]byte(totp(key, s)), []byte(code)) == 1 {
+			tf.LastStep = s
+			return nil
+		}
+	}
+
+	return errors.New("invalid code")
+}
+
+// Enable turns two-factor authentication on once the user proves having
+// the secret.
+func (tf *TwoFactor) Enable(code string, t time.Time) error {
+	if tf.Enabled {
+		return errors.New("two-factor authentication already enabled")
+	}
+
+	if err := tf.Verify(code, t); err != nil {
+		return err
+	}
+
+	tf.Enabled = true
+
+	return nil
+}
+
+func totp(key []byte, step int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	off := sum[len(sum)-1] & 0x0f
+	bin := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", totpDigits, bin%mod)
+}
+
+// RecoveryCode replaces a TOTP code once, for users who lost their
+// authenticator. Only the hash of the code is kept.
+type RecoveryCode struct {
+	UserUUID  uuid.UUID
+	Hash      string
+	Used      bool
+	CreatedAt time.Time
+}
+
+// NewRecoveryCodes generates n recovery codes, returning the codes to show
+// the user once and the hashed ones to keep.
+func NewRecoveryCodes(userUUID uuid.UUID, n int, t time.Time) ([]string, []RecoveryCode, error) {
+	codes := make([]string, 0, n)
+	hashed := make([]RecoveryCode, 0, n)
+
+	for i := 0; i < n; i++ {
+		b := make([]byte, 6)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		enc := totpEncoding.EncodeToString(b)
+		code := enc[:5] + "-" + enc[5:]
+
+		codes = append(codes, code)
+		hashed = append(hashed, RecoveryCode{
+			UserUUID:  userUUID,
+			Hash:      HashRecoveryCode(code),
+			CreatedAt: t,
+		})
+	}
+
+	return codes, hashed, nil
+}
+
+// HashRecoveryCode hashes the recovery code as typed by the user. The codes
+// are random, so a plain hash is enough to keep them from being read.
+func HashRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+
+	return hex.EncodeToString(sum[:])
+}
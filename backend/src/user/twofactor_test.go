@@ -0,0 +1,140 @@
+package user
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors.
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTP(t *testing.T) {
+	// The RFC 6238 SHA-1 vectors have 8 digits, 6 digit codes are their
+	// last 6 digits.
+	tests := map[string]struct {
+		unix int64
+		code string
+	}{
+		"59":          {unix: 59, code: "287082"},
+		"1111111109":  {unix: 1111111109, code: "081804"},
+		"1111111111":  {unix: 1111111111, code: "050471"},
+		"1234567890":  {unix: 1234567890, code: "005924"},
+		"2000000000":  {unix: 2000000000, code: "279037"},
+		"20000000000": {unix: 20000000000, code: "353130"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if code := totp([]byte("12345678901234567890"), test.unix/totpPeriod); code != test.code {
+				t.Errorf("want code %s, got %s", test.code, code)
+			}
+
+			tf := TwoFactor{Secret: rfc6238Secret}
+
+			if err := tf.Verify(test.code, time.Unix(test.unix, 0)); err != nil {
+				t.Errorf("want code accepted, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTwoFactorVerify(t *testing.T) {
+	var (
+		// The code of the 1111111111 vector is valid from 1111111110 to
+		// 1111111139.
+		code  = "050471"
+		step  = int64(1111111111 / totpPeriod)
+		start = time.Unix(step*totpPeriod, 0)
+	)
+
+	tests := map[string]struct {
+		at       time.Time
+		lastStep int64
+		// code replaces the code of the vector if set.
+		code string
+		err  bool
+	}{
+		"current step": {
+			at: start,
+		},
+		"end of current step": {
+			at: start.Add(totpPeriod*time.Second - time.Second),
+		},
+		"one step later": {
+			at: start.Add(totpPeriod * time.Second),
+		},
+		"end of one step later": {
+			at: start.Add(2*totpPeriod*time.Second - time.Second),
+		},
+		"one step earlier": {
+			at: start.Add(-totpPeriod * time.Second),
+		},
+		"two steps later": {
+			at:  start.Add(2 * totpPeriod * time.Second),
+			err: true,
+		},
+		"just over one step earlier": {
+			at:  start.Add(-totpPeriod*time.Second - time.Second),
+			err: true,
+		},
+		"code already used": {
+			at:       start,
+			lastStep: step,
+			err:      true,
+		},
+		"later code already used": {
+			at:       start,
+			lastStep: step + 1,
+			err:      true,
+		},
+		"earlier code used": {
+			at:       start,
+			lastStep: step - 1,
+		},
+		"wrong code": {
+			at:   start,
+			code: "050472",
+			err:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tf := TwoFactor{
+				Secret:   rfc6238Secret,
+				LastStep: test.lastStep,
+			}
+
+			c := code
+			if test.code != "" {
+				c = test.code
+			}
+
+			err := tf.Verify(c, test.at)
+			if test.err {
+				if err == nil {
+					t.Fatal("want error, got nil")
+				}
+
+				if tf.LastStep != test.lastStep {
+					t.Errorf("want last step %d kept, got %d", test.lastStep, tf.LastStep)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+
+			if tf.LastStep != step {
+				t.Errorf("want last step %d, got %d", step, tf.LastStep)
+			}
+
+			// The same code cannot be used twice.
+			if err = tf.Verify(c, test.at); err == nil {
+				t.Error("want replayed code rejected, got nil")
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ramasauskas/ispbet/purse"
 	"github.com/shopspring/decimal"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -38,10 +39,64 @@ func (u *User) Login(p string) bool {
 	return true
 }
 
+// ChangePassword sets a new password if the old one is right.
+func (u *User) ChangePassword(old, p string) error {
+	if !u.Login(old) {
+		return errors.New("invalid password")
+	}
+
+	if p == "" {
+		return errors.New("password not provided")
+	}
+
+	return u.SetPassword(p)
+}
+
+func (u *User) Rename(first, last string) error {
+	if first == "" {
+		return errors.New("first name not provided")
+	}
+
+	if last == "" {
+		return errors.New("last name not provided")
+	}
+
+	u.FirstName = first
+	u.LastName = last
+
+	return nil
+}
+
+// ChangeEmail marks the email of the user unverified. The user keeps the
+// current address until the new one is verified.
+func (u *User) ChangeEmail(email string) error {
+	if email == "" {
+		return errors.New("email not provided")
+	}
+
+	if email == u.Email {
+		return errors.New("email not changed")
+	}
+
+	u.EmailVerified = false
+
+	return nil
+}
+
 type BetUser struct {
 	User
 	IdentityVerified bool
 	Balance          decimal.Decimal
+	// Currency of the user's wallet, every amount of the user is in it.
+	Currency purse.Currency
+	// BonusBalance holds promotional funds that cannot be withdrawn or
+	// staked until the wagering requirements of the user's bonuses are met
+	// and they are released to Balance.
+	BonusBalance decimal.Decimal
+	// ExclusionKind and ExcludedUntil hold the latest exclusion of the
+	// user, if any, which is permanent if ExcludedUntil is zero.
+	ExclusionKind ExclusionKind
+	ExcludedUntil time.Time
 }
 
 func (bu BetUser) CreateVerificationRequest(id, portrait string) (IdentityVerification, error) {
@@ -59,10 +114,6 @@ func (bu BetUser) CreateVerificationRequest(id, portrait string) (IdentityVerifi
 	}, nil
 }
 
-func (bu BetUser) CanBet() bool {
-	return bu.IdentityVerified && bu.EmailVerified
-}
-
 func (bu *BetUser) Credit(amount decimal.Decimal) error {
 	if amount.IsNegative() {
 		return errors.New("cannot credit negative amount")
@@ -87,6 +138,47 @@ func (bu *BetUser) Debit(amount decimal.Decimal) error {
 	return nil
 }
 
+// Reverse takes back an amount credited to the user earlier. Unlike Debit
+// it may leave the balance negative, if allowed to.
+func (bu *BetUser) Reverse(amount decimal.Decimal, allowNegative bool) error {
+	if !allowNegative {
+		return bu.Debit(amount)
+	}
+
+	if amount.IsNegative() {
+		return errors.New("cannot debit negative amount")
+	}
+
+	bu.Balance = bu.Balance.Sub(amount)
+
+	return nil
+}
+
+func (bu *BetUser) CreditBonus(amount decimal.Decimal) error {
+	if !amount.IsPositive() {
+		return errors.New("bonus amount must be positive")
+	}
+
+	bu.BonusBalance = bu.BonusBalance.Add(amount)
+
+	return nil
+}
+
+// ReleaseBonus moves the amount of a wagered through bonus, or what is
+// left of it in the bonus balance, to the withdrawable balance and returns
+// the amount moved.
+func (bu *BetUser) ReleaseBonus(amount decimal.Decimal) decimal.Decimal {
+	amount = decimal.Min(amount, bu.BonusBalance)
+	if !amount.IsPositive() {
+		return decimal.Zero
+	}
+
+	bu.Balance = bu.Balance.Add(amount)
+	bu.BonusBalance = bu.BonusBalance.Sub(amount)
+
+	return amount
+}
+
 type AdminLog struct {
 	UUID      uuid.UUID
 	AdminUUID uuid.UUID
@@ -169,7 +261,11 @@ func VerifyBetUserIdentity(u *BetUser, ver *IdentityVerification) error {
 	return nil
 }
 
-func VerifyUserEmail(u *User, ev *EmailVerification) error {
+func VerifyUserEmail(u *User, ev *EmailVerification, t time.Time) error {
+	if ev.UserUUID != u.UUID {
+		return errors.New("token does not belong to user")
+	}
+
 	if u.EmailVerified {
 		return errors.New("user already verified")
 	}
@@ -178,14 +274,65 @@ func VerifyUserEmail(u *User, ev *EmailVerification) error {
 		return errors.New("token already activated")
 	}
 
+	if !t.Before(ev.ExpiresAt) {
+		return errors.New("token expired")
+	}
+
+	if ev.Email != "" {
+		u.Email = ev.Email
+	}
+
 	u.EmailVerified = true
 	ev.Activated = true
 
 	return nil
 }
 
+// EmailVerification is a token sent to Email, the address the user takes
+// once the token is activated before it expires.
 type EmailVerification struct {
 	UserUUID  uuid.UUID
 	Token     string
+	Email     string
 	Activated bool
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// PasswordReset is a single-use token emailed to the user that allows
+// setting a new password until it expires.
+type PasswordReset struct {
+	UserUUID  uuid.UUID
+	Token     string
+	Used      bool
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// ResetPassword sets the new password of the user with the token, using
+// it up.
+func ResetPassword(u *User, pr *PasswordReset, password string, t time.Time) error {
+	if pr.UserUUID != u.UUID {
+		return errors.New("token does not belong to user")
+	}
+
+	if pr.Used {
+		return errors.New("token already used")
+	}
+
+	if !t.Before(pr.ExpiresAt) {
+		return errors.New("token expired")
+	}
+
+	if password == "" {
+		return errors.New("password not provided")
+	}
+
+	if err := u.SetPassword(password); err != nil {
+		return err
+	}
+
+	pr.Used = true
+
+	return nil
 }